@@ -5,6 +5,7 @@ import (
 
 	"github.com/gruntwork-io/go-commons/entrypoint"
 	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/urfave/cli"
 )
 
@@ -38,8 +39,8 @@ func run(cliContext *cli.Context) error {
 		return fmt.Errorf("You must specify at least one instance type")
 	}
 
-	// Create mock testing.T implementation so we can re-use Terratest methods
-	t := MockTestingT{MockName: "pick-instance-type"}
+	// Create a testing.T implementation that records failures so we can re-use Terratest methods
+	t := testing.NewRecordingT("pick-instance-type")
 
 	recommendedInstanceType, err := aws.GetRecommendedInstanceTypeE(t, region, instanceTypes)
 	if err != nil {
@@ -64,19 +65,3 @@ func main() {
 
 	entrypoint.RunApp(app)
 }
-
-// MockTestingT is a mock implementation of testing.TestingT. All the functions are essentially no-ops. This allows us
-// to use Terratest methods outside of a testing context (e.g., in a CLI tool).
-type MockTestingT struct {
-	MockName string
-}
-
-func (t MockTestingT) Fail()                                     {}
-func (t MockTestingT) FailNow()                                  {}
-func (t MockTestingT) Fatal(args ...interface{})                 {}
-func (t MockTestingT) Fatalf(format string, args ...interface{}) {}
-func (t MockTestingT) Error(args ...interface{})                 {}
-func (t MockTestingT) Errorf(format string, args ...interface{}) {}
-func (t MockTestingT) Name() string {
-	return t.MockName
-}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gruntwork-io/go-commons/entrypoint"
+	"github.com/gruntwork-io/terratest/modules/git"
+	"github.com/gruntwork-io/terratest/modules/test-selection"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/urfave/cli"
+)
+
+const CustomUsageText = `Usage: select-tests [OPTIONS]
+
+This tool selects the Go tests to run for the changes in a git repo of Terraform modules since a base ref, so that pull requests only run the tests of the modules they affect. It finds the modules with changed files and, following the local "source" references between modules, all the modules that depend on them, and outputs the tests of those modules. Tests are linked to modules through paths to module folders in the test code, e.g. TerraformDir: "../examples/foo", or through "// terratest:module ../modules/foo" comments on tests or at the top of test files. Tests in packages with changed Go files, or that depend on them according to go list, are selected too, and all the tests are selected if a go.mod or go.sum file changed or go list fails.
+
+Options:
+
+  --base-ref REF     The ref to compare with. Changes since the commit HEAD and REF have in common are selected,
+                     including uncommitted changes. Default: origin/master.
+  --dir DIR          A folder in the git repo. Default: the current folder.
+  --format FORMAT    The output format, one of:
+                       run       One line per package with the package and the pattern for its -run flag,
+                                 e.g. "./test ^(TestFoo|TestBar)$". This is the default.
+                       packages  One line per package, e.g. "./test".
+                       tests     One line per test with the package and the test name, e.g. "./test TestFoo".
+                       json      A JSON array of the tests with their packages and the modules they test.
+  --help             Show this help text and exit.
+
+Example:
+
+  select-tests --base-ref origin/master | while read -r pkg pattern; do go test "$pkg" -run "$pattern"; done
+`
+
+// selectedTest is the JSON output for a test, with slash separated paths on all platforms.
+type selectedTest struct {
+	Package string
+	Name    string
+	Modules []string
+}
+
+func run(cliContext *cli.Context) error {
+	baseRef := cliContext.String("base-ref")
+	format := cliContext.String("format")
+
+	// Create a testing.T implementation that records failures so we can re-use Terratest methods
+	t := testing.NewRecordingT("select-tests")
+
+	root, err := git.GetRepoRootE(t, cliContext.String("dir"))
+	if err != nil {
+		return err
+	}
+	mergeBase, err := git.GetMergeBaseE(t, root, baseRef, "HEAD")
+	if err != nil {
+		return err
+	}
+	changedFiles, err := git.ChangedFilesSinceE(t, root, mergeBase)
+	if err != nil {
+		return err
+	}
+
+	tests, err := test_selection.SelectTests(root, changedFiles)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "run":
+		for _, packageTests := range test_selection.GroupByPackage(tests) {
+			fmt.Println(packagePath(packageTests.Package), packageTests.RunPattern())
+		}
+	case "packages":
+		for _, packageTests := range test_selection.GroupByPackage(tests) {
+			fmt.Println(packagePath(packageTests.Package))
+		}
+	case "tests":
+		for _, test := range tests {
+			fmt.Println(packagePath(test.Package), test.Name)
+		}
+	case "json":
+		output := []selectedTest{}
+		for _, test := range tests {
+			modules := []string{}
+			for _, module := range test.Modules {
+				modules = append(modules, filepath.ToSlash(module))
+			}
+			output = append(output, selectedTest{Package: packagePath(test.Package), Name: test.Name, Modules: modules})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	default:
+		return fmt.Errorf("Unknown format %q. Must be one of: run, packages, tests, json", format)
+	}
+	return nil
+}
+
+// packagePath returns the path of the given package folder, relative to the root of the repo, as go test expects it.
+func packagePath(folder string) string {
+	if folder == "." {
+		return "."
+	}
+	return "./" + filepath.ToSlash(folder)
+}
+
+func main() {
+	app := entrypoint.NewApp()
+	cli.AppHelpTemplate = CustomUsageText
+	entrypoint.HelpTextLineWidth = 120
+
+	app.Name = "select-tests"
+	app.Author = "Gruntwork <www.gruntwork.io>"
+	app.Description = `This tool selects the Go tests to run for the changes in a git repo of Terraform modules since a base ref, so that pull requests only run the tests of the modules they affect.`
+	app.Action = run
+
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "base-ref",
+			Value: "origin/master",
+			Usage: "The `REF` to compare with.",
+		},
+		cli.StringFlag{
+			Name:  "dir",
+			Value: ".",
+			Usage: "A `DIR` in the git repo.",
+		},
+		cli.StringFlag{
+			Name:  "format",
+			Value: "run",
+			Usage: "The output `FORMAT`, one of: run, packages, tests, json.",
+		},
+	}
+
+	entrypoint.RunApp(app)
+}
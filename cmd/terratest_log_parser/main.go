@@ -1,15 +1,19 @@
 // A CLI command to parse parallel terratest output to produce test summaries and break out interleaved test output.
 //
-// This command will take as input a terratest log output from either stdin (through a pipe) or from a file, and output
-// to a directory the following files:
+// This command will take as input a terratest log output, either plain `go test -v` output or a `go test -json` event
+// stream, from either stdin (through a pipe) or from a file, and output to a directory the following files:
 // outputDir
 //   |-> TEST_NAME.log
 //   |-> summary.log
 //   |-> report.xml
+//   |-> summary.json
+//   |-> summary.html
 // where:
 // - `TEST_NAME.log` is a log for each test run that only includes the relevant logs for that test.
 // - `summary.log` is a summary of all the tests in the suite, including PASS/FAIL information.
-// - `report.xml` is the test summary in junit XML format to be consumed by a CI engine.
+// - `report.xml` is the test summary in junit XML format to be consumed by a CI engine, with the log of each test
+//   attached as its system-out.
+// - `summary.json` and `summary.html` contain the test counts, durations, failures and slowest tests of the run.
 //
 // Certain tradeoffs were made in the decision to implement this functionality as a separate parsing command, as opposed
 // to being built into the logger module as part of `Logf`. Specifically, this implementation avoids the difficulties of
@@ -56,7 +60,7 @@ A tool for parsing parallel terratest output to produce a test summary and to br
 Options:
    --log-level LEVEL  Set the log level to LEVEL. Must be one of: [panic fatal error warning info debug]
                       (default: "info")
-   --testlog value    Path to file containing test log. If unset will use stdin.
+   --testlog value    Path to file containing test log, either go test -v or go test -json output. If unset will use stdin.
    --outputdir value  Path to directory to output test output to. If unset will use the current directory.
    --help, -h         show help
 `
@@ -112,7 +116,7 @@ func main() {
 	logInputFlag := cli.StringFlag{
 		Name:  "testlog, l",
 		Value: "",
-		Usage: "Path to file containing test log. If unset will use stdin.",
+		Usage: "Path to file containing test log, either go test -v or go test -json output. If unset will use stdin.",
 	}
 	outputDirFlag := cli.StringFlag{
 		Name:  "outputdir, o",
@@ -0,0 +1,255 @@
+package collections
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ListDifference is the difference between an expected and an actual list, ignoring the order of the items.
+type ListDifference[T any] struct {
+	// Missing are the items that are in the expected list, but not in the actual list.
+	Missing []T
+	// Extra are the items that are in the actual list, but not in the expected list.
+	Extra []T
+}
+
+// Empty returns true if the lists have the same items.
+func (diff ListDifference[T]) Empty() bool {
+	return len(diff.Missing) == 0 && len(diff.Extra) == 0
+}
+
+// String returns the difference with a line for each missing item, starting with "-", and each extra item, starting
+// with "+", or an empty string if there is no difference.
+func (diff ListDifference[T]) String() string {
+	var lines []string
+	for _, item := range diff.Missing {
+		lines = append(lines, "- "+formatDiffValue(item))
+	}
+	for _, item := range diff.Extra {
+		lines = append(lines, "+ "+formatDiffValue(item))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValueChange is a value that is different in the expected and the actual map.
+type ValueChange[V any] struct {
+	Expected V
+	Actual   V
+}
+
+// MapDifference is the difference between an expected and an actual map.
+type MapDifference[K comparable, V any] struct {
+	// Missing are the entries whose keys are in the expected map, but not in the actual map.
+	Missing map[K]V
+	// Extra are the entries whose keys are in the actual map, but not in the expected map.
+	Extra map[K]V
+	// Changed are the entries whose keys are in both maps, but with different values.
+	Changed map[K]ValueChange[V]
+}
+
+// Empty returns true if the maps have the same entries.
+func (diff MapDifference[K, V]) Empty() bool {
+	return len(diff.Missing) == 0 && len(diff.Extra) == 0 && len(diff.Changed) == 0
+}
+
+// String returns the difference with a line for each missing entry, starting with "-", each extra entry, starting
+// with "+", and each changed entry, starting with "~", sorted by key, or an empty string if there is no difference.
+func (diff MapDifference[K, V]) String() string {
+	var lines []string
+	for _, key := range sortedKeys(diff.Missing) {
+		lines = append(lines, fmt.Sprintf("- %s: %s", formatDiffValue(key), formatDiffValue(diff.Missing[key])))
+	}
+	for _, key := range sortedKeys(diff.Extra) {
+		lines = append(lines, fmt.Sprintf("+ %s: %s", formatDiffValue(key), formatDiffValue(diff.Extra[key])))
+	}
+	for _, key := range sortedKeys(diff.Changed) {
+		change := diff.Changed[key]
+		lines = append(lines, fmt.Sprintf("~ %s: expected %s, actual %s", formatDiffValue(key), formatDiffValue(change.Expected), formatDiffValue(change.Actual)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ListDiff returns the items that are missing from or extra in the actual list compared to the expected list,
+// ignoring the order of the items. Duplicates count, so ["a", "a"] and ["a"] differ by one "a".
+func ListDiff[T comparable](expected []T, actual []T) ListDifference[T] {
+	counts := map[T]int{}
+	for _, item := range actual {
+		counts[item]++
+	}
+
+	diff := ListDifference[T]{Missing: []T{}, Extra: []T{}}
+	for _, item := range expected {
+		if counts[item] > 0 {
+			counts[item]--
+		} else {
+			diff.Missing = append(diff.Missing, item)
+		}
+	}
+	for _, item := range actual {
+		if counts[item] > 0 {
+			counts[item]--
+			diff.Extra = append(diff.Extra, item)
+		}
+	}
+	return diff
+}
+
+// MapDiff returns the entries that are missing from, extra in, or changed in the actual map compared to the expected
+// map. Values are compared with reflect.DeepEqual.
+func MapDiff[K comparable, V any](expected map[K]V, actual map[K]V) MapDifference[K, V] {
+	diff := MapDifference[K, V]{Missing: map[K]V{}, Extra: map[K]V{}, Changed: map[K]ValueChange[V]{}}
+	for key, expectedValue := range expected {
+		actualValue, ok := actual[key]
+		switch {
+		case !ok:
+			diff.Missing[key] = expectedValue
+		case !reflect.DeepEqual(expectedValue, actualValue):
+			diff.Changed[key] = ValueChange[V]{Expected: expectedValue, Actual: actualValue}
+		}
+	}
+	for key, actualValue := range actual {
+		if _, ok := expected[key]; !ok {
+			diff.Extra[key] = actualValue
+		}
+	}
+	return diff
+}
+
+// KeyedListDiff compares the items of the expected and the actual list that have the same key, as returned by the
+// given function, e.g. subnets by ID or security group rules by port, and returns the items that are missing from,
+// extra in, or changed in the actual list. If several items in a list have the same key, the last one is used.
+func KeyedListDiff[T any, K comparable](expected []T, actual []T, key func(item T) K) MapDifference[K, T] {
+	return MapDiff(keyBy(expected, key), keyBy(actual, key))
+}
+
+func keyBy[T any, K comparable](list []T, key func(item T) K) map[K]T {
+	out := make(map[K]T, len(list))
+	for _, item := range list {
+		out[key(item)] = item
+	}
+	return out
+}
+
+// Diff returns a human readable difference between the expected and the actual value, or an empty string if they are
+// equal according to reflect.DeepEqual. Lists (slices and arrays) are compared ignoring the order of their items, as
+// with ListDiff, and maps are compared by key, as with MapDiff. Other values are shown in full.
+func Diff(expected interface{}, actual interface{}) string {
+	expectedValue := reflect.ValueOf(expected)
+	actualValue := reflect.ValueOf(actual)
+
+	switch {
+	case isList(expectedValue) && isList(actualValue):
+		return reflectListDiff(expectedValue, actualValue).String()
+	case expectedValue.Kind() == reflect.Map && actualValue.Kind() == reflect.Map:
+		return reflectMapDiff(expectedValue, actualValue).String()
+	case reflect.DeepEqual(expected, actual):
+		return ""
+	}
+	return fmt.Sprintf("- %s\n+ %s", formatDiffValue(expected), formatDiffValue(actual))
+}
+
+// AssertNoDiff checks that the expected and the actual value are equal and, if they are not, marks the test as failed
+// with their difference as returned by Diff. Returns true if they are equal.
+func AssertNoDiff(t testing.TestingT, expected interface{}, actual interface{}) bool {
+	if diff := Diff(expected, actual); diff != "" {
+		t.Errorf("Expected and actual values differ (-expected +actual):\n%s", diff)
+		return false
+	}
+	return true
+}
+
+func isList(value reflect.Value) bool {
+	return value.Kind() == reflect.Slice || value.Kind() == reflect.Array
+}
+
+// reflectListDiff is ListDiff for lists of any type, including those with items that are not comparable.
+func reflectListDiff(expected reflect.Value, actual reflect.Value) ListDifference[interface{}] {
+	matched := make([]bool, actual.Len())
+
+	diff := ListDifference[interface{}]{}
+	for i := 0; i < expected.Len(); i++ {
+		item := expected.Index(i).Interface()
+		found := false
+		for j := 0; j < actual.Len(); j++ {
+			if !matched[j] && reflect.DeepEqual(item, actual.Index(j).Interface()) {
+				matched[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff.Missing = append(diff.Missing, item)
+		}
+	}
+	for j := 0; j < actual.Len(); j++ {
+		if !matched[j] {
+			diff.Extra = append(diff.Extra, actual.Index(j).Interface())
+		}
+	}
+	return diff
+}
+
+// reflectMapDiff is MapDiff for maps of any type, so that maps with different value types, such as map[string]string
+// and map[string]interface{}, can be compared.
+func reflectMapDiff(expected reflect.Value, actual reflect.Value) MapDifference[formattedKey, interface{}] {
+	diff := MapDifference[formattedKey, interface{}]{Missing: map[formattedKey]interface{}{}, Extra: map[formattedKey]interface{}{}, Changed: map[formattedKey]ValueChange[interface{}]{}}
+
+	iter := expected.MapRange()
+	for iter.Next() {
+		key := formattedKey(formatDiffValue(iter.Key().Interface()))
+		actualValue := mapIndex(actual, iter.Key())
+		switch {
+		case !actualValue.IsValid():
+			diff.Missing[key] = iter.Value().Interface()
+		case !reflect.DeepEqual(iter.Value().Interface(), actualValue.Interface()):
+			diff.Changed[key] = ValueChange[interface{}]{Expected: iter.Value().Interface(), Actual: actualValue.Interface()}
+		}
+	}
+
+	iter = actual.MapRange()
+	for iter.Next() {
+		if !mapIndex(expected, iter.Key()).IsValid() {
+			diff.Extra[formattedKey(formatDiffValue(iter.Key().Interface()))] = iter.Value().Interface()
+		}
+	}
+	return diff
+}
+
+// mapIndex returns the value of the given key in the given map, converting the key to the key type of the map if
+// needed, or the zero reflect.Value if the map doesn't contain the key.
+func mapIndex(m reflect.Value, key reflect.Value) reflect.Value {
+	keyType := m.Type().Key()
+	if key.Type() != keyType {
+		if !key.Type().ConvertibleTo(keyType) {
+			return reflect.Value{}
+		}
+		key = key.Convert(keyType)
+	}
+	return m.MapIndex(key)
+}
+
+// sortedKeys returns the keys of the given map sorted by their formatted value.
+func sortedKeys[K comparable, V any](m map[K]V) []K {
+	keys := Keys(m)
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	return keys
+}
+
+// formattedKey is a map key that is already formatted for a diff.
+type formattedKey string
+
+// formatDiffValue formats a value for a diff, quoting strings so that empty strings and spaces are visible.
+func formatDiffValue(value interface{}) string {
+	if key, ok := value.(formattedKey); ok {
+		return string(key)
+	}
+	if str, ok := value.(string); ok {
+		return strconv.Quote(str)
+	}
+	return fmt.Sprintf("%+v", value)
+}
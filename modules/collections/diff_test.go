@@ -0,0 +1,107 @@
+package collections
+
+import (
+	"testing"
+
+	tftesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDiff(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		description string
+		expected    []string
+		actual      []string
+		missing     []string
+		extra       []string
+	}{
+		{"empty lists", []string{}, []string{}, []string{}, []string{}},
+		{"same items in a different order", []string{"a", "b"}, []string{"b", "a"}, []string{}, []string{}},
+		{"missing and extra items", []string{"a", "b", "c"}, []string{"c", "d"}, []string{"a", "b"}, []string{"d"}},
+		{"duplicates", []string{"a", "a", "b"}, []string{"a", "b", "b"}, []string{"a"}, []string{"b"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.description, func(t *testing.T) {
+			t.Parallel()
+			diff := ListDiff(testCase.expected, testCase.actual)
+			assert.Equal(t, testCase.missing, diff.Missing)
+			assert.Equal(t, testCase.extra, diff.Extra)
+			assert.Equal(t, len(testCase.missing) == 0 && len(testCase.extra) == 0, diff.Empty())
+		})
+	}
+}
+
+func TestMapDiff(t *testing.T) {
+	t.Parallel()
+
+	diff := MapDiff(
+		map[string]interface{}{"region": "us-east-1", "count": 2, "tags": map[string]string{"env": "test"}, "name": "web"},
+		map[string]interface{}{"region": "us-east-1", "count": 3, "tags": map[string]string{"env": "test"}, "zone": "a"},
+	)
+	assert.Equal(t, map[string]interface{}{"name": "web"}, diff.Missing)
+	assert.Equal(t, map[string]interface{}{"zone": "a"}, diff.Extra)
+	assert.Equal(t, map[string]ValueChange[interface{}]{"count": {Expected: 2, Actual: 3}}, diff.Changed)
+	assert.Equal(t, "- \"name\": \"web\"\n+ \"zone\": \"a\"\n~ \"count\": expected 2, actual 3", diff.String())
+
+	assert.True(t, MapDiff(map[int]string{1: "a"}, map[int]string{1: "a"}).Empty())
+}
+
+type securityRule struct {
+	Port     int
+	Protocol string
+	Cidr     string
+}
+
+func TestKeyedListDiff(t *testing.T) {
+	t.Parallel()
+
+	expected := []securityRule{{22, "tcp", "10.0.0.0/8"}, {443, "tcp", "0.0.0.0/0"}}
+	actual := []securityRule{{443, "tcp", "0.0.0.0/0"}, {22, "tcp", "0.0.0.0/0"}, {53, "udp", "10.0.0.0/8"}}
+
+	diff := KeyedListDiff(expected, actual, func(rule securityRule) int { return rule.Port })
+	assert.Empty(t, diff.Missing)
+	assert.Equal(t, map[int]securityRule{53: {53, "udp", "10.0.0.0/8"}}, diff.Extra)
+	assert.Equal(t, "+ 53: {Port:53 Protocol:udp Cidr:10.0.0.0/8}\n~ 22: expected {Port:22 Protocol:tcp Cidr:10.0.0.0/8}, actual {Port:22 Protocol:tcp Cidr:0.0.0.0/0}", diff.String())
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		description string
+		expected    interface{}
+		actual      interface{}
+		diff        string
+	}{
+		{"equal lists", []string{"a", "b"}, []string{"b", "a"}, ""},
+		{"lists", []string{"subnet-1", "subnet-2"}, []string{"subnet-2", "subnet-3"}, "- \"subnet-1\"\n+ \"subnet-3\""},
+		{"lists of maps", []map[string]int{{"a": 1}}, []map[string]int{{"a": 2}}, "- map[a:1]\n+ map[a:2]"},
+		{"maps with different value types", map[string]string{"a": "1", "b": "2"}, map[string]interface{}{"a": "1", "c": 3}, "- \"b\": \"2\"\n+ \"c\": 3"},
+		{"equal values", 1, 1, ""},
+		{"values", "foo", "bar", "- \"foo\"\n+ \"bar\""},
+		{"nil", nil, nil, ""},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.description, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, testCase.diff, Diff(testCase.expected, testCase.actual))
+		})
+	}
+}
+
+func TestAssertNoDiff(t *testing.T) {
+	t.Parallel()
+
+	recording := tftesting.NewRecordingT("assert-no-diff")
+	assert.True(t, AssertNoDiff(recording, []int{1, 2}, []int{2, 1}))
+	assert.False(t, recording.Failed())
+
+	assert.False(t, AssertNoDiff(recording, []int{1, 2}, []int{2, 3}))
+	assert.Equal(t, []string{"Expected and actual values differ (-expected +actual):\n- 1\n+ 3"}, recording.Messages())
+}
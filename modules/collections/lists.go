@@ -3,38 +3,107 @@ package collections
 // ListIntersection returns all the items in both list1 and list2. Note that this will dedup the items so that the
 // output is more predictable. Otherwise, the end list depends on which list was used as the base.
 func ListIntersection(list1 []string, list2 []string) []string {
-	out := []string{}
+	return Intersection(list1, list2)
+}
+
+// ListSubtract removes all the items in list2 from list1.
+func ListSubtract(list1 []string, list2 []string) []string {
+	return Subtract(list1, list2)
+}
+
+// ListContains returns true if the given list of strings (haystack) contains the given string (needle).
+func ListContains(haystack []string, needle string) bool {
+	return Contains(haystack, needle)
+}
 
+// Intersection returns all the items in both list1 and list2, in the order of list1. Note that this will dedup the
+// items so that the output is more predictable. Otherwise, the end list depends on which list was used as the base.
+func Intersection[T comparable](list1 []T, list2 []T) []T {
+	inList2 := toSet(list2)
+	seen := map[T]bool{}
+
+	out := []T{}
 	// Only need to iterate list1, because we want items in both lists, not union.
 	for _, item := range list1 {
-		if ListContains(list2, item) && !ListContains(out, item) {
+		if inList2[item] && !seen[item] {
+			seen[item] = true
 			out = append(out, item)
 		}
 	}
-
 	return out
 }
 
-// ListSubtract removes all the items in list2 from list1.
-func ListSubtract(list1 []string, list2 []string) []string {
-	out := []string{}
+// Union returns all the items in list1 or list2, in the order they first appear in list1 and then list2, without
+// duplicates.
+func Union[T comparable](list1 []T, list2 []T) []T {
+	return Unique(append(append([]T{}, list1...), list2...))
+}
 
+// Subtract removes all the items in list2 from list1.
+func Subtract[T comparable](list1 []T, list2 []T) []T {
+	inList2 := toSet(list2)
+
+	out := []T{}
 	for _, item := range list1 {
-		if !ListContains(list2, item) {
+		if !inList2[item] {
 			out = append(out, item)
 		}
 	}
-
 	return out
 }
 
-// ListContains returns true if the given list of strings (haystack) contains the given string (needle).
-func ListContains(haystack []string, needle string) bool {
-	for _, str := range haystack {
-		if needle == str {
-			return true
+// Contains returns true if the given list (haystack) contains the given item (needle).
+func Contains[T comparable](haystack []T, needle T) bool {
+	return IndexOf(haystack, needle) >= 0
+}
+
+// IndexOf returns the index of the first occurrence of the given item (needle) in the given list (haystack), or -1 if
+// the list doesn't contain the item.
+func IndexOf[T comparable](haystack []T, needle T) int {
+	for index, item := range haystack {
+		if item == needle {
+			return index
 		}
 	}
+	return -1
+}
 
-	return false
+// Unique returns the items of the given list without duplicates, in the order they first appear.
+func Unique[T comparable](list []T) []T {
+	seen := map[T]bool{}
+
+	out := []T{}
+	for _, item := range list {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Keys returns the keys of the given map, in no particular order.
+func Keys[K comparable, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Values returns the values of the given map, in no particular order.
+func Values[K comparable, V any](m map[K]V) []V {
+	values := make([]V, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
+
+func toSet[T comparable](list []T) map[T]bool {
+	set := make(map[T]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+	return set
 }
@@ -87,3 +87,18 @@ func TestIntersection(t *testing.T) {
 		})
 	}
 }
+
+func TestGenericListFunctions(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []int{2, 3}, Intersection([]int{1, 2, 3, 2}, []int{3, 2, 4}))
+	assert.Equal(t, []int{1}, Subtract([]int{1, 2, 3}, []int{2, 3}))
+	assert.Equal(t, []int{1, 2, 3, 4}, Union([]int{1, 2, 1}, []int{3, 2, 4}))
+	assert.Equal(t, []string{"b", "a"}, Unique([]string{"b", "a", "b"}))
+	assert.True(t, Contains([]float64{1.5, 2.5}, 2.5))
+	assert.False(t, Contains([]float64{1.5, 2.5}, 3))
+	assert.Equal(t, 1, IndexOf([]string{"a", "b", "b"}, "b"))
+	assert.Equal(t, -1, IndexOf([]string{}, "b"))
+	assert.ElementsMatch(t, []string{"a", "b"}, Keys(map[string]int{"a": 1, "b": 2}))
+	assert.ElementsMatch(t, []int{1, 2}, Values(map[string]int{"a": 1, "b": 2}))
+}
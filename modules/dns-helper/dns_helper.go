@@ -101,6 +101,29 @@ func DNSLookupAuthoritativeE(t testing.TestingT, query DNSQuery, resolvers []str
 	return DNSLookupE(t, query, nameservers)
 }
 
+// DNSLookupAuthoritativeWithTTL gets authoritative answers for the specified record and type, with their TTL.
+// If resolvers are defined, uses them instead of the default system ones to find the authoritative nameservers.
+// Fails on any error from DNSLookupAuthoritativeWithTTLE.
+func DNSLookupAuthoritativeWithTTL(t testing.TestingT, query DNSQuery, resolvers []string) DNSAnswersWithTTL {
+	res, err := DNSLookupAuthoritativeWithTTLE(t, query, resolvers)
+	require.NoError(t, err)
+	return res
+}
+
+// DNSLookupAuthoritativeWithTTLE gets authoritative answers for the specified record and type, with their TTL.
+// If resolvers are defined, uses them instead of the default system ones to find the authoritative nameservers.
+// Returns NotFoundError when no answer found in any authoritative nameserver.
+// Returns any underlying error from individual lookups.
+func DNSLookupAuthoritativeWithTTLE(t testing.TestingT, query DNSQuery, resolvers []string) (DNSAnswersWithTTL, error) {
+	nameservers, err := DNSFindNameserversE(t, query.Name, resolvers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return DNSLookupWithTTLE(t, query, nameservers)
+}
+
 // DNSLookupAuthoritativeWithRetry repeatedly gets authoritative answers for the specified record and type
 // until ANY of the authoritative nameservers found replies with non-empty answer matching the expectedAnswers,
 // or until max retries has been exceeded.
@@ -117,14 +140,15 @@ func DNSLookupAuthoritativeWithRetry(t testing.TestingT, query DNSQuery, resolve
 // or until max retries has been exceeded.
 // If resolvers are defined, uses them instead of the default system ones to find the authoritative nameservers.
 func DNSLookupAuthoritativeWithRetryE(t testing.TestingT, query DNSQuery, resolvers []string, maxRetries int, sleepBetweenRetries time.Duration) (DNSAnswers, error) {
-	res, err := retry.DoWithRetryInterfaceE(
-		t, fmt.Sprintf("DNSLookupAuthoritativeE %s record for %s using authoritative nameservers", query.Type, query.Name),
-		maxRetries, sleepBetweenRetries,
-		func() (interface{}, error) {
-			return DNSLookupAuthoritativeE(t, query, resolvers)
-		})
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("DNSLookupAuthoritativeE %s record for %s using authoritative nameservers", query.Type, query.Name),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
 
-	return res.(DNSAnswers), err
+	return retry.DoE(t, policy, func() (DNSAnswers, error) {
+		return DNSLookupAuthoritativeE(t, query, resolvers)
+	})
 }
 
 // DNSLookupAuthoritativeAll gets authoritative answers for the specified record and type.
@@ -184,14 +208,15 @@ func DNSLookupAuthoritativeAllWithRetry(t testing.TestingT, query DNSQuery, reso
 // until ALL authoritative nameservers reply with the exact same non-empty answers or until max retries has been exceeded.
 // If defined, uses the given resolvers instead of the default system ones to find the authoritative nameservers.
 func DNSLookupAuthoritativeAllWithRetryE(t testing.TestingT, query DNSQuery, resolvers []string, maxRetries int, sleepBetweenRetries time.Duration) (DNSAnswers, error) {
-	res, err := retry.DoWithRetryInterfaceE(
-		t, fmt.Sprintf("DNSLookupAuthoritativeAllE %s record for %s using authoritative nameservers", query.Type, query.Name),
-		maxRetries, sleepBetweenRetries,
-		func() (interface{}, error) {
-			return DNSLookupAuthoritativeAllE(t, query, resolvers)
-		})
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("DNSLookupAuthoritativeAllE %s record for %s using authoritative nameservers", query.Type, query.Name),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
 
-	return res.(DNSAnswers), err
+	return retry.DoE(t, policy, func() (DNSAnswers, error) {
+		return DNSLookupAuthoritativeAllE(t, query, resolvers)
+	})
 }
 
 // DNSLookupAuthoritativeAllWithValidation gets authoritative answers for the specified record and type.
@@ -217,7 +242,7 @@ func DNSLookupAuthoritativeAllWithValidationE(t testing.TestingT, query DNSQuery
 		return err
 	}
 
-	if !reflect.DeepEqual(answers, expectedAnswers) {
+	if !answers.Matches(expectedAnswers) {
 		err := &ValidationError{Query: query, Answers: answers, ExpectedAnswers: expectedAnswers}
 		return err
 	}
@@ -252,7 +277,7 @@ func DNSLookupAuthoritativeAllWithValidationRetryE(t testing.TestingT, query DNS
 
 // DNSLookup sends a DNS query for the specified record and type using the given resolvers.
 // Fails on any error.
-// Supported record types: A, AAAA, CNAME, MX, NS, TXT
+// Supported record types: A, AAAA, CNAME, MX, NS, TXT, SRV, CAA, SOA, PTR, SPF, DS, DNSKEY
 func DNSLookup(t testing.TestingT, query DNSQuery, resolvers []string) DNSAnswers {
 	res, err := DNSLookupE(t, query, resolvers)
 	require.NoError(t, err)
@@ -262,14 +287,36 @@ func DNSLookup(t testing.TestingT, query DNSQuery, resolvers []string) DNSAnswer
 // DNSLookupE sends a DNS query for the specified record and type using the given resolvers.
 // Returns QueryTypeError when record type is not supported.
 // Returns any underlying error.
-// Supported record types: A, AAAA, CNAME, MX, NS, TXT
+// Supported record types: A, AAAA, CNAME, MX, NS, TXT, SRV, CAA, SOA, PTR, SPF, DS, DNSKEY
 func DNSLookupE(t testing.TestingT, query DNSQuery, resolvers []string) (DNSAnswers, error) {
+	res, err := DNSLookupWithTTLE(t, query, resolvers)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Answers(), nil
+}
+
+// DNSLookupWithTTL sends a DNS query for the specified record and type using the given resolvers,
+// and returns the answers with their TTL.
+// Fails on any error.
+func DNSLookupWithTTL(t testing.TestingT, query DNSQuery, resolvers []string) DNSAnswersWithTTL {
+	res, err := DNSLookupWithTTLE(t, query, resolvers)
+	require.NoError(t, err)
+	return res
+}
+
+// DNSLookupWithTTLE sends a DNS query for the specified record and type using the given resolvers,
+// and returns the answers with their TTL.
+// Returns QueryTypeError when record type is not supported.
+// Returns any underlying error.
+func DNSLookupWithTTLE(t testing.TestingT, query DNSQuery, resolvers []string) (DNSAnswersWithTTL, error) {
 	if len(resolvers) == 0 {
 		err := &NoResolversError{}
 		return nil, err
 	}
 
-	var dnsAnswers DNSAnswers
+	var dnsAnswers DNSAnswersWithTTL
 	var err error
 	for _, resolver := range resolvers {
 		dnsAnswers, err = dnsLookup(t, query, resolver)
@@ -283,65 +330,121 @@ func DNSLookupE(t testing.TestingT, query DNSQuery, resolvers []string) (DNSAnsw
 }
 
 // dnsLookup sends a DNS query for the specified record and type using the given resolver.
-// Returns DNSAnswers to the DNSQuery.
+// Returns DNSAnswersWithTTL to the DNSQuery.
 // If no records found, returns NotFoundError.
-func dnsLookup(t testing.TestingT, query DNSQuery, resolver string) (DNSAnswers, error) {
-	switch query.Type {
-	case "A", "AAAA", "CNAME", "MX", "NS", "TXT":
-	default:
-		err := &QueryTypeError{query.Type}
+func dnsLookup(t testing.TestingT, query DNSQuery, resolver string) (DNSAnswersWithTTL, error) {
+	in, err := sendDNSQuery(t, query, resolver, false)
+	if err != nil {
 		return nil, err
 	}
 
-	qType, ok := dns.StringToType[strings.ToUpper(query.Type)]
-	if !ok {
-		err := &QueryTypeError{query.Type}
+	dnsAnswers, _ := answersFromMsg(in)
+	if len(dnsAnswers) == 0 {
+		err := &NotFoundError{query, withDefaultPort(resolver)}
 		return nil, err
 	}
 
-	if strings.LastIndex(resolver, ":") <= strings.LastIndex(resolver, "]") {
-		resolver += ":53"
-	}
+	return dnsAnswers, nil
+}
 
-	c := new(dns.Client)
-	m := new(dns.Msg)
-	m.SetQuestion(dns.Fqdn(query.Name), qType)
+// supportedQueryTypes are the record types that can be queried with DNSLookup and friends
+var supportedQueryTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "MX": true, "NS": true, "TXT": true,
+	"SRV": true, "CAA": true, "SOA": true, "PTR": true, "SPF": true, "DS": true, "DNSKEY": true,
+}
+
+// sendDNSQuery sends a DNS query for the specified record and type to the given resolver and returns the reply.
+// When dnssec is true, the query asks for DNSSEC records and for the resolver to validate them.
+func sendDNSQuery(t testing.TestingT, query DNSQuery, resolver string, dnssec bool) (*dns.Msg, error) {
+	m, err := newQueryMsg(query, dnssec)
+	if err != nil {
+		return nil, err
+	}
 
-	in, _, err := c.Exchange(m, resolver)
+	c := &dns.Client{Net: string(DNSOverUDP), Timeout: DefaultDNSQueryTimeout}
+	in, err := dnsExchange(c, m, resolver)
 	if err != nil {
 		logger.Logf(t, "Error sending DNS query %s: %s", query, err)
 		return nil, err
 	}
 
-	if len(in.Answer) == 0 {
-		err := &NotFoundError{query, resolver}
+	return in, nil
+}
+
+// dnsExchange sends the DNS message to the given resolver with the given client and returns the reply. If the client
+// uses UDP and the reply is truncated, the message is sent again over TCP, as resolvers do.
+func dnsExchange(c *dns.Client, m *dns.Msg, resolver string) (*dns.Msg, error) {
+	in, _, err := c.Exchange(m, withDefaultPort(resolver))
+	if err == nil && in.Truncated && (c.Net == "" || c.Net == string(DNSOverUDP)) {
+		tcp := &dns.Client{Net: string(DNSOverTCP), Timeout: c.Timeout}
+		in, _, err = tcp.Exchange(m, withDefaultPort(resolver))
+	}
+	return in, err
+}
+
+// newQueryMsg builds the DNS message for the specified record and type.
+// Returns QueryTypeError when record type is not supported.
+func newQueryMsg(query DNSQuery, dnssec bool) (*dns.Msg, error) {
+	if !supportedQueryTypes[query.Type] {
+		err := &QueryTypeError{query.Type}
 		return nil, err
 	}
 
-	var dnsAnswers DNSAnswers
-
-	for _, a := range in.Answer {
-		switch at := a.(type) {
-		case *dns.A:
-			dnsAnswers = append(dnsAnswers, DNSAnswer{"A", at.A.String()})
-		case *dns.AAAA:
-			dnsAnswers = append(dnsAnswers, DNSAnswer{"AAAA", at.AAAA.String()})
-		case *dns.CNAME:
-			dnsAnswers = append(dnsAnswers, DNSAnswer{"CNAME", at.Target})
-		case *dns.NS:
-			dnsAnswers = append(dnsAnswers, DNSAnswer{"NS", at.Ns})
-		case *dns.MX:
-			dnsAnswers = append(dnsAnswers, DNSAnswer{"MX", fmt.Sprintf("%d %s", at.Preference, at.Mx)})
+	qType, ok := dns.StringToType[strings.ToUpper(query.Type)]
+	if !ok {
+		err := &QueryTypeError{query.Type}
+		return nil, err
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(query.Name), qType)
+	if dnssec {
+		m.AuthenticatedData = true
+		m.SetEdns0(4096, true)
+	}
+
+	return m, nil
+}
+
+// withDefaultPort adds the default DNS port to the resolver address if it doesn't have one
+func withDefaultPort(resolver string) string {
+	if strings.LastIndex(resolver, ":") <= strings.LastIndex(resolver, "]") {
+		return resolver + ":53"
+	}
+	return resolver
+}
+
+// answersFromMsg returns the records in the answer section of the DNS reply, with the RRSIG records apart
+func answersFromMsg(in *dns.Msg) (answers DNSAnswersWithTTL, signatures DNSAnswers) {
+	for _, rr := range in.Answer {
+		header := rr.Header()
+		answerType := dns.TypeToString[header.Rrtype]
+
+		switch at := rr.(type) {
 		case *dns.TXT:
 			for _, txt := range at.Txt {
-				dnsAnswers = append(dnsAnswers, DNSAnswer{"TXT", fmt.Sprintf(`"%s"`, txt)})
+				answers = append(answers, DNSAnswerWithTTL{DNSAnswer{answerType, fmt.Sprintf(`"%s"`, txt)}, header.Ttl})
 			}
+		case *dns.SPF:
+			for _, txt := range at.Txt {
+				answers = append(answers, DNSAnswerWithTTL{DNSAnswer{answerType, fmt.Sprintf(`"%s"`, txt)}, header.Ttl})
+			}
+		case *dns.RRSIG:
+			signatures = append(signatures, DNSAnswer{answerType, recordData(rr)})
+		default:
+			answers = append(answers, DNSAnswerWithTTL{DNSAnswer{answerType, recordData(rr)}, header.Ttl})
 		}
 	}
 
-	dnsAnswers.Sort()
+	answers.Sort()
+	signatures.Sort()
 
-	return dnsAnswers, nil
+	return answers, signatures
+}
+
+// recordData returns the data of the record in zone file format, i.e. everything after the name, TTL, class and type
+func recordData(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
 }
 
 // DNSQuery type
@@ -349,7 +452,8 @@ type DNSQuery struct {
 	Type, Name string
 }
 
-// DNSAnswer type
+// DNSAnswer type. Value is the data of the record in zone file format, e.g. "10 mail.example.com." for MX records.
+// Use the typed accessors, such as SRV or CAA, to get the fields of the record.
 type DNSAnswer struct {
 	Type, Value string
 }
@@ -364,6 +468,72 @@ type DNSAnswers []DNSAnswer
 // Sort sorts the answers by type and value
 func (a DNSAnswers) Sort() {
 	sort.Slice(a, func(i, j int) bool {
-		return a[i].Type < a[j].Type || a[i].Value < a[j].Value
+		if a[i].Type != a[j].Type {
+			return a[i].Type < a[j].Type
+		}
+		return a[i].Value < a[j].Value
 	})
 }
+
+// Matches returns true if the answers are the same as the expected ones, in any order
+func (a DNSAnswers) Matches(expected DNSAnswers) bool {
+	if len(a) != len(expected) {
+		return false
+	}
+
+	matched := make([]bool, len(a))
+	for _, e := range expected {
+		found := false
+		for i, answer := range a {
+			if !matched[i] && answer == e {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DNSAnswerWithTTL type. TTL is the time to live of the record in seconds, as given by the resolver.
+type DNSAnswerWithTTL struct {
+	DNSAnswer
+	TTL uint32
+}
+
+func (a DNSAnswerWithTTL) String() string {
+	return fmt.Sprintf("%d %s", a.TTL, a.DNSAnswer)
+}
+
+// DNSAnswersWithTTL type
+type DNSAnswersWithTTL []DNSAnswerWithTTL
+
+// Sort sorts the answers by type, value and TTL
+func (a DNSAnswersWithTTL) Sort() {
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].Type != a[j].Type {
+			return a[i].Type < a[j].Type
+		}
+		if a[i].Value != a[j].Value {
+			return a[i].Value < a[j].Value
+		}
+		return a[i].TTL < a[j].TTL
+	})
+}
+
+// Answers returns the answers without their TTL
+func (a DNSAnswersWithTTL) Answers() DNSAnswers {
+	if a == nil {
+		return nil
+	}
+
+	answers := make(DNSAnswers, len(a))
+	for i, answer := range a {
+		answers[i] = answer.DNSAnswer
+	}
+	return answers
+}
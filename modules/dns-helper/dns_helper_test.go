@@ -1,6 +1,9 @@
 package dns_helper
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -19,7 +22,24 @@ var publicDomainNameservers = []string{
 	"ns-853.awsdns-42.net",
 }
 
-var testDNSDatabase = dnsDatabase{
+var testDomain = "gruntwork.io"
+
+// testDNSRecords are the records of the testDomain zone served by the LocalDNSServers of the tests
+var testDNSRecords = map[string]DNSAnswers{
+	"a." + testDomain:          {{"A", "2.2.2.2"}, {"A", "1.1.1.1"}},
+	"aaaa." + testDomain:       {{"AAAA", "2001:db8::aaaa"}},
+	"terratest." + testDomain:  {{"CNAME", "gruntwork-io.github.io."}},
+	"cname1." + testDomain:     {{"CNAME", "cname2." + testDomain + "."}},
+	"cname2." + testDomain:     {{"CNAME", "cname3." + testDomain + "."}},
+	"cname3." + testDomain:     {{"CNAME", "cname4." + testDomain + "."}},
+	"cname4." + testDomain:     {{"CNAME", "cnamefinal." + testDomain + "."}},
+	"cnamefinal." + testDomain: {{"A", "1.1.1.1"}},
+	"txt." + testDomain:        {{"TXT", `"This is a text."`}},
+	testDomain:                 {{"MX", "10 mail." + testDomain + "."}},
+}
+
+// testDNSDatabase maps queries to the testDNSRecords to their expected answers
+var testDNSDatabase = map[DNSQuery]DNSAnswers{
 	DNSQuery{"A", "a." + testDomain}: DNSAnswers{
 		{"A", "2.2.2.2"},
 		{"A", "1.1.1.1"},
@@ -87,15 +107,14 @@ func TestOkTerratestDNSLookupAuthoritative(t *testing.T) {
 }
 
 // ***********************************
-// Tests that use local dnsTestServers
+// Tests that use LocalDNSServers
 
 // Lookup should succeed with answers from just one authoritative nameserver
 func TestOkLocalDNSLookupAuthoritative(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
+	s1.AddZone(t, testDNSZone(s1, s2, testDNSRecords))
 	for dnsQuery, expected := range testDNSDatabase {
-		s1.AddEntryToDNSDatabase(dnsQuery, expected)
 		res, err := DNSLookupAuthoritativeE(t, dnsQuery, []string{s1.Address(), s2.Address()})
 		require.NoError(t, err)
 		require.ElementsMatch(t, res, expected)
@@ -106,7 +125,6 @@ func TestOkLocalDNSLookupAuthoritative(t *testing.T) {
 func TestErrorLocalDNSLookupAuthoritative(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "txt." + testDomain}
 	_, err := DNSLookupAuthoritativeE(t, dnsQuery, []string{s1.Address(), s2.Address()})
 	if _, ok := err.(*NotFoundError); !ok {
@@ -118,21 +136,68 @@ func TestErrorLocalDNSLookupAuthoritative(t *testing.T) {
 func TestOkLocalDNSLookupAuthoritativeAll(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
+	s1.AddZone(t, testDNSZone(s1, s2, testDNSRecords))
+	s2.AddZone(t, testDNSZone(s1, s2, testDNSRecords))
 	for dnsQuery, expected := range testDNSDatabase {
-		s1.AddEntryToDNSDatabase(dnsQuery, expected)
-		s2.AddEntryToDNSDatabase(dnsQuery, expected)
-		res, err := DNSLookupAuthoritativeE(t, dnsQuery, []string{s1.Address(), s2.Address()})
+		res, err := DNSLookupAuthoritativeAllE(t, dnsQuery, []string{s1.Address()})
 		require.NoError(t, err)
 		require.ElementsMatch(t, res, expected)
 	}
 }
 
+// Lookup should succeed when the authoritative nameservers only differ in the TTL of the answers
+func TestOkLocalDNSLookupAuthoritativeAllDifferentTTL(t *testing.T) {
+	t.Parallel()
+	s1, s2 := setupTestDNSServers(t)
+	zone := testDNSZone(s1, s2, map[string]DNSAnswers{"a." + testDomain: {{"A", "1.1.1.1"}}})
+	zone.TTL = 60
+	s1.AddZone(t, zone)
+	zone.TTL = 300
+	s2.AddZone(t, zone)
+
+	dnsQuery := DNSQuery{"A", "a." + testDomain}
+	res, err := DNSLookupAuthoritativeAllE(t, dnsQuery, []string{s1.Address()})
+	require.NoError(t, err)
+	require.Equal(t, DNSAnswers{{"A", "1.1.1.1"}}, res)
+	require.Equal(t, DNSAnswersWithTTL{{DNSAnswer{"A", "1.1.1.1"}, 300}}, DNSLookupWithTTL(t, dnsQuery, []string{s2.Address()}))
+}
+
+// Lookup should return the answers of the authoritative nameservers with their TTL
+func TestOkLocalDNSLookupAuthoritativeWithTTL(t *testing.T) {
+	t.Parallel()
+	s := NewLocalDNSServer(t, &LocalDNSServerOptions{DefaultTTL: 120})
+	s.AddZone(t, DNSZone{Origin: testDomain, Records: map[string]DNSAnswers{"a." + testDomain: {{"A", "1.1.1.1"}}}})
+	res, err := DNSLookupAuthoritativeWithTTLE(t, DNSQuery{"A", "a." + testDomain}, []string{s.Address()})
+	require.NoError(t, err)
+	require.Equal(t, DNSAnswersWithTTL{{DNSAnswer{"A", "1.1.1.1"}, 120}}, res)
+}
+
+// Lookup should retry over TCP when the answers don't fit in a UDP message
+func TestOkLocalDNSLookupTruncated(t *testing.T) {
+	t.Parallel()
+	var answers DNSAnswers
+	for i := 0; i < 100; i++ {
+		answers = append(answers, DNSAnswer{"TXT", fmt.Sprintf(`"%03d %s"`, i, strings.Repeat("x", 60))})
+	}
+	s := NewLocalDNSServer(t, nil)
+	s.AddZone(t, DNSZone{Origin: testDomain, Records: map[string]DNSAnswers{"txt." + testDomain: answers}})
+	dnsQuery := DNSQuery{"TXT", "txt." + testDomain}
+
+	// Without EDNS0 the answers don't fit in 512 bytes, and with DNSSEC they don't fit in 4096 bytes either
+	require.Equal(t, answers, DNSLookup(t, dnsQuery, []string{s.Address()}))
+	require.Equal(t, answers, DNSLookupWithDNSSEC(t, dnsQuery, []string{s.Address()}).Answers)
+
+	var protocols []string
+	for _, query := range s.Queries() {
+		protocols = append(protocols, query.Protocol)
+	}
+	require.Equal(t, []string{"udp", "tcp", "udp", "tcp"}, protocols)
+}
+
 // Lookup should fail because of missing answers from all authoritative nameservers
 func TestError1DNSLookupAuthoritativeAll(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "txt." + testDomain}
 	_, err := DNSLookupAuthoritativeAllE(t, dnsQuery, []string{s1.Address(), s2.Address()})
 	if _, ok := err.(*NotFoundError); !ok {
@@ -144,9 +209,8 @@ func TestError1DNSLookupAuthoritativeAll(t *testing.T) {
 func TestError2DNSLookupAuthoritativeAll(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
-	s1.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "1.1.1.1"}})
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "1.1.1.1"}})
 	_, err := DNSLookupAuthoritativeAllE(t, dnsQuery, []string{s1.Address(), s2.Address()})
 	if _, ok := err.(*NotFoundError); !ok {
 		t.Errorf("unexpected error, got %q", err)
@@ -157,10 +221,9 @@ func TestError2DNSLookupAuthoritativeAll(t *testing.T) {
 func TestError3DNSLookupAuthoritativeAll(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
-	s1.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "1.1.1.1"}})
-	s2.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "1.1.1.1"}})
+	s2.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
 	_, err := DNSLookupAuthoritativeAllE(t, dnsQuery, []string{s1.Address(), s2.Address()})
 	if _, ok := err.(*InconsistentAuthoritativeError); !ok {
 		t.Errorf("unexpected error, got %q", err)
@@ -171,7 +234,6 @@ func TestError3DNSLookupAuthoritativeAll(t *testing.T) {
 func TestError4DNSLookupAuthoritativeAll(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "this.domain.doesnt.exist"}
 	_, err := DNSLookupAuthoritativeAllE(t, dnsQuery, []string{s1.Address(), s2.Address()})
 	if _, ok := err.(*NSNotFoundError); !ok {
@@ -183,11 +245,10 @@ func TestError4DNSLookupAuthoritativeAll(t *testing.T) {
 // Retry lookups should succeed with answers from just one authoritative nameserver
 func TestOkDNSLookupAuthoritativeWithRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
 	res, err := DNSLookupAuthoritativeWithRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, 5, time.Second)
 	require.NoError(t, err)
 	require.ElementsMatch(t, res, expectedRes)
@@ -197,8 +258,7 @@ func TestOkDNSLookupAuthoritativeWithRetry(t *testing.T) {
 // Retry lookups should fail because of missing answers from all authoritative nameservers
 func TestErrorDNSLookupAuthoritativeWithRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "txt." + testDomain}
 	_, err := DNSLookupAuthoritativeWithRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, 5, time.Second)
 	require.Error(t, err)
@@ -211,13 +271,12 @@ func TestErrorDNSLookupAuthoritativeWithRetry(t *testing.T) {
 // Retry lookups should succeed with consistent answers
 func TestOkDNSLookupAuthoritativeAllWithRetryNotfound(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
+	setRecordsLater(t, s2, dnsQuery, expectedRes)
 	res, err := DNSLookupAuthoritativeAllWithRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, 5, time.Second)
 	require.NoError(t, err)
 	require.ElementsMatch(t, res, expectedRes)
@@ -227,14 +286,13 @@ func TestOkDNSLookupAuthoritativeAllWithRetryNotfound(t *testing.T) {
 // Retry lookups should succeed with consistent answers
 func TestOkDNSLookupAuthoritativeAllWithRetryInconsistent(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
+	s2.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
+	setRecordsLater(t, s2, dnsQuery, expectedRes)
 	res, err := DNSLookupAuthoritativeAllWithRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, 5, time.Second)
 	require.NoError(t, err)
 	require.ElementsMatch(t, res, expectedRes)
@@ -244,12 +302,11 @@ func TestOkDNSLookupAuthoritativeAllWithRetryInconsistent(t *testing.T) {
 // Retry lookups should fail because of inconsistent answers from authoritative nameservers
 func TestErrorDNSLookupAuthoritativeAllWithRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
-	s1.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}})
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, DNSAnswers{{"A", "1.1.1.1"}})
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
+	setRecordsLater(t, s1, dnsQuery, DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}})
+	setRecordsLater(t, s2, dnsQuery, DNSAnswers{{"A", "1.1.1.1"}})
 	_, err := DNSLookupAuthoritativeAllWithRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, 5, time.Second)
 	require.Error(t, err)
 	if _, ok := err.(retry.MaxRetriesExceeded); !ok {
@@ -261,11 +318,10 @@ func TestErrorDNSLookupAuthoritativeAllWithRetry(t *testing.T) {
 func TestOkDNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabase(dnsQuery, expectedRes)
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
+	s2.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
 	err := DNSLookupAuthoritativeAllWithValidationE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes)
 	require.NoError(t, err)
 }
@@ -274,7 +330,6 @@ func TestOkDNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 func TestErrorDNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
 	err := DNSLookupAuthoritativeAllWithValidationE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes)
@@ -288,10 +343,9 @@ func TestErrorDNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 func TestError2DNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
 	err := DNSLookupAuthoritativeAllWithValidationE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes)
 	require.Error(t, err)
 	if _, ok := err.(*NotFoundError); !ok {
@@ -303,11 +357,10 @@ func TestError2DNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 func TestError3DNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 	t.Parallel()
 	s1, s2 := setupTestDNSServers(t)
-	defer shutDownServers(t, s1, s2)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
+	s2.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
 	err := DNSLookupAuthoritativeAllWithValidationE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes)
 	require.Error(t, err)
 	if _, ok := err.(*InconsistentAuthoritativeError); !ok {
@@ -319,12 +372,11 @@ func TestError3DNSLookupAuthoritativeAllWithValidation(t *testing.T) {
 // Retry lookups should succeed with consistent and validated replies
 func TestOkDNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
+	setRecordsLater(t, s2, dnsQuery, expectedRes)
 	err := DNSLookupAuthoritativeAllWithValidationRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes, 5, time.Second)
 	require.NoError(t, err)
 }
@@ -333,13 +385,12 @@ func TestOkDNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 // Retry lookups should succeed with consistent and validated replies
 func TestOk2DNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
+	setRecordsLater(t, s2, dnsQuery, expectedRes)
 	err := DNSLookupAuthoritativeAllWithValidationRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes, 5, time.Second)
 	require.NoError(t, err)
 }
@@ -348,14 +399,13 @@ func TestOk2DNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 // Retry lookups should succeed with consistent and validated replies
 func TestOk3DNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
+	s2.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
+	setRecordsLater(t, s2, dnsQuery, expectedRes)
 	err := DNSLookupAuthoritativeAllWithValidationRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes, 5, time.Second)
 	require.NoError(t, err)
 }
@@ -364,23 +414,61 @@ func TestOk3DNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 // Retry lookups should fail also because of inconsistent authoritative replies
 func TestErrorDNSLookupAuthoritativeAllWithValidationRetry(t *testing.T) {
 	t.Parallel()
-	s1, s2 := setupTestDNSServersRetry(t)
-	defer shutDownServers(t, s1, s2)
+	s1, s2 := setupTestDNSServers(t)
 	dnsQuery := DNSQuery{"A", "a." + testDomain}
 	expectedRes := DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}
-	s1.AddEntryToDNSDatabase(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabase(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
-	s1.AddEntryToDNSDatabaseRetry(dnsQuery, expectedRes)
-	s2.AddEntryToDNSDatabaseRetry(dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expectedRes)
+	s2.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{"A", "2.2.2.2"}})
+	setRecordsLater(t, s1, dnsQuery, expectedRes)
+	setRecordsLater(t, s2, dnsQuery, DNSAnswers{{"A", "2.2.2.2"}})
 	err := DNSLookupAuthoritativeAllWithValidationRetryE(t, dnsQuery, []string{s1.Address(), s2.Address()}, expectedRes, 5, time.Second)
 	if _, ok := err.(retry.MaxRetriesExceeded); !ok {
 		t.Errorf("unexpected error, got %q", err)
 	}
 }
 
-func shutDownServers(t *testing.T, s1, s2 *dnsTestServer) {
-	err := s1.Server.Shutdown()
-	assert.NoError(t, err)
-	err = s2.Server.Shutdown()
-	assert.NoError(t, err)
+// testDNSZone returns a zone for the testDomain with the given records and NS records pointing to the given servers
+func testDNSZone(s1, s2 *LocalDNSServer, records map[string]DNSAnswers) DNSZone {
+	zone := DNSZone{
+		Origin:  testDomain,
+		Records: map[string]DNSAnswers{testDomain: {{"NS", s1.Address() + "."}, {"NS", s2.Address() + "."}}},
+	}
+	for name, answers := range records {
+		zone.Records[name] = append(zone.Records[name], answers...)
+	}
+	return zone
+}
+
+// setupTestDNSServers runs and returns 2x LocalDNSServer serving the testDomain zone, without records other than
+// the NS records pointing to themselves
+func setupTestDNSServers(t *testing.T) (s1, s2 *LocalDNSServer) {
+	s1 = NewLocalDNSServer(t, nil)
+	// Like real nameservers, the second one doesn't give the answers in the same order
+	s2 = NewLocalDNSServer(t, &LocalDNSServerOptions{ReverseAnswers: true})
+	s1.AddZone(t, testDNSZone(s1, s2, nil))
+	s2.AddZone(t, testDNSZone(s1, s2, nil))
+	return s1, s2
+}
+
+// retryTestDelay is the time after which setRecordsLater changes the records, so that the first lookups fail and the
+// retries see the new records
+const retryTestDelay = 1500 * time.Millisecond
+
+// setRecordsLater sets the answers to the query on the server after retryTestDelay, unless the test is done by then
+func setRecordsLater(t *testing.T, s *LocalDNSServer, query DNSQuery, answers DNSAnswers) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-time.After(retryTestDelay):
+			assert.NoError(t, s.SetRecordsE(t, query.Name, query.Type, answers))
+		case <-done:
+		}
+	}()
+	t.Cleanup(func() {
+		close(done)
+		wg.Wait()
+	})
 }
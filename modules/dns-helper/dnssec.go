@@ -0,0 +1,131 @@
+package dns_helper
+
+import (
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// DNSSECAnswers are the answers to a DNS query sent with DNSSEC enabled
+type DNSSECAnswers struct {
+	Answers DNSAnswers
+	// Signatures are the RRSIG records sent along with the answers
+	Signatures DNSAnswers
+	// Authenticated is true if the resolver set the AD flag, i.e. validated the answers with DNSSEC.
+	// Only validating recursive resolvers set it, authoritative nameservers don't.
+	Authenticated bool
+	// Resolver is the resolver that replied
+	Resolver string
+}
+
+// DNSLookupWithDNSSEC sends a DNS query for the specified record and type using the given resolvers,
+// asking for DNSSEC records and validation.
+// Fails on any error.
+func DNSLookupWithDNSSEC(t testing.TestingT, query DNSQuery, resolvers []string) DNSSECAnswers {
+	res, err := DNSLookupWithDNSSECE(t, query, resolvers)
+	require.NoError(t, err)
+	return res
+}
+
+// DNSLookupWithDNSSECE sends a DNS query for the specified record and type using the given resolvers,
+// asking for DNSSEC records and validation.
+// Returns NotFoundError when no answer found.
+// Returns any underlying error.
+func DNSLookupWithDNSSECE(t testing.TestingT, query DNSQuery, resolvers []string) (DNSSECAnswers, error) {
+	if len(resolvers) == 0 {
+		err := &NoResolversError{}
+		return DNSSECAnswers{}, err
+	}
+
+	var err error
+	for _, resolver := range resolvers {
+		var res DNSSECAnswers
+		res, err = dnsLookupWithDNSSEC(t, query, resolver)
+
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	return DNSSECAnswers{}, err
+}
+
+// AssertDNSSECSigned checks that the answers to the DNS query come with RRSIG records.
+// Use it with authoritative nameservers to check that a zone is signed.
+// Fails on any error or when the answers aren't signed.
+func AssertDNSSECSigned(t testing.TestingT, query DNSQuery, resolvers []string) {
+	err := AssertDNSSECSignedE(t, query, resolvers)
+	require.NoError(t, err)
+}
+
+// AssertDNSSECSignedE checks that the answers to the DNS query come with RRSIG records.
+// Use it with authoritative nameservers to check that a zone is signed.
+// Returns DNSSECError when the answers aren't signed.
+// Returns any underlying error from DNSLookupWithDNSSECE.
+func AssertDNSSECSignedE(t testing.TestingT, query DNSQuery, resolvers []string) error {
+	res, err := DNSLookupWithDNSSECE(t, query, resolvers)
+	if err != nil {
+		return err
+	}
+
+	if len(res.Signatures) == 0 {
+		err := &DNSSECError{Query: query, Resolver: res.Resolver, Reason: "no RRSIG records in the answer"}
+		return err
+	}
+
+	return nil
+}
+
+// AssertDNSSECAuthenticated checks that the resolvers validate the answers to the DNS query with DNSSEC,
+// i.e. that they set the AD flag and send RRSIG records.
+// Use it with validating recursive resolvers, such as 1.1.1.1 or 8.8.8.8.
+// Fails on any error or when the answers aren't authenticated.
+func AssertDNSSECAuthenticated(t testing.TestingT, query DNSQuery, resolvers []string) {
+	err := AssertDNSSECAuthenticatedE(t, query, resolvers)
+	require.NoError(t, err)
+}
+
+// AssertDNSSECAuthenticatedE checks that the resolvers validate the answers to the DNS query with DNSSEC,
+// i.e. that they set the AD flag and send RRSIG records.
+// Use it with validating recursive resolvers, such as 1.1.1.1 or 8.8.8.8.
+// Returns DNSSECError when the answers aren't authenticated.
+// Returns any underlying error from DNSLookupWithDNSSECE.
+func AssertDNSSECAuthenticatedE(t testing.TestingT, query DNSQuery, resolvers []string) error {
+	res, err := DNSLookupWithDNSSECE(t, query, resolvers)
+	if err != nil {
+		return err
+	}
+
+	if !res.Authenticated {
+		err := &DNSSECError{Query: query, Resolver: res.Resolver, Reason: "the AD flag is not set"}
+		return err
+	}
+
+	if len(res.Signatures) == 0 {
+		err := &DNSSECError{Query: query, Resolver: res.Resolver, Reason: "no RRSIG records in the answer"}
+		return err
+	}
+
+	return nil
+}
+
+// dnsLookupWithDNSSEC sends a DNS query with DNSSEC enabled for the specified record and type using the given resolver.
+// If no records found, returns NotFoundError.
+func dnsLookupWithDNSSEC(t testing.TestingT, query DNSQuery, resolver string) (DNSSECAnswers, error) {
+	in, err := sendDNSQuery(t, query, resolver, true)
+	if err != nil {
+		return DNSSECAnswers{}, err
+	}
+
+	answers, signatures := answersFromMsg(in)
+	if len(answers) == 0 {
+		err := &NotFoundError{query, withDefaultPort(resolver)}
+		return DNSSECAnswers{}, err
+	}
+
+	return DNSSECAnswers{
+		Answers:       answers.Answers(),
+		Signatures:    signatures,
+		Authenticated: in.AuthenticatedData,
+		Resolver:      withDefaultPort(resolver),
+	}, nil
+}
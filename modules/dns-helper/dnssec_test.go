@@ -0,0 +1,99 @@
+package dns_helper
+
+import (
+	"crypto"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runSignedTestDNSServer runs a LocalDNSServer that answers A queries for the testDomain with a record signed with a
+// freshly generated key when the query has the DO bit set. When authenticated is true, it also sets the AD flag, like a
+// validating recursive resolver.
+func runSignedTestDNSServer(t *testing.T, authenticated bool) *LocalDNSServer {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: testDomain + ".", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	privateKey, err := key.Generate(256)
+	require.NoError(t, err)
+
+	a, err := dns.NewRR(fmt.Sprintf("%s. 300 IN A 1.1.1.1", testDomain))
+	require.NoError(t, err)
+	signature := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: testDomain + ".", Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: 300},
+		KeyTag:     key.KeyTag(),
+		SignerName: key.Hdr.Name,
+		Algorithm:  key.Algorithm,
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	require.NoError(t, signature.Sign(privateKey.(crypto.Signer), []dns.RR{a}))
+	require.NoError(t, signature.Verify(key, []dns.RR{a}))
+
+	zone := DNSZone{
+		Origin: testDomain,
+		Records: map[string]DNSAnswers{
+			testDomain: {{"A", recordData(a)}, {"RRSIG", recordData(signature)}},
+		},
+	}
+	return NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{zone}, Validating: authenticated})
+}
+
+func TestOkDNSLookupWithDNSSEC(t *testing.T) {
+	t.Parallel()
+	s := runSignedTestDNSServer(t, true)
+	dnsQuery := DNSQuery{"A", testDomain}
+
+	res := DNSLookupWithDNSSEC(t, dnsQuery, []string{s.Address()})
+	assert.Equal(t, DNSAnswers{{Type: "A", Value: "1.1.1.1"}}, res.Answers)
+	require.Len(t, res.Signatures, 1)
+	assert.Equal(t, "RRSIG", res.Signatures[0].Type)
+	assert.True(t, res.Authenticated)
+	assert.Equal(t, s.Address(), res.Resolver)
+
+	AssertDNSSECSigned(t, dnsQuery, []string{s.Address()})
+	AssertDNSSECAuthenticated(t, dnsQuery, []string{s.Address()})
+
+	// Plain lookups don't ask for the signatures
+	res2, err := DNSLookupE(t, dnsQuery, []string{s.Address()})
+	require.NoError(t, err)
+	assert.Equal(t, res.Answers, res2)
+}
+
+// The answers are signed, but the server doesn't validate them, like an authoritative nameserver
+func TestErrorDNSSECAuthenticated(t *testing.T) {
+	t.Parallel()
+	s := runSignedTestDNSServer(t, false)
+	dnsQuery := DNSQuery{"A", testDomain}
+
+	AssertDNSSECSigned(t, dnsQuery, []string{s.Address()})
+	err := AssertDNSSECAuthenticatedE(t, dnsQuery, []string{s.Address()})
+	if _, ok := err.(*DNSSECError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+}
+
+// The answers aren't signed
+func TestErrorDNSSECSigned(t *testing.T) {
+	t.Parallel()
+	s1, _ := setupTestDNSServers(t)
+	dnsQuery := DNSQuery{"A", "a." + testDomain}
+	s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, DNSAnswers{{Type: "A", Value: "1.1.1.1"}})
+
+	err := AssertDNSSECSignedE(t, dnsQuery, []string{s1.Address()})
+	if _, ok := err.(*DNSSECError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+
+	_, err = DNSLookupWithDNSSECE(t, dnsQuery, nil)
+	if _, ok := err.(*NoResolversError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+}
@@ -70,3 +70,70 @@ type ValidationError struct {
 func (err ValidationError) Error() string {
 	return fmt.Sprintf("Unexpected answer to DNS query %s. Got: %s Expected: %s", err.Query, err.Answers, err.ExpectedAnswers)
 }
+
+// RecordTypeError is an error that occurs if the data of an answer is requested as a different record type
+type RecordTypeError struct {
+	Answer       DNSAnswer
+	ExpectedType string
+}
+
+func (err RecordTypeError) Error() string {
+	return fmt.Sprintf("Answer %s is not a %s record", err.Answer, err.ExpectedType)
+}
+
+// DNSSECError is an error that occurs if the answers to a DNS query fail a DNSSEC check
+type DNSSECError struct {
+	Query    DNSQuery
+	Resolver string
+	Reason   string
+}
+
+func (err DNSSECError) Error() string {
+	return fmt.Sprintf("DNSSEC check failed for DNS query %s to %s: %s", err.Query, err.Resolver, err.Reason)
+}
+
+// ZoneNotFoundError is an error that occurs if a name doesn't belong to any zone of a LocalDNSServer, or to the zone
+// it's added to
+type ZoneNotFoundError struct {
+	Name string
+	Zone string
+}
+
+func (err ZoneNotFoundError) Error() string {
+	if err.Zone != "" {
+		return fmt.Sprintf("Name %s is not in zone %s", err.Name, err.Zone)
+	}
+	return fmt.Sprintf("Name %s is not in any zone of the local DNS server", err.Name)
+}
+
+// ProtocolError is an error that occurs if a resolver has an unknown DNS protocol
+type ProtocolError struct {
+	Protocol DNSProtocol
+}
+
+func (err ProtocolError) Error() string {
+	return fmt.Sprintf("Unknown DNS protocol: %s", err.Protocol)
+}
+
+// PropagationError is an error that occurs if fewer resolvers than the quorum give the expected answers to a DNS query
+type PropagationError struct {
+	Propagation     DNSPropagation
+	ExpectedAnswers DNSAnswers
+	Matching        int
+	Quorum          int
+}
+
+func (err PropagationError) Error() string {
+	return fmt.Sprintf("DNS query %s not propagated: %d of %d resolvers gave the expected answers %s, quorum is %d. %s", err.Propagation.Query, err.Matching, len(err.Propagation.Results), err.ExpectedAnswers, err.Quorum, err.Propagation)
+}
+
+// QuorumError is an error that occurs if the quorum of a propagation check is negative or greater than the number of
+// resolvers, so that it can never be met
+type QuorumError struct {
+	Quorum    int
+	Resolvers int
+}
+
+func (err QuorumError) Error() string {
+	return fmt.Sprintf("Invalid quorum %d for a propagation check with %d resolvers", err.Quorum, err.Resolvers)
+}
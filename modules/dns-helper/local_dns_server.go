@@ -0,0 +1,723 @@
+package dns_helper
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// defaultLocalDNSServerTTL is the TTL of records that don't set one
+const defaultLocalDNSServerTTL = 300
+
+// maxReferrals is the number of referrals a recursive LocalDNSServer follows before giving up
+const maxReferrals = 10
+
+// DNSZone is a zone served by a LocalDNSServer
+type DNSZone struct {
+	// Origin is the domain name at the apex of the zone, e.g. example.com
+	Origin string
+	// Records maps domain names in the zone, e.g. www.example.com, to their records.
+	// If the apex has no SOA record, one is generated. If it has no NS records, the server is used as nameserver, with
+	// its address as host name, e.g. 127.0.0.1:53535., so that the other functions of this package can use it.
+	// RRSIG records are returned along with the records they cover to queries with the DNSSEC OK bit set.
+	Records map[string]DNSAnswers
+	// TTL is the TTL of the records of the zone, including the ones set later with SetRecords. Defaults to the
+	// DefaultTTL of the server.
+	TTL uint32
+}
+
+// LocalDNSServerOptions configures a LocalDNSServer
+type LocalDNSServerOptions struct {
+	// Zones are served by the server
+	Zones []DNSZone
+	// ZoneFiles maps zone origins to the paths of zone files in RFC 1035 format to load them from
+	ZoneFiles map[string]string
+	// DefaultTTL is the TTL of the records of zones that don't set one. Defaults to 300 seconds.
+	DefaultTTL uint32
+	// Recursive makes the server follow referrals to delegated zones, e.g. to other LocalDNSServers, for queries that
+	// ask for recursion, like a recursive resolver would. Otherwise it answers with the referral.
+	Recursive bool
+	// Validating makes the server set the AD flag in replies with signed answers to queries with the DNSSEC OK bit set,
+	// like a validating resolver would. The signatures aren't verified.
+	Validating bool
+	// ReverseAnswers reverses the order of the answers in replies, like nameservers that rotate the records of a set,
+	// to check that clients don't depend on the order of the answers.
+	ReverseAnswers bool
+}
+
+// DNSFailureMode makes a LocalDNSServer fail to answer queries, to test failover between nameservers
+type DNSFailureMode string
+
+const (
+	// DNSNoFailure answers queries normally
+	DNSNoFailure DNSFailureMode = ""
+	// DNSFailWithServFail answers all queries with SERVFAIL
+	DNSFailWithServFail DNSFailureMode = "SERVFAIL"
+	// DNSFailWithRefused answers all queries with REFUSED
+	DNSFailWithRefused DNSFailureMode = "REFUSED"
+	// DNSFailByDropping doesn't answer queries at all, so that clients time out
+	DNSFailByDropping DNSFailureMode = "DROP"
+)
+
+// RecordedDNSQuery is a query received by a LocalDNSServer
+type RecordedDNSQuery struct {
+	Query DNSQuery
+	// Protocol is udp or tcp
+	Protocol string
+	// Client is the address the query came from
+	Client string
+	// RecursionDesired is true if the client asked for recursion
+	RecursionDesired bool
+	Time             time.Time
+}
+
+// LocalDNSServer is an authoritative DNS server running in the test process, on an ephemeral port of 127.0.0.1,
+// over both UDP and TCP. Use it to test code that resolves names, including failover between nameservers and
+// delegation between zones, without depending on the public DNS infrastructure.
+type LocalDNSServer struct {
+	options    LocalDNSServerOptions
+	address    string
+	udpServer  *dns.Server
+	tcpServer  *dns.Server
+	closeOnce  sync.Once
+	lock       sync.Mutex
+	zones      map[string]*localZone
+	queries    []RecordedDNSQuery
+	failure    DNSFailureMode
+	serialBase uint32
+}
+
+// localZone stores the records of a zone by lowercase fully qualified name and type, and the TTL of new records
+type localZone struct {
+	origin  string
+	ttl     uint32
+	records map[string]map[uint16][]dns.RR
+}
+
+// NewLocalDNSServer starts a LocalDNSServer serving the zones in the given options. If the test supports cleanup
+// functions, like *testing.T does, the server is stopped when the test finishes; otherwise, call Close when you're
+// done. Fails on any error.
+func NewLocalDNSServer(t testing.TestingT, options *LocalDNSServerOptions) *LocalDNSServer {
+	server, err := NewLocalDNSServerE(t, options)
+	require.NoError(t, err)
+	return server
+}
+
+// NewLocalDNSServerE starts a LocalDNSServer serving the zones in the given options. If the test supports cleanup
+// functions, like *testing.T does, the server is stopped when the test finishes; otherwise, call Close when you're
+// done.
+// Returns any error listening or loading the zones.
+func NewLocalDNSServerE(t testing.TestingT, options *LocalDNSServerOptions) (*LocalDNSServer, error) {
+	if options == nil {
+		options = &LocalDNSServerOptions{}
+	}
+
+	server := &LocalDNSServer{
+		options:    *options,
+		zones:      map[string]*localZone{},
+		serialBase: uint32(time.Now().Unix()),
+	}
+	if server.options.DefaultTTL == 0 {
+		server.options.DefaultTTL = defaultLocalDNSServerTTL
+	}
+
+	packetConn, listener, err := listenUDPAndTCP()
+	if err != nil {
+		return nil, err
+	}
+	server.address = packetConn.LocalAddr().String()
+
+	for _, zone := range options.Zones {
+		if err := server.AddZoneE(t, zone); err != nil {
+			packetConn.Close()
+			listener.Close()
+			return nil, err
+		}
+	}
+	for origin, path := range options.ZoneFiles {
+		if err := server.LoadZoneFileE(t, origin, path); err != nil {
+			packetConn.Close()
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	handler := dns.HandlerFunc(server.serveDNS)
+	server.udpServer = &dns.Server{PacketConn: packetConn, Handler: handler}
+	server.tcpServer = &dns.Server{Listener: listener, Handler: handler}
+	for _, s := range []*dns.Server{server.udpServer, server.tcpServer} {
+		started := make(chan struct{})
+		s.NotifyStartedFunc = func() { close(started) }
+		go s.ActivateAndServe()
+		<-started
+	}
+
+	logger.Logf(t, "Started local DNS server at %s", server.address)
+
+	if tt, ok := t.(interface{ Cleanup(func()) }); ok {
+		tt.Cleanup(server.Close)
+	}
+	return server, nil
+}
+
+// listenUDPAndTCP listens on the same ephemeral port of 127.0.0.1 for both UDP and TCP
+func listenUDPAndTCP() (net.PacketConn, net.Listener, error) {
+	var err error
+	for i := 0; i < 10; i++ {
+		var packetConn net.PacketConn
+		packetConn, err = net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var listener net.Listener
+		listener, err = net.Listen("tcp", packetConn.LocalAddr().String())
+		if err == nil {
+			return packetConn, listener, nil
+		}
+		packetConn.Close()
+	}
+
+	return nil, nil, err
+}
+
+// Address returns the host:port address of the server, to be used as resolver
+func (s *LocalDNSServer) Address() string {
+	return s.address
+}
+
+// AddZone adds the zone to the server, replacing any zone with the same origin.
+// Fails on any error.
+func (s *LocalDNSServer) AddZone(t testing.TestingT, zone DNSZone) {
+	err := s.AddZoneE(t, zone)
+	require.NoError(t, err)
+}
+
+// AddZoneE adds the zone to the server, replacing any zone with the same origin.
+// Returns an error if any record is invalid.
+func (s *LocalDNSServer) AddZoneE(t testing.TestingT, zone DNSZone) error {
+	z := &localZone{origin: canonicalName(zone.Origin), ttl: zone.TTL, records: map[string]map[uint16][]dns.RR{}}
+	if z.ttl == 0 {
+		z.ttl = s.options.DefaultTTL
+	}
+
+	for name, answers := range zone.Records {
+		if !dns.IsSubDomain(z.origin, canonicalName(name)) {
+			err := &ZoneNotFoundError{Name: name, Zone: zone.Origin}
+			return err
+		}
+		for _, answer := range answers {
+			rr, err := newRR(name, answer, z.ttl)
+			if err != nil {
+				return err
+			}
+			z.add(rr)
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.addDefaultApexRecords(z)
+	s.zones[z.origin] = z
+
+	return nil
+}
+
+// LoadZoneFile adds the zone with the given origin to the server, reading its records from a zone file in RFC 1035
+// format. Relative names in the file are relative to the origin.
+// Fails on any error.
+func (s *LocalDNSServer) LoadZoneFile(t testing.TestingT, origin string, path string) {
+	err := s.LoadZoneFileE(t, origin, path)
+	require.NoError(t, err)
+}
+
+// LoadZoneFileE adds the zone with the given origin to the server, reading its records from a zone file in RFC 1035
+// format. Relative names in the file are relative to the origin.
+// Returns any error reading or parsing the file.
+func (s *LocalDNSServer) LoadZoneFileE(t testing.TestingT, origin string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	z := &localZone{origin: canonicalName(origin), ttl: s.options.DefaultTTL, records: map[string]map[uint16][]dns.RR{}}
+
+	parser := dns.NewZoneParser(file, z.origin, path)
+	parser.SetDefaultTTL(s.options.DefaultTTL)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		if !dns.IsSubDomain(z.origin, canonicalName(rr.Header().Name)) {
+			err := &ZoneNotFoundError{Name: rr.Header().Name, Zone: origin}
+			return err
+		}
+		z.add(rr)
+	}
+	if err := parser.Err(); err != nil {
+		return err
+	}
+
+	logger.Logf(t, "Loaded zone %s from %s", z.origin, path)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.addDefaultApexRecords(z)
+	s.zones[z.origin] = z
+
+	return nil
+}
+
+// RemoveZone stops serving the zone with the given origin
+func (s *LocalDNSServer) RemoveZone(origin string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.zones, canonicalName(origin))
+}
+
+// SetRecords replaces the records of the given type for the given name with the given answers, and increments the
+// serial of the zone. If answers is empty, the records are removed.
+// Fails if no zone of the server contains the name, or any answer is invalid.
+func (s *LocalDNSServer) SetRecords(t testing.TestingT, name string, recordType string, answers DNSAnswers) {
+	err := s.SetRecordsE(t, name, recordType, answers)
+	require.NoError(t, err)
+}
+
+// SetRecordsE replaces the records of the given type for the given name with the given answers, and increments the
+// serial of the zone. If answers is empty, the records are removed.
+// Returns ZoneNotFoundError if no zone of the server contains the name.
+// Returns QueryTypeError if the record type is unknown.
+// Returns any error parsing the answers.
+func (s *LocalDNSServer) SetRecordsE(t testing.TestingT, name string, recordType string, answers DNSAnswers) error {
+	rrType, ok := dns.StringToType[strings.ToUpper(recordType)]
+	if !ok {
+		err := &QueryTypeError{recordType}
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	z := s.findZone(canonicalName(name))
+	if z == nil {
+		err := &ZoneNotFoundError{Name: name}
+		return err
+	}
+
+	var rrs []dns.RR
+	for _, answer := range answers {
+		if answer.Type != recordType {
+			err := &RecordTypeError{Answer: answer, ExpectedType: recordType}
+			return err
+		}
+		rr, err := newRR(name, answer, z.ttl)
+		if err != nil {
+			return err
+		}
+		rrs = append(rrs, rr)
+	}
+
+	fqdn := canonicalName(name)
+	if len(rrs) == 0 {
+		delete(z.records[fqdn], rrType)
+		if len(z.records[fqdn]) == 0 {
+			delete(z.records, fqdn)
+		}
+	} else {
+		if z.records[fqdn] == nil {
+			z.records[fqdn] = map[uint16][]dns.RR{}
+		}
+		z.records[fqdn][rrType] = rrs
+	}
+
+	if rrType != dns.TypeSOA {
+		z.incrementSerial()
+	}
+
+	logger.Logf(t, "Set %s records of %s on local DNS server %s to %s", recordType, name, s.address, answers)
+
+	return nil
+}
+
+// Delegate makes the given subdomain of a zone of this server a separate zone served by the given servers, by adding
+// NS records for it that point to their addresses. The servers should serve a zone for the subdomain, e.g. added with
+// AddZone.
+// Fails on any error.
+func (s *LocalDNSServer) Delegate(t testing.TestingT, name string, servers ...*LocalDNSServer) {
+	err := s.DelegateE(t, name, servers...)
+	require.NoError(t, err)
+}
+
+// DelegateE makes the given subdomain of a zone of this server a separate zone served by the given servers, by adding
+// NS records for it that point to their addresses. The servers should serve a zone for the subdomain, e.g. added with
+// AddZone.
+// Returns ZoneNotFoundError if no zone of the server contains the name.
+func (s *LocalDNSServer) DelegateE(t testing.TestingT, name string, servers ...*LocalDNSServer) error {
+	var answers DNSAnswers
+	for _, server := range servers {
+		answers = append(answers, DNSAnswer{Type: "NS", Value: server.Address() + "."})
+	}
+	return s.SetRecordsE(t, name, "NS", answers)
+}
+
+// Queries returns the queries the server received, in the order they were received
+func (s *LocalDNSServer) Queries() []RecordedDNSQuery {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]RecordedDNSQuery{}, s.queries...)
+}
+
+// ResetQueries forgets the queries the server received so far
+func (s *LocalDNSServer) ResetQueries() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.queries = nil
+}
+
+// SetFailureMode makes the server fail to answer queries in the given way, until it's set back to DNSNoFailure
+func (s *LocalDNSServer) SetFailureMode(mode DNSFailureMode) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failure = mode
+}
+
+// Close stops the server. It's safe to call Close more than once.
+func (s *LocalDNSServer) Close() {
+	s.closeOnce.Do(func() {
+		s.udpServer.Shutdown()
+		s.tcpServer.Shutdown()
+	})
+}
+
+// newRR returns the record for the answer to the given name, with the given TTL
+func newRR(name string, answer DNSAnswer, ttl uint32) (dns.RR, error) {
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", canonicalName(name), ttl, answer.Type, answer.Value))
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil {
+		return nil, fmt.Errorf("answer %s has no data", answer)
+	}
+
+	return rr, nil
+}
+
+// addDefaultApexRecords adds an SOA record and NS records pointing to the server to the apex of the zone, unless it
+// already has them
+func (s *LocalDNSServer) addDefaultApexRecords(z *localZone) {
+	if len(z.records[z.origin][dns.TypeSOA]) == 0 {
+		z.add(&dns.SOA{
+			Hdr:     dns.RR_Header{Name: z.origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: z.ttl},
+			Ns:      s.address + ".",
+			Mbox:    "hostmaster." + z.origin,
+			Serial:  s.serialBase,
+			Refresh: 7200,
+			Retry:   900,
+			Expire:  1209600,
+			Minttl:  z.ttl,
+		})
+	}
+	if len(z.records[z.origin][dns.TypeNS]) == 0 {
+		z.add(&dns.NS{
+			Hdr: dns.RR_Header{Name: z.origin, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: z.ttl},
+			Ns:  s.address + ".",
+		})
+	}
+}
+
+// findZone returns the most specific zone that contains the fully qualified name, or nil if there is none
+func (s *LocalDNSServer) findZone(fqdn string) *localZone {
+	var found *localZone
+	for origin, z := range s.zones {
+		if dns.IsSubDomain(origin, fqdn) && (found == nil || dns.CountLabel(origin) > dns.CountLabel(found.origin)) {
+			found = z
+		}
+	}
+	return found
+}
+
+// serveDNS records and answers a query
+func (s *LocalDNSServer) serveDNS(w dns.ResponseWriter, r *dns.Msg) {
+	if len(r.Question) == 0 {
+		return
+	}
+	q := r.Question[0]
+
+	s.lock.Lock()
+	s.queries = append(s.queries, RecordedDNSQuery{
+		Query:            DNSQuery{Type: dns.TypeToString[q.Qtype], Name: strings.TrimSuffix(q.Name, ".")},
+		Protocol:         w.RemoteAddr().Network(),
+		Client:           w.RemoteAddr().String(),
+		RecursionDesired: r.RecursionDesired,
+		Time:             time.Now(),
+	})
+	failure := s.failure
+	s.lock.Unlock()
+
+	opt := r.IsEdns0()
+	dnssec := opt != nil && opt.Do()
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	switch failure {
+	case DNSFailByDropping:
+		return
+	case DNSFailWithServFail:
+		m.Rcode = dns.RcodeServerFailure
+	case DNSFailWithRefused:
+		m.Rcode = dns.RcodeRefused
+	default:
+		s.answer(m, q, dnssec)
+		if s.options.Recursive && r.RecursionDesired && !m.Authoritative && len(m.Ns) > 0 {
+			s.followReferrals(m, q)
+		}
+		if dnssec && s.options.Validating {
+			m.AuthenticatedData = hasSignatures(m.Answer)
+		}
+		if s.options.ReverseAnswers {
+			for i, j := 0, len(m.Answer)-1; i < j; i, j = i+1, j-1 {
+				m.Answer[i], m.Answer[j] = m.Answer[j], m.Answer[i]
+			}
+		}
+	}
+
+	// Like other nameservers, answers that don't fit in the buffer of the client are truncated over UDP, so that the
+	// client retries over TCP
+	if w.RemoteAddr().Network() == "udp" {
+		size := dns.MinMsgSize
+		if opt != nil {
+			size = int(opt.UDPSize())
+		}
+		m.Truncate(size)
+	}
+
+	w.WriteMsg(m)
+}
+
+// answer fills the reply to the question from the zones of the server. When dnssec is true, the answers include their
+// RRSIG records.
+func (s *LocalDNSServer) answer(m *dns.Msg, q dns.Question, dnssec bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name := canonicalName(q.Name)
+	for i := 0; i < 8; i++ {
+		z := s.findZone(name)
+		if z == nil {
+			if len(m.Answer) == 0 {
+				m.Rcode = dns.RcodeRefused
+			}
+			return
+		}
+
+		if referral := z.delegation(name, q.Qtype); referral != nil {
+			if len(m.Answer) == 0 {
+				m.Ns = referral
+				m.Extra = z.glue(referral)
+			}
+			return
+		}
+
+		m.Authoritative = true
+		records := z.records[name]
+		if rrs := records[q.Qtype]; len(rrs) > 0 {
+			m.Answer = append(m.Answer, rrs...)
+			if dnssec {
+				m.Answer = append(m.Answer, z.signatures(name, q.Qtype)...)
+			}
+			return
+		}
+
+		// Follow CNAME records within the zones of the server
+		if cnames := records[dns.TypeCNAME]; len(cnames) > 0 && q.Qtype != dns.TypeCNAME {
+			m.Answer = append(m.Answer, cnames[0])
+			if dnssec {
+				m.Answer = append(m.Answer, z.signatures(name, dns.TypeCNAME)...)
+			}
+			name = canonicalName(cnames[0].(*dns.CNAME).Target)
+			continue
+		}
+
+		if len(m.Answer) == 0 {
+			m.Ns = z.records[z.origin][dns.TypeSOA]
+			if !z.exists(name) {
+				m.Rcode = dns.RcodeNameError
+			}
+		}
+		return
+	}
+}
+
+// followReferrals resolves the question by sending it to the nameservers in the referral of the reply, and so on
+// until a nameserver answers it, and replaces the reply with that answer
+func (s *LocalDNSServer) followReferrals(m *dns.Msg, q dns.Question) {
+	client := new(dns.Client)
+	referral, glue := m.Ns, m.Extra
+
+	for i := 0; i < maxReferrals; i++ {
+		var in *dns.Msg
+		for _, address := range nameserverAddresses(referral, glue) {
+			query := new(dns.Msg)
+			query.SetQuestion(q.Name, q.Qtype)
+			query.RecursionDesired = false
+
+			var err error
+			in, _, err = client.Exchange(query, address)
+			if err == nil && in.Rcode != dns.RcodeServerFailure && in.Rcode != dns.RcodeRefused {
+				break
+			}
+			in = nil
+		}
+
+		if in == nil {
+			m.Rcode = dns.RcodeServerFailure
+			m.Ns, m.Extra = nil, nil
+			return
+		}
+
+		if in.Authoritative || len(in.Ns) == 0 || in.Ns[0].Header().Rrtype != dns.TypeNS {
+			m.Answer = append(m.Answer, in.Answer...)
+			m.Ns = in.Ns
+			m.Extra = nil
+			m.Rcode = in.Rcode
+			m.RecursionAvailable = true
+			return
+		}
+
+		referral, glue = in.Ns, in.Extra
+	}
+
+	m.Rcode = dns.RcodeServerFailure
+	m.Ns, m.Extra = nil, nil
+}
+
+// nameserverAddresses returns the addresses of the nameservers in the NS records, using their glue records, or their
+// host names when they are addresses, like the ones of a LocalDNSServer
+func nameserverAddresses(referral []dns.RR, glue []dns.RR) []string {
+	var addresses []string
+	for _, rr := range referral {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+
+		host := strings.TrimSuffix(ns.Ns, ".")
+		if _, _, err := net.SplitHostPort(host); err == nil {
+			addresses = append(addresses, host)
+			continue
+		}
+
+		for _, g := range glue {
+			switch a := g.(type) {
+			case *dns.A:
+				if strings.EqualFold(a.Hdr.Name, ns.Ns) {
+					addresses = append(addresses, net.JoinHostPort(a.A.String(), "53"))
+				}
+			case *dns.AAAA:
+				if strings.EqualFold(a.Hdr.Name, ns.Ns) {
+					addresses = append(addresses, net.JoinHostPort(a.AAAA.String(), "53"))
+				}
+			}
+		}
+	}
+	return addresses
+}
+
+// add adds the record to the zone
+func (z *localZone) add(rr dns.RR) {
+	name := canonicalName(rr.Header().Name)
+	rr.Header().Name = name
+	if z.records[name] == nil {
+		z.records[name] = map[uint16][]dns.RR{}
+	}
+	z.records[name][rr.Header().Rrtype] = append(z.records[name][rr.Header().Rrtype], rr)
+}
+
+// delegation returns the NS records of the closest zone cut between the name and the origin of the zone, if the name
+// is in a delegated subdomain. DS records are answered by the parent zone, so the cut itself isn't delegated for them.
+func (z *localZone) delegation(name string, qtype uint16) []dns.RR {
+	labels := dns.SplitDomainName(name)
+	originLabels := dns.CountLabel(z.origin)
+
+	for i := 0; i < len(labels)-originLabels; i++ {
+		candidate := dns.Fqdn(strings.Join(labels[i:], "."))
+		if i == 0 && qtype == dns.TypeDS {
+			continue
+		}
+		if ns := z.records[candidate][dns.TypeNS]; len(ns) > 0 {
+			return ns
+		}
+	}
+	return nil
+}
+
+// glue returns the address records in the zone for the nameservers of the referral
+func (z *localZone) glue(referral []dns.RR) []dns.RR {
+	var glue []dns.RR
+	for _, rr := range referral {
+		if ns, ok := rr.(*dns.NS); ok {
+			records := z.records[canonicalName(ns.Ns)]
+			glue = append(glue, records[dns.TypeA]...)
+			glue = append(glue, records[dns.TypeAAAA]...)
+		}
+	}
+	return glue
+}
+
+// signatures returns the RRSIG records of the name that cover its records of the given type
+func (z *localZone) signatures(name string, rrType uint16) []dns.RR {
+	var signatures []dns.RR
+	for _, rr := range z.records[name][dns.TypeRRSIG] {
+		if rr.(*dns.RRSIG).TypeCovered == rrType {
+			signatures = append(signatures, rr)
+		}
+	}
+	return signatures
+}
+
+// exists returns true if the zone has records for the name or for any name below it
+func (z *localZone) exists(name string) bool {
+	if len(z.records[name]) > 0 {
+		return true
+	}
+	for other := range z.records {
+		if strings.HasSuffix(other, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// incrementSerial increments the serial of the SOA record of the zone
+func (z *localZone) incrementSerial() {
+	// The records may be being sent in a reply, so they are replaced instead of modified
+	var soas []dns.RR
+	for _, rr := range z.records[z.origin][dns.TypeSOA] {
+		soa := dns.Copy(rr).(*dns.SOA)
+		soa.Serial++
+		soas = append(soas, soa)
+	}
+	z.records[z.origin][dns.TypeSOA] = soas
+}
+
+// hasSignatures returns true if any of the records is an RRSIG record
+func hasSignatures(rrs []dns.RR) bool {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeRRSIG {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalName returns the name fully qualified and in lower case
+func canonicalName(name string) string {
+	return dns.CanonicalName(name)
+}
@@ -0,0 +1,251 @@
+package dns_helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var localTestZone = DNSZone{
+	Origin: "example.com",
+	Records: map[string]DNSAnswers{
+		"example.com": {
+			{Type: "MX", Value: "10 mail.example.com."},
+			{Type: "TXT", Value: `"v=spf1 -all"`},
+		},
+		"www.example.com": {
+			{Type: "A", Value: "10.0.0.1"},
+			{Type: "A", Value: "10.0.0.2"},
+		},
+		"alias.example.com": {
+			{Type: "CNAME", Value: "www.example.com."},
+		},
+		"_https._tcp.example.com": {
+			{Type: "SRV", Value: "10 5 443 www.example.com."},
+		},
+	},
+	TTL: 60,
+}
+
+func TestLocalDNSServerZones(t *testing.T) {
+	t.Parallel()
+	s := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{localTestZone}})
+	resolvers := []string{s.Address()}
+
+	assert.Equal(t, DNSAnswers{{Type: "A", Value: "10.0.0.1"}, {Type: "A", Value: "10.0.0.2"}}, DNSLookup(t, DNSQuery{"A", "www.example.com"}, resolvers))
+	assert.Equal(t, DNSAnswersWithTTL{{DNSAnswer{"MX", "10 mail.example.com."}, 60}}, DNSLookupWithTTL(t, DNSQuery{"MX", "EXAMPLE.com"}, resolvers))
+	assert.Equal(t, DNSAnswers{
+		{Type: "A", Value: "10.0.0.1"},
+		{Type: "A", Value: "10.0.0.2"},
+		{Type: "CNAME", Value: "www.example.com."},
+	}, DNSLookup(t, DNSQuery{"A", "alias.example.com"}, resolvers))
+
+	// The zone gets an SOA record and the server as nameserver, so that the authoritative lookups work
+	soa, err := DNSLookup(t, DNSQuery{"SOA", "example.com"}, resolvers)[0].SOA()
+	require.NoError(t, err)
+	assert.Equal(t, "hostmaster.example.com.", soa.Mailbox)
+	assert.Equal(t, DNSAnswers{{Type: "NS", Value: s.Address() + "."}}, DNSLookup(t, DNSQuery{"NS", "example.com"}, resolvers))
+	DNSLookupAuthoritativeAllWithValidation(t, DNSQuery{"SRV", "_https._tcp.example.com"}, resolvers, DNSAnswers{{Type: "SRV", Value: "10 5 443 www.example.com."}})
+
+	// Missing names and records, and names outside the zones of the server
+	for _, testCase := range []struct {
+		query DNSQuery
+		rcode int
+	}{
+		{DNSQuery{"A", "missing.example.com"}, dns.RcodeNameError},
+		{DNSQuery{"AAAA", "www.example.com"}, dns.RcodeSuccess},
+		{DNSQuery{"A", "_tcp.example.com"}, dns.RcodeSuccess},
+		{DNSQuery{"A", "example.org"}, dns.RcodeRefused},
+	} {
+		_, err := DNSLookupE(t, testCase.query, resolvers)
+		if _, ok := err.(*NotFoundError); !ok {
+			t.Errorf("unexpected error for %s, got %q", testCase.query, err)
+		}
+		assert.Equal(t, testCase.rcode, exchange(t, s, testCase.query, "udp", true).Rcode, fmt.Sprintf("%s %s", testCase.query.Type, testCase.query.Name))
+	}
+
+	// Queries over TCP work too, and all queries are recorded
+	s.ResetQueries()
+	in := exchange(t, s, DNSQuery{"A", "www.example.com"}, "tcp", true)
+	assert.True(t, in.Authoritative)
+	assert.Len(t, in.Answer, 2)
+	queries := s.Queries()
+	require.Len(t, queries, 1)
+	assert.Equal(t, DNSQuery{"A", "www.example.com"}, queries[0].Query)
+	assert.Equal(t, "tcp", queries[0].Protocol)
+	assert.True(t, queries[0].RecursionDesired)
+}
+
+func TestLocalDNSServerZoneFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "example.com.zone")
+	zoneFile := `$TTL 120
+@       IN SOA ns1 hostmaster 2020010101 7200 900 1209600 60
+        IN NS  ns1
+ns1     IN A   127.0.0.1
+www 30  IN A   10.0.0.1
+`
+	require.NoError(t, ioutil.WriteFile(path, []byte(zoneFile), 0644))
+
+	s := NewLocalDNSServer(t, &LocalDNSServerOptions{ZoneFiles: map[string]string{"example.com": path}})
+	resolvers := []string{s.Address()}
+
+	assert.Equal(t, DNSAnswersWithTTL{{DNSAnswer{"A", "10.0.0.1"}, 30}}, DNSLookupWithTTL(t, DNSQuery{"A", "www.example.com"}, resolvers))
+	assert.Equal(t, DNSAnswersWithTTL{{DNSAnswer{"NS", "ns1.example.com."}, 120}}, DNSLookupWithTTL(t, DNSQuery{"NS", "example.com"}, resolvers))
+	soa, err := DNSLookup(t, DNSQuery{"SOA", "example.com"}, resolvers)[0].SOA()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2020010101), soa.Serial)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("www.example.org. IN A 10.0.0.1\n"), 0644))
+	err = s.LoadZoneFileE(t, "example.com", path)
+	if _, ok := err.(*ZoneNotFoundError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+	assert.Error(t, s.LoadZoneFileE(t, "example.com", filepath.Join(t.TempDir(), "missing.zone")))
+}
+
+func TestLocalDNSServerUpdates(t *testing.T) {
+	t.Parallel()
+	s := NewLocalDNSServer(t, nil)
+	resolvers := []string{s.Address()}
+
+	s.AddZone(t, DNSZone{Origin: "example.com", TTL: 60})
+	serial := func() uint32 {
+		soa, err := DNSLookup(t, DNSQuery{"SOA", "example.com"}, resolvers)[0].SOA()
+		require.NoError(t, err)
+		return soa.Serial
+	}
+	initialSerial := serial()
+
+	s.SetRecords(t, "www.example.com", "A", DNSAnswers{{Type: "A", Value: "10.0.0.1"}})
+	assert.Equal(t, DNSAnswersWithTTL{{DNSAnswer{"A", "10.0.0.1"}, 60}}, DNSLookupWithTTL(t, DNSQuery{"A", "www.example.com"}, resolvers))
+	assert.Equal(t, initialSerial+1, serial())
+
+	s.SetRecords(t, "www.example.com", "A", DNSAnswers{{Type: "A", Value: "10.0.0.2"}})
+	assert.Equal(t, DNSAnswers{{Type: "A", Value: "10.0.0.2"}}, DNSLookup(t, DNSQuery{"A", "www.example.com"}, resolvers))
+
+	s.SetRecords(t, "www.example.com", "A", nil)
+	_, err := DNSLookupE(t, DNSQuery{"A", "www.example.com"}, resolvers)
+	if _, ok := err.(*NotFoundError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+	assert.Equal(t, initialSerial+3, serial())
+
+	err = s.SetRecordsE(t, "www.example.org", "A", DNSAnswers{{Type: "A", Value: "10.0.0.1"}})
+	if _, ok := err.(*ZoneNotFoundError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+	err = s.SetRecordsE(t, "www.example.com", "A", DNSAnswers{{Type: "AAAA", Value: "::1"}})
+	if _, ok := err.(*RecordTypeError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+	err = s.AddZoneE(t, DNSZone{Origin: "example.net", Records: map[string]DNSAnswers{"example.com": {{Type: "A", Value: "10.0.0.1"}}}})
+	if _, ok := err.(*ZoneNotFoundError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+
+	s.RemoveZone("example.com")
+	assert.Equal(t, dns.RcodeRefused, exchange(t, s, DNSQuery{"SOA", "example.com"}, "udp", false).Rcode)
+}
+
+func TestLocalDNSServerDelegation(t *testing.T) {
+	t.Parallel()
+	parent := NewLocalDNSServer(t, &LocalDNSServerOptions{Recursive: true, Zones: []DNSZone{{Origin: "example.com"}}})
+	child1 := NewLocalDNSServer(t, nil)
+	child2 := NewLocalDNSServer(t, nil)
+	sub := DNSZone{Origin: "sub.example.com", Records: map[string]DNSAnswers{"www.sub.example.com": {{Type: "A", Value: "10.0.0.3"}}}}
+	child1.AddZone(t, sub)
+	child2.AddZone(t, sub)
+	parent.Delegate(t, "sub.example.com", child1, child2)
+
+	// Without recursion, the parent answers with a referral to the children
+	in := exchange(t, parent, DNSQuery{"A", "www.sub.example.com"}, "udp", false)
+	assert.False(t, in.Authoritative)
+	assert.Empty(t, in.Answer)
+	assert.Len(t, in.Ns, 2)
+
+	// With recursion, the parent follows the referral
+	in = exchange(t, parent, DNSQuery{"A", "www.sub.example.com"}, "udp", true)
+	assert.True(t, in.RecursionAvailable)
+	require.Len(t, in.Answer, 1)
+	assert.Equal(t, "10.0.0.3", in.Answer[0].(*dns.A).A.String())
+
+	// The authoritative nameservers of the subdomain are found through the parent. Each child lists only itself as
+	// nameserver of its zone, so the answer comes from whichever child the parent asked.
+	query := DNSQuery{"A", "www.sub.example.com"}
+	nameservers := DNSFindNameservers(t, query.Name, []string{parent.Address()})
+	require.Len(t, nameservers, 1)
+	assert.Contains(t, []string{child1.Address(), child2.Address()}, nameservers[0])
+	DNSLookupAuthoritativeAllWithValidation(t, query, []string{parent.Address()}, DNSAnswers{{Type: "A", Value: "10.0.0.3"}})
+
+	// The parent fails over to the other child when one fails
+	child1.SetFailureMode(DNSFailWithServFail)
+	assert.Equal(t, DNSAnswers{{Type: "A", Value: "10.0.0.3"}}, DNSLookup(t, query, []string{parent.Address()}))
+	child2.SetFailureMode(DNSFailWithRefused)
+	assert.Equal(t, dns.RcodeServerFailure, exchange(t, parent, query, "udp", true).Rcode)
+
+	// The DS records of the subdomain are answered by the parent
+	parent.SetRecords(t, "sub.example.com", "DS", DNSAnswers{{Type: "DS", Value: "12345 13 2 3490A6806D47F17A34C29E2CE80E8A999FFBE4BE"}})
+	assert.Len(t, DNSLookup(t, DNSQuery{"DS", "sub.example.com"}, []string{parent.Address()}), 1)
+}
+
+func TestLocalDNSServerFailureModes(t *testing.T) {
+	t.Parallel()
+	s1 := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{localTestZone}})
+	s2 := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{localTestZone}})
+	query := DNSQuery{"A", "www.example.com"}
+
+	s1.SetFailureMode(DNSFailWithServFail)
+	assert.Equal(t, dns.RcodeServerFailure, exchange(t, s1, query, "udp", false).Rcode)
+	assert.Len(t, DNSLookup(t, query, []string{s1.Address(), s2.Address()}), 2)
+
+	s1.SetFailureMode(DNSFailByDropping)
+	c := &dns.Client{Timeout: 100 * time.Millisecond}
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	_, _, err := c.Exchange(m, s1.Address())
+	assert.Error(t, err)
+
+	s1.SetFailureMode(DNSNoFailure)
+	assert.Len(t, DNSLookup(t, query, []string{s1.Address()}), 2)
+	assert.Len(t, s1.Queries(), 4)
+
+	s1.Close()
+	s1.Close()
+	_, err = DNSLookupE(t, query, []string{s1.Address()})
+	assert.Error(t, err)
+}
+
+func TestLocalDNSServerReverseAnswers(t *testing.T) {
+	t.Parallel()
+	s1 := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{localTestZone}})
+	s2 := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{localTestZone}, ReverseAnswers: true})
+	query := DNSQuery{"A", "www.example.com"}
+
+	in1 := exchange(t, s1, query, "udp", false)
+	in2 := exchange(t, s2, query, "udp", false)
+	require.Len(t, in1.Answer, 2)
+	require.Len(t, in2.Answer, 2)
+	assert.Equal(t, in1.Answer[0].String(), in2.Answer[1].String())
+	assert.Equal(t, in1.Answer[1].String(), in2.Answer[0].String())
+
+	// The lookups don't depend on the order of the answers
+	assert.Equal(t, DNSLookup(t, query, []string{s1.Address()}), DNSLookup(t, query, []string{s2.Address()}))
+}
+
+// exchange sends the DNS query to the server over the given protocol and returns the reply
+func exchange(t *testing.T, s *LocalDNSServer, query DNSQuery, protocol string, recursionDesired bool) *dns.Msg {
+	c := &dns.Client{Net: protocol}
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(query.Name), dns.StringToType[query.Type])
+	m.RecursionDesired = recursionDesired
+	in, _, err := c.Exchange(m, s.Address())
+	require.NoError(t, err)
+	return in
+}
@@ -0,0 +1,333 @@
+package dns_helper
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+// DNSProtocol is the transport used to send DNS queries to a resolver
+type DNSProtocol string
+
+const (
+	// DNSOverUDP sends queries over plain UDP, the default
+	DNSOverUDP DNSProtocol = "udp"
+	// DNSOverTCP sends queries over plain TCP
+	DNSOverTCP DNSProtocol = "tcp"
+	// DNSOverHTTPS sends queries as RFC 8484 POST requests to a DNS-over-HTTPS endpoint
+	DNSOverHTTPS DNSProtocol = "https"
+)
+
+// DefaultDNSQueryTimeout is the timeout of each DNS query, unless the options of a propagation check set another one
+const DefaultDNSQueryTimeout = 5 * time.Second
+
+// dnsMessageContentType is the media type of DNS messages in DNS-over-HTTPS requests and responses
+const dnsMessageContentType = "application/dns-message"
+
+// DNSResolver is a resolver to query in a propagation check
+type DNSResolver struct {
+	// Address is host[:port] for UDP and TCP, with 53 as the default port, or the URL of the endpoint for
+	// DNS-over-HTTPS, e.g. https://cloudflare-dns.com/dns-query
+	Address string
+	// Protocol defaults to DNSOverUDP
+	Protocol DNSProtocol
+}
+
+func (r DNSResolver) protocol() DNSProtocol {
+	if r.Protocol == "" {
+		return DNSOverUDP
+	}
+	return r.Protocol
+}
+
+func (r DNSResolver) String() string {
+	if r.protocol() == DNSOverHTTPS {
+		return r.Address
+	}
+	return fmt.Sprintf("%s/%s", withDefaultPort(r.Address), r.protocol())
+}
+
+// DNSPropagationOptions configures which resolvers a propagation check queries and how many of them must agree
+type DNSPropagationOptions struct {
+	// Resolvers are the recursive resolvers to query
+	Resolvers []DNSResolver
+
+	// Authoritative also queries all the authoritative nameservers of the name, as found by DNSFindNameservers
+	Authoritative bool
+	// NameserverResolvers are used to find the authoritative nameservers. If nil, the system ones are used.
+	NameserverResolvers []string
+	// AuthoritativeProtocol is the protocol used to query the authoritative nameservers. Defaults to DNSOverUDP.
+	AuthoritativeProtocol DNSProtocol
+
+	// Quorum is the number of resolvers, authoritative nameservers included, that must give the expected answers.
+	// Zero means all of them. A negative quorum, or one greater than the number of resolvers, is an error.
+	Quorum int
+	// Timeout of each query. Defaults to DefaultDNSQueryTimeout.
+	Timeout time.Duration
+	// HTTPClient is used for DNS-over-HTTPS queries, e.g. to trust a test certificate. Defaults to a client with the
+	// query timeout.
+	HTTPClient *http.Client
+}
+
+func (options *DNSPropagationOptions) timeout() time.Duration {
+	if options.Timeout == 0 {
+		return DefaultDNSQueryTimeout
+	}
+	return options.Timeout
+}
+
+func (options *DNSPropagationOptions) httpClient() *http.Client {
+	if options.HTTPClient == nil {
+		return &http.Client{Timeout: options.timeout()}
+	}
+	return options.HTTPClient
+}
+
+// DNSResolverResult is the outcome of a DNS query to a single resolver
+type DNSResolverResult struct {
+	Resolver DNSResolver
+	// Authoritative is true when the resolver is one of the authoritative nameservers of the name
+	Authoritative bool
+	Answers       DNSAnswers
+	// Error is set when the query failed or got no answers, in which case it's a NotFoundError
+	Error error
+}
+
+// DNSPropagation is the answer matrix of a propagation check, with the result of each resolver in the order they were
+// queried: the recursive resolvers first, then the authoritative nameservers.
+type DNSPropagation struct {
+	Query   DNSQuery
+	Results []DNSResolverResult
+}
+
+// Matching returns the results whose answers match the expected ones, in any order.
+func (p DNSPropagation) Matching(expectedAnswers DNSAnswers) []DNSResolverResult {
+	var matching []DNSResolverResult
+	for _, result := range p.Results {
+		if result.Error == nil && result.Answers.Matches(expectedAnswers) {
+			matching = append(matching, result)
+		}
+	}
+	return matching
+}
+
+func (p DNSPropagation) String() string {
+	lines := []string{fmt.Sprintf("Answers to DNS query %s:", p.Query)}
+	for _, result := range p.Results {
+		resolver := result.Resolver.String()
+		if result.Authoritative {
+			resolver += " (authoritative)"
+		}
+		if result.Error != nil {
+			lines = append(lines, fmt.Sprintf("- %s: error: %s", resolver, result.Error))
+		} else {
+			lines = append(lines, fmt.Sprintf("- %s: %s", resolver, result.Answers))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DNSLookupPropagation sends the DNS query to all the resolvers of the options, and to the authoritative nameservers
+// of the name if enabled, and returns the answers of each one. Fails on any error from DNSLookupPropagationE.
+func DNSLookupPropagation(t testing.TestingT, query DNSQuery, options *DNSPropagationOptions) DNSPropagation {
+	res, err := DNSLookupPropagationE(t, query, options)
+	require.NoError(t, err)
+	return res
+}
+
+// DNSLookupPropagationE sends the DNS query to all the resolvers of the options, and to the authoritative nameservers
+// of the name if enabled, and returns the answers of each one. Failed queries are reported in the results.
+// Returns QueryTypeError when record type is not supported.
+// Returns NoResolversError when there are no resolvers to query.
+// Returns any underlying error from DNSFindNameserversE.
+func DNSLookupPropagationE(t testing.TestingT, query DNSQuery, options *DNSPropagationOptions) (DNSPropagation, error) {
+	if options == nil {
+		options = &DNSPropagationOptions{}
+	}
+
+	if _, err := newQueryMsg(query, false); err != nil {
+		return DNSPropagation{}, err
+	}
+
+	results := []DNSResolverResult{}
+	for _, resolver := range options.Resolvers {
+		results = append(results, DNSResolverResult{Resolver: resolver})
+	}
+
+	if options.Authoritative {
+		nameservers, err := DNSFindNameserversE(t, query.Name, options.NameserverResolvers)
+		if err != nil {
+			return DNSPropagation{}, err
+		}
+		for _, ns := range nameservers {
+			resolver := DNSResolver{Address: ns, Protocol: options.AuthoritativeProtocol}
+			results = append(results, DNSResolverResult{Resolver: resolver, Authoritative: true})
+		}
+	}
+
+	if len(results) == 0 {
+		err := &NoResolversError{}
+		return DNSPropagation{}, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(result *DNSResolverResult) {
+			defer wg.Done()
+			result.Answers, result.Error = dnsLookupWithProtocol(query, result.Resolver, options)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	propagation := DNSPropagation{Query: query, Results: results}
+	logger.Logf(t, "%s", propagation)
+	return propagation, nil
+}
+
+// AssertDNSPropagated checks that at least a quorum of the resolvers of the options give the expected answers to the
+// DNS query. Fails on any error from AssertDNSPropagatedE.
+func AssertDNSPropagated(t testing.TestingT, query DNSQuery, expectedAnswers DNSAnswers, options *DNSPropagationOptions) DNSPropagation {
+	res, err := AssertDNSPropagatedE(t, query, expectedAnswers, options)
+	require.NoError(t, err)
+	return res
+}
+
+// AssertDNSPropagatedE checks that at least a quorum of the resolvers of the options give the expected answers to the
+// DNS query, and returns the answers of each resolver.
+// Returns PropagationError when fewer resolvers than the quorum give the expected answers.
+// Returns QuorumError when the quorum is negative or greater than the number of resolvers.
+// Returns any underlying error from DNSLookupPropagationE.
+func AssertDNSPropagatedE(t testing.TestingT, query DNSQuery, expectedAnswers DNSAnswers, options *DNSPropagationOptions) (DNSPropagation, error) {
+	if options != nil && options.Quorum < 0 {
+		err := &QuorumError{Quorum: options.Quorum, Resolvers: len(options.Resolvers)}
+		return DNSPropagation{}, err
+	}
+
+	propagation, err := DNSLookupPropagationE(t, query, options)
+	if err != nil {
+		return propagation, err
+	}
+
+	quorum := len(propagation.Results)
+	if options != nil && options.Quorum > 0 {
+		quorum = options.Quorum
+	}
+	if quorum > len(propagation.Results) {
+		err := &QuorumError{Quorum: quorum, Resolvers: len(propagation.Results)}
+		return propagation, err
+	}
+
+	matching := len(propagation.Matching(expectedAnswers))
+	if matching < quorum {
+		err := &PropagationError{Propagation: propagation, ExpectedAnswers: expectedAnswers, Matching: matching, Quorum: quorum}
+		return propagation, err
+	}
+
+	return propagation, nil
+}
+
+// WaitForDNSPropagation repeatedly sends the DNS query to the resolvers of the options until at least a quorum of them
+// give the expected answers, or until max retries has been exceeded. Fails when max retries has been exceeded.
+func WaitForDNSPropagation(t testing.TestingT, query DNSQuery, expectedAnswers DNSAnswers, options *DNSPropagationOptions, maxRetries int, sleepBetweenRetries time.Duration) DNSPropagation {
+	res, err := WaitForDNSPropagationE(t, query, expectedAnswers, options, maxRetries, sleepBetweenRetries)
+	require.NoError(t, err)
+	return res
+}
+
+// WaitForDNSPropagationE repeatedly sends the DNS query to the resolvers of the options until at least a quorum of
+// them give the expected answers, or until max retries has been exceeded, and returns the answers of each resolver.
+func WaitForDNSPropagationE(t testing.TestingT, query DNSQuery, expectedAnswers DNSAnswers, options *DNSPropagationOptions, maxRetries int, sleepBetweenRetries time.Duration) (DNSPropagation, error) {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("Waiting for propagation of %s record for %s", query.Type, query.Name),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+
+	return retry.DoE(t, policy, func() (DNSPropagation, error) {
+		return AssertDNSPropagatedE(t, query, expectedAnswers, options)
+	})
+}
+
+// dnsLookupWithProtocol sends a DNS query for the specified record and type to the given resolver, using the protocol
+// of the resolver. Truncated replies over UDP are retried over TCP. If no records found, returns NotFoundError.
+func dnsLookupWithProtocol(query DNSQuery, resolver DNSResolver, options *DNSPropagationOptions) (DNSAnswers, error) {
+	m, err := newQueryMsg(query, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var in *dns.Msg
+	switch resolver.protocol() {
+	case DNSOverUDP, DNSOverTCP:
+		c := &dns.Client{Net: string(resolver.protocol()), Timeout: options.timeout()}
+		in, err = dnsExchange(c, m, resolver.Address)
+	case DNSOverHTTPS:
+		in, err = dnsOverHTTPSExchange(options.httpClient(), m, resolver.Address)
+	default:
+		err = &ProtocolError{resolver.Protocol}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Rcode != dns.RcodeSuccess && in.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s replied %s", resolver, dns.RcodeToString[in.Rcode])
+	}
+
+	dnsAnswers, _ := answersFromMsg(in)
+	if len(dnsAnswers) == 0 {
+		err := &NotFoundError{query, resolver.String()}
+		return nil, err
+	}
+
+	return dnsAnswers.Answers(), nil
+}
+
+// dnsOverHTTPSExchange sends the DNS message to the DNS-over-HTTPS endpoint at the given URL as described in RFC 8484
+// and returns the reply.
+func dnsOverHTTPSExchange(client *http.Client, m *dns.Msg, url string) (*dns.Msg, error) {
+	// RFC 8484 recommends an ID of 0 so that the responses can be cached
+	m.Id = 0
+	packed, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dnsMessageContentType)
+	req.Header.Set("Accept", dnsMessageContentType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS request to %s failed with status %d: %s", url, resp.StatusCode, body)
+	}
+
+	in := new(dns.Msg)
+	if err := in.Unpack(body); err != nil {
+		return nil, fmt.Errorf("invalid DNS-over-HTTPS response from %s: %v", url, err)
+	}
+	return in, nil
+}
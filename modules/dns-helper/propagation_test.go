@@ -0,0 +1,191 @@
+package dns_helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var propagationTestZone = DNSZone{
+	Origin: "example.com",
+	Records: map[string]DNSAnswers{
+		"www.example.com": {{Type: "A", Value: "10.0.0.1"}},
+	},
+}
+
+// runDNSOverHTTPSServer runs a DNS-over-HTTPS endpoint that forwards the queries to the given local DNS server
+func runDNSOverHTTPSServer(t *testing.T, upstream *LocalDNSServer) *httptest.Server {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dnsMessageContentType {
+			http.Error(w, "unsupported request", http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		m := new(dns.Msg)
+		if err := m.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		in, err := dns.Exchange(m, upstream.Address())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		packed, err := in.Pack()
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", dnsMessageContentType)
+		w.Write(packed)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDNSLookupPropagation(t *testing.T) {
+	t.Parallel()
+	authoritative := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	udpResolver := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	tcpResolver := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	dohUpstream := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	doh := runDNSOverHTTPSServer(t, dohUpstream)
+
+	options := &DNSPropagationOptions{
+		Resolvers: []DNSResolver{
+			{Address: udpResolver.Address()},
+			{Address: tcpResolver.Address(), Protocol: DNSOverTCP},
+			{Address: doh.URL, Protocol: DNSOverHTTPS},
+		},
+		Authoritative:         true,
+		NameserverResolvers:   []string{authoritative.Address()},
+		AuthoritativeProtocol: DNSOverTCP,
+		HTTPClient:            doh.Client(),
+	}
+	query := DNSQuery{"A", "www.example.com"}
+	expected := DNSAnswers{{Type: "A", Value: "10.0.0.1"}}
+
+	propagation := DNSLookupPropagation(t, query, options)
+	require.Len(t, propagation.Results, 4)
+	for _, result := range propagation.Results {
+		assert.NoError(t, result.Error)
+		assert.Equal(t, expected, result.Answers)
+	}
+	assert.Equal(t, []bool{false, false, false, true}, []bool{
+		propagation.Results[0].Authoritative, propagation.Results[1].Authoritative,
+		propagation.Results[2].Authoritative, propagation.Results[3].Authoritative,
+	})
+	assert.Equal(t, DNSResolver{Address: authoritative.Address(), Protocol: DNSOverTCP}, propagation.Results[3].Resolver)
+	assert.Equal(t, "udp", udpResolver.Queries()[0].Protocol)
+	assert.Equal(t, "tcp", tcpResolver.Queries()[0].Protocol)
+	assert.Len(t, dohUpstream.Queries(), 1)
+	assert.Len(t, AssertDNSPropagated(t, query, expected, options).Matching(expected), 4)
+
+	// One stale and one failing resolver only pass with a lower quorum
+	tcpResolver.SetRecords(t, "www.example.com", "A", DNSAnswers{{Type: "A", Value: "10.0.0.9"}})
+	dohUpstream.SetFailureMode(DNSFailWithServFail)
+	propagation, err := AssertDNSPropagatedE(t, query, expected, options)
+	require.Error(t, err)
+	propagationErr, ok := err.(*PropagationError)
+	require.True(t, ok, "unexpected error %q", err)
+	assert.Equal(t, 2, propagationErr.Matching)
+	assert.Equal(t, 4, propagationErr.Quorum)
+	assert.Equal(t, DNSAnswers{{Type: "A", Value: "10.0.0.9"}}, propagation.Results[1].Answers)
+	assert.Error(t, propagation.Results[2].Error)
+	assert.Contains(t, err.Error(), doh.URL+": error:")
+
+	options.Quorum = 2
+	AssertDNSPropagated(t, query, expected, options)
+
+	// Resolvers replying NXDOMAIN have no answers
+	missing := DNSLookupPropagation(t, DNSQuery{"A", "missing.example.com"}, options)
+	if _, ok := missing.Results[0].Error.(*NotFoundError); !ok {
+		t.Errorf("unexpected error, got %q", missing.Results[0].Error)
+	}
+}
+
+func TestWaitForDNSPropagation(t *testing.T) {
+	t.Parallel()
+	resolver1 := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	resolver2 := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	options := &DNSPropagationOptions{
+		Resolvers: []DNSResolver{{Address: resolver1.Address()}, {Address: resolver2.Address()}},
+		Timeout:   time.Second,
+	}
+	query := DNSQuery{"A", "www.example.com"}
+	expected := DNSAnswers{{Type: "A", Value: "10.0.0.2"}}
+
+	resolver1.SetRecords(t, "www.example.com", "A", expected)
+	_, err := WaitForDNSPropagationE(t, query, expected, options, 2, 10*time.Millisecond)
+	assert.Error(t, err)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		assert.NoError(t, resolver2.SetRecordsE(t, "www.example.com", "A", expected))
+	}()
+	propagation := WaitForDNSPropagation(t, query, expected, options, 50, 50*time.Millisecond)
+	assert.Len(t, propagation.Matching(expected), 2)
+}
+
+func TestDNSLookupPropagationTruncated(t *testing.T) {
+	t.Parallel()
+	var answers DNSAnswers
+	for i := 0; i < 20; i++ {
+		answers = append(answers, DNSAnswer{"TXT", fmt.Sprintf(`"%02d %s"`, i, strings.Repeat("x", 60))})
+	}
+	resolver := NewLocalDNSServer(t, &LocalDNSServerOptions{
+		Zones: []DNSZone{{Origin: "example.com", Records: map[string]DNSAnswers{"txt.example.com": answers}}},
+	})
+	options := &DNSPropagationOptions{Resolvers: []DNSResolver{{Address: resolver.Address()}}}
+
+	// The answers don't fit in 512 bytes, so the query is sent again over TCP
+	propagation := AssertDNSPropagated(t, DNSQuery{"TXT", "txt.example.com"}, answers, options)
+	assert.Equal(t, answers, propagation.Results[0].Answers)
+	queries := resolver.Queries()
+	require.Len(t, queries, 2)
+	assert.Equal(t, "udp", queries[0].Protocol)
+	assert.Equal(t, "tcp", queries[1].Protocol)
+}
+
+func TestDNSLookupPropagationErrors(t *testing.T) {
+	t.Parallel()
+	resolver := NewLocalDNSServer(t, &LocalDNSServerOptions{Zones: []DNSZone{propagationTestZone}})
+	query := DNSQuery{"A", "www.example.com"}
+
+	_, err := DNSLookupPropagationE(t, query, nil)
+	if _, ok := err.(*NoResolversError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+
+	options := &DNSPropagationOptions{Resolvers: []DNSResolver{{Address: resolver.Address()}}}
+	_, err = DNSLookupPropagationE(t, DNSQuery{"HINFO", "www.example.com"}, options)
+	if _, ok := err.(*QueryTypeError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+
+	notFound := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(notFound.Close)
+	options.Resolvers = []DNSResolver{
+		{Address: resolver.Address(), Protocol: "quic"},
+		{Address: notFound.URL, Protocol: DNSOverHTTPS},
+	}
+	propagation := DNSLookupPropagation(t, query, options)
+	if _, ok := propagation.Results[0].Error.(*ProtocolError); !ok {
+		t.Errorf("unexpected error, got %q", propagation.Results[0].Error)
+	}
+	assert.Contains(t, propagation.Results[1].Error.Error(), "status 404")
+
+	options = &DNSPropagationOptions{Resolvers: []DNSResolver{{Address: resolver.Address()}}, Quorum: 2}
+	_, err = AssertDNSPropagatedE(t, query, DNSAnswers{{"A", "10.0.0.1"}}, options)
+	assert.Equal(t, &QuorumError{Quorum: 2, Resolvers: 1}, err)
+
+	options.Quorum = -1
+	_, err = AssertDNSPropagatedE(t, query, DNSAnswers{{"A", "10.0.0.1"}}, options)
+	assert.Equal(t, &QuorumError{Quorum: -1, Resolvers: 1}, err)
+}
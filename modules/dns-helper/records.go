@@ -0,0 +1,147 @@
+package dns_helper
+
+import (
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// MXRecord is the data of an MX record
+type MXRecord struct {
+	Preference uint16
+	Host       string
+}
+
+// SRVRecord is the data of an SRV record
+type SRVRecord struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// CAARecord is the data of a CAA record
+type CAARecord struct {
+	Flag  uint8
+	Tag   string
+	Value string
+}
+
+// SOARecord is the data of an SOA record
+type SOARecord struct {
+	Nameserver string
+	Mailbox    string
+	Serial     uint32
+	Refresh    uint32
+	Retry      uint32
+	Expire     uint32
+	MinimumTTL uint32
+}
+
+// DSRecord is the data of a DS record
+type DSRecord struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     string
+}
+
+// DNSKEYRecord is the data of a DNSKEY record
+type DNSKEYRecord struct {
+	Flags     uint16
+	Protocol  uint8
+	Algorithm uint8
+	PublicKey string
+}
+
+// MX returns the data of an MX answer.
+// Returns RecordTypeError if the answer is of another type.
+func (a DNSAnswer) MX() (MXRecord, error) {
+	rr, err := a.parse("MX")
+	if err != nil {
+		return MXRecord{}, err
+	}
+	mx := rr.(*dns.MX)
+	return MXRecord{Preference: mx.Preference, Host: mx.Mx}, nil
+}
+
+// SRV returns the data of an SRV answer.
+// Returns RecordTypeError if the answer is of another type.
+func (a DNSAnswer) SRV() (SRVRecord, error) {
+	rr, err := a.parse("SRV")
+	if err != nil {
+		return SRVRecord{}, err
+	}
+	srv := rr.(*dns.SRV)
+	return SRVRecord{Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Target: srv.Target}, nil
+}
+
+// CAA returns the data of a CAA answer.
+// Returns RecordTypeError if the answer is of another type.
+func (a DNSAnswer) CAA() (CAARecord, error) {
+	rr, err := a.parse("CAA")
+	if err != nil {
+		return CAARecord{}, err
+	}
+	caa := rr.(*dns.CAA)
+	return CAARecord{Flag: caa.Flag, Tag: caa.Tag, Value: caa.Value}, nil
+}
+
+// SOA returns the data of an SOA answer.
+// Returns RecordTypeError if the answer is of another type.
+func (a DNSAnswer) SOA() (SOARecord, error) {
+	rr, err := a.parse("SOA")
+	if err != nil {
+		return SOARecord{}, err
+	}
+	soa := rr.(*dns.SOA)
+	return SOARecord{
+		Nameserver: soa.Ns,
+		Mailbox:    soa.Mbox,
+		Serial:     soa.Serial,
+		Refresh:    soa.Refresh,
+		Retry:      soa.Retry,
+		Expire:     soa.Expire,
+		MinimumTTL: soa.Minttl,
+	}, nil
+}
+
+// DS returns the data of a DS answer.
+// Returns RecordTypeError if the answer is of another type.
+func (a DNSAnswer) DS() (DSRecord, error) {
+	rr, err := a.parse("DS")
+	if err != nil {
+		return DSRecord{}, err
+	}
+	ds := rr.(*dns.DS)
+	return DSRecord{KeyTag: ds.KeyTag, Algorithm: ds.Algorithm, DigestType: ds.DigestType, Digest: ds.Digest}, nil
+}
+
+// DNSKEY returns the data of a DNSKEY answer.
+// Returns RecordTypeError if the answer is of another type.
+func (a DNSAnswer) DNSKEY() (DNSKEYRecord, error) {
+	rr, err := a.parse("DNSKEY")
+	if err != nil {
+		return DNSKEYRecord{}, err
+	}
+	key := rr.(*dns.DNSKEY)
+	return DNSKEYRecord{Flags: key.Flags, Protocol: key.Protocol, Algorithm: key.Algorithm, PublicKey: key.PublicKey}, nil
+}
+
+// parse parses the answer as a record of the expected type
+func (a DNSAnswer) parse(expectedType string) (dns.RR, error) {
+	if a.Type != expectedType {
+		err := &RecordTypeError{Answer: a, ExpectedType: expectedType}
+		return nil, err
+	}
+
+	rr, err := dns.NewRR(fmt.Sprintf(". IN %s %s", a.Type, a.Value))
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil {
+		return nil, fmt.Errorf("answer %s has no data", a)
+	}
+
+	return rr, nil
+}
@@ -0,0 +1,124 @@
+package dns_helper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRecordsDNSDatabase = map[DNSQuery]DNSAnswers{
+	DNSQuery{"SRV", "_https._tcp." + testDomain}: DNSAnswers{
+		{Type: "SRV", Value: "10 5 443 api." + testDomain + "."},
+	},
+
+	DNSQuery{"CAA", testDomain}: DNSAnswers{
+		{Type: "CAA", Value: `0 issue "letsencrypt.org"`},
+	},
+
+	DNSQuery{"SOA", testDomain}: DNSAnswers{
+		{Type: "SOA", Value: "ns1." + testDomain + ". hostmaster." + testDomain + ". 2020010101 7200 900 1209600 86400"},
+	},
+
+	DNSQuery{"PTR", "4.3.2.1.in-addr.arpa"}: DNSAnswers{
+		{Type: "PTR", Value: "host." + testDomain + "."},
+	},
+
+	DNSQuery{"SPF", testDomain}: DNSAnswers{
+		{Type: "SPF", Value: `"v=spf1 -all"`},
+	},
+
+	DNSQuery{"DS", testDomain}: DNSAnswers{
+		{Type: "DS", Value: "12345 13 2 3490A6806D47F17A34C29E2CE80E8A999FFBE4BE"},
+	},
+
+	DNSQuery{"DNSKEY", testDomain}: DNSAnswers{
+		{Type: "DNSKEY", Value: "257 3 13 mdsswUyr3DPW132mOi8V9xESWE8jTo0dxCjjnopKl+GqJxpVXckHAeF+KkxLbxILfDLUT0rAK9iUzy1L53eKGQ=="},
+	},
+}
+
+// Lookups of all the supported record types should return the records
+func TestOkLocalDNSLookupRecordTypes(t *testing.T) {
+	t.Parallel()
+	s1, _ := setupTestDNSServers(t)
+	s1.AddZone(t, DNSZone{Origin: "in-addr.arpa"})
+	for dnsQuery, expected := range testRecordsDNSDatabase {
+		s1.SetRecords(t, dnsQuery.Name, dnsQuery.Type, expected)
+		res, err := DNSLookupE(t, dnsQuery, []string{s1.Address()})
+		require.NoError(t, err)
+		require.Equal(t, expected, res)
+	}
+}
+
+// Lookup should fail because the record type is not supported
+func TestErrorDNSLookupRecordType(t *testing.T) {
+	t.Parallel()
+	_, err := DNSLookupE(t, DNSQuery{"HINFO", testDomain}, []string{"127.0.0.1:1"})
+	if _, ok := err.(*QueryTypeError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+}
+
+func TestDNSAnswerTypedAccessors(t *testing.T) {
+	t.Parallel()
+
+	srv, err := testRecordsDNSDatabase[DNSQuery{"SRV", "_https._tcp." + testDomain}][0].SRV()
+	require.NoError(t, err)
+	assert.Equal(t, SRVRecord{Priority: 10, Weight: 5, Port: 443, Target: "api." + testDomain + "."}, srv)
+
+	caa, err := testRecordsDNSDatabase[DNSQuery{"CAA", testDomain}][0].CAA()
+	require.NoError(t, err)
+	assert.Equal(t, CAARecord{Flag: 0, Tag: "issue", Value: "letsencrypt.org"}, caa)
+
+	soa, err := testRecordsDNSDatabase[DNSQuery{"SOA", testDomain}][0].SOA()
+	require.NoError(t, err)
+	assert.Equal(t, SOARecord{
+		Nameserver: "ns1." + testDomain + ".",
+		Mailbox:    "hostmaster." + testDomain + ".",
+		Serial:     2020010101,
+		Refresh:    7200,
+		Retry:      900,
+		Expire:     1209600,
+		MinimumTTL: 86400,
+	}, soa)
+
+	ds, err := testRecordsDNSDatabase[DNSQuery{"DS", testDomain}][0].DS()
+	require.NoError(t, err)
+	assert.Equal(t, DSRecord{KeyTag: 12345, Algorithm: 13, DigestType: 2, Digest: "3490A6806D47F17A34C29E2CE80E8A999FFBE4BE"}, ds)
+
+	key, err := testRecordsDNSDatabase[DNSQuery{"DNSKEY", testDomain}][0].DNSKEY()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(257), key.Flags)
+	assert.Equal(t, uint8(13), key.Algorithm)
+
+	mx, err := DNSAnswer{Type: "MX", Value: "10 mail." + testDomain + "."}.MX()
+	require.NoError(t, err)
+	assert.Equal(t, MXRecord{Preference: 10, Host: "mail." + testDomain + "."}, mx)
+
+	_, err = DNSAnswer{Type: "A", Value: "1.1.1.1"}.SRV()
+	if _, ok := err.(*RecordTypeError); !ok {
+		t.Errorf("unexpected error, got %q", err)
+	}
+
+	_, err = DNSAnswer{Type: "SRV", Value: "not an srv record"}.SRV()
+	require.Error(t, err)
+}
+
+func TestDNSAnswersMatches(t *testing.T) {
+	t.Parallel()
+
+	answers := DNSAnswers{{Type: "A", Value: "1.1.1.1"}, {Type: "A", Value: "2.2.2.2"}}
+	assert.True(t, answers.Matches(DNSAnswers{{Type: "A", Value: "2.2.2.2"}, {Type: "A", Value: "1.1.1.1"}}))
+	assert.False(t, answers.Matches(DNSAnswers{{Type: "A", Value: "1.1.1.1"}, {Type: "A", Value: "1.1.1.1"}}))
+	assert.False(t, answers.Matches(DNSAnswers{{Type: "A", Value: "1.1.1.1"}}))
+}
+
+func TestDNSAnswersWithTTL(t *testing.T) {
+	t.Parallel()
+
+	answers := DNSAnswersWithTTL{{DNSAnswer{"A", "2.2.2.2"}, 60}, {DNSAnswer{"A", "1.1.1.1"}, 300}}
+	answers.Sort()
+	assert.Equal(t, DNSAnswers{{"A", "1.1.1.1"}, {"A", "2.2.2.2"}}, answers.Answers())
+	assert.Equal(t, "300 A 1.1.1.1", answers[0].String())
+	assert.Nil(t, DNSAnswersWithTTL(nil).Answers())
+}
@@ -0,0 +1,203 @@
+package environment
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// envTag is the struct tag with the name of the env var of a field, optionally followed by ",required".
+	envTag = "env"
+	// defaultTag is the struct tag with the value of a field when its env var is not set.
+	defaultTag = "default"
+	// separatorTag is the struct tag with the separator of the values of slice fields. Defaults to ",".
+	separatorTag = "separator"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Load populates the fields of the struct that cfg points to from env vars, based on the struct tags of the fields:
+//
+//	type Config struct {
+//		Region   string        `env:"AWS_DEFAULT_REGION,required"`
+//		Zones    []string      `env:"ZONES" separator:";"`
+//		Timeout  time.Duration `env:"TIMEOUT" default:"5m"`
+//		Parallel bool          `env:"PARALLEL" default:"true"`
+//	}
+//
+// The env tag is the name of the env var, followed by ",required" if the env var must be set to a non-empty value.
+// The default tag is used when the env var is not set or empty. Slice fields are split on the separator tag, which
+// defaults to ",", with spaces around the values trimmed. Fields can be strings, bools, ints, uints, floats,
+// time.Durations, types that implement encoding.TextUnmarshaler, and slices and pointers of those. Nested structs
+// without an env tag are loaded too. This will fail the test, listing all the missing and invalid env vars, if there
+// is an error.
+func Load(t testing.TestingT, cfg interface{}) {
+	if err := LoadE(t, cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// LoadE populates the fields of the struct that cfg points to from env vars, based on the struct tags of the fields.
+// See Load. Returns an error listing all the missing and invalid env vars.
+func LoadE(t testing.TestingT, cfg interface{}) error {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("environment.Load expects a non-nil pointer to a struct, but got %T", cfg)
+	}
+
+	var errs *multierror.Error
+	loadStruct(value.Elem(), &errs)
+	return errs.ErrorOrNil()
+}
+
+// loadStruct populates the fields of the given struct value, appending any errors to errs.
+func loadStruct(value reflect.Value, errs **multierror.Error) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := value.Field(i)
+		if field.PkgPath != "" {
+			// Unexported fields can't be set
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup(envTag)
+		if !hasTag {
+			if field.Type.Kind() == reflect.Struct && !reflect.PtrTo(field.Type).Implements(textUnmarshalerType) {
+				loadStruct(fieldValue, errs)
+			}
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		required := false
+		for _, option := range parts[1:] {
+			if option == "required" {
+				required = true
+			}
+		}
+
+		raw := os.Getenv(name)
+		if raw == "" {
+			raw = field.Tag.Get(defaultTag)
+		}
+		if raw == "" {
+			if required {
+				*errs = multierror.Append(*errs, RequiredEnvVarNotSetError{Name: name})
+			}
+			continue
+		}
+
+		separator, hasSeparator := field.Tag.Lookup(separatorTag)
+		if !hasSeparator {
+			separator = ","
+		}
+		if err := setValue(fieldValue, raw, separator); err != nil {
+			*errs = multierror.Append(*errs, InvalidEnvVarError{Name: name, Value: raw, Type: field.Type.String(), Underlying: err})
+		}
+	}
+}
+
+// setValue parses the given raw string into the given value, based on its type.
+func setValue(value reflect.Value, raw string, separator string) error {
+	if value.Kind() == reflect.Ptr {
+		pointer := reflect.New(value.Type().Elem())
+		if err := setValue(pointer.Elem(), raw, separator); err != nil {
+			return err
+		}
+		value.Set(pointer)
+		return nil
+	}
+
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalerType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+	}
+
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 0, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 0, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(parsed)
+	case reflect.Slice:
+		items := strings.Split(raw, separator)
+		slice := reflect.MakeSlice(value.Type(), 0, len(items))
+		for _, item := range items {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			element := reflect.New(value.Type().Elem()).Elem()
+			if err := setValue(element, item, separator); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, element)
+		}
+		value.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// RequiredEnvVarNotSetError is returned by Load when a required env var is not set.
+type RequiredEnvVarNotSetError struct {
+	Name string
+}
+
+func (err RequiredEnvVarNotSetError) Error() string {
+	return fmt.Sprintf("Required env var %s is not set", err.Name)
+}
+
+// InvalidEnvVarError is returned by Load when the value of an env var can't be parsed into the type of its field.
+type InvalidEnvVarError struct {
+	Name       string
+	Value      string
+	Type       string
+	Underlying error
+}
+
+func (err InvalidEnvVarError) Error() string {
+	return fmt.Sprintf("Env var %s has invalid value %q for type %s: %v", err.Name, err.Value, err.Type, err.Underlying)
+}
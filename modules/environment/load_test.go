@@ -0,0 +1,84 @@
+package environment
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Region   string        `env:"TERRATEST_TEST_REGION,required"`
+	Zones    []string      `env:"TERRATEST_TEST_ZONES"`
+	Ports    []int         `env:"TERRATEST_TEST_PORTS" separator:";"`
+	Timeout  time.Duration `env:"TERRATEST_TEST_TIMEOUT" default:"5m"`
+	Parallel bool          `env:"TERRATEST_TEST_PARALLEL" default:"true"`
+	Retries  *int          `env:"TERRATEST_TEST_RETRIES"`
+	Ratio    float64       `env:"TERRATEST_TEST_RATIO"`
+	IP       net.IP        `env:"TERRATEST_TEST_IP"`
+	Nested   testNestedConfig
+	ignored  string
+	Untagged string
+}
+
+type testNestedConfig struct {
+	Role string `env:"TERRATEST_TEST_ROLE" default:"admin"`
+}
+
+func TestLoad(t *testing.T) {
+	// These tests can not run in parallel, since they manipulate env vars
+	// DO NOT ADD THIS: t.Parallel()
+
+	SetScoped(t, "TERRATEST_TEST_REGION", "eu-west-1")
+	SetScoped(t, "TERRATEST_TEST_ZONES", "eu-west-1a, eu-west-1b,")
+	SetScoped(t, "TERRATEST_TEST_PORTS", "80;443")
+	SetScoped(t, "TERRATEST_TEST_PARALLEL", "false")
+	SetScoped(t, "TERRATEST_TEST_RETRIES", "3")
+	SetScoped(t, "TERRATEST_TEST_RATIO", "0.5")
+	SetScoped(t, "TERRATEST_TEST_IP", "10.0.0.1")
+	UnsetScoped(t, "TERRATEST_TEST_TIMEOUT")
+	UnsetScoped(t, "TERRATEST_TEST_ROLE")
+
+	var cfg testConfig
+	Load(t, &cfg)
+
+	retries := 3
+	expected := testConfig{
+		Region:   "eu-west-1",
+		Zones:    []string{"eu-west-1a", "eu-west-1b"},
+		Ports:    []int{80, 443},
+		Timeout:  5 * time.Minute,
+		Parallel: false,
+		Retries:  &retries,
+		Ratio:    0.5,
+		IP:       net.ParseIP("10.0.0.1"),
+		Nested:   testNestedConfig{Role: "admin"},
+	}
+	assert.Equal(t, expected, cfg)
+}
+
+func TestLoadReturnsAllErrors(t *testing.T) {
+	// These tests can not run in parallel, since they manipulate env vars
+	// DO NOT ADD THIS: t.Parallel()
+
+	UnsetScoped(t, "TERRATEST_TEST_REGION")
+	SetScoped(t, "TERRATEST_TEST_PORTS", "80;http")
+	SetScoped(t, "TERRATEST_TEST_TIMEOUT", "5")
+
+	var cfg testConfig
+	err := LoadE(t, &cfg)
+	require.IsType(t, &multierror.Error{}, err)
+
+	errs := err.(*multierror.Error).Errors
+	require.Len(t, errs, 3)
+	assert.Equal(t, RequiredEnvVarNotSetError{Name: "TERRATEST_TEST_REGION"}, errs[0])
+	assert.IsType(t, InvalidEnvVarError{}, errs[1])
+	assert.Equal(t, "TERRATEST_TEST_PORTS", errs[1].(InvalidEnvVarError).Name)
+	assert.Equal(t, "TERRATEST_TEST_TIMEOUT", errs[2].(InvalidEnvVarError).Name)
+
+	assert.Error(t, LoadE(t, cfg))
+	assert.Error(t, LoadE(t, (*testConfig)(nil)))
+}
@@ -0,0 +1,153 @@
+package environment
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// FailOnScopedConflicts makes SetScoped and UnsetScoped fail the test, rather than log a warning, when another test
+// that is still running, other than a parent test, has changed the same env var with SetScoped or UnsetScoped. Env vars
+// are shared by the whole process, so tests that run in parallel and change the same env var, such as
+// AWS_DEFAULT_REGION or SKIP_* stage variables, affect each other.
+var FailOnScopedConflicts = false
+
+var (
+	scopedEnvVarsLock sync.Mutex
+	// scopedEnvVars maps each env var changed with SetScoped or UnsetScoped to its changes by tests that haven't
+	// finished yet.
+	scopedEnvVars = map[string]*scopedEnvVar{}
+)
+
+// scopedEnvVar is an env var changed with SetScoped or UnsetScoped.
+type scopedEnvVar struct {
+	// previous is the value of the env var before the first change, restored when the last test that changed it
+	// finishes.
+	previous string
+	wasSet   bool
+	// changes are the changes by tests that haven't finished yet, in order.
+	changes []scopedEnvVarChange
+}
+
+// scopedEnvVarChange is a change of an env var by a test.
+type scopedEnvVarChange struct {
+	test  string
+	value string
+	isSet bool
+}
+
+// SetScoped sets the given env var to the given value until the test finishes, when the previous value is restored, or
+// the latest value set by another test that changed it and is still running. The test must support Cleanup, like
+// *testing.T. Changing an env var that another running test changed with SetScoped logs a warning, or fails the test
+// if FailOnScopedConflicts is set. This will fail the test if there is an error.
+func SetScoped(t testing.TestingT, key string, value string) {
+	if err := SetScopedE(t, key, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SetScopedE sets the given env var to the given value until the test finishes, when the previous value is restored,
+// or the latest value set by another test that changed it and is still running. The test must support Cleanup, like
+// *testing.T. Returns a ScopedEnvVarConflictError if another running test changed the env var with SetScoped and
+// FailOnScopedConflicts is set.
+func SetScopedE(t testing.TestingT, key string, value string) error {
+	return changeScopedE(t, key, value, true)
+}
+
+// UnsetScoped unsets the given env var until the test finishes, when the previous value is restored. See SetScoped.
+// This will fail the test if there is an error.
+func UnsetScoped(t testing.TestingT, key string) {
+	if err := UnsetScopedE(t, key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// UnsetScopedE unsets the given env var until the test finishes, when the previous value is restored. See SetScopedE.
+func UnsetScopedE(t testing.TestingT, key string) error {
+	return changeScopedE(t, key, "", false)
+}
+
+// changeScopedE registers the change of the env var by the test, checking for conflicts with other tests, and
+// applies it. When the test finishes, the env var is set back to the latest change by a test that is still running,
+// or to the value it had before the first change if there's none.
+func changeScopedE(t testing.TestingT, key string, value string, isSet bool) error {
+	tt, ok := t.(interface{ Cleanup(func()) })
+	if !ok {
+		return fmt.Errorf("Cannot change env var %s for the duration of test %s, because %T doesn't support Cleanup", key, t.Name(), t)
+	}
+
+	scopedEnvVarsLock.Lock()
+	defer scopedEnvVarsLock.Unlock()
+
+	envVar, exists := scopedEnvVars[key]
+	if !exists {
+		previous, wasSet := os.LookupEnv(key)
+		envVar = &scopedEnvVar{previous: previous, wasSet: wasSet}
+	}
+
+	for _, change := range envVar.changes {
+		// Subtests run while their parent test is still running, so changes by the test or its parents don't conflict
+		if change.test != t.Name() && !strings.HasPrefix(t.Name(), change.test+"/") {
+			err := ScopedEnvVarConflictError{Name: key, Test: t.Name(), OtherTest: change.test}
+			if FailOnScopedConflicts {
+				return err
+			}
+			logger.Logf(t, "WARNING: %s", err.Error())
+			break
+		}
+	}
+
+	if err := applyEnvVar(key, value, isSet); err != nil {
+		return err
+	}
+	envVar.changes = append(envVar.changes, scopedEnvVarChange{test: t.Name(), value: value, isSet: isSet})
+	scopedEnvVars[key] = envVar
+
+	tt.Cleanup(func() {
+		scopedEnvVarsLock.Lock()
+		defer scopedEnvVarsLock.Unlock()
+
+		envVar.removeChange(t.Name())
+		if len(envVar.changes) == 0 {
+			applyEnvVar(key, envVar.previous, envVar.wasSet)
+			delete(scopedEnvVars, key)
+		} else {
+			latest := envVar.changes[len(envVar.changes)-1]
+			applyEnvVar(key, latest.value, latest.isSet)
+		}
+	})
+	return nil
+}
+
+// removeChange removes the latest change of the env var by the given test.
+func (envVar *scopedEnvVar) removeChange(testName string) {
+	for i := len(envVar.changes) - 1; i >= 0; i-- {
+		if envVar.changes[i].test == testName {
+			envVar.changes = append(envVar.changes[:i:i], envVar.changes[i+1:]...)
+			return
+		}
+	}
+}
+
+func applyEnvVar(key string, value string, isSet bool) error {
+	if isSet {
+		return os.Setenv(key, value)
+	}
+	return os.Unsetenv(key)
+}
+
+// ScopedEnvVarConflictError is returned when a test changes an env var with SetScoped or UnsetScoped while another
+// running test has changed it too.
+type ScopedEnvVarConflictError struct {
+	Name      string
+	Test      string
+	OtherTest string
+}
+
+func (err ScopedEnvVarConflictError) Error() string {
+	return fmt.Sprintf("Test %s is changing env var %s, which test %s has changed and is still running. Tests that run in parallel and change the same env var affect each other.", err.Test, err.Name, err.OtherTest)
+}
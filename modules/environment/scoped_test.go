@@ -0,0 +1,144 @@
+package environment
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// scopedT is a test with its own name whose cleanup functions run when finish is called, to simulate tests that run
+// at the same time.
+type scopedT struct {
+	*testing.T
+	name     string
+	cleanups []func()
+}
+
+func (t *scopedT) Name() string {
+	return t.name
+}
+
+func (t *scopedT) Cleanup(cleanup func()) {
+	t.cleanups = append(t.cleanups, cleanup)
+}
+
+func (t *scopedT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+	t.cleanups = nil
+}
+
+func TestSetScopedRestoresPreviousValue(t *testing.T) {
+	// These tests can not run in parallel, since they manipulate env vars
+	// DO NOT ADD THIS: t.Parallel()
+
+	os.Setenv("TERRATEST_TEST_SCOPED", "original")
+	defer os.Unsetenv("TERRATEST_TEST_SCOPED")
+	os.Unsetenv("TERRATEST_TEST_SCOPED_UNSET")
+
+	first := &scopedT{T: t, name: "first"}
+	SetScoped(first, "TERRATEST_TEST_SCOPED", "foo")
+	SetScoped(first, "TERRATEST_TEST_SCOPED", "bar")
+	SetScoped(first, "TERRATEST_TEST_SCOPED_UNSET", "baz")
+	assert.Equal(t, "bar", os.Getenv("TERRATEST_TEST_SCOPED"))
+	assert.Equal(t, "baz", os.Getenv("TERRATEST_TEST_SCOPED_UNSET"))
+
+	first.finish()
+	assert.Equal(t, "original", os.Getenv("TERRATEST_TEST_SCOPED"))
+	_, isSet := os.LookupEnv("TERRATEST_TEST_SCOPED_UNSET")
+	assert.False(t, isSet)
+	assert.NotContains(t, scopedEnvVars, "TERRATEST_TEST_SCOPED")
+}
+
+func TestSetScopedDetectsConflicts(t *testing.T) {
+	// These tests can not run in parallel, since they manipulate env vars
+	// DO NOT ADD THIS: t.Parallel()
+
+	defer os.Unsetenv("TERRATEST_TEST_SCOPED")
+
+	first := &scopedT{T: t, name: "first"}
+	second := &scopedT{T: t, name: "second"}
+
+	// By default, conflicts only log a warning
+	SetScoped(first, "TERRATEST_TEST_SCOPED", "foo")
+	assert.NoError(t, SetScopedE(second, "TERRATEST_TEST_SCOPED", "bar"))
+	assert.Equal(t, "bar", os.Getenv("TERRATEST_TEST_SCOPED"))
+
+	FailOnScopedConflicts = true
+	defer func() { FailOnScopedConflicts = false }()
+
+	err := UnsetScopedE(second, "TERRATEST_TEST_SCOPED")
+	assert.Equal(t, ScopedEnvVarConflictError{Name: "TERRATEST_TEST_SCOPED", Test: "second", OtherTest: "first"}, err)
+	assert.Equal(t, "bar", os.Getenv("TERRATEST_TEST_SCOPED"))
+
+	second.finish()
+	first.finish()
+	assert.NoError(t, SetScopedE(second, "TERRATEST_TEST_SCOPED", "baz"))
+	second.finish()
+}
+
+func TestSetScopedRestoresValuesOfRunningTests(t *testing.T) {
+	// These tests can not run in parallel, since they manipulate env vars
+	// DO NOT ADD THIS: t.Parallel()
+
+	os.Setenv("TERRATEST_TEST_SCOPED", "original")
+	defer os.Unsetenv("TERRATEST_TEST_SCOPED")
+
+	first := &scopedT{T: t, name: "first"}
+	second := &scopedT{T: t, name: "second"}
+	third := &scopedT{T: t, name: "third"}
+
+	SetScoped(first, "TERRATEST_TEST_SCOPED", "foo")
+	SetScoped(second, "TERRATEST_TEST_SCOPED", "bar")
+	UnsetScoped(third, "TERRATEST_TEST_SCOPED")
+
+	// The latest change by a running test stays in effect
+	second.finish()
+	_, isSet := os.LookupEnv("TERRATEST_TEST_SCOPED")
+	assert.False(t, isSet)
+	third.finish()
+	assert.Equal(t, "foo", os.Getenv("TERRATEST_TEST_SCOPED"))
+
+	// The value from before the first change is restored when the last test finishes
+	first.finish()
+	assert.Equal(t, "original", os.Getenv("TERRATEST_TEST_SCOPED"))
+	assert.NotContains(t, scopedEnvVars, "TERRATEST_TEST_SCOPED")
+
+	SetScoped(first, "TERRATEST_TEST_SCOPED", "foo")
+	SetScoped(second, "TERRATEST_TEST_SCOPED", "bar")
+	first.finish()
+	assert.Equal(t, "bar", os.Getenv("TERRATEST_TEST_SCOPED"))
+	second.finish()
+	assert.Equal(t, "original", os.Getenv("TERRATEST_TEST_SCOPED"))
+}
+
+func TestSetScopedAllowsSubtests(t *testing.T) {
+	// These tests can not run in parallel, since they manipulate env vars
+	// DO NOT ADD THIS: t.Parallel()
+
+	defer os.Unsetenv("TERRATEST_TEST_SCOPED")
+
+	FailOnScopedConflicts = true
+	defer func() { FailOnScopedConflicts = false }()
+
+	parent := &scopedT{T: t, name: "TestParent"}
+	subtest := &scopedT{T: t, name: "TestParent/subtest"}
+	nested := &scopedT{T: t, name: "TestParent/subtest/nested"}
+	sibling := &scopedT{T: t, name: "TestParentOther"}
+
+	SetScoped(parent, "TERRATEST_TEST_SCOPED", "foo")
+	assert.NoError(t, SetScopedE(subtest, "TERRATEST_TEST_SCOPED", "bar"))
+	assert.NoError(t, SetScopedE(nested, "TERRATEST_TEST_SCOPED", "baz"))
+	err := SetScopedE(sibling, "TERRATEST_TEST_SCOPED", "qux")
+	assert.Equal(t, ScopedEnvVarConflictError{Name: "TERRATEST_TEST_SCOPED", Test: "TestParentOther", OtherTest: "TestParent"}, err)
+	assert.Equal(t, "baz", os.Getenv("TERRATEST_TEST_SCOPED"))
+
+	nested.finish()
+	subtest.finish()
+	assert.Equal(t, "foo", os.Getenv("TERRATEST_TEST_SCOPED"))
+	parent.finish()
+	_, isSet := os.LookupEnv("TERRATEST_TEST_SCOPED")
+	assert.False(t, isSet)
+}
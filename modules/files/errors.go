@@ -10,3 +10,14 @@ type DirNotFoundError struct {
 func (err DirNotFoundError) Error() string {
 	return fmt.Sprintf("Directory was not found: \"%s\"", err.Directory)
 }
+
+// ModuleOutsideRootError is an error that occurs if a Terraform module depends on a local module outside of the root
+// folder being copied
+type ModuleOutsideRootError struct {
+	Module     string
+	RootFolder string
+}
+
+func (err ModuleOutsideRootError) Error() string {
+	return fmt.Sprintf("Module \"%s\" is outside of the root folder \"%s\"", err.Module, err.RootFolder)
+}
@@ -0,0 +1,156 @@
+package files
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single gitignore style pattern.
+type ignoreRule struct {
+	// base is the folder the pattern is relative to, e.g. the folder of the .gitignore file it came from.
+	base     string
+	pattern  *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreMatcher decides whether paths are ignored, based on gitignore style patterns. As in git, the last matching
+// pattern wins, so later patterns can re-include paths with a "!" prefix.
+type ignoreMatcher struct {
+	rules []ignoreRule
+}
+
+// addPatterns adds the given gitignore style patterns, relative to the given base folder.
+func (matcher *ignoreMatcher) addPatterns(base string, patterns []string) {
+	for _, pattern := range patterns {
+		if rule, ok := parseIgnorePattern(base, pattern); ok {
+			matcher.rules = append(matcher.rules, rule)
+		}
+	}
+}
+
+// addGitignoreFile adds the patterns in the .gitignore file of the given folder, if there is one.
+func (matcher *ignoreMatcher) addGitignoreFile(folder string) error {
+	file, err := os.Open(filepath.Join(folder, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	matcher.addPatterns(folder, patterns)
+	return nil
+}
+
+// clone returns a copy of the matcher, so patterns can be added for a subfolder without affecting its siblings.
+func (matcher *ignoreMatcher) clone() *ignoreMatcher {
+	return &ignoreMatcher{rules: append([]ignoreRule{}, matcher.rules...)}
+}
+
+// isIgnored returns true if the given path is ignored.
+func (matcher *ignoreMatcher) isIgnored(path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range matcher.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		relative, err := filepath.Rel(rule.base, path)
+		if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			continue
+		}
+		relative = filepath.ToSlash(relative)
+		if !rule.anchored {
+			relative = filepath.Base(relative)
+		}
+
+		if rule.pattern.MatchString(relative) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnorePattern parses a single line of a gitignore file. Returns false for blank lines and comments.
+func parseIgnorePattern(base string, pattern string) (ignoreRule, bool) {
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+	pattern = strings.TrimPrefix(pattern, "\\")
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	// Patterns with a slash at the beginning or in the middle are relative to the base folder, other patterns match
+	// the name of a file or folder at any depth.
+	if strings.Contains(pattern, "/") {
+		rule.anchored = true
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	if pattern == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = regexp.MustCompile("^" + globToRegexp(pattern) + "$")
+	return rule, true
+}
+
+// globToRegexp converts a gitignore glob, which supports *, ?, [...] and **, to a regular expression.
+func globToRegexp(glob string) string {
+	var expr strings.Builder
+	for i := 0; i < len(glob); i++ {
+		char := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expr.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			expr.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case char == '*':
+			expr.WriteString("[^/]*")
+		case char == '?':
+			expr.WriteString("[^/]")
+		case char == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	return expr.String()
+}
@@ -0,0 +1,34 @@
+package files
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl, which makes a file share the data of another file on copy-on-write file systems.
+const ficlone = 0x40049409
+
+// reflinkFile creates the destination file as a copy-on-write clone of the source file.
+func reflinkFile(source string, destination string) error {
+	sourceFile, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	destinationFile, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer destinationFile.Close()
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, destinationFile.Fd(), ficlone, sourceFile.Fd()); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package files
+
+import "errors"
+
+// reflinkFile is not supported on this platform, so files are always copied instead.
+func reflinkFile(source string, destination string) error {
+	return errors.New("reflinks are only supported on Linux")
+}
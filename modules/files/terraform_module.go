@@ -0,0 +1,334 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// LinkMode is how files are copied by CopyTerraformModule.
+type LinkMode int
+
+const (
+	// LinkModeCopy copies the contents of each file.
+	LinkModeCopy LinkMode = iota
+	// LinkModeHardlink creates a hard link to each file, which is much faster for large folders, but means that writing
+	// to a copied file in place also changes the original. Falls back to copying when a hard link can't be created,
+	// e.g. across file systems.
+	LinkModeHardlink
+	// LinkModeReflink creates a copy-on-write clone of each file on file systems that support it, such as Btrfs and XFS
+	// on Linux. Falls back to copying everywhere else.
+	LinkModeReflink
+)
+
+// DefaultTerraformIgnorePatterns are the gitignore style patterns of files and folders that CopyTerraformModule never
+// copies: hidden files and folders, such as .terraform and .git, node_modules folders, and Terraform state and
+// terraform.tfvars files, as with CopyTerraformFolderToTemp.
+var DefaultTerraformIgnorePatterns = []string{
+	".*",
+	"node_modules/",
+	"terraform.tfstate",
+	"terraform.tfstate.backup",
+	"terraform.tfvars",
+}
+
+// CopyOptions configures CopyTerraformModule.
+type CopyOptions struct {
+	// IgnorePatterns are gitignore style patterns, relative to the root folder, of files and folders that are not
+	// copied, in addition to DefaultTerraformIgnorePatterns. Patterns starting with "!" re-include files and folders
+	// ignored by the default patterns, e.g. "!.terraform.lock.hcl".
+	IgnorePatterns []string
+	// UseGitignore also applies the patterns in the .gitignore files of the copied folders and their parents up to the
+	// root folder.
+	UseGitignore bool
+	// LinkMode is how files are copied. Defaults to copying their contents.
+	LinkMode LinkMode
+	// ExtraFolders are folders, relative to the root folder, that are copied along with the module and its
+	// dependencies, e.g. folders of scripts or templates that the module references with paths like
+	// "${path.module}/../scripts".
+	ExtraFolders []string
+}
+
+// TerraformModuleDependencies returns the folder of the given Terraform module and the folders of all the local modules
+// it depends on, directly or indirectly, through module blocks with a source such as "../modules/vpc". The folders are
+// relative to the root folder, in sorted order. Returns an error if a dependency is outside of the root folder.
+func TerraformModuleDependencies(rootFolder string, moduleFolder string) ([]string, error) {
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := map[string]bool{}
+	queue := []string{filepath.Join(absRootFolder, moduleFolder)}
+	for len(queue) > 0 {
+		folder := queue[0]
+		queue = queue[1:]
+		if visited[folder] {
+			continue
+		}
+
+		relative, err := filepath.Rel(absRootFolder, folder)
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return nil, ModuleOutsideRootError{Module: folder, RootFolder: absRootFolder}
+		}
+		if !IsExistingDir(folder) {
+			return nil, DirNotFoundError{Directory: folder}
+		}
+		visited[folder] = true
+
+		sources, err := localModuleSources(folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			queue = append(queue, filepath.Join(folder, filepath.FromSlash(source)))
+		}
+	}
+
+	var folders []string
+	for folder := range visited {
+		relative, _ := filepath.Rel(absRootFolder, folder)
+		folders = append(folders, relative)
+	}
+	sort.Strings(folders)
+	return folders, nil
+}
+
+// localModuleSources returns the sources of the module blocks in the Terraform files of the given folder that refer to
+// local paths, i.e. that start with ./ or ../.
+func localModuleSources(folder string) ([]string, error) {
+	tfFiles, err := filepath.Glob(filepath.Join(folder, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	jsonFiles, err := filepath.Glob(filepath.Join(folder, "*.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	schema := &hcl.BodySchema{Blocks: []hcl.BlockHeaderSchema{{Type: "module", LabelNames: []string{"name"}}}}
+	moduleSchema := &hcl.BodySchema{Attributes: []hcl.AttributeSchema{{Name: "source"}}}
+
+	var sources []string
+	for _, path := range append(tfFiles, jsonFiles...) {
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		if strings.HasSuffix(path, ".json") {
+			file, diags = parser.ParseJSONFile(path)
+		} else {
+			file, diags = parser.ParseHCLFile(path)
+		}
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		content, _, _ := file.Body.PartialContent(schema)
+		for _, block := range content.Blocks {
+			moduleContent, _, _ := block.Body.PartialContent(moduleSchema)
+			attribute, ok := moduleContent.Attributes["source"]
+			if !ok {
+				continue
+			}
+
+			// Sources that are not plain strings, which Terraform doesn't allow anyway, are ignored
+			value, diags := attribute.Expr.Value(nil)
+			if diags.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+				continue
+			}
+
+			source := value.AsString()
+			if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") ||
+				strings.HasPrefix(source, `.\`) || strings.HasPrefix(source, `..\`) {
+				sources = append(sources, strings.ReplaceAll(source, `\`, "/"))
+			}
+		}
+	}
+	return sources, nil
+}
+
+// CopyTerraformModuleToTemp copies the given Terraform module, and only the local modules it depends on, from the root
+// folder to a temp folder with a unique name and the given prefix, preserving their paths relative to the root folder.
+// Returns the path of the copy of the root folder, so the copy of the module is at filepath.Join(result, moduleFolder).
+// See CopyTerraformModule.
+func CopyTerraformModuleToTemp(rootFolder string, moduleFolder string, tempFolderPrefix string, options CopyOptions) (string, error) {
+	exists, err := FileExistsE(rootFolder)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", DirNotFoundError{Directory: rootFolder}
+	}
+
+	tmpDir, err := ioutil.TempDir("", tempFolderPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	// Inside of the temp folder, we create a subfolder that preserves the name of the folder we're copying from.
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return "", err
+	}
+	destFolder := filepath.Join(tmpDir, filepath.Base(absRootFolder))
+
+	if err := CopyTerraformModule(rootFolder, moduleFolder, destFolder, options); err != nil {
+		return "", err
+	}
+	return destFolder, nil
+}
+
+// CopyTerraformModule copies the given Terraform module, and only the local modules it depends on (see
+// TerraformModuleDependencies), from the root folder to the destination folder, preserving their paths relative to the
+// root folder. This is much faster than copying the whole root folder in large repos with many modules. Subfolders
+// of the copied modules are copied too, such as folders of templates, unless they are Terraform modules themselves
+// that are not dependencies. Files matching DefaultTerraformIgnorePatterns and the patterns in the options are skipped.
+func CopyTerraformModule(rootFolder string, moduleFolder string, destination string, options CopyOptions) error {
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return err
+	}
+
+	folders, err := TerraformModuleDependencies(absRootFolder, moduleFolder)
+	if err != nil {
+		return err
+	}
+	modules := map[string]bool{}
+	for _, folder := range folders {
+		modules[filepath.Join(absRootFolder, folder)] = true
+	}
+	for _, folder := range options.ExtraFolders {
+		folder = filepath.Clean(folder)
+		if !IsExistingDir(filepath.Join(absRootFolder, folder)) {
+			return DirNotFoundError{Directory: filepath.Join(absRootFolder, folder)}
+		}
+		folders = append(folders, folder)
+		modules[filepath.Join(absRootFolder, folder)] = true
+	}
+
+	rootMatcher := &ignoreMatcher{}
+	rootMatcher.addPatterns(absRootFolder, DefaultTerraformIgnorePatterns)
+	rootMatcher.addPatterns(absRootFolder, options.IgnorePatterns)
+
+	copier := moduleCopier{modules: modules, options: options}
+	for _, folder := range folders {
+		source := filepath.Join(absRootFolder, folder)
+		dest := filepath.Join(destination, folder)
+
+		matcher := rootMatcher.clone()
+		if options.UseGitignore {
+			// Apply the .gitignore files of the parent folders, from the root folder down
+			parent := absRootFolder
+			for _, part := range strings.Split(folder, string(filepath.Separator)) {
+				if err := matcher.addGitignoreFile(parent); err != nil {
+					return err
+				}
+				if part == "." {
+					break
+				}
+				parent = filepath.Join(parent, part)
+			}
+		}
+
+		if err := os.MkdirAll(dest, 0777); err != nil {
+			return err
+		}
+		if err := copier.copyFolder(source, dest, matcher, folder == "."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type moduleCopier struct {
+	// modules are the absolute paths of the module folders and extra folders to copy, which are copied separately.
+	modules map[string]bool
+	options CopyOptions
+}
+
+// copyFolder copies the contents of the source folder to the destination folder, skipping ignored files, the folders of
+// other Terraform modules, and the folders of the modules to copy, which are copied separately. The matcher must
+// already contain the patterns of the .gitignore files of the parents of the source folder.
+func (copier moduleCopier) copyFolder(source string, destination string, matcher *ignoreMatcher, gitignoreLoaded bool) error {
+	if copier.options.UseGitignore && !gitignoreLoaded {
+		matcher = matcher.clone()
+		if err := matcher.addGitignoreFile(source); err != nil {
+			return err
+		}
+	}
+
+	files, err := ioutil.ReadDir(source)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		src := filepath.Join(source, file.Name())
+		dest := filepath.Join(destination, file.Name())
+
+		if matcher.isIgnored(src, file.IsDir()) {
+			continue
+		}
+
+		switch {
+		case file.IsDir():
+			if copier.modules[src] || containsTerraformFiles(src) {
+				continue
+			}
+			if err := os.MkdirAll(dest, file.Mode()); err != nil {
+				return err
+			}
+			if err := copier.copyFolder(src, dest, matcher, false); err != nil {
+				return err
+			}
+		case isSymLink(file):
+			if err := copySymLink(src, dest); err != nil {
+				return err
+			}
+		default:
+			if err := linkOrCopyFile(src, dest, copier.options.LinkMode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// containsTerraformFiles returns true if the given folder contains .tf or .tf.json files, i.e. if it is a Terraform
+// module.
+func containsTerraformFiles(folder string) bool {
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if !file.IsDir() && (strings.HasSuffix(file.Name(), ".tf") || strings.HasSuffix(file.Name(), ".tf.json")) {
+			return true
+		}
+	}
+	return false
+}
+
+// linkOrCopyFile creates the destination file from the source file with the given link mode, falling back to copying
+// the file if linking fails.
+func linkOrCopyFile(source string, destination string, mode LinkMode) error {
+	switch mode {
+	case LinkModeHardlink:
+		if err := os.Link(source, destination); err == nil {
+			return nil
+		}
+	case LinkModeReflink:
+		if err := reflinkFile(source, destination); err == nil {
+			return nil
+		}
+		// Remove the file that may have been created by the failed attempt
+		os.Remove(destination)
+	}
+	return CopyFile(source, destination)
+}
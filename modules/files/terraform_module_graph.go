@@ -0,0 +1,121 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindTerraformModules returns the folders under the root folder, including the root folder itself, that contain .tf
+// or .tf.json files, relative to the root folder and in sorted order. Folders matching DefaultTerraformIgnorePatterns,
+// such as .terraform and node_modules, are skipped.
+func FindTerraformModules(rootFolder string) ([]string, error) {
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &ignoreMatcher{}
+	matcher.addPatterns(absRootFolder, DefaultTerraformIgnorePatterns)
+
+	modules := []string{}
+	err = filepath.Walk(absRootFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != absRootFolder && matcher.isIgnored(path, true) {
+			return filepath.SkipDir
+		}
+		if containsTerraformFiles(path) {
+			relative, err := filepath.Rel(absRootFolder, path)
+			if err != nil {
+				return err
+			}
+			modules = append(modules, relative)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(modules)
+	return modules, nil
+}
+
+// AffectedTerraformModules returns the Terraform modules under the root folder that are affected by changes to the
+// given files: the modules that contain a changed file, in their own folder or in a subfolder that is not a module,
+// such as a folder of templates, and all the modules that depend on those, directly or indirectly, through local
+// module sources (see TerraformModuleDependencies). The changed files and the returned module folders are relative
+// to the root folder, and the modules are in sorted order. Changed files that are not in a module are ignored.
+func AffectedTerraformModules(rootFolder string, changedFiles []string) ([]string, error) {
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := FindTerraformModules(absRootFolder)
+	if err != nil {
+		return nil, err
+	}
+	isModule := map[string]bool{}
+	for _, module := range modules {
+		isModule[module] = true
+	}
+
+	// dependents maps each module to the modules that have it as a local module source
+	dependents := map[string][]string{}
+	for _, module := range modules {
+		sources, err := localModuleSources(filepath.Join(absRootFolder, module))
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			dependency := filepath.Join(module, filepath.FromSlash(source))
+			dependents[dependency] = append(dependents[dependency], module)
+		}
+	}
+
+	affected := map[string]bool{}
+	var queue []string
+	for _, file := range changedFiles {
+		if module, ok := containingModule(filepath.Clean(filepath.FromSlash(file)), isModule); ok {
+			queue = append(queue, module)
+		}
+	}
+	for len(queue) > 0 {
+		module := queue[0]
+		queue = queue[1:]
+		if affected[module] {
+			continue
+		}
+		affected[module] = true
+		queue = append(queue, dependents[module]...)
+	}
+
+	result := []string{}
+	for module := range affected {
+		result = append(result, module)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// containingModule returns the closest folder of the given file that is a module.
+func containingModule(file string, isModule map[string]bool) (string, bool) {
+	if file == ".." || strings.HasPrefix(file, ".."+string(filepath.Separator)) || filepath.IsAbs(file) {
+		return "", false
+	}
+	for folder := filepath.Dir(file); ; folder = filepath.Dir(folder) {
+		if isModule[folder] {
+			return folder, true
+		}
+		if folder == "." {
+			return "", false
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTerraformRepo creates a repo with an example that depends on some of its modules, and returns its path.
+func writeTerraformRepo(t *testing.T) string {
+	root, err := ioutil.TempDir("", "terraform-repo")
+	require.NoError(t, err)
+
+	repoFiles := map[string]string{
+		"main.tf":                            `resource "null_resource" "root" {}`,
+		".gitignore":                         "*.log\n",
+		"README.md":                          "# Repo",
+		"modules/vpc/main.tf":                `module "subnets" { source = "../subnets" }`,
+		"modules/vpc/templates/user-data.sh": "#!/bin/bash",
+		"modules/vpc/debug.log":              "debug",
+		"modules/subnets/main.tf.json":       `{"resource": {"null_resource": {"subnet": {}}}}`,
+		"modules/unused/main.tf":             `resource "null_resource" "unused" {}`,
+		"examples/complete/main.tf": `
+module "vpc" {
+  source = "../../modules/vpc"
+}
+
+module "root" {
+  source = "../.."
+}
+
+module "consul" {
+  source = "hashicorp/consul/aws"
+}
+`,
+		"examples/complete/terraform.tfstate":       "{}",
+		"examples/complete/.terraform/modules.json": "{}",
+		"examples/complete/node_modules/foo/a.js":   "",
+		"examples/other/main.tf":                    `resource "null_resource" "other" {}`,
+		"test/example_test.go":                      "package test",
+	}
+	for path, contents := range repoFiles {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	return root
+}
+
+// listFiles returns the paths of all the files in the given folder, relative to it.
+func listFiles(t *testing.T, root string) []string {
+	var paths []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(root, path)
+		paths = append(paths, filepath.ToSlash(relative))
+		return err
+	})
+	require.NoError(t, err)
+	sort.Strings(paths)
+	return paths
+}
+
+func TestTerraformModuleDependencies(t *testing.T) {
+	t.Parallel()
+
+	root := writeTerraformRepo(t)
+	defer os.RemoveAll(root)
+
+	folders, err := TerraformModuleDependencies(root, "examples/complete")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".", filepath.FromSlash("examples/complete"), filepath.FromSlash("modules/subnets"), filepath.FromSlash("modules/vpc")}, folders)
+
+	_, err = TerraformModuleDependencies(filepath.Join(root, "modules"), "vpc")
+	require.NoError(t, err)
+
+	_, err = TerraformModuleDependencies(filepath.Join(root, "examples"), "complete")
+	assert.IsType(t, ModuleOutsideRootError{}, err)
+}
+
+func TestCopyTerraformModule(t *testing.T) {
+	t.Parallel()
+
+	root := writeTerraformRepo(t)
+	defer os.RemoveAll(root)
+
+	testCases := []struct {
+		name     string
+		options  CopyOptions
+		expected []string
+	}{
+		{
+			"defaults",
+			CopyOptions{},
+			[]string{
+				"README.md",
+				"examples/complete/main.tf",
+				"main.tf",
+				"modules/subnets/main.tf.json",
+				"modules/vpc/debug.log",
+				"modules/vpc/main.tf",
+				"modules/vpc/templates/user-data.sh",
+				"test/example_test.go",
+			},
+		},
+		{
+			"gitignore and patterns",
+			CopyOptions{UseGitignore: true, IgnorePatterns: []string{"/test/", "!.terraform/", "**/templates"}, LinkMode: LinkModeHardlink},
+			[]string{
+				"README.md",
+				"examples/complete/.terraform/modules.json",
+				"examples/complete/main.tf",
+				"main.tf",
+				"modules/subnets/main.tf.json",
+				"modules/vpc/main.tf",
+			},
+		},
+		{
+			"extra folders",
+			CopyOptions{ExtraFolders: []string{"examples/other"}, IgnorePatterns: []string{"*.md", "test"}, LinkMode: LinkModeReflink},
+			[]string{
+				"examples/complete/main.tf",
+				"examples/other/main.tf",
+				"main.tf",
+				"modules/subnets/main.tf.json",
+				"modules/vpc/debug.log",
+				"modules/vpc/main.tf",
+				"modules/vpc/templates/user-data.sh",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			tmpRoot, err := CopyTerraformModuleToTemp(root, "examples/complete", "copy-terraform-module", testCase.options)
+			require.NoError(t, err)
+			defer os.RemoveAll(filepath.Dir(tmpRoot))
+
+			assert.Equal(t, filepath.Base(root), filepath.Base(tmpRoot))
+			assert.Equal(t, testCase.expected, listFiles(t, tmpRoot))
+
+			contents, err := ioutil.ReadFile(filepath.Join(tmpRoot, "modules", "vpc", "main.tf"))
+			require.NoError(t, err)
+			assert.Equal(t, `module "subnets" { source = "../subnets" }`, string(contents))
+		})
+	}
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	t.Parallel()
+
+	matcher := &ignoreMatcher{}
+	matcher.addPatterns("/repo", []string{"# comment", "", "*.log", "!keep.log", "build/", "/docs/*.md", "**/cache/**", `\!important`, "file[0-9].txt"})
+
+	testCases := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"/repo/a/debug.log", false, true},
+		{"/repo/a/keep.log", false, false},
+		{"/repo/a/build", true, true},
+		{"/repo/a/build", false, false},
+		{"/repo/docs/index.md", false, true},
+		{"/repo/a/docs/index.md", false, false},
+		{"/repo/a/cache/b/c", false, true},
+		{"/repo/!important", false, true},
+		{"/repo/file1.txt", false, true},
+		{"/repo/fileA.txt", false, false},
+		{"/repo/..debug.log", false, true},
+		{"/other/debug.log", false, false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, matcher.isIgnored(filepath.FromSlash(testCase.path), testCase.isDir), testCase.path)
+	}
+}
+
+func TestFindTerraformModules(t *testing.T) {
+	t.Parallel()
+
+	root := writeTerraformRepo(t)
+	defer os.RemoveAll(root)
+
+	modules, err := FindTerraformModules(root)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".", filepath.FromSlash("examples/complete"), filepath.FromSlash("examples/other"), filepath.FromSlash("modules/subnets"), filepath.FromSlash("modules/unused"), filepath.FromSlash("modules/vpc")}, modules)
+}
+
+func TestAffectedTerraformModules(t *testing.T) {
+	t.Parallel()
+
+	root := writeTerraformRepo(t)
+	defer os.RemoveAll(root)
+
+	testCases := []struct {
+		changedFiles []string
+		expected     []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"modules/unused/main.tf"}, []string{filepath.FromSlash("modules/unused")}},
+		{[]string{"modules/subnets/main.tf.json"}, []string{filepath.FromSlash("examples/complete"), filepath.FromSlash("modules/subnets"), filepath.FromSlash("modules/vpc")}},
+		{[]string{"modules/vpc/templates/user-data.sh", "../outside.tf"}, []string{filepath.FromSlash("examples/complete"), filepath.FromSlash("modules/vpc")}},
+		{[]string{"README.md"}, []string{".", filepath.FromSlash("examples/complete")}},
+	}
+
+	for _, testCase := range testCases {
+		affected, err := AffectedTerraformModules(root, testCase.changedFiles)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, affected, "%v", testCase.changedFiles)
+	}
+}
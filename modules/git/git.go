@@ -2,6 +2,8 @@
 package git
 
 import (
+	"bytes"
+	"fmt"
 	"os/exec"
 	"strings"
 
@@ -11,23 +13,33 @@ import (
 // GetCurrentBranchName retrieves the current branch name or
 // empty string in case of detached state.
 func GetCurrentBranchName(t testing.TestingT) string {
-	out, err := GetCurrentBranchNameE(t)
+	return GetCurrentBranchNameForDir(t, "")
+}
+
+// GetCurrentBranchNameE retrieves the current branch name or
+// empty string in case of detached state.
+func GetCurrentBranchNameE(t testing.TestingT) (string, error) {
+	return GetCurrentBranchNameForDirE(t, "")
+}
+
+// GetCurrentBranchNameForDir retrieves the current branch name of the repo in the given working dir or empty string in
+// case of detached state. An empty working dir means the current working dir.
+func GetCurrentBranchNameForDir(t testing.TestingT, workingDir string) string {
+	out, err := GetCurrentBranchNameForDirE(t, workingDir)
 	if err != nil {
 		t.Fatal(err)
 	}
 	return out
 }
 
-// GetCurrentBranchNameE retrieves the current branch name or
-// empty string in case of detached state.
-func GetCurrentBranchNameE(t testing.TestingT) (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	bytes, err := cmd.Output()
+// GetCurrentBranchNameForDirE retrieves the current branch name of the repo in the given working dir or empty string
+// in case of detached state. An empty working dir means the current working dir.
+func GetCurrentBranchNameForDirE(t testing.TestingT, workingDir string) (string, error) {
+	name, err := runGitCommandE(t, workingDir, "rev-parse", "--abbrev-ref", "HEAD")
 	if err != nil {
 		return "", err
 	}
 
-	name := strings.TrimSpace(string(bytes))
 	if name == "HEAD" {
 		return "", nil
 	}
@@ -38,17 +50,29 @@ func GetCurrentBranchNameE(t testing.TestingT) (string, error) {
 // GetCurrentGitRef retrieves current branch name, lightweight (non-annotated) tag or
 // if tag points to the commit exact tag value.
 func GetCurrentGitRef(t testing.TestingT) string {
-	out, err := GetCurrentGitRefE(t)
+	return GetCurrentGitRefForDir(t, "")
+}
+
+// GetCurrentGitRefE retrieves current branch name, lightweight (non-annotated) tag or
+// if tag points to the commit exact tag value.
+func GetCurrentGitRefE(t testing.TestingT) (string, error) {
+	return GetCurrentGitRefForDirE(t, "")
+}
+
+// GetCurrentGitRefForDir retrieves the current branch name, lightweight (non-annotated) tag or if tag points to the
+// commit exact tag value of the repo in the given working dir. An empty working dir means the current working dir.
+func GetCurrentGitRefForDir(t testing.TestingT, workingDir string) string {
+	out, err := GetCurrentGitRefForDirE(t, workingDir)
 	if err != nil {
 		t.Fatal(err)
 	}
 	return out
 }
 
-// GetCurrentGitRefE retrieves current branch name, lightweight (non-annotated) tag or
-// if tag points to the commit exact tag value.
-func GetCurrentGitRefE(t testing.TestingT) (string, error) {
-	out, err := GetCurrentBranchNameE(t)
+// GetCurrentGitRefForDirE retrieves the current branch name, lightweight (non-annotated) tag or if tag points to the
+// commit exact tag value of the repo in the given working dir. An empty working dir means the current working dir.
+func GetCurrentGitRefForDirE(t testing.TestingT, workingDir string) (string, error) {
+	out, err := GetCurrentBranchNameForDirE(t, workingDir)
 
 	if err != nil {
 		return "", err
@@ -58,7 +82,7 @@ func GetCurrentGitRefE(t testing.TestingT) (string, error) {
 		return out, nil
 	}
 
-	out, err = GetTagE(t)
+	out, err = GetTagForDirE(t, workingDir)
 	if err != nil {
 		return "", err
 	}
@@ -68,10 +92,27 @@ func GetCurrentGitRefE(t testing.TestingT) (string, error) {
 // GetTagE retrieves lightweight (non-annotated) tag or if tag points
 // to the commit exact tag value.
 func GetTagE(t testing.TestingT) (string, error) {
-	cmd := exec.Command("git", "describe", "--tags")
-	bytes, err := cmd.Output()
+	return GetTagForDirE(t, "")
+}
+
+// GetTagForDirE retrieves lightweight (non-annotated) tag or if tag points to the commit exact tag value of the repo
+// in the given working dir. An empty working dir means the current working dir.
+func GetTagForDirE(t testing.TestingT, workingDir string) (string, error) {
+	return runGitCommandE(t, workingDir, "describe", "--tags")
+}
+
+// runGitCommandE runs git with the given args in the given working dir and returns its trimmed stdout. The stderr of
+// git is included in the error if it fails, which wraps the *exec.ExitError of the command.
+func runGitCommandE(t testing.TestingT, workingDir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = workingDir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("git %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
 	}
-	return strings.TrimSpace(string(bytes)), nil
+	return strings.TrimSpace(string(out)), nil
 }
@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -77,3 +78,15 @@ func TestGitRefChecks(t *testing.T) {
 	t.Run("GetCurrentRefReturnsTagValue", testGetCurrentRefReturnsTagValue)
 	t.Run("GetCurrentRefReturnsLightTagValue", testGetCurrentRefReturnsLightTagValue)
 }
+
+// The errors of failing git commands wrap the *exec.ExitError, as the raw error of the command did before
+func TestGitCommandErrorWrapsExitError(t *testing.T) {
+	t.Parallel()
+
+	_, err := GetTagForDirE(t, t.TempDir())
+	require.Error(t, err)
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "unexpected error %q", err)
+	assert.NotEqual(t, 0, exitErr.ExitCode())
+	assert.Contains(t, err.Error(), "not a git repository")
+}
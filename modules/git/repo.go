@@ -0,0 +1,224 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Clone clones the repo at the given URL into a new temp folder and checks out the given ref, such as a branch, tag or
+// commit SHA, unless it's empty. Returns the path of the clone, which is deleted when the test finishes if the test
+// supports Cleanup, like *testing.T. Otherwise, it's up to the caller to delete the parent folder of the clone. This
+// will fail the test if there is an error.
+func Clone(t testing.TestingT, repoURL string, ref string) string {
+	dir, err := CloneE(t, repoURL, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// CloneE clones the repo at the given URL into a new temp folder and checks out the given ref, such as a branch, tag or
+// commit SHA, unless it's empty. Returns the path of the clone, which is deleted when the test finishes if the test
+// supports Cleanup, like *testing.T. Otherwise, it's up to the caller to delete the parent folder of the clone. The
+// temp folder is deleted right away if the clone or checkout fails.
+func CloneE(t testing.TestingT, repoURL string, ref string) (string, error) {
+	tmpDir, err := ioutil.TempDir("", "terratest-git-clone")
+	if err != nil {
+		return "", err
+	}
+	if tt, ok := t.(interface{ Cleanup(func()) }); ok {
+		tt.Cleanup(func() { os.RemoveAll(tmpDir) })
+	}
+
+	dir := filepath.Join(tmpDir, repoName(repoURL))
+	logger.Logf(t, "Cloning %s into %s", repoURL, dir)
+	if _, err := runGitCommandE(t, "", "clone", "--quiet", repoURL, dir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+
+	if ref != "" {
+		if err := CheckoutRefE(t, dir, ref); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// repoName returns the name of the repo at the given URL, e.g. "terratest" for
+// "https://github.com/gruntwork-io/terratest.git".
+func repoName(repoURL string) string {
+	name := strings.TrimSuffix(strings.TrimRight(repoURL, `/\`), ".git")
+	if index := strings.LastIndexAny(name, `/\:`); index >= 0 {
+		name = name[index+1:]
+	}
+	if name == "" {
+		return "repo"
+	}
+	return name
+}
+
+// CheckoutRef checks out the given ref, such as a branch, tag or commit SHA, in the repo in the given working dir. This
+// will fail the test if there is an error.
+func CheckoutRef(t testing.TestingT, workingDir string, ref string) {
+	if err := CheckoutRefE(t, workingDir, ref); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// CheckoutRefE checks out the given ref, such as a branch, tag or commit SHA, in the repo in the given working dir.
+func CheckoutRefE(t testing.TestingT, workingDir string, ref string) error {
+	logger.Logf(t, "Checking out %s in %s", ref, workingDir)
+	_, err := runGitCommandE(t, workingDir, "checkout", "--quiet", ref)
+	return err
+}
+
+// GetCommitSha returns the full SHA of the commit the given ref points to in the repo in the given working dir, e.g.
+// "HEAD" for the current commit. This will fail the test if there is an error.
+func GetCommitSha(t testing.TestingT, workingDir string, ref string) string {
+	sha, err := GetCommitShaE(t, workingDir, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+// GetCommitShaE returns the full SHA of the commit the given ref points to in the repo in the given working dir, e.g.
+// "HEAD" for the current commit.
+func GetCommitShaE(t testing.TestingT, workingDir string, ref string) (string, error) {
+	return runGitCommandE(t, workingDir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+}
+
+// GetMergeBase returns the SHA of the best common ancestor of the given refs in the repo in the given working dir, e.g.
+// the commit a branch was created from. This will fail the test if there is an error.
+func GetMergeBase(t testing.TestingT, workingDir string, ref string, otherRef string) string {
+	sha, err := GetMergeBaseE(t, workingDir, ref, otherRef)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+// GetMergeBaseE returns the SHA of the best common ancestor of the given refs in the repo in the given working dir,
+// e.g. the commit a branch was created from.
+func GetMergeBaseE(t testing.TestingT, workingDir string, ref string, otherRef string) (string, error) {
+	return runGitCommandE(t, workingDir, "merge-base", ref, otherRef)
+}
+
+// IsDirty returns true if the repo in the given working dir has uncommitted changes, including untracked files that
+// are not ignored. This will fail the test if there is an error.
+func IsDirty(t testing.TestingT, workingDir string) bool {
+	dirty, err := IsDirtyE(t, workingDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dirty
+}
+
+// IsDirtyE returns true if the repo in the given working dir has uncommitted changes, including untracked files that
+// are not ignored.
+func IsDirtyE(t testing.TestingT, workingDir string) (bool, error) {
+	out, err := runGitCommandE(t, workingDir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// ChangedFilesSince returns the files that changed in the repo in the given working dir since the given ref, including
+// uncommitted changes and untracked files that are not ignored, in sorted order. The paths are relative to the root
+// of the repo. If paths are given, only the changes under those paths, relative to the working dir, are returned,
+// e.g. to skip the tests of a module when none of its files changed. This will fail the test if there is an error.
+func ChangedFilesSince(t testing.TestingT, workingDir string, ref string, paths ...string) []string {
+	changedFiles, err := ChangedFilesSinceE(t, workingDir, ref, paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return changedFiles
+}
+
+// ChangedFilesSinceE returns the files that changed in the repo in the given working dir since the given ref,
+// including uncommitted changes and untracked files that are not ignored, in sorted order. The paths are relative to
+// the root of the repo. If paths are given, only the changes under those paths, relative to the working dir, are
+// returned.
+func ChangedFilesSinceE(t testing.TestingT, workingDir string, ref string, paths ...string) ([]string, error) {
+	diffArgs := append([]string{"diff", "--name-only", ref, "--"}, paths...)
+	changed, err := runGitCommandE(t, workingDir, diffArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	// ls-files returns paths relative to the working dir, unless --full-name is set
+	untrackedArgs := append([]string{"ls-files", "--others", "--exclude-standard", "--full-name", "--"}, paths...)
+	untracked, err := runGitCommandE(t, workingDir, untrackedArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	changedFiles := []string{}
+	for _, file := range strings.Split(changed+"\n"+untracked, "\n") {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			changedFiles = append(changedFiles, file)
+		}
+	}
+	sort.Strings(changedFiles)
+	return changedFiles, nil
+}
+
+// GetRepoRoot returns the absolute path of the root of the repo in the given working dir. This will fail the test if
+// there is an error.
+func GetRepoRoot(t testing.TestingT, workingDir string) string {
+	root, err := GetRepoRootE(t, workingDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// GetRepoRootE returns the absolute path of the root of the repo in the given working dir.
+func GetRepoRootE(t testing.TestingT, workingDir string) (string, error) {
+	root, err := runGitCommandE(t, workingDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(root), nil
+}
+
+// AffectedTerraformModulesSince returns the Terraform modules in the repo in the given working dir that are affected
+// by the changes since the given ref (see ChangedFilesSince): the modules with changed files and the modules that
+// depend on them (see files.AffectedTerraformModules). The module folders are relative to the root of the repo. This
+// will fail the test if there is an error.
+func AffectedTerraformModulesSince(t testing.TestingT, workingDir string, ref string) []string {
+	modules, err := AffectedTerraformModulesSinceE(t, workingDir, ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return modules
+}
+
+// AffectedTerraformModulesSinceE returns the Terraform modules in the repo in the given working dir that are affected
+// by the changes since the given ref (see ChangedFilesSince): the modules with changed files and the modules that
+// depend on them (see files.AffectedTerraformModules). The module folders are relative to the root of the repo.
+func AffectedTerraformModulesSinceE(t testing.TestingT, workingDir string, ref string) ([]string, error) {
+	root, err := GetRepoRootE(t, workingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changedFiles, err := ChangedFilesSinceE(t, root, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	return files.AffectedTerraformModules(root, changedFiles)
+}
@@ -0,0 +1,191 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestRepo creates a git repo with a commit for each of the given tags, each adding a file named after the tag,
+// and returns its path.
+func createTestRepo(t *testing.T, tags ...string) string {
+	dir, err := ioutil.TempDir("", "git-repo")
+	require.NoError(t, err)
+
+	runGit(t, dir, "init", "--quiet")
+	runGit(t, dir, "symbolic-ref", "HEAD", "refs/heads/main")
+	for _, tag := range tags {
+		writeFile(t, dir, tag+".txt", tag)
+		runGit(t, dir, "add", "-A")
+		runGit(t, dir, "commit", "--quiet", "-m", tag)
+		runGit(t, dir, "tag", tag)
+	}
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	args = append([]string{"-c", "user.name=Terratest", "-c", "user.email=terratest@example.com", "-c", "commit.gpgsign=false", "-c", "tag.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+func writeFile(t *testing.T, dir string, path string, contents string) {
+	path = filepath.Join(dir, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+}
+
+func TestCloneAndCheckoutRef(t *testing.T) {
+	t.Parallel()
+
+	repo := createTestRepo(t, "v0.1.0", "v0.2.0")
+	defer os.RemoveAll(repo)
+
+	clone := Clone(t, repo, "v0.1.0")
+	assert.Equal(t, filepath.Base(repo), filepath.Base(clone))
+	assert.FileExists(t, filepath.Join(clone, "v0.1.0.txt"))
+	assert.False(t, fileExists(filepath.Join(clone, "v0.2.0.txt")))
+	assert.Equal(t, "v0.1.0", GetCurrentGitRefForDir(t, clone))
+	assert.Equal(t, GetCommitSha(t, repo, "v0.1.0"), GetCommitSha(t, clone, "HEAD"))
+
+	CheckoutRef(t, clone, "main")
+	assert.Equal(t, "main", GetCurrentBranchNameForDir(t, clone))
+	assert.FileExists(t, filepath.Join(clone, "v0.2.0.txt"))
+
+	assert.Error(t, CheckoutRefE(t, clone, "v9.9.9"))
+	_, err := GetCommitShaE(t, clone, "v9.9.9")
+	assert.Error(t, err)
+}
+
+func TestCloneIsRemoved(t *testing.T) {
+	t.Parallel()
+
+	repo := createTestRepo(t, "v0.1.0")
+	defer os.RemoveAll(repo)
+
+	var clone string
+	t.Run("Clone", func(t *testing.T) {
+		clone = Clone(t, repo, "v0.1.0")
+		assert.FileExists(t, filepath.Join(clone, "v0.1.0.txt"))
+	})
+	_, err := os.Stat(filepath.Dir(clone))
+	assert.True(t, os.IsNotExist(err), "expected %s to be removed, got %v", clone, err)
+
+}
+
+// noCleanupT hides the Cleanup method of the wrapped test
+type noCleanupT struct {
+	terratesting.TestingT
+}
+
+// Not parallel, so that no other test creates clones while it lists them
+func TestCloneIsRemovedOnError(t *testing.T) {
+	repo := createTestRepo(t, "v0.1.0")
+	defer os.RemoveAll(repo)
+
+	pattern := filepath.Join(os.TempDir(), "terratest-git-clone*")
+	before, err := filepath.Glob(pattern)
+	require.NoError(t, err)
+
+	_, err = CloneE(noCleanupT{t}, repo, "v9.9.9")
+	assert.Error(t, err)
+	_, err = CloneE(noCleanupT{t}, filepath.Join(repo, "missing"), "")
+	assert.Error(t, err)
+
+	after, err := filepath.Glob(pattern)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestIsDirtyAndChangedFilesSince(t *testing.T) {
+	t.Parallel()
+
+	repo := createTestRepo(t, "v1.0.0")
+	defer os.RemoveAll(repo)
+
+	assert.False(t, IsDirty(t, repo))
+	assert.Empty(t, ChangedFilesSince(t, repo, "v1.0.0"))
+
+	writeFile(t, repo, "modules/vpc/main.tf", "")
+	writeFile(t, repo, "modules/subnets/main.tf", "")
+	runGit(t, repo, "add", "modules/vpc")
+	runGit(t, repo, "commit", "--quiet", "-m", "Add vpc")
+	assert.True(t, IsDirty(t, repo))
+
+	writeFile(t, repo, "v1.0.0.txt", "changed")
+	assert.Equal(t, []string{"modules/subnets/main.tf", "modules/vpc/main.tf", "v1.0.0.txt"}, ChangedFilesSince(t, repo, "v1.0.0"))
+	assert.Equal(t, []string{"modules/vpc/main.tf"}, ChangedFilesSince(t, repo, "v1.0.0", "modules/vpc"))
+	assert.Equal(t, []string{"modules/subnets/main.tf"}, ChangedFilesSince(t, filepath.Join(repo, "modules"), "HEAD", "subnets"))
+	assert.Empty(t, ChangedFilesSince(t, repo, "HEAD", "modules/vpc"))
+	assert.Equal(t, GetCommitSha(t, repo, "v1.0.0"), GetMergeBase(t, repo, "v1.0.0", "HEAD"))
+}
+
+func TestListTagsAndGetLatestReleaseTag(t *testing.T) {
+	t.Parallel()
+
+	repo := createTestRepo(t, "v1.10.0", "v1.2.0", "not-a-version", "v2.0.0-rc.1", "1.9.3", "v2.0.0-beta.11", "v2.0.0-beta.2", "v1.2.0-alpha")
+	defer os.RemoveAll(repo)
+
+	expected := []string{"v1.2.0-alpha", "v1.2.0", "1.9.3", "v1.10.0", "v2.0.0-beta.2", "v2.0.0-beta.11", "v2.0.0-rc.1"}
+	assert.Equal(t, expected, ListTags(t, repo))
+	assert.Equal(t, "v1.10.0", GetLatestReleaseTag(t, repo))
+
+	empty := createTestRepo(t, "v1.0.0-rc.1")
+	defer os.RemoveAll(empty)
+
+	_, err := GetLatestReleaseTagE(t, empty)
+	assert.Equal(t, NoReleaseTagError{WorkingDir: empty}, err)
+}
+
+func TestParseSemver(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		tag   string
+		valid bool
+	}{
+		{"v1.2.3", true},
+		{"1.2.3", true},
+		{"v1.2.3-rc.1+build.5", true},
+		{"v1.2", false},
+		{"v01.2.3", false},
+		{"v1.2.3-", false},
+		{"release-1", false},
+	}
+
+	for _, testCase := range testCases {
+		_, ok := parseSemver(testCase.tag)
+		assert.Equal(t, testCase.valid, ok, testCase.tag)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestAffectedTerraformModulesSince(t *testing.T) {
+	t.Parallel()
+
+	repo := createTestRepo(t)
+	defer os.RemoveAll(repo)
+
+	writeFile(t, repo, "modules/vpc/main.tf", "")
+	writeFile(t, repo, "modules/iam/main.tf", "")
+	writeFile(t, repo, "examples/vpc/main.tf", `module "vpc" { source = "../../modules/vpc" }`)
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "--quiet", "-m", "Add modules")
+
+	assert.Empty(t, AffectedTerraformModulesSince(t, repo, "HEAD"))
+
+	writeFile(t, repo, "modules/vpc/variables.tf", "")
+	assert.Equal(t, []string{filepath.FromSlash("examples/vpc"), filepath.FromSlash("modules/vpc")}, AffectedTerraformModulesSince(t, filepath.Join(repo, "modules"), "HEAD"))
+}
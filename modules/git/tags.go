@@ -0,0 +1,169 @@
+package git
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ListTags returns the tags of the repo in the given working dir that are semantic versions, such as "v1.2.3" or
+// "1.3.0-rc.1", sorted from the lowest to the highest version. Other tags are left out. This will fail the test if
+// there is an error.
+func ListTags(t testing.TestingT, workingDir string) []string {
+	tags, err := ListTagsE(t, workingDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tags
+}
+
+// ListTagsE returns the tags of the repo in the given working dir that are semantic versions, such as "v1.2.3" or
+// "1.3.0-rc.1", sorted from the lowest to the highest version. Other tags are left out.
+func ListTagsE(t testing.TestingT, workingDir string) ([]string, error) {
+	out, err := runGitCommandE(t, workingDir, "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []semver
+	for _, tag := range strings.Split(out, "\n") {
+		if version, ok := parseSemver(tag); ok {
+			versions = append(versions, version)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].compare(versions[j]) < 0 })
+
+	tags := []string{}
+	for _, version := range versions {
+		tags = append(tags, version.tag)
+	}
+	return tags, nil
+}
+
+// GetLatestReleaseTag returns the tag of the highest semantic version of the repo in the given working dir, ignoring
+// pre-releases such as "v1.3.0-rc.1". This will fail the test if there is an error or no release tag.
+func GetLatestReleaseTag(t testing.TestingT, workingDir string) string {
+	tag, err := GetLatestReleaseTagE(t, workingDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tag
+}
+
+// GetLatestReleaseTagE returns the tag of the highest semantic version of the repo in the given working dir, ignoring
+// pre-releases such as "v1.3.0-rc.1". Returns a NoReleaseTagError if there is no release tag.
+func GetLatestReleaseTagE(t testing.TestingT, workingDir string) (string, error) {
+	tags, err := ListTagsE(t, workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(tags) - 1; i >= 0; i-- {
+		if version, _ := parseSemver(tags[i]); version.prerelease == nil {
+			return tags[i], nil
+		}
+	}
+	return "", NoReleaseTagError{WorkingDir: workingDir}
+}
+
+// semver is a parsed semantic version (https://semver.org), with an optional "v" prefix.
+type semver struct {
+	tag        string
+	numbers    [3]uint64
+	prerelease []string
+}
+
+// parseSemver parses the given tag as a semantic version. Returns false if it isn't one.
+func parseSemver(tag string) (semver, bool) {
+	version := semver{tag: tag}
+
+	value := strings.TrimPrefix(tag, "v")
+	if index := strings.IndexByte(value, '+'); index >= 0 {
+		value = value[:index]
+	}
+	if index := strings.IndexByte(value, '-'); index >= 0 {
+		version.prerelease = strings.Split(value[index+1:], ".")
+		value = value[:index]
+		for _, identifier := range version.prerelease {
+			if identifier == "" {
+				return semver{}, false
+			}
+		}
+	}
+
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	for i, part := range parts {
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil || (len(part) > 1 && part[0] == '0') {
+			return semver{}, false
+		}
+		version.numbers[i] = number
+	}
+	return version, true
+}
+
+// compare returns -1, 0 or 1 if the version has a lower, the same or a higher precedence than the other version.
+func (version semver) compare(other semver) int {
+	for i := range version.numbers {
+		if version.numbers[i] != other.numbers[i] {
+			return compareUint(version.numbers[i], other.numbers[i])
+		}
+	}
+
+	// A pre-release has a lower precedence than the release
+	switch {
+	case version.prerelease == nil && other.prerelease == nil:
+		return 0
+	case version.prerelease == nil:
+		return 1
+	case other.prerelease == nil:
+		return -1
+	}
+
+	for i := 0; i < len(version.prerelease) && i < len(other.prerelease); i++ {
+		if result := comparePrereleaseIdentifiers(version.prerelease[i], other.prerelease[i]); result != 0 {
+			return result
+		}
+	}
+	return compareUint(uint64(len(version.prerelease)), uint64(len(other.prerelease)))
+}
+
+// comparePrereleaseIdentifiers compares pre-release identifiers: numbers numerically, other identifiers in ASCII
+// order, and numbers before other identifiers.
+func comparePrereleaseIdentifiers(a string, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(aNumber, bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a uint64, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// NoReleaseTagError is returned when a repo has no tag that is a semantic version of a release.
+type NoReleaseTagError struct {
+	WorkingDir string
+}
+
+func (err NoReleaseTagError) Error() string {
+	return "No release tag found in the git repo in " + strconv.Quote(err.WorkingDir)
+}
@@ -0,0 +1,42 @@
+package grpc_helper
+
+import "fmt"
+
+// NotServingError is an error that occurs if a gRPC health check reports a status other than SERVING.
+type NotServingError struct {
+	Address string
+	Service string
+	Status  string
+}
+
+func (err NotServingError) Error() string {
+	return fmt.Sprintf("Expected gRPC service %q at %s to be SERVING, but it is %s", err.Service, err.Address, err.Status)
+}
+
+// InvalidMethodNameError is an error that occurs if a method name isn't of the form package.Service/Method.
+type InvalidMethodNameError struct {
+	Method string
+}
+
+func (err InvalidMethodNameError) Error() string {
+	return fmt.Sprintf("Invalid gRPC method name %q: expected package.Service/Method", err.Method)
+}
+
+// MethodNotFoundError is an error that occurs if the server doesn't describe a method through reflection.
+type MethodNotFoundError struct {
+	Service string
+	Method  string
+}
+
+func (err MethodNotFoundError) Error() string {
+	return fmt.Sprintf("Service %s has no method %s", err.Service, err.Method)
+}
+
+// StreamingMethodError is an error that occurs if a unary call is made to a streaming method.
+type StreamingMethodError struct {
+	Method string
+}
+
+func (err StreamingMethodError) Error() string {
+	return fmt.Sprintf("Method %s is a streaming method: only unary methods can be invoked", err.Method)
+}
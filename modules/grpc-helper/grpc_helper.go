@@ -0,0 +1,100 @@
+// Package grpc_helper contains helpers to check gRPC endpoints.
+package grpc_helper
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultTimeout is the timeout of calls that don't set Options.Timeout.
+const defaultTimeout = 10 * time.Second
+
+// Options configures the connection and the calls made to a gRPC server. The zero value, as well as nil, makes
+// plaintext calls with a 10 second timeout.
+type Options struct {
+	// TLSConfig is the TLS configuration, e.g. with the root CAs to trust. If neither it nor ClientCertificates is set,
+	// the connection is plaintext, so use an empty tls.Config to connect over TLS with the system roots.
+	TLSConfig *tls.Config
+	// ClientCertificates are added to the certificates of TLSConfig, for servers that require mutual TLS. Use
+	// tls.LoadX509KeyPair or tls.X509KeyPair to load them.
+	ClientCertificates []tls.Certificate
+
+	// Timeout is the time limit for each call, including connecting to the server. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// BearerToken sets the authorization metadata to "Bearer <token>".
+	BearerToken string
+	// Metadata is sent with each call, like HTTP headers.
+	Metadata map[string]string
+	// Authority overrides the :authority pseudo-header, and the TLS server name, which default to the host of the
+	// address. Optional.
+	Authority string
+}
+
+// timeout returns the timeout of a call.
+func (options *Options) timeout() time.Duration {
+	if options == nil || options.Timeout == 0 {
+		return defaultTimeout
+	}
+	return options.Timeout
+}
+
+// dialOptions returns the options to connect to a server with.
+func (options *Options) dialOptions() []grpc.DialOption {
+	if options == nil {
+		return []grpc.DialOption{grpc.WithInsecure()}
+	}
+
+	var dialOptions []grpc.DialOption
+	if options.TLSConfig != nil || len(options.ClientCertificates) > 0 {
+		tlsConfig := &tls.Config{}
+		if options.TLSConfig != nil {
+			tlsConfig = options.TLSConfig.Clone()
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, options.ClientCertificates...)
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		dialOptions = append(dialOptions, grpc.WithInsecure())
+	}
+	if options.Authority != "" {
+		dialOptions = append(dialOptions, grpc.WithAuthority(options.Authority))
+	}
+	return dialOptions
+}
+
+// outgoingContext returns a context for a call that carries the metadata of the options and times out after the
+// timeout of the options.
+func (options *Options) outgoingContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), options.timeout())
+	if options == nil {
+		return ctx, cancel
+	}
+
+	for key, value := range options.Metadata {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+	if options.BearerToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+options.BearerToken)
+	}
+	return ctx, cancel
+}
+
+// withConnectionE connects to the gRPC server at the given address (host:port) and runs the given action with the
+// connection and a context for the calls, closing the connection afterwards.
+func withConnectionE(address string, options *Options, action func(ctx context.Context, conn *grpc.ClientConn) error) error {
+	ctx, cancel := options.outgoingContext()
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, address, options.dialOptions()...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return action(ctx, conn)
+}
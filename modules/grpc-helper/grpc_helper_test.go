@@ -0,0 +1,96 @@
+package grpc_helper
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// testServer is a gRPC server on a local port with the health and server reflection services.
+type testServer struct {
+	Address string
+	Health  *health.Server
+}
+
+// startTestServer starts a gRPC server on a local port with the health and server reflection services. If token isn't
+// empty, calls must have the authorization metadata "Bearer <token>". If cert isn't nil, the server uses TLS.
+func startTestServer(t *testing.T, token string, cert *tls.Certificate) *testServer {
+	var serverOptions []grpc.ServerOption
+	if token != "" {
+		serverOptions = append(serverOptions, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			md, _ := metadata.FromIncomingContext(ctx)
+			if values := md.Get("authorization"); len(values) != 1 || values[0] != "Bearer "+token {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			return handler(ctx, req)
+		}))
+	}
+	if cert != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewServerTLSFromCert(cert)))
+	}
+
+	server := grpc.NewServer(serverOptions...)
+	healthServer := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return &testServer{Address: listener.Addr().String(), Health: healthServer}
+}
+
+// generateTestCertificate generates a self-signed certificate for localhost and 127.0.0.1.
+func generateTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "terratest"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, roots
+}
+
+// closedAddress returns the address of a local TCP port that nothing listens on.
+func closedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return address
+}
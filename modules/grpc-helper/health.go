@@ -0,0 +1,88 @@
+package grpc_helper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// GetHealthStatus calls the standard gRPC health check protocol (grpc.health.v1.Health/Check) on the server at the
+// given address (host:port) for the given service, or for the whole server if service is empty, and returns the
+// status, e.g. "SERVING" or "NOT_SERVING". This will fail the test if the call fails.
+func GetHealthStatus(t testing.TestingT, address string, service string, options *Options) string {
+	status, err := GetHealthStatusE(t, address, service, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+// GetHealthStatusE calls the standard gRPC health check protocol (grpc.health.v1.Health/Check) on the server at the
+// given address (host:port) for the given service, or for the whole server if service is empty, and returns the
+// status, e.g. "SERVING" or "NOT_SERVING".
+func GetHealthStatusE(t testing.TestingT, address string, service string, options *Options) (string, error) {
+	logger.Logf(t, "Checking the gRPC health of service %q at %s", service, address)
+
+	var status string
+	err := withConnectionE(address, options, func(ctx context.Context, conn *grpc.ClientConn) error {
+		response, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		status = response.GetStatus().String()
+		return nil
+	})
+	return status, err
+}
+
+// AssertServing checks that the server at the given address (host:port) reports the given service, or the whole
+// server if service is empty, as SERVING with the standard gRPC health check protocol. This will fail the test if it
+// doesn't.
+func AssertServing(t testing.TestingT, address string, service string, options *Options) {
+	if err := AssertServingE(t, address, service, options); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertServingE checks that the server at the given address (host:port) reports the given service, or the whole
+// server if service is empty, as SERVING with the standard gRPC health check protocol. Returns a NotServingError if it
+// doesn't.
+func AssertServingE(t testing.TestingT, address string, service string, options *Options) error {
+	status, err := GetHealthStatusE(t, address, service, options)
+	if err != nil {
+		return err
+	}
+	if status != grpc_health_v1.HealthCheckResponse_SERVING.String() {
+		return NotServingError{Address: address, Service: service, Status: status}
+	}
+	return nil
+}
+
+// WaitForServing repeatedly checks the health of the given service, or the whole server if service is empty, at the
+// given address (host:port) until it is SERVING or max retries has been exceeded. This will fail the test if it never
+// is.
+func WaitForServing(t testing.TestingT, address string, service string, options *Options, retries int, sleepBetweenRetries time.Duration) {
+	if err := WaitForServingE(t, address, service, options, retries, sleepBetweenRetries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WaitForServingE repeatedly checks the health of the given service, or the whole server if service is empty, at the
+// given address (host:port) until it is SERVING or max retries has been exceeded.
+func WaitForServingE(t testing.TestingT, address string, service string, options *Options, retries int, sleepBetweenRetries time.Duration) error {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("Waiting for gRPC service %q at %s to be serving", service, address),
+		MaxRetries:          retries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+	_, err := retry.DoE(t, policy, func() (interface{}, error) {
+		return nil, AssertServingE(t, address, service, options)
+	})
+	return err
+}
@@ -0,0 +1,56 @@
+package grpc_helper
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealthChecks(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, "", nil)
+	server.Health.SetServingStatus("api", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	assert.Equal(t, "SERVING", GetHealthStatus(t, server.Address, "", nil))
+	assert.Equal(t, "NOT_SERVING", GetHealthStatus(t, server.Address, "api", nil))
+	AssertServing(t, server.Address, "", nil)
+
+	err := AssertServingE(t, server.Address, "api", nil)
+	assert.Equal(t, NotServingError{Address: server.Address, Service: "api", Status: "NOT_SERVING"}, err)
+
+	_, err = GetHealthStatusE(t, server.Address, "unknown", nil)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		server.Health.SetServingStatus("api", grpc_health_v1.HealthCheckResponse_SERVING)
+	}()
+	WaitForServing(t, server.Address, "api", nil, 20, 50*time.Millisecond)
+}
+
+func TestHealthCheckOptions(t *testing.T) {
+	t.Parallel()
+
+	cert, roots := generateTestCertificate(t)
+	server := startTestServer(t, "secret", &cert)
+
+	AssertServing(t, server.Address, "", &Options{TLSConfig: &tls.Config{RootCAs: roots}, BearerToken: "secret"})
+	AssertServing(t, server.Address, "", &Options{TLSConfig: &tls.Config{RootCAs: roots}, Metadata: map[string]string{"authorization": "Bearer secret"}, Authority: "localhost"})
+
+	_, err := GetHealthStatusE(t, server.Address, "", &Options{TLSConfig: &tls.Config{RootCAs: roots}, BearerToken: "wrong"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// A plaintext connection to a TLS server fails
+	_, err = GetHealthStatusE(t, server.Address, "", &Options{BearerToken: "secret", Timeout: time.Second})
+	require.Error(t, err)
+
+	err = WaitForServingE(t, closedAddress(t), "", &Options{Timeout: 100 * time.Millisecond}, 1, 10*time.Millisecond)
+	assert.Error(t, err)
+}
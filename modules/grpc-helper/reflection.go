@@ -0,0 +1,291 @@
+package grpc_helper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ListServices lists the services of the server at the given address (host:port) through gRPC server reflection. This
+// will fail the test if the server doesn't support reflection.
+func ListServices(t testing.TestingT, address string, options *Options) []string {
+	services, err := ListServicesE(t, address, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return services
+}
+
+// ListServicesE lists the services of the server at the given address (host:port) through gRPC server reflection.
+// Returns an error if the server doesn't support reflection.
+func ListServicesE(t testing.TestingT, address string, options *Options) ([]string, error) {
+	var services []string
+	err := withConnectionE(address, options, func(ctx context.Context, conn *grpc.ClientConn) error {
+		client, err := newReflectionClientE(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer client.close()
+
+		response, err := client.request(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_ListServices{ListServices: "*"},
+		})
+		if err != nil {
+			return err
+		}
+		for _, service := range response.GetListServicesResponse().GetService() {
+			services = append(services, service.GetName())
+		}
+		return nil
+	})
+	sort.Strings(services)
+	return services, err
+}
+
+// InvokeWithReflection calls the given unary method, e.g. "helloworld.Greeter/SayHello", on the server at the given
+// address (host:port) with a request given as JSON, and returns the response as JSON. The request and response types
+// are looked up through gRPC server reflection, so no generated code is needed. This will fail the test if the call
+// fails.
+func InvokeWithReflection(t testing.TestingT, address string, method string, requestJSON string, options *Options) string {
+	response, err := InvokeWithReflectionE(t, address, method, requestJSON, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// InvokeWithReflectionE calls the given unary method, e.g. "helloworld.Greeter/SayHello", on the server at the given
+// address (host:port) with a request given as JSON, and returns the response as JSON. The request and response types
+// are looked up through gRPC server reflection, so no generated code is needed.
+func InvokeWithReflectionE(t testing.TestingT, address string, method string, requestJSON string, options *Options) (string, error) {
+	serviceName, methodName, err := splitMethodName(method)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Logf(t, "Invoking gRPC method %s/%s at %s", serviceName, methodName, address)
+
+	var responseJSON string
+	err = withConnectionE(address, options, func(ctx context.Context, conn *grpc.ClientConn) error {
+		descriptor, err := findMethodE(ctx, conn, serviceName, methodName)
+		if err != nil {
+			return err
+		}
+
+		request := dynamicpb.NewMessage(descriptor.Input())
+		if strings.TrimSpace(requestJSON) != "" {
+			if err := protojson.Unmarshal([]byte(requestJSON), request); err != nil {
+				return fmt.Errorf("invalid request for %s: %v", descriptor.FullName(), err)
+			}
+		}
+
+		response := dynamicpb.NewMessage(descriptor.Output())
+		if err := conn.Invoke(ctx, fmt.Sprintf("/%s/%s", serviceName, methodName), request, response); err != nil {
+			return err
+		}
+
+		out, err := protojson.Marshal(response)
+		if err != nil {
+			return err
+		}
+		responseJSON = string(out)
+		return nil
+	})
+	return responseJSON, err
+}
+
+// InvokeWithReflectionWithRetry repeatedly calls the given unary method on the server at the given address
+// (host:port), as in InvokeWithReflection, until the call succeeds or max retries has been exceeded, and returns the
+// response as JSON. This will fail the test if the call never succeeds.
+func InvokeWithReflectionWithRetry(t testing.TestingT, address string, method string, requestJSON string, options *Options, retries int, sleepBetweenRetries time.Duration) string {
+	response, err := InvokeWithReflectionWithRetryE(t, address, method, requestJSON, options, retries, sleepBetweenRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// InvokeWithReflectionWithRetryE repeatedly calls the given unary method on the server at the given address
+// (host:port), as in InvokeWithReflectionE, until the call succeeds or max retries has been exceeded, and returns the
+// response as JSON.
+func InvokeWithReflectionWithRetryE(t testing.TestingT, address string, method string, requestJSON string, options *Options, retries int, sleepBetweenRetries time.Duration) (string, error) {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("Invoking gRPC method %s at %s", method, address),
+		MaxRetries:          retries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+	return retry.DoE(t, policy, func() (string, error) {
+		return InvokeWithReflectionE(t, address, method, requestJSON, options)
+	})
+}
+
+// splitMethodName splits a method name of the form package.Service/Method, /package.Service/Method or
+// package.Service.Method into the full service name and the method name.
+func splitMethodName(method string) (string, string, error) {
+	name := strings.TrimPrefix(method, "/")
+	separator := strings.LastIndex(name, "/")
+	if separator == -1 {
+		separator = strings.LastIndex(name, ".")
+	}
+	if separator <= 0 || separator == len(name)-1 {
+		return "", "", InvalidMethodNameError{Method: method}
+	}
+	return name[:separator], name[separator+1:], nil
+}
+
+// findMethodE looks up the descriptor of the given unary method through server reflection.
+func findMethodE(ctx context.Context, conn *grpc.ClientConn, serviceName string, methodName string) (protoreflect.MethodDescriptor, error) {
+	client, err := newReflectionClientE(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer client.close()
+
+	files, err := client.filesContainingSymbolE(serviceName)
+	if err != nil {
+		return nil, err
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, err
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %T, not a service", serviceName, descriptor)
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, MethodNotFoundError{Service: serviceName, Method: methodName}
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, StreamingMethodError{Method: fmt.Sprintf("%s/%s", serviceName, methodName)}
+	}
+	return method, nil
+}
+
+// reflectionClient is a stream to the server reflection service of a server, along with the file descriptors it has
+// returned so far.
+type reflectionClient struct {
+	stream rpb.ServerReflection_ServerReflectionInfoClient
+	files  map[string]*descriptorpb.FileDescriptorProto
+}
+
+// newReflectionClientE opens a stream to the server reflection service of the server behind the given connection.
+func newReflectionClientE(ctx context.Context, conn *grpc.ClientConn) (*reflectionClient, error) {
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &reflectionClient{stream: stream, files: map[string]*descriptorpb.FileDescriptorProto{}}, nil
+}
+
+// close closes the stream.
+func (client *reflectionClient) close() {
+	client.stream.CloseSend()
+}
+
+// request sends the given request on the stream and waits for the response.
+func (client *reflectionClient) request(request *rpb.ServerReflectionRequest) (*rpb.ServerReflectionResponse, error) {
+	if err := client.stream.Send(request); err != nil {
+		return nil, err
+	}
+	response, err := client.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	if errorResponse := response.GetErrorResponse(); errorResponse != nil {
+		return nil, status.Error(codes.Code(errorResponse.GetErrorCode()), errorResponse.GetErrorMessage())
+	}
+	return response, nil
+}
+
+// requestFilesE sends the given request for file descriptors and stores the returned ones. Returns the name of the
+// first file, which is the one that was asked for; the others are its dependencies.
+func (client *reflectionClient) requestFilesE(request *rpb.ServerReflectionRequest) (string, error) {
+	response, err := client.request(request)
+	if err != nil {
+		return "", err
+	}
+
+	var first string
+	for _, encoded := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		file := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(encoded, file); err != nil {
+			return "", err
+		}
+		if first == "" {
+			first = file.GetName()
+		}
+		client.files[file.GetName()] = file
+	}
+	if first == "" {
+		return "", fmt.Errorf("server reflection returned no file descriptor")
+	}
+	return first, nil
+}
+
+// filesContainingSymbolE returns a registry with the file that defines the given symbol and all its dependencies.
+func (client *reflectionClient) filesContainingSymbolE(symbol string) (*protoregistry.Files, error) {
+	name, err := client.requestFilesE(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: symbol},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	files := &protoregistry.Files{}
+	if err := client.registerFileE(files, name); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// registerFileE adds the file with the given name to the given registry after its dependencies, fetching the ones the
+// server hasn't returned yet.
+func (client *reflectionClient) registerFileE(files *protoregistry.Files, name string) error {
+	if _, err := files.FindFileByPath(name); err == nil {
+		return nil
+	}
+
+	file, ok := client.files[name]
+	if !ok {
+		if _, err := client.requestFilesE(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+		}); err != nil {
+			return err
+		}
+		if file, ok = client.files[name]; !ok {
+			return fmt.Errorf("server reflection didn't return file %s", name)
+		}
+	}
+
+	for _, dependency := range file.GetDependency() {
+		if err := client.registerFileE(files, dependency); err != nil {
+			return err
+		}
+	}
+
+	descriptor, err := protodesc.NewFile(file, files)
+	if err != nil {
+		return err
+	}
+	return files.RegisterFile(descriptor)
+}
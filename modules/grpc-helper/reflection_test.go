@@ -0,0 +1,73 @@
+package grpc_helper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestListServices(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, "", nil)
+	assert.Equal(t, []string{"grpc.health.v1.Health", "grpc.reflection.v1alpha.ServerReflection"}, ListServices(t, server.Address, nil))
+}
+
+func TestInvokeWithReflection(t *testing.T) {
+	t.Parallel()
+
+	server := startTestServer(t, "", nil)
+	server.Health.SetServingStatus("api", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	assert.JSONEq(t, `{"status": "SERVING"}`, InvokeWithReflection(t, server.Address, "grpc.health.v1.Health/Check", "", nil))
+	assert.JSONEq(t, `{"status": "NOT_SERVING"}`, InvokeWithReflection(t, server.Address, "/grpc.health.v1.Health/Check", `{"service": "api"}`, nil))
+	assert.JSONEq(t, `{"status": "SERVING"}`, InvokeWithReflectionWithRetry(t, server.Address, "grpc.health.v1.Health.Check", `{}`, nil, 1, 10*time.Millisecond))
+
+	_, err := InvokeWithReflectionE(t, server.Address, "grpc.health.v1.Health/Missing", "", nil)
+	assert.Equal(t, MethodNotFoundError{Service: "grpc.health.v1.Health", Method: "Missing"}, err)
+
+	_, err = InvokeWithReflectionE(t, server.Address, "grpc.health.v1.Health/Watch", "", nil)
+	assert.Equal(t, StreamingMethodError{Method: "grpc.health.v1.Health/Watch"}, err)
+
+	_, err = InvokeWithReflectionE(t, server.Address, "grpc.health.v1.Missing/Check", "", nil)
+	assert.Error(t, err)
+
+	_, err = InvokeWithReflectionE(t, server.Address, "grpc.health.v1.Health/Check", `{"unknown": 1}`, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid request for grpc.health.v1.Health.Check")
+}
+
+func TestSplitMethodName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		method  string
+		service string
+		name    string
+	}{
+		{"pkg.Service/Method", "pkg.Service", "Method"},
+		{"/pkg.Service/Method", "pkg.Service", "Method"},
+		{"pkg.Service.Method", "pkg.Service", "Method"},
+		{"Service/Method", "Service", "Method"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.method, func(t *testing.T) {
+			t.Parallel()
+
+			service, name, err := splitMethodName(testCase.method)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.service, service)
+			assert.Equal(t, testCase.name, name)
+		})
+	}
+
+	for _, method := range []string{"Method", "/Method", "pkg.Service/", ""} {
+		_, _, err := splitMethodName(method)
+		assert.Equal(t, InvalidMethodNameError{Method: method}, err)
+	}
+}
@@ -2,6 +2,7 @@ package helm
 
 import (
 	"github.com/gruntwork-io/go-commons/errors"
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
@@ -61,7 +62,7 @@ func RunHelmCommandAndGetOutputE(t testing.TestingT, options *Options, cmd strin
 		Args:       args,
 		WorkingDir: ".",
 		Env:        options.EnvVars,
-		Logger:     options.Logger,
+		Logger:     options.Logger.WithFields(logger.Fields{logger.FieldModule: "helm"}),
 	}
 	return shell.RunCommandAndGetOutputE(t, helmCmd)
 }
@@ -0,0 +1,403 @@
+package http_helper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// AvailabilityCheckOptions configures an AvailabilityCheck.
+type AvailabilityCheckOptions struct {
+	// Workers is the number of workers that send requests concurrently. Defaults to 1.
+	Workers int
+	// Interval is the time each worker waits between its requests. The workers are staggered, so with 4 workers and an
+	// interval of 1 second, there is a request every 250 milliseconds. Defaults to 1 second.
+	Interval time.Duration
+	// RequestOptions configures the requests, e.g. their timeout. Optional.
+	RequestOptions *RequestOptions
+	// Validators decide if a response is a success. Defaults to a status code from 200 to 299.
+	Validators []ResponseValidator
+}
+
+// AvailabilityCheck sends requests to a URL from several concurrent workers until it's stopped and records the result
+// of every request. Use it to measure the availability and latency of a service while it's being changed, e.g. to
+// check that a rolling deployment has no downtime. Create one with StartAvailabilityCheck.
+type AvailabilityCheck struct {
+	url     string
+	options AvailabilityCheckOptions
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	// client sends the requests of all the workers, reusing connections as a browser or another client of the service
+	// would, or clientErr is why it couldn't be created, e.g. invalid request options.
+	client    *requestClient
+	clientErr error
+
+	lock    sync.Mutex
+	start   time.Time
+	end     time.Time
+	results []CheckResult
+}
+
+// CheckResult is the result of one request of an AvailabilityCheck.
+type CheckResult struct {
+	// Worker is the number of the worker that sent the request, from 0.
+	Worker int
+	// Time is when the request was sent.
+	Time time.Time
+	// Latency is how long the request took until the body of the response was read, or until it failed.
+	Latency time.Duration
+	// StatusCode is the status code of the response, or 0 if there was no response.
+	StatusCode int
+	// Success is true if there was a response and it passed the validators.
+	Success bool
+	// Error is why the request failed, e.g. "HTTP 503" or "connect: connection refused", or empty on success.
+	Error string
+}
+
+// AvailabilitySummary summarizes the results of an AvailabilityCheck.
+type AvailabilitySummary struct {
+	// Start and End are when the check was started and stopped.
+	Start time.Time
+	End   time.Time
+
+	// Requests is the number of requests that were sent.
+	Requests int
+	// Successes is the number of successful requests.
+	Successes int
+	// SuccessRatio is Successes / Requests, or 0 if no requests were sent.
+	SuccessRatio float64
+
+	// P50, P95, P99 and MaxLatency are the percentiles of the latency of the successful requests.
+	P50        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	MaxLatency time.Duration
+
+	// LongestOutage is the longest time during which all requests failed: from the first failed request until the
+	// next successful one, or until the end of the last request if the check was stopped during an outage.
+	LongestOutage time.Duration
+	// LongestOutageStart is when the longest outage started, or the zero time if there was no outage.
+	LongestOutageStart time.Time
+
+	// Errors is the number of failed requests for each reason, e.g. {"HTTP 503": 3, "connect: connection refused": 1}.
+	Errors map[string]int
+}
+
+// AvailabilitySLO is a service level objective that an AvailabilitySummary can be checked against. Zero fields are
+// not checked.
+type AvailabilitySLO struct {
+	// MinSuccessRatio is the minimum ratio of successful requests, e.g. 0.999 for 99.9%.
+	MinSuccessRatio float64
+	// MaxOutage is the maximum duration of an outage.
+	MaxOutage time.Duration
+	// MaxP50, MaxP95 and MaxP99 are the maximum latency percentiles.
+	MaxP50 time.Duration
+	MaxP95 time.Duration
+	MaxP99 time.Duration
+}
+
+// StartAvailabilityCheck starts sending GET requests to the given URL in the background, as configured by the given
+// options, until Stop is called. Failed requests are recorded and don't fail the test; check the summary returned by
+// Stop instead, e.g. with AssertAvailabilitySLO.
+func StartAvailabilityCheck(t testing.TestingT, url string, options *AvailabilityCheckOptions) *AvailabilityCheck {
+	check := &AvailabilityCheck{url: url, start: time.Now()}
+	if options != nil {
+		check.options = *options
+	}
+	if check.options.Workers <= 0 {
+		check.options.Workers = 1
+	}
+	if check.options.Interval <= 0 {
+		check.options.Interval = time.Second
+	}
+
+	check.client, check.clientErr = newRequestClientE(check.options.RequestOptions)
+
+	logger.Logf(t, "Starting availability check of URL %s with %d workers every %s", url, check.options.Workers, check.options.Interval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	check.cancel = cancel
+	for worker := 0; worker < check.options.Workers; worker++ {
+		check.wg.Add(1)
+		go check.runWorker(ctx, worker)
+	}
+	return check
+}
+
+// CheckAvailabilityDuring runs an availability check of the given URL while the given function runs, e.g. a
+// terraform apply that replaces the servers behind a load balancer, and returns the summary.
+func CheckAvailabilityDuring(t testing.TestingT, url string, options *AvailabilityCheckOptions, action func()) AvailabilitySummary {
+	check := StartAvailabilityCheck(t, url, options)
+	defer check.Stop()
+	action()
+	return check.Stop()
+}
+
+// Stop stops sending requests, waits for the requests in flight to finish, closes the idle connections and returns the
+// summary of the results. It's safe to call Stop several times.
+func (check *AvailabilityCheck) Stop() AvailabilitySummary {
+	check.cancel()
+	check.wg.Wait()
+	if check.client != nil {
+		check.client.closeIdleConnections()
+	}
+
+	check.lock.Lock()
+	if check.end.IsZero() {
+		check.end = time.Now()
+	}
+	check.lock.Unlock()
+
+	return check.Summary()
+}
+
+// Results returns the results of the requests so far, ordered by the time they were sent.
+func (check *AvailabilityCheck) Results() []CheckResult {
+	check.lock.Lock()
+	defer check.lock.Unlock()
+
+	results := append([]CheckResult{}, check.results...)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Time.Before(results[j].Time) })
+	return results
+}
+
+// Summary returns the summary of the results so far.
+func (check *AvailabilityCheck) Summary() AvailabilitySummary {
+	check.lock.Lock()
+	start, end := check.start, check.end
+	check.lock.Unlock()
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return SummarizeAvailability(start, end, check.Results())
+}
+
+func (check *AvailabilityCheck) runWorker(ctx context.Context, worker int) {
+	defer check.wg.Done()
+
+	// Stagger the workers so that their requests are spread evenly over the interval
+	delay := check.options.Interval * time.Duration(worker) / time.Duration(check.options.Workers)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		result := check.checkOnce(worker)
+
+		check.lock.Lock()
+		check.results = append(check.results, result)
+		check.lock.Unlock()
+
+		delay = check.options.Interval
+	}
+}
+
+func (check *AvailabilityCheck) checkOnce(worker int) CheckResult {
+	result := CheckResult{Worker: worker, Time: time.Now()}
+
+	response, err := check.get()
+	result.Latency = time.Since(result.Time)
+	if err != nil {
+		result.Error = classifyRequestError(err)
+		return result
+	}
+
+	result.StatusCode = response.StatusCode
+	validators := check.options.Validators
+	if len(validators) == 0 {
+		validators = []ResponseValidator{validateSuccessStatusCode}
+	}
+	for _, validator := range validators {
+		if err := validator(response); err != nil {
+			result.Error = fmt.Sprintf("HTTP %d", response.StatusCode)
+			if response.StatusCode >= 200 && response.StatusCode < 300 {
+				result.Error += ": " + strings.SplitN(err.Error(), "\n", 2)[0]
+			}
+			return result
+		}
+	}
+	result.Success = true
+	return result
+}
+
+// get performs an HTTP GET on the URL of the check like HttpGetResponseWithOptionsE, but with the client of the check
+// and without logging, which would flood the test output when checking often.
+func (check *AvailabilityCheck) get() (*HttpResponse, error) {
+	if check.clientErr != nil {
+		return nil, check.clientErr
+	}
+	req, err := http.NewRequest(http.MethodGet, check.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return check.client.sendE(req)
+}
+
+func validateSuccessStatusCode(response *HttpResponse) error {
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("Expected a status code from 200 to 299, but got %d", response.StatusCode)
+	}
+	return nil
+}
+
+// classifyRequestError returns the reason of the given request error without the details that differ between
+// requests, such as the URL and the local port, so that errors can be counted by reason.
+func classifyRequestError(err error) string {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if urlErr.Timeout() {
+			return "timeout"
+		}
+		err = urlErr.Err
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// SummarizeAvailability returns the summary of the given results of an availability check that ran from start to end.
+func SummarizeAvailability(start time.Time, end time.Time, results []CheckResult) AvailabilitySummary {
+	summary := AvailabilitySummary{Start: start, End: end, Requests: len(results), Errors: map[string]int{}}
+
+	sorted := append([]CheckResult{}, results...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	var latencies []time.Duration
+	var outageStart time.Time
+	recordOutage := func(outageEnd time.Time) {
+		if duration := outageEnd.Sub(outageStart); duration > summary.LongestOutage {
+			summary.LongestOutage = duration
+			summary.LongestOutageStart = outageStart
+		}
+		outageStart = time.Time{}
+	}
+
+	for i, result := range sorted {
+		if result.Success {
+			summary.Successes++
+			latencies = append(latencies, result.Latency)
+			if !outageStart.IsZero() {
+				recordOutage(result.Time)
+			}
+			continue
+		}
+
+		summary.Errors[result.Error]++
+		if outageStart.IsZero() {
+			outageStart = result.Time
+		}
+		if i == len(sorted)-1 {
+			recordOutage(result.Time.Add(result.Latency))
+		}
+	}
+
+	if summary.Requests > 0 {
+		summary.SuccessRatio = float64(summary.Successes) / float64(summary.Requests)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	summary.P50 = latencyPercentile(latencies, 50)
+	summary.P95 = latencyPercentile(latencies, 95)
+	summary.P99 = latencyPercentile(latencies, 99)
+	summary.MaxLatency = latencyPercentile(latencies, 100)
+	return summary
+}
+
+// latencyPercentile returns the given percentile of the given sorted latencies, using the nearest-rank method.
+func latencyPercentile(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String returns a human readable summary, e.g. for logging.
+func (summary AvailabilitySummary) String() string {
+	var errs []string
+	for _, reason := range sortedErrorReasons(summary.Errors) {
+		errs = append(errs, fmt.Sprintf("%s: %d", reason, summary.Errors[reason]))
+	}
+	if len(errs) == 0 {
+		errs = []string{"none"}
+	}
+
+	return fmt.Sprintf(
+		"%d/%d requests succeeded (%.3f%%) over %s. Latency: p50 %s, p95 %s, p99 %s, max %s. Longest outage: %s. Errors: %s.",
+		summary.Successes, summary.Requests, summary.SuccessRatio*100, summary.End.Sub(summary.Start).Round(time.Millisecond),
+		summary.P50, summary.P95, summary.P99, summary.MaxLatency, summary.LongestOutage, strings.Join(errs, ", "),
+	)
+}
+
+// sortedErrorReasons returns the reasons of the given error counts, most frequent first.
+func sortedErrorReasons(errorCounts map[string]int) []string {
+	reasons := make([]string, 0, len(errorCounts))
+	for reason := range errorCounts {
+		reasons = append(reasons, reason)
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if errorCounts[reasons[i]] != errorCounts[reasons[j]] {
+			return errorCounts[reasons[i]] > errorCounts[reasons[j]]
+		}
+		return reasons[i] < reasons[j]
+	})
+	return reasons
+}
+
+// AssertAvailabilitySLO checks that the given availability summary meets the given SLO. If it doesn't, fail the test.
+func AssertAvailabilitySLO(t testing.TestingT, summary AvailabilitySummary, slo AvailabilitySLO) {
+	err := AssertAvailabilitySLOE(t, summary, slo)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertAvailabilitySLOE checks that the given availability summary meets the given SLO. If it doesn't, return an
+// AvailabilitySLOViolatedError with all the objectives that were missed. A summary without requests, e.g. because the
+// check was stopped before the first request, never meets an SLO.
+func AssertAvailabilitySLOE(t testing.TestingT, summary AvailabilitySummary, slo AvailabilitySLO) error {
+	logger.Logf(t, "Availability: %s", summary)
+
+	if summary.Requests == 0 {
+		return AvailabilitySLOViolatedError{Violations: []string{"no requests were sent"}, Summary: summary}
+	}
+
+	var violations []string
+	if slo.MinSuccessRatio > 0 && summary.SuccessRatio < slo.MinSuccessRatio {
+		violations = append(violations, fmt.Sprintf("success ratio %.3f%% is below %.3f%%", summary.SuccessRatio*100, slo.MinSuccessRatio*100))
+	}
+	if slo.MaxOutage > 0 && summary.LongestOutage > slo.MaxOutage {
+		violations = append(violations, fmt.Sprintf("outage of %s starting at %s is longer than %s", summary.LongestOutage, summary.LongestOutageStart.Format(time.RFC3339Nano), slo.MaxOutage))
+	}
+	for _, percentile := range []struct {
+		name   string
+		actual time.Duration
+		max    time.Duration
+	}{{"p50", summary.P50, slo.MaxP50}, {"p95", summary.P95, slo.MaxP95}, {"p99", summary.P99, slo.MaxP99}} {
+		if percentile.max > 0 && percentile.actual > percentile.max {
+			violations = append(violations, fmt.Sprintf("%s latency %s is above %s", percentile.name, percentile.actual, percentile.max))
+		}
+	}
+
+	if len(violations) > 0 {
+		return AvailabilitySLOViolatedError{Violations: violations, Summary: summary}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package http_helper
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvailabilityCheck(t *testing.T) {
+	t.Parallel()
+
+	server := NewMockServer(t, nil)
+	server.Stub(MockRequest{}, MockResponse{StatusCode: 503, Times: 5})
+	server.Stub(MockRequest{}, MockResponse{Body: "ok"})
+
+	summary := CheckAvailabilityDuring(t, server.URL, &AvailabilityCheckOptions{Workers: 3, Interval: 30 * time.Millisecond}, func() {
+		time.Sleep(500 * time.Millisecond)
+	})
+
+	assert.Equal(t, map[string]int{"HTTP 503": 5}, summary.Errors)
+	assert.Equal(t, summary.Requests, summary.Successes+5)
+	assert.Equal(t, len(server.Requests()), summary.Requests)
+	assert.Greater(t, summary.Successes, 10)
+	assert.Greater(t, int64(summary.LongestOutage), int64(0))
+	assert.False(t, summary.LongestOutageStart.IsZero())
+	assert.Greater(t, int64(summary.P99), int64(0))
+
+	AssertAvailabilitySLO(t, summary, AvailabilitySLO{MinSuccessRatio: 0.5, MaxOutage: 5 * time.Second, MaxP99: 5 * time.Second})
+	err := AssertAvailabilitySLOE(t, summary, AvailabilitySLO{MinSuccessRatio: 0.999})
+	require.Error(t, err)
+	assert.IsType(t, AvailabilitySLOViolatedError{}, err)
+}
+
+func TestAvailabilityCheckValidatorsAndErrors(t *testing.T) {
+	t.Parallel()
+
+	server := NewMockServer(t, nil)
+	server.Stub(MockRequest{}, MockResponse{Body: `{"status": "starting"}`})
+
+	check := StartAvailabilityCheck(t, server.URL, &AvailabilityCheckOptions{Interval: 20 * time.Millisecond, Validators: []ResponseValidator{ValidateJSONPath("$.status", "ready")}})
+	time.Sleep(100 * time.Millisecond)
+	server.Close()
+	time.Sleep(100 * time.Millisecond)
+	summary := check.Stop()
+
+	assert.Equal(t, 0, summary.Successes)
+	assert.Greater(t, summary.Errors[`HTTP 200: Value of JSONPath "$.status" differs from the expected value (-expected +actual):`], 0)
+	assert.Greater(t, summary.Errors["connect: connection refused"], 0)
+	assert.Equal(t, summary.Requests, len(check.Results()))
+
+	// Stopping again returns the same summary
+	assert.Equal(t, summary.Requests, check.Stop().Requests)
+}
+
+func TestAvailabilityCheckReusesConnections(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	connStates := map[http.ConnState]int{}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		lock.Lock()
+		defer lock.Unlock()
+		connStates[state]++
+	}
+	server.Start()
+	defer server.Close()
+
+	summary := CheckAvailabilityDuring(t, server.URL, &AvailabilityCheckOptions{Workers: 2, Interval: 20 * time.Millisecond}, func() {
+		time.Sleep(200 * time.Millisecond)
+	})
+	assert.Greater(t, summary.Successes, 10)
+
+	// Stop closes the idle connections, which the server notices asynchronously
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return connStates[http.StateClosed] == connStates[http.StateNew]
+	}, 5*time.Second, 10*time.Millisecond)
+	lock.Lock()
+	defer lock.Unlock()
+	assert.LessOrEqual(t, connStates[http.StateNew], 2)
+}
+
+func TestAvailabilityCheckInvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	summary := CheckAvailabilityDuring(t, "http://127.0.0.1:1", &AvailabilityCheckOptions{
+		Interval:       10 * time.Millisecond,
+		RequestOptions: &RequestOptions{BearerToken: "token", BasicAuth: &BasicAuth{}},
+	}, func() {
+		time.Sleep(50 * time.Millisecond)
+	})
+
+	assert.Equal(t, 0, summary.Successes)
+	assert.Equal(t, summary.Requests, summary.Errors[ConflictingRequestOptionsError{Option: "BasicAuth", OtherOption: "BearerToken"}.Error()])
+}
+
+func TestAvailabilityCheckStoppedImmediately(t *testing.T) {
+	t.Parallel()
+
+	// A request may be sent before the check stops, so make it fail to not meet the SLO in either case
+	server := NewMockServer(t, nil)
+	server.Stub(MockRequest{}, MockResponse{StatusCode: 503})
+
+	summary := StartAvailabilityCheck(t, server.URL, &AvailabilityCheckOptions{Interval: time.Minute}).Stop()
+
+	assert.Equal(t, 0, summary.Successes)
+	assert.Equal(t, 0.0, summary.SuccessRatio)
+	err := AssertAvailabilitySLOE(t, summary, AvailabilitySLO{MinSuccessRatio: 0.5})
+	require.Error(t, err)
+	assert.IsType(t, AvailabilitySLOViolatedError{}, err)
+}
+
+func TestSummarizeAvailability(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(milliseconds int) time.Time { return start.Add(time.Duration(milliseconds) * time.Millisecond) }
+	ok := func(milliseconds int, latency time.Duration) CheckResult {
+		return CheckResult{Time: at(milliseconds), Latency: latency, StatusCode: 200, Success: true}
+	}
+	failed := func(milliseconds int, reason string) CheckResult {
+		return CheckResult{Time: at(milliseconds), Latency: 5 * time.Millisecond, Error: reason}
+	}
+
+	results := []CheckResult{
+		ok(0, 10*time.Millisecond),
+		failed(100, "HTTP 502"),
+		ok(600, 30*time.Millisecond),
+		failed(200, "timeout"),
+		ok(700, 20*time.Millisecond),
+		failed(800, "HTTP 502"),
+		failed(900, "HTTP 502"),
+		ok(1000, 40*time.Millisecond),
+		failed(1100, "timeout"),
+	}
+
+	summary := SummarizeAvailability(start, at(1200), results)
+	assert.Equal(t, 9, summary.Requests)
+	assert.Equal(t, 4, summary.Successes)
+	assert.InDelta(t, 4.0/9.0, summary.SuccessRatio, 0.0001)
+	assert.Equal(t, 20*time.Millisecond, summary.P50)
+	assert.Equal(t, 40*time.Millisecond, summary.P95)
+	assert.Equal(t, 40*time.Millisecond, summary.P99)
+	assert.Equal(t, 40*time.Millisecond, summary.MaxLatency)
+	assert.Equal(t, 500*time.Millisecond, summary.LongestOutage)
+	assert.Equal(t, at(100), summary.LongestOutageStart)
+	assert.Equal(t, map[string]int{"HTTP 502": 3, "timeout": 2}, summary.Errors)
+	assert.Equal(t, "4/9 requests succeeded (44.444%) over 1.2s. Latency: p50 20ms, p95 40ms, p99 40ms, max 40ms. Longest outage: 500ms. Errors: HTTP 502: 3, timeout: 2.", summary.String())
+
+	err := AssertAvailabilitySLOE(t, summary, AvailabilitySLO{MinSuccessRatio: 0.999, MaxOutage: 2 * time.Second, MaxP50: 10 * time.Millisecond})
+	require.Error(t, err)
+	assert.Equal(t, []string{"success ratio 44.444% is below 99.900%", "p50 latency 20ms is above 10ms"}, err.(AvailabilitySLOViolatedError).Violations)
+
+	empty := SummarizeAvailability(start, at(1000), nil)
+	assert.Equal(t, 0.0, empty.SuccessRatio)
+	err = AssertAvailabilitySLOE(t, empty, AvailabilitySLO{MaxOutage: time.Second})
+	require.Error(t, err)
+	assert.Equal(t, []string{"no requests were sent"}, err.(AvailabilitySLOViolatedError).Violations)
+}
+
+func TestClassifyRequestError(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := HttpGetE(t, "http://127.0.0.1:1", nil)
+	require.Error(t, err)
+	assert.Equal(t, "connect: connection refused", classifyRequestError(err))
+	assert.Equal(t, "boom", classifyRequestError(errors.New("boom")))
+}
@@ -1,6 +1,10 @@
 package http_helper
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ValidationFunctionFailed is an error that occurs if a validation function fails.
 type ValidationFunctionFailed struct {
@@ -12,3 +16,166 @@ type ValidationFunctionFailed struct {
 func (err ValidationFunctionFailed) Error() string {
 	return fmt.Sprintf("Validation failed for URL %s. Response status: %d. Response body:\n%s", err.Url, err.Status, err.Body)
 }
+
+// ConflictingRequestOptionsError is an error that occurs if RequestOptions that can't be used together are set.
+type ConflictingRequestOptionsError struct {
+	Option      string
+	OtherOption string
+}
+
+func (err ConflictingRequestOptionsError) Error() string {
+	return fmt.Sprintf("The %s and %s request options can't be used together", err.Option, err.OtherOption)
+}
+
+// UnsupportedRequestOptionError is an error that occurs if a RequestOptions field is set that doesn't apply to the
+// kind of connection being made.
+type UnsupportedRequestOptionError struct {
+	Option string
+	Usage  string
+}
+
+func (err UnsupportedRequestOptionError) Error() string {
+	return fmt.Sprintf("The %s request option isn't supported for %s", err.Option, err.Usage)
+}
+
+// ResponseValidationFailed is an error that occurs if any of the validators of a response fail.
+type ResponseValidationFailed struct {
+	Url      string
+	Status   int
+	Failures []error
+}
+
+func (err ResponseValidationFailed) Error() string {
+	messages := []string{}
+	for _, failure := range err.Failures {
+		messages = append(messages, "- "+strings.ReplaceAll(failure.Error(), "\n", "\n  "))
+	}
+	return fmt.Sprintf("Validation failed for URL %s. Response status: %d. Failed validations:\n%s", err.Url, err.Status, strings.Join(messages, "\n"))
+}
+
+// UnexpectedStatusCodeError is an error that occurs if a response has a different status code than expected.
+type UnexpectedStatusCodeError struct {
+	Expected int
+	Actual   int
+}
+
+func (err UnexpectedStatusCodeError) Error() string {
+	return fmt.Sprintf("Expected status code %d, but got %d", err.Expected, err.Actual)
+}
+
+// BodyRegexMismatchError is an error that occurs if a response body doesn't match a regular expression.
+type BodyRegexMismatchError struct {
+	Pattern string
+	Body    string
+}
+
+func (err BodyRegexMismatchError) Error() string {
+	return fmt.Sprintf("Response body doesn't match regex %q. Response body:\n%s", err.Pattern, err.Body)
+}
+
+// InvalidJSONBodyError is an error that occurs if a response body that should be JSON can't be parsed.
+type InvalidJSONBodyError struct {
+	Body       string
+	Underlying error
+}
+
+func (err InvalidJSONBodyError) Error() string {
+	return fmt.Sprintf("Response body is not valid JSON: %s. Response body:\n%s", err.Underlying, err.Body)
+}
+
+// JSONPathNotFoundError is an error that occurs if a JSONPath expression doesn't select anything in a response body.
+type JSONPathNotFoundError struct {
+	Expression string
+	Underlying error
+}
+
+func (err JSONPathNotFoundError) Error() string {
+	return fmt.Sprintf("JSONPath %q not found in response body: %s", err.Expression, err.Underlying)
+}
+
+// JSONValueMismatchError is an error that occurs if the value selected by a JSONPath or JMESPath expression in a
+// response body is not the expected one.
+type JSONValueMismatchError struct {
+	Language   string
+	Expression string
+	Diff       string
+}
+
+func (err JSONValueMismatchError) Error() string {
+	return fmt.Sprintf("Value of %s %q differs from the expected value (-expected +actual):\n%s", err.Language, err.Expression, err.Diff)
+}
+
+// JSONSchemaValidationError is an error that occurs if a response body is not valid according to a JSON Schema.
+type JSONSchemaValidationError struct {
+	Errors []string
+}
+
+func (err JSONSchemaValidationError) Error() string {
+	return fmt.Sprintf("Response body doesn't match the schema:\n%s", strings.Join(err.Errors, "\n"))
+}
+
+// OpenAPIOperationNotFoundError is an error that occurs if an OpenAPI spec doesn't have the operation to validate a
+// response against.
+type OpenAPIOperationNotFoundError struct {
+	Method string
+	Path   string
+}
+
+func (err OpenAPIOperationNotFoundError) Error() string {
+	return fmt.Sprintf("OpenAPI spec has no %s operation for path %s", strings.ToUpper(err.Method), err.Path)
+}
+
+// UndocumentedStatusCodeError is an error that occurs if a response has a status code that is not documented for the
+// operation in an OpenAPI spec.
+type UndocumentedStatusCodeError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+func (err UndocumentedStatusCodeError) Error() string {
+	return fmt.Sprintf("Status code %d is not documented for %s %s in the OpenAPI spec", err.StatusCode, strings.ToUpper(err.Method), err.Path)
+}
+
+// AvailabilitySLOViolatedError is an error that occurs if the results of an availability check don't meet an SLO.
+type AvailabilitySLOViolatedError struct {
+	Violations []string
+	Summary    AvailabilitySummary
+}
+
+func (err AvailabilitySLOViolatedError) Error() string {
+	return fmt.Sprintf("Availability SLO violated: %s. %s", strings.Join(err.Violations, "; "), err.Summary)
+}
+
+// InvalidWebSocketURLError is an error that occurs if a WebSocket URL doesn't use the ws or wss scheme.
+type InvalidWebSocketURLError struct {
+	Url string
+}
+
+func (err InvalidWebSocketURLError) Error() string {
+	return fmt.Sprintf("Invalid WebSocket URL %s: the scheme must be ws or wss", err.Url)
+}
+
+// WebSocketMessageNotReceivedError is an error that occurs if no message passing the validators is received over a
+// WebSocket in time.
+type WebSocketMessageNotReceivedError struct {
+	Url     string
+	Timeout time.Duration
+	// Received are the messages that were received but didn't pass the validators.
+	Received []WebSocketMessage
+	// LastFailures are the failed validations of the last received message.
+	LastFailures []error
+}
+
+func (err WebSocketMessageNotReceivedError) Error() string {
+	message := fmt.Sprintf("No matching message received from WebSocket %s within %s", err.Url, err.Timeout)
+	if len(err.Received) == 0 {
+		return message + ". No messages were received."
+	}
+
+	failures := []string{}
+	for _, failure := range err.LastFailures {
+		failures = append(failures, "- "+strings.ReplaceAll(failure.Error(), "\n", "\n  "))
+	}
+	return fmt.Sprintf("%s. Received %d messages, failed validations of the last one:\n%s", message, len(err.Received), strings.Join(failures, "\n"))
+}
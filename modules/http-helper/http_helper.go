@@ -6,9 +6,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/gruntwork-io/terratest/modules/logger"
@@ -29,32 +27,27 @@ func HttpGet(t testing.TestingT, url string, tlsConfig *tls.Config) (int, string
 // HttpGetE performs an HTTP GET, with an optional pointer to a custom TLS configuration, on the given URL and
 // return the HTTP status code, body, and any error.
 func HttpGetE(t testing.TestingT, url string, tlsConfig *tls.Config) (int, string, error) {
-	logger.Logf(t, "Making an HTTP GET call to URL %s", url)
-
-	// Set HTTP client transport config
-	tr := http.DefaultTransport.(*http.Transport).Clone()
-	tr.TLSClientConfig = tlsConfig
-
-	client := http.Client{
-		// By default, Go does not impose a timeout, so an HTTP connection attempt can hang for a LONG time.
-		Timeout: 10 * time.Second,
-		// Include the previously created transport config
-		Transport: tr,
-	}
+	return HttpGetWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig})
+}
 
-	resp, err := client.Get(url)
+// HttpGetWithOptions performs an HTTP GET on the given URL with the given options and
+// return the HTTP status code and body. If there's any error, fail the test.
+func HttpGetWithOptions(t testing.TestingT, url string, options *RequestOptions) (int, string) {
+	statusCode, body, err := HttpGetWithOptionsE(t, url, options)
 	if err != nil {
-		return -1, "", err
+		t.Fatal(err)
 	}
+	return statusCode, body
+}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-
+// HttpGetWithOptionsE performs an HTTP GET on the given URL with the given options and
+// return the HTTP status code, body, and any error.
+func HttpGetWithOptionsE(t testing.TestingT, url string, options *RequestOptions) (int, string, error) {
+	response, err := HttpGetResponseWithOptionsE(t, url, options)
 	if err != nil {
 		return -1, "", err
 	}
-
-	return resp.StatusCode, strings.TrimSpace(string(body)), nil
+	return response.StatusCode, response.BodyString(), nil
 }
 
 // HttpGetWithValidation performs an HTTP GET on the given URL and verify that you get back the expected status code and body. If either
@@ -69,7 +62,22 @@ func HttpGetWithValidation(t testing.TestingT, url string, tlsConfig *tls.Config
 // HttpGetWithValidationE performs an HTTP GET on the given URL and verify that you get back the expected status code and body. If either
 // doesn't match, return an error.
 func HttpGetWithValidationE(t testing.TestingT, url string, tlsConfig *tls.Config, expectedStatusCode int, expectedBody string) error {
-	return HttpGetWithCustomValidationE(t, url, tlsConfig, func(statusCode int, body string) bool {
+	return HttpGetWithValidationWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig}, expectedStatusCode, expectedBody)
+}
+
+// HttpGetWithValidationWithOptions performs an HTTP GET on the given URL with the given options and verify that you get back the expected status code and body. If either
+// doesn't match, fail the test.
+func HttpGetWithValidationWithOptions(t testing.TestingT, url string, options *RequestOptions, expectedStatusCode int, expectedBody string) {
+	err := HttpGetWithValidationWithOptionsE(t, url, options, expectedStatusCode, expectedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithValidationWithOptionsE performs an HTTP GET on the given URL with the given options and verify that you get back the expected status code and body. If either
+// doesn't match, return an error.
+func HttpGetWithValidationWithOptionsE(t testing.TestingT, url string, options *RequestOptions, expectedStatusCode int, expectedBody string) error {
+	return HttpGetWithCustomValidationWithOptionsE(t, url, options, func(statusCode int, body string) bool {
 		return statusCode == expectedStatusCode && body == expectedBody
 	})
 }
@@ -84,7 +92,20 @@ func HttpGetWithCustomValidation(t testing.TestingT, url string, tlsConfig *tls.
 
 // HttpGetWithCustomValidationE performs an HTTP GET on the given URL and validate the returned status code and body using the given function.
 func HttpGetWithCustomValidationE(t testing.TestingT, url string, tlsConfig *tls.Config, validateResponse func(int, string) bool) error {
-	statusCode, body, err := HttpGetE(t, url, tlsConfig)
+	return HttpGetWithCustomValidationWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig}, validateResponse)
+}
+
+// HttpGetWithCustomValidationWithOptions performs an HTTP GET on the given URL with the given options and validate the returned status code and body using the given function.
+func HttpGetWithCustomValidationWithOptions(t testing.TestingT, url string, options *RequestOptions, validateResponse func(int, string) bool) {
+	err := HttpGetWithCustomValidationWithOptionsE(t, url, options, validateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithCustomValidationWithOptionsE performs an HTTP GET on the given URL with the given options and validate the returned status code and body using the given function.
+func HttpGetWithCustomValidationWithOptionsE(t testing.TestingT, url string, options *RequestOptions, validateResponse func(int, string) bool) error {
+	statusCode, body, err := HttpGetWithOptionsE(t, url, options)
 
 	if err != nil {
 		return err
@@ -109,8 +130,23 @@ func HttpGetWithRetry(t testing.TestingT, url string, tlsConfig *tls.Config, exp
 // HttpGetWithRetryE repeatedly performs an HTTP GET on the given URL until the given status code and body are returned or until max
 // retries has been exceeded.
 func HttpGetWithRetryE(t testing.TestingT, url string, tlsConfig *tls.Config, expectedStatus int, expectedBody string, retries int, sleepBetweenRetries time.Duration) error {
+	return HttpGetWithRetryWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig}, expectedStatus, expectedBody, retries, sleepBetweenRetries)
+}
+
+// HttpGetWithRetryWithOptions repeatedly performs an HTTP GET on the given URL with the given options until the given status code and body are returned or until max
+// retries has been exceeded.
+func HttpGetWithRetryWithOptions(t testing.TestingT, url string, options *RequestOptions, expectedStatus int, expectedBody string, retries int, sleepBetweenRetries time.Duration) {
+	err := HttpGetWithRetryWithOptionsE(t, url, options, expectedStatus, expectedBody, retries, sleepBetweenRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithRetryWithOptionsE repeatedly performs an HTTP GET on the given URL with the given options until the given status code and body are returned or until max
+// retries has been exceeded.
+func HttpGetWithRetryWithOptionsE(t testing.TestingT, url string, options *RequestOptions, expectedStatus int, expectedBody string, retries int, sleepBetweenRetries time.Duration) error {
 	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP GET to URL %s", url), retries, sleepBetweenRetries, func() (string, error) {
-		return "", HttpGetWithValidationE(t, url, tlsConfig, expectedStatus, expectedBody)
+		return "", HttpGetWithValidationWithOptionsE(t, url, options, expectedStatus, expectedBody)
 	})
 
 	return err
@@ -128,8 +164,23 @@ func HttpGetWithRetryWithCustomValidation(t testing.TestingT, url string, tlsCon
 // HttpGetWithRetryWithCustomValidationE repeatedly performs an HTTP GET on the given URL until the given validation function returns true or max retries
 // has been exceeded.
 func HttpGetWithRetryWithCustomValidationE(t testing.TestingT, url string, tlsConfig *tls.Config, retries int, sleepBetweenRetries time.Duration, validateResponse func(int, string) bool) error {
+	return HttpGetWithRetryWithCustomValidationWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig}, retries, sleepBetweenRetries, validateResponse)
+}
+
+// HttpGetWithRetryWithCustomValidationWithOptions repeatedly performs an HTTP GET on the given URL with the given options until the given validation function returns true or max retries
+// has been exceeded.
+func HttpGetWithRetryWithCustomValidationWithOptions(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration, validateResponse func(int, string) bool) {
+	err := HttpGetWithRetryWithCustomValidationWithOptionsE(t, url, options, retries, sleepBetweenRetries, validateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithRetryWithCustomValidationWithOptionsE repeatedly performs an HTTP GET on the given URL with the given options until the given validation function returns true or max retries
+// has been exceeded.
+func HttpGetWithRetryWithCustomValidationWithOptionsE(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration, validateResponse func(int, string) bool) error {
 	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP GET to URL %s", url), retries, sleepBetweenRetries, func() (string, error) {
-		return "", HttpGetWithCustomValidationE(t, url, tlsConfig, validateResponse)
+		return "", HttpGetWithCustomValidationWithOptionsE(t, url, options, validateResponse)
 	})
 
 	return err
@@ -153,32 +204,32 @@ func HTTPDoE(
 	t testing.TestingT, method string, url string, body io.Reader,
 	headers map[string]string, tlsConfig *tls.Config,
 ) (int, string, error) {
-	logger.Logf(t, "Making an HTTP %s call to URL %s", method, url)
-
-	tr := &http.Transport{
-		TLSClientConfig: tlsConfig,
-	}
-
-	client := http.Client{
-		// By default, Go does not impose a timeout, so an HTTP connection attempt can hang for a LONG time.
-		Timeout:   10 * time.Second,
-		Transport: tr,
-	}
+	return HTTPDoWithOptionsE(t, method, url, body, headers, httpDoOptions(tlsConfig))
+}
 
-	req := newRequest(method, url, body, headers)
-	resp, err := client.Do(req)
+// HTTPDoWithOptions performs the given HTTP method on the given URL with the given options and return the HTTP status code and body.
+// If there's any error, fail the test.
+func HTTPDoWithOptions(
+	t testing.TestingT, method string, url string, body io.Reader,
+	headers map[string]string, options *RequestOptions,
+) (int, string) {
+	statusCode, respBody, err := HTTPDoWithOptionsE(t, method, url, body, headers, options)
 	if err != nil {
-		return -1, "", err
+		t.Fatal(err)
 	}
+	return statusCode, respBody
+}
 
-	defer resp.Body.Close()
-	respBody, err := ioutil.ReadAll(resp.Body)
-
+// HTTPDoWithOptionsE performs the given HTTP method on the given URL with the given options and return the HTTP status code, body, and any error.
+func HTTPDoWithOptionsE(
+	t testing.TestingT, method string, url string, body io.Reader,
+	headers map[string]string, options *RequestOptions,
+) (int, string, error) {
+	response, err := HTTPDoResponseWithOptionsE(t, method, url, body, headers, options)
 	if err != nil {
 		return -1, "", err
 	}
-
-	return resp.StatusCode, strings.TrimSpace(string(respBody)), nil
+	return response.StatusCode, response.BodyString(), nil
 }
 
 // HTTPDoWithRetry repeatedly performs the given HTTP method on the given URL until the given status code and body are
@@ -204,12 +255,39 @@ func HTTPDoWithRetryE(
 	t testing.TestingT, method string, url string,
 	body []byte, headers map[string]string, expectedStatus int,
 	retries int, sleepBetweenRetries time.Duration, tlsConfig *tls.Config,
+) (string, error) {
+	return HTTPDoWithRetryWithOptionsE(t, method, url, body, headers, expectedStatus, retries, sleepBetweenRetries, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoWithRetryWithOptions repeatedly performs the given HTTP method on the given URL with the given options until the given status code and body are
+// returned or until max retries has been exceeded.
+// The function compares the expected status code against the received one and fails if they don't match.
+func HTTPDoWithRetryWithOptions(
+	t testing.TestingT, method string, url string,
+	body []byte, headers map[string]string, expectedStatus int,
+	retries int, sleepBetweenRetries time.Duration, options *RequestOptions,
+) string {
+	out, err := HTTPDoWithRetryWithOptionsE(t, method, url, body,
+		headers, expectedStatus, retries, sleepBetweenRetries, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// HTTPDoWithRetryWithOptionsE repeatedly performs the given HTTP method on the given URL with the given options until the given status code and body are
+// returned or until max retries has been exceeded.
+// The function compares the expected status code against the received one and fails if they don't match.
+func HTTPDoWithRetryWithOptionsE(
+	t testing.TestingT, method string, url string,
+	body []byte, headers map[string]string, expectedStatus int,
+	retries int, sleepBetweenRetries time.Duration, options *RequestOptions,
 ) (string, error) {
 	out, err := retry.DoWithRetryE(
 		t, fmt.Sprintf("HTTP %s to URL %s", method, url), retries,
 		sleepBetweenRetries, func() (string, error) {
 			bodyReader := bytes.NewReader(body)
-			statusCode, out, err := HTTPDoE(t, method, url, bodyReader, headers, tlsConfig)
+			statusCode, out, err := HTTPDoWithOptionsE(t, method, url, bodyReader, headers, options)
 			if err != nil {
 				return "", err
 			}
@@ -242,11 +320,34 @@ func HTTPDoWithValidationRetryE(
 	t testing.TestingT, method string, url string,
 	body []byte, headers map[string]string, expectedStatus int,
 	expectedBody string, retries int, sleepBetweenRetries time.Duration, tlsConfig *tls.Config,
+) error {
+	return HTTPDoWithValidationRetryWithOptionsE(t, method, url, body, headers, expectedStatus, expectedBody, retries, sleepBetweenRetries, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoWithValidationRetryWithOptions repeatedly performs the given HTTP method on the given URL with the given options until the given status code and
+// body are returned or until max retries has been exceeded.
+func HTTPDoWithValidationRetryWithOptions(
+	t testing.TestingT, method string, url string,
+	body []byte, headers map[string]string, expectedStatus int,
+	expectedBody string, retries int, sleepBetweenRetries time.Duration, options *RequestOptions,
+) {
+	err := HTTPDoWithValidationRetryWithOptionsE(t, method, url, body, headers, expectedStatus, expectedBody, retries, sleepBetweenRetries, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithValidationRetryWithOptionsE repeatedly performs the given HTTP method on the given URL with the given options until the given status code and
+// body are returned or until max retries has been exceeded.
+func HTTPDoWithValidationRetryWithOptionsE(
+	t testing.TestingT, method string, url string,
+	body []byte, headers map[string]string, expectedStatus int,
+	expectedBody string, retries int, sleepBetweenRetries time.Duration, options *RequestOptions,
 ) error {
 	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP %s to URL %s", method, url), retries,
 		sleepBetweenRetries, func() (string, error) {
 			bodyReader := bytes.NewReader(body)
-			return "", HTTPDoWithValidationE(t, method, url, bodyReader, headers, expectedStatus, expectedBody, tlsConfig)
+			return "", HTTPDoWithValidationWithOptionsE(t, method, url, bodyReader, headers, expectedStatus, expectedBody, options)
 		})
 
 	return err
@@ -264,9 +365,24 @@ func HTTPDoWithValidation(t testing.TestingT, method string, url string, body io
 // HTTPDoWithValidationE performs the given HTTP method on the given URL and verify that you get back the expected status
 // code and body. If either doesn't match, return an error.
 func HTTPDoWithValidationE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, expectedStatusCode int, expectedBody string, tlsConfig *tls.Config) error {
-	return HTTPDoWithCustomValidationE(t, method, url, body, headers, func(statusCode int, body string) bool {
+	return HTTPDoWithValidationWithOptionsE(t, method, url, body, headers, expectedStatusCode, expectedBody, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoWithValidationWithOptions performs the given HTTP method on the given URL with the given options and verify that you get back the expected status
+// code and body. If either doesn't match, fail the test.
+func HTTPDoWithValidationWithOptions(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, expectedStatusCode int, expectedBody string, options *RequestOptions) {
+	err := HTTPDoWithValidationWithOptionsE(t, method, url, body, headers, expectedStatusCode, expectedBody, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithValidationWithOptionsE performs the given HTTP method on the given URL with the given options and verify that you get back the expected status
+// code and body. If either doesn't match, return an error.
+func HTTPDoWithValidationWithOptionsE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, expectedStatusCode int, expectedBody string, options *RequestOptions) error {
+	return HTTPDoWithCustomValidationWithOptionsE(t, method, url, body, headers, func(statusCode int, body string) bool {
 		return statusCode == expectedStatusCode && body == expectedBody
-	}, tlsConfig)
+	}, options)
 }
 
 // HTTPDoWithCustomValidation performs the given HTTP method on the given URL and validate the returned status code and
@@ -281,7 +397,22 @@ func HTTPDoWithCustomValidation(t testing.TestingT, method string, url string, b
 // HTTPDoWithCustomValidationE performs the given HTTP method on the given URL and validate the returned status code and
 // body using the given function.
 func HTTPDoWithCustomValidationE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(int, string) bool, tlsConfig *tls.Config) error {
-	statusCode, respBody, err := HTTPDoE(t, method, url, body, headers, tlsConfig)
+	return HTTPDoWithCustomValidationWithOptionsE(t, method, url, body, headers, validateResponse, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoWithCustomValidationWithOptions performs the given HTTP method on the given URL with the given options and validate the returned status code and
+// body using the given function.
+func HTTPDoWithCustomValidationWithOptions(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(int, string) bool, options *RequestOptions) {
+	err := HTTPDoWithCustomValidationWithOptionsE(t, method, url, body, headers, validateResponse, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithCustomValidationWithOptionsE performs the given HTTP method on the given URL with the given options and validate the returned status code and
+// body using the given function.
+func HTTPDoWithCustomValidationWithOptionsE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(int, string) bool, options *RequestOptions) error {
+	statusCode, respBody, err := HTTPDoWithOptionsE(t, method, url, body, headers, options)
 
 	if err != nil {
 		return err
@@ -294,13 +425,13 @@ func HTTPDoWithCustomValidationE(t testing.TestingT, method string, url string,
 	return nil
 }
 
-func newRequest(method string, url string, body io.Reader, headers map[string]string) *http.Request {
+func newRequest(method string, url string, body io.Reader, headers map[string]string) (*http.Request, error) {
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
-	return req
+	return req, nil
 }
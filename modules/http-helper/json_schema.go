@@ -0,0 +1,157 @@
+package http_helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonpointer"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// validateJSONSchema validates the given value against the given JSON Schema, of draft 4, 6 or 7, with gojsonschema
+// and returns the errors, each prefixed with the JSONPath of the invalid value, in sorted order. Returns an error if
+// the schema is invalid or one of its $refs can't be loaded.
+func validateJSONSchema(schema interface{}, value interface{}) ([]string, error) {
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %s", err)
+	}
+	result, err := compiled.Validate(gojsonschema.NewGoLoader(value))
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []string
+	for _, resultErr := range result.Errors() {
+		errs = append(errs, fmt.Sprintf("%s: %s", jsonSchemaErrorPath(value, resultErr.Context()), resultErr.Description()))
+	}
+	// The order in which gojsonschema checks properties is random
+	sort.Strings(errs)
+	return errs, nil
+}
+
+// jsonSchemaErrorPath returns the JSONPath, e.g. "$.items[0].id", of the value at the given context of a gojsonschema
+// error in the given document.
+func jsonSchemaErrorPath(document interface{}, context *gojsonschema.JsonContext) string {
+	path := "$"
+	if context == nil {
+		return path
+	}
+
+	// The first token is the root, "(root)"
+	const separator = "\x00"
+	tokens := strings.Split(context.String(separator), separator)[1:]
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case []interface{}:
+			path = fmt.Sprintf("%s[%s]", path, token)
+			if index, err := strconv.Atoi(token); err == nil && index >= 0 && index < len(node) {
+				current = node[index]
+			}
+		case map[string]interface{}:
+			path = jsonPathChild(path, token)
+			current = node[token]
+		default:
+			path = jsonPathChild(path, token)
+		}
+	}
+	return path
+}
+
+// openAPISchemaKey is the key under which the schema to validate against is added to a copy of an OpenAPI spec, so
+// that the $refs in the schema, e.g. "#/components/schemas/User", are resolved against the spec.
+const openAPISchemaKey = "x-terratest-schema"
+
+// openAPIToJSONSchema returns a JSON Schema for the given schema in the given OpenAPI spec. The nullable keyword of
+// OpenAPI 3.0, which JSON Schema doesn't have, is converted to a "null" type.
+func openAPIToJSONSchema(spec interface{}, schema interface{}) interface{} {
+	root := map[string]interface{}{}
+	if specMap, ok := spec.(map[string]interface{}); ok {
+		for key, value := range specMap {
+			root[key] = value
+		}
+	}
+	root[openAPISchemaKey] = schema
+	// Keywords next to a $ref are ignored, so the other keys of the spec are only used to resolve $refs
+	root["$ref"] = "#/" + openAPISchemaKey
+	convertNullable(root)
+	return root
+}
+
+// convertNullable adds "null" to the types of the schemas with "nullable: true" in the given document.
+func convertNullable(document interface{}) {
+	switch node := document.(type) {
+	case map[string]interface{}:
+		if nullable, _ := node["nullable"].(bool); nullable {
+			switch types := node["type"].(type) {
+			case string:
+				node["type"] = []interface{}{types, "null"}
+			case []interface{}:
+				node["type"] = append(types, "null")
+			}
+		}
+		for _, value := range node {
+			convertNullable(value)
+		}
+	case []interface{}:
+		for _, value := range node {
+			convertNullable(value)
+		}
+	}
+}
+
+// resolveJSONPointer returns the value in the given document that the given $ref, such as
+// "#/components/responses/NotFound", points to. Only refs within the document are supported.
+func resolveJSONPointer(document interface{}, ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("$ref %q is not supported: only refs within the document, starting with #, are", ref)
+	}
+	pointer, err := url.PathUnescape(strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %s", ref, err)
+	}
+	jsonPointer, err := gojsonpointer.NewJsonPointer(pointer)
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %s", ref, err)
+	}
+	value, _, err := jsonPointer.Get(document)
+	if err != nil {
+		return nil, fmt.Errorf("$ref %q not found", ref)
+	}
+	return value, nil
+}
+
+// identifierRegexp matches property names that can be used in JSONPaths without quotes.
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPathChild returns the JSONPath of the given property of the value at the given path.
+func jsonPathChild(path string, name string) string {
+	if identifierRegexp.MatchString(name) {
+		return path + "." + name
+	}
+	return fmt.Sprintf("%s[%s]", path, strconv.Quote(name))
+}
+
+func sortedJSONKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatJSON formats the given value as compact JSON for error messages.
+func formatJSON(value interface{}) string {
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(out)
+}
@@ -0,0 +1,94 @@
+package http_helper
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJSONSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		schema string
+		value  string
+		errors []string
+	}{
+		{"valid", `{"type": "object", "required": ["a"]}`, `{"a": 1}`, nil},
+		{"type", `{"type": "integer"}`, `1.5`, []string{"$: Invalid type. Expected: integer, given: number"}},
+		{"format", `{"format": "email"}`, `"not an email"`, []string{"$: Does not match format 'email'"}},
+		{"unknown format", `{"format": "int64"}`, `1`, nil},
+		{"quoted property path", `{"properties": {"a b": {"type": "string"}}}`, `{"a b": 1}`, []string{`$["a b"]: Invalid type. Expected: string, given: integer`}},
+		{"draft 4 exclusive maximum", `{"maximum": 1, "exclusiveMaximum": true}`, `1`, []string{"$: Must be less than 1"}},
+		{"ref", `{"definitions": {"a~b": {"type": "string"}}, "items": {"$ref": "#/definitions/a~0b"}}`, `[1]`, []string{"$[0]: Invalid type. Expected: string, given: integer"}},
+		{"nested", `{"properties": {"items": {"items": {"required": ["id"]}}}}`, `{"items": [{}, {"id": 1}, {}]}`, []string{"$.items[0]: id is required", "$.items[2]: id is required"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			schema, err := parseYAMLOrJSON([]byte(testCase.schema))
+			require.NoError(t, err)
+			value, err := parseYAMLOrJSON([]byte(testCase.value))
+			require.NoError(t, err)
+
+			errs, err := validateJSONSchema(schema, value)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.errors, errs)
+		})
+	}
+}
+
+func TestValidateJSONSchemaRefs(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "json-schema")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "id.json"), []byte(`{"definitions": {"id": {"type": "string"}}}`), 0644))
+
+	schema := map[string]interface{}{"$ref": "file://" + filepath.ToSlash(filepath.Join(dir, "id.json")) + "#/definitions/id"}
+	errs, err := validateJSONSchema(schema, 1.0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"$: Invalid type. Expected: string, given: integer"}, errs)
+
+	_, err = validateJSONSchema(map[string]interface{}{"$ref": "#/definitions/missing"}, 1.0)
+	assert.Error(t, err)
+}
+
+func TestOpenAPIToJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	spec, err := parseYAMLOrJSON([]byte(`
+components:
+  schemas:
+    Zone: {type: string, nullable: true}
+    Tags: {type: [object, array], nullable: true}
+`))
+	require.NoError(t, err)
+	schema := openAPIToJSONSchema(spec, map[string]interface{}{
+		"properties": map[string]interface{}{
+			"zone": map[string]interface{}{"$ref": "#/components/schemas/Zone"},
+			"tags": map[string]interface{}{"$ref": "#/components/schemas/Tags"},
+		},
+	})
+
+	value, err := parseYAMLOrJSON([]byte(`{"zone": null, "tags": null}`))
+	require.NoError(t, err)
+	errs, err := validateJSONSchema(schema, value)
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	value, err = parseYAMLOrJSON([]byte(`{"zone": 1}`))
+	require.NoError(t, err)
+	errs, err = validateJSONSchema(schema, value)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"$.zone: Invalid type. Expected: [string,null], given: integer"}, errs)
+}
@@ -0,0 +1,374 @@
+package http_helper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// MockServer is an HTTP server for tests that responds to requests with stubbed responses and records the requests it
+// receives. Each MockServer listens on its own ephemeral port on localhost and has its own routes, so tests that use
+// MockServers can run in parallel.
+type MockServer struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:54321, or https://... if TLS is enabled.
+	URL string
+	// Port is the port the server listens on.
+	Port int
+
+	listener      net.Listener
+	server        *http.Server
+	mux           *http.ServeMux
+	caCertificate *x509.Certificate
+	closeOnce     sync.Once
+
+	lock     sync.Mutex
+	stubs    []*mockStub
+	requests []RecordedRequest
+}
+
+// MockServerOptions configures a MockServer.
+type MockServerOptions struct {
+	// TLS serves HTTPS with a certificate signed by a CA that is generated for the server. Use TLSConfig to get a TLS
+	// configuration that trusts the CA.
+	TLS bool
+	// Hostnames are host names or IP addresses that are added to the certificate of the server, in addition to
+	// localhost, 127.0.0.1 and ::1, so that it can be reached through them, e.g. with RequestOptions.Resolve.
+	Hostnames []string
+}
+
+// MockRequest selects the requests that a stubbed response is returned for. Empty fields match any request.
+type MockRequest struct {
+	// Method is the HTTP method, e.g. GET.
+	Method string
+	// Path is the exact path of the URL, e.g. /health.
+	Path string
+	// PathPrefix is a prefix of the path of the URL, e.g. /api/.
+	PathPrefix string
+	// Headers are headers that the request must have, with exactly these values.
+	Headers map[string]string
+}
+
+// MockResponse is a stubbed response.
+type MockResponse struct {
+	// StatusCode is the status code of the response. Defaults to 200.
+	StatusCode int
+	// Headers are the headers of the response.
+	Headers map[string]string
+	// Body is the body of the response.
+	Body string
+	// Delay is how long to wait before responding, to simulate a slow server.
+	Delay time.Duration
+	// CloseConnection closes the connection without responding, to simulate a network failure.
+	CloseConnection bool
+	// Times is the number of requests that this response is returned for, after which the next matching stub is
+	// used. Defaults to unlimited. Stub the same request several times to return different responses over time, e.g.
+	// a 503 for the first 2 requests and then a 200.
+	Times int
+}
+
+// RecordedRequest is a request received by a MockServer.
+type RecordedRequest struct {
+	Method string
+	// Path is the path of the URL, e.g. /health.
+	Path string
+	// RequestURI is the path and the query of the URL, e.g. /health?verbose=true.
+	RequestURI string
+	Header     http.Header
+	Body       []byte
+	// Time is when the request was received.
+	Time time.Time
+	// Matched is true if the request was handled by a stub or a handler, and false if the server returned a 404.
+	Matched bool
+}
+
+type mockStub struct {
+	request  MockRequest
+	response MockResponse
+	uses     int
+}
+
+// NewMockServer starts a MockServer on an ephemeral port. If the test supports cleanup functions, like *testing.T
+// does, the server is stopped when the test finishes; otherwise, call Close when you're done. If there's any error,
+// fail the test.
+func NewMockServer(t testing.TestingT, options *MockServerOptions) *MockServer {
+	server, err := NewMockServerE(t, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// NewMockServerE starts a MockServer on an ephemeral port. If the test supports cleanup functions, like *testing.T
+// does, the server is stopped when the test finishes; otherwise, call Close when you're done.
+func NewMockServerE(t testing.TestingT, options *MockServerOptions) (*MockServer, error) {
+	if options == nil {
+		options = &MockServerOptions{}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	mock := &MockServer{
+		Port:     listener.Addr().(*net.TCPAddr).Port,
+		listener: listener,
+		mux:      http.NewServeMux(),
+	}
+	mock.server = &http.Server{Handler: http.HandlerFunc(mock.serveHTTP)}
+
+	scheme := "http"
+	if options.TLS {
+		caCertificate, certificate, err := generateMockServerCertificates(options.Hostnames)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		mock.caCertificate = caCertificate
+		mock.listener = tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{certificate}, NextProtos: []string{"http/1.1"}})
+		scheme = "https"
+	}
+	mock.URL = fmt.Sprintf("%s://%s", scheme, listener.Addr().String())
+
+	logger.Logf(t, "Starting mock HTTP server at %s", mock.URL)
+	go mock.server.Serve(mock.listener)
+
+	if tt, ok := t.(interface{ Cleanup(func()) }); ok {
+		tt.Cleanup(mock.Close)
+	}
+	return mock, nil
+}
+
+// Stub makes the server return the given response for requests that match the given request. Stubs are tried in the
+// order they were added, so add more specific stubs first.
+func (mock *MockServer) Stub(request MockRequest, response MockResponse) {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	mock.stubs = append(mock.stubs, &mockStub{request: request, response: response})
+}
+
+// HandleFunc registers a handler for the given pattern, as with http.ServeMux, for requests that don't match any
+// stub. The handler is only registered on this server.
+func (mock *MockServer) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	mock.mux.HandleFunc(pattern, handler)
+}
+
+// Requests returns the requests the server received, in the order they were received.
+func (mock *MockServer) Requests() []RecordedRequest {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	return append([]RecordedRequest{}, mock.requests...)
+}
+
+// RequestsMatching returns the requests the server received that match the given request, in the order they were
+// received.
+func (mock *MockServer) RequestsMatching(request MockRequest) []RecordedRequest {
+	var out []RecordedRequest
+	for _, recorded := range mock.Requests() {
+		if request.matches(recorded.Method, recorded.Path, recorded.Header) {
+			out = append(out, recorded)
+		}
+	}
+	return out
+}
+
+// Reset removes all the stubs and recorded requests. Handlers registered with HandleFunc are kept.
+func (mock *MockServer) Reset() {
+	mock.lock.Lock()
+	defer mock.lock.Unlock()
+	mock.stubs = nil
+	mock.requests = nil
+}
+
+// TLSConfig returns a TLS configuration that trusts the CA of the server, or nil if the server doesn't use TLS.
+func (mock *MockServer) TLSConfig() *tls.Config {
+	if mock.caCertificate == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(mock.caCertificate)
+	return &tls.Config{RootCAs: pool}
+}
+
+// CACertificatePEM returns the PEM encoded certificate of the CA of the server, e.g. to write it to a file for tools
+// that need it, or nil if the server doesn't use TLS.
+func (mock *MockServer) CACertificatePEM() []byte {
+	if mock.caCertificate == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mock.caCertificate.Raw})
+}
+
+// Close stops the server. It's safe to call Close several times.
+func (mock *MockServer) Close() {
+	mock.closeOnce.Do(func() {
+		mock.server.Close()
+	})
+}
+
+func (mock *MockServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	recorded := RecordedRequest{
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RequestURI: r.RequestURI,
+		Header:     r.Header.Clone(),
+		Body:       body,
+		Time:       time.Now(),
+	}
+
+	mock.lock.Lock()
+	stub := mock.findStub(r)
+	var handler http.Handler
+	if stub == nil {
+		if h, pattern := mock.mux.Handler(r); pattern != "" {
+			handler = h
+		}
+	}
+	recorded.Matched = stub != nil || handler != nil
+	mock.requests = append(mock.requests, recorded)
+	mock.lock.Unlock()
+
+	switch {
+	case stub != nil:
+		writeMockResponse(w, stub.response)
+	case handler != nil:
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		handler.ServeHTTP(w, r)
+	default:
+		http.Error(w, fmt.Sprintf("No stub matches %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+	}
+}
+
+// findStub returns the first stub that matches the given request and hasn't been used up, and counts the use. Must
+// be called while holding the lock.
+func (mock *MockServer) findStub(r *http.Request) *mockStub {
+	for _, stub := range mock.stubs {
+		if stub.response.Times > 0 && stub.uses >= stub.response.Times {
+			continue
+		}
+		if stub.request.matches(r.Method, r.URL.Path, r.Header) {
+			stub.uses++
+			return stub
+		}
+	}
+	return nil
+}
+
+func (request MockRequest) matches(method string, path string, header http.Header) bool {
+	if request.Method != "" && !strings.EqualFold(request.Method, method) {
+		return false
+	}
+	if request.Path != "" && request.Path != path {
+		return false
+	}
+	if request.PathPrefix != "" && !strings.HasPrefix(path, request.PathPrefix) {
+		return false
+	}
+	for name, value := range request.Headers {
+		if header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func writeMockResponse(w http.ResponseWriter, response MockResponse) {
+	if response.Delay > 0 {
+		time.Sleep(response.Delay)
+	}
+
+	if response.CloseConnection {
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		// The connection can't be taken over, e.g. with HTTP/2, so abort the response instead
+		panic(http.ErrAbortHandler)
+	}
+
+	for name, value := range response.Headers {
+		w.Header().Set(name, value)
+	}
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	w.WriteHeader(statusCode)
+	w.Write([]byte(response.Body))
+}
+
+// generateMockServerCertificates generates a CA and a certificate signed by it for localhost and the given host
+// names.
+func generateMockServerCertificates(hostnames []string) (*x509.Certificate, tls.Certificate, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Terratest Mock Server CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	caCertificate, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+
+	dnsNames := []string{"localhost"}
+	ipAddresses := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	for _, hostname := range hostnames {
+		if ip := net.ParseIP(hostname); ip != nil {
+			ipAddresses = append(ipAddresses, ip)
+		} else {
+			dnsNames = append(dnsNames, hostname)
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     dnsNames,
+		IPAddresses:  ipAddresses,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCertificate, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, tls.Certificate{}, err
+	}
+
+	return caCertificate, tls.Certificate{Certificate: [][]byte{der, caDER}, PrivateKey: key}, nil
+}
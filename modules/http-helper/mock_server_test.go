@@ -0,0 +1,112 @@
+package http_helper
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockServerStubs(t *testing.T) {
+	t.Parallel()
+
+	server := NewMockServer(t, nil)
+	server.Stub(MockRequest{Method: "GET", Path: "/health", Headers: map[string]string{"Authorization": "Bearer admin"}}, MockResponse{Body: "admin"})
+	server.Stub(MockRequest{Method: "GET", Path: "/health"}, MockResponse{StatusCode: 503, Body: "starting", Times: 2})
+	server.Stub(MockRequest{Method: "GET", Path: "/health"}, MockResponse{Body: "ok", Headers: map[string]string{"X-Version": "1"}})
+	server.Stub(MockRequest{PathPrefix: "/api/"}, MockResponse{StatusCode: 201})
+
+	HttpGetWithValidation(t, server.URL+"/health", nil, 503, "starting")
+	HttpGetWithValidation(t, server.URL+"/health", nil, 503, "starting")
+	response := HttpGetResponse(t, server.URL+"/health", nil)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, "1", response.Header.Get("X-Version"))
+	HttpGetWithValidationWithOptions(t, server.URL+"/health", &RequestOptions{BearerToken: "admin"}, 200, "admin")
+	HTTPDoWithValidation(t, "POST", server.URL+"/api/users", strings.NewReader(`{"name": "terratest"}`), nil, 201, "", nil)
+	HttpGetWithValidation(t, server.URL+"/missing", nil, 404, "No stub matches GET /missing")
+
+	requests := server.Requests()
+	require.Len(t, requests, 6)
+	assert.Equal(t, "POST", requests[4].Method)
+	assert.Equal(t, "/api/users", requests[4].Path)
+	assert.Equal(t, `{"name": "terratest"}`, string(requests[4].Body))
+	assert.True(t, requests[4].Matched)
+	assert.False(t, requests[5].Matched)
+	assert.Len(t, server.RequestsMatching(MockRequest{Method: "GET", Path: "/health"}), 4)
+
+	server.Reset()
+	assert.Empty(t, server.Requests())
+	HttpGetWithValidation(t, server.URL+"/health", nil, 404, "No stub matches GET /health")
+}
+
+func TestMockServerHandleFunc(t *testing.T) {
+	t.Parallel()
+
+	// Both servers register the same path, which would panic with http.DefaultServeMux
+	for _, text := range []string{"first", "second"} {
+		server := NewMockServer(t, nil)
+		server.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(text + " " + r.URL.Query().Get("q")))
+		})
+		HttpGetWithValidation(t, server.URL+"/echo?q=hello", nil, 200, text+" hello")
+		assert.Equal(t, "/echo?q=hello", server.Requests()[0].RequestURI)
+	}
+}
+
+func TestMockServerFailureInjection(t *testing.T) {
+	t.Parallel()
+
+	server := NewMockServer(t, nil)
+	server.Stub(MockRequest{Path: "/slow"}, MockResponse{Delay: 200 * time.Millisecond})
+	server.Stub(MockRequest{Path: "/broken"}, MockResponse{CloseConnection: true})
+
+	_, _, err := HttpGetWithOptionsE(t, server.URL+"/slow", &RequestOptions{Timeout: 50 * time.Millisecond})
+	assert.Error(t, err)
+
+	_, _, err = HttpGetE(t, server.URL+"/broken", nil)
+	assert.Error(t, err)
+}
+
+func TestMockServerTLS(t *testing.T) {
+	t.Parallel()
+
+	server := NewMockServer(t, &MockServerOptions{TLS: true, Hostnames: []string{"api.terratest.invalid"}})
+	server.Stub(MockRequest{}, MockResponse{Body: "secure"})
+
+	assert.True(t, strings.HasPrefix(server.URL, "https://127.0.0.1:"))
+	HttpGetWithValidation(t, server.URL, server.TLSConfig(), 200, "secure")
+
+	// The certificate is valid for the extra host names too
+	host := net.JoinHostPort("api.terratest.invalid", server.URL[strings.LastIndex(server.URL, ":")+1:])
+	options := &RequestOptions{TLSConfig: server.TLSConfig(), Resolve: map[string]string{host: "127.0.0.1"}}
+	HttpGetWithValidationWithOptions(t, "https://"+host, options, 200, "secure")
+
+	// Without the CA, the certificate isn't trusted
+	_, _, err := HttpGetE(t, server.URL, nil)
+	assert.Error(t, err)
+
+	block, _ := pem.Decode(server.CACertificatePEM())
+	require.NotNil(t, block)
+	ca, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.True(t, ca.IsCA)
+}
+
+func TestMockServerClose(t *testing.T) {
+	t.Parallel()
+
+	server := NewMockServer(t, nil)
+	server.Close()
+	server.Close()
+
+	_, _, err := HttpGetE(t, server.URL, nil)
+	assert.Error(t, err)
+	assert.Nil(t, server.TLSConfig())
+	assert.Nil(t, server.CACertificatePEM())
+}
@@ -0,0 +1,201 @@
+package http_helper
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// defaultTimeout is the timeout of requests that don't set RequestOptions.Timeout.
+	defaultTimeout = 10 * time.Second
+	// defaultMaxRedirects is the number of redirects that are followed for requests that don't set
+	// RequestOptions.MaxRedirects, as with the default http.Client.
+	defaultMaxRedirects = 10
+)
+
+// RequestOptions configures the HTTP client used for a request. Every function in this package that takes a TLS
+// configuration has a WithOptions variant that takes RequestOptions instead, e.g. HttpGetWithOptions for HttpGet. The
+// zero value, as well as nil, behaves like HttpGet and the other HttpGet functions without options: a 10 second
+// timeout, proxies from the environment and up to 10 redirects. The HTTPDo functions without options differ in that
+// they don't use proxies from the environment, nor HTTP/2 when given a TLS configuration.
+type RequestOptions struct {
+	// TLSConfig is the TLS configuration, e.g. with the root CAs to trust. Optional.
+	TLSConfig *tls.Config
+	// ClientCertificates are added to the certificates of TLSConfig, for servers that require mutual TLS. Use
+	// tls.LoadX509KeyPair or tls.X509KeyPair to load them.
+	ClientCertificates []tls.Certificate
+
+	// Timeout is the time limit for the whole request, including redirects and reading the body. Defaults to 10
+	// seconds.
+	Timeout time.Duration
+
+	// ProxyURL is the URL of the proxy to send requests through, e.g. http://proxy.example.com:3128. If it's not set,
+	// the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. Can't be used with
+	// Resolve or UnixSocket.
+	ProxyURL string
+
+	// BasicAuth sets the Authorization header to use HTTP basic authentication. Can't be used with BearerToken.
+	BasicAuth *BasicAuth
+	// BearerToken sets the Authorization header to "Bearer <token>". Can't be used with BasicAuth.
+	BearerToken string
+
+	// Resolve overrides the addresses that host names resolve to, like the --resolve option of curl. The keys are
+	// "host:port" and the values are the IP address, or "address:port", to connect to instead. The URL, and so the Host
+	// header and the TLS server name, is left as it is, which makes it possible to test a specific load balancer or
+	// server behind a DNS name, or a DNS name that doesn't exist yet.
+	Resolve map[string]string
+	// UnixSocket is the path of a unix socket to connect to instead of the host and port of the URL, e.g.
+	// /var/run/docker.sock. The proxy environment variables are ignored. Can't be used with Resolve or ProxyURL.
+	UnixSocket string
+
+	// DisableRedirects returns redirect responses instead of following them.
+	DisableRedirects bool
+	// MaxRedirects is the number of redirects that are followed before the request fails. Defaults to 10.
+	MaxRedirects int
+
+	// DisableHTTP2 makes requests over HTTPS use HTTP/1.1 even if the server supports HTTP/2.
+	DisableHTTP2 bool
+
+	// bareTransport makes requests use an http.Transport with only the TLS configuration set, as the HTTPDo functions
+	// without options always did, rather than one based on http.DefaultTransport.
+	bareTransport bool
+}
+
+// httpDoOptions returns the options of the HTTPDo functions without options for the given TLS configuration.
+func httpDoOptions(tlsConfig *tls.Config) *RequestOptions {
+	return &RequestOptions{TLSConfig: tlsConfig, bareTransport: true}
+}
+
+// BasicAuth is a user name and password for HTTP basic authentication.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+// timeout returns the timeout of the request.
+func (options *RequestOptions) timeout() time.Duration {
+	if options == nil || options.Timeout == 0 {
+		return defaultTimeout
+	}
+	return options.Timeout
+}
+
+// maxRedirectCount returns the number of redirects to follow.
+func (options *RequestOptions) maxRedirectCount() int {
+	if options == nil || options.MaxRedirects == 0 {
+		return defaultMaxRedirects
+	}
+	return options.MaxRedirects
+}
+
+// validate returns an error if options that can't be used together are set.
+func (options *RequestOptions) validate() error {
+	if options == nil {
+		return nil
+	}
+	if options.BasicAuth != nil && options.BearerToken != "" {
+		return ConflictingRequestOptionsError{Option: "BasicAuth", OtherOption: "BearerToken"}
+	}
+	if options.UnixSocket != "" && len(options.Resolve) > 0 {
+		return ConflictingRequestOptionsError{Option: "UnixSocket", OtherOption: "Resolve"}
+	}
+	if options.ProxyURL != "" && options.UnixSocket != "" {
+		return ConflictingRequestOptionsError{Option: "ProxyURL", OtherOption: "UnixSocket"}
+	}
+	if options.ProxyURL != "" && len(options.Resolve) > 0 {
+		return ConflictingRequestOptionsError{Option: "ProxyURL", OtherOption: "Resolve"}
+	}
+	return nil
+}
+
+// authorize adds the Authorization header for the configured credentials, if any, to the given request.
+func (options *RequestOptions) authorize(req *http.Request) {
+	switch {
+	case options == nil:
+	case options.BasicAuth != nil:
+		req.SetBasicAuth(options.BasicAuth.Username, options.BasicAuth.Password)
+	case options.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+options.BearerToken)
+	}
+}
+
+// tlsConfig returns a copy of TLSConfig with the client certificates added, or nil if neither is set.
+func (options *RequestOptions) tlsConfig() *tls.Config {
+	if options == nil || (options.TLSConfig == nil && len(options.ClientCertificates) == 0) {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if options.TLSConfig != nil {
+		tlsConfig = options.TLSConfig.Clone()
+	}
+	tlsConfig.Certificates = append(tlsConfig.Certificates, options.ClientCertificates...)
+	return tlsConfig
+}
+
+// newTransportE returns a transport for the given options, based on http.DefaultTransport unless bareTransport is set.
+func (options *RequestOptions) newTransportE() (*http.Transport, error) {
+	if options == nil {
+		return http.DefaultTransport.(*http.Transport).Clone(), nil
+	}
+
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if options.bareTransport {
+		tr = &http.Transport{}
+	}
+
+	if tlsConfig := options.tlsConfig(); tlsConfig != nil {
+		tr.TLSClientConfig = tlsConfig
+	}
+
+	if options.ProxyURL != "" {
+		proxyURL, err := url.Parse(options.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	switch {
+	case options.UnixSocket != "":
+		// Requests to a proxy would be sent over the unix socket in proxy form
+		tr.Proxy = nil
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", options.UnixSocket)
+		}
+	case len(options.Resolve) > 0:
+		tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, resolveAddress(options.Resolve, addr))
+		}
+	}
+
+	if options.DisableHTTP2 {
+		tr.ForceAttemptHTTP2 = false
+		// A non-nil, empty map disables HTTP/2 for TLS connections
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return tr, nil
+}
+
+// resolveAddress returns the address to connect to for the given "host:port" address according to the given
+// overrides, keeping the port if the override is only an IP address.
+func resolveAddress(overrides map[string]string, addr string) string {
+	override, ok := overrides[addr]
+	if !ok {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(override); err == nil {
+		return override
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return override
+	}
+	return net.JoinHostPort(override, port)
+}
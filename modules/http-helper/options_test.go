@@ -0,0 +1,220 @@
+package http_helper
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestOptionsTimeout(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	defer ts.Close()
+
+	_, _, err := HttpGetWithOptionsE(t, ts.URL, &RequestOptions{Timeout: 50 * time.Millisecond})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+}
+
+func TestRequestOptionsAuth(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	})
+	defer ts.Close()
+
+	testCases := []struct {
+		name     string
+		options  *RequestOptions
+		expected string
+	}{
+		{"none", nil, ""},
+		{"basic", &RequestOptions{BasicAuth: &BasicAuth{Username: "user", Password: "pass"}}, "Basic dXNlcjpwYXNz"},
+		{"bearer", &RequestOptions{BearerToken: "token"}, "Bearer token"},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			HTTPDoWithValidationWithOptions(t, "GET", ts.URL, nil, nil, 200, testCase.expected, testCase.options)
+		})
+	}
+
+	_, _, err := HttpGetWithOptionsE(t, ts.URL, &RequestOptions{BasicAuth: &BasicAuth{}, BearerToken: "token"})
+	assert.Equal(t, ConflictingRequestOptionsError{Option: "BasicAuth", OtherOption: "BearerToken"}, err)
+}
+
+func TestRequestOptionsResolve(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	})
+	defer ts.Close()
+
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	require.NoError(t, err)
+	host := net.JoinHostPort("terratest.invalid", port)
+
+	HttpGetWithValidationWithOptions(t, "http://"+host, &RequestOptions{Resolve: map[string]string{host: "127.0.0.1"}}, 200, host)
+
+	_, _, err = HttpGetWithOptionsE(t, "http://"+host, &RequestOptions{Resolve: map[string]string{host: "127.0.0.1"}, ProxyURL: ts.URL})
+	assert.Equal(t, ConflictingRequestOptionsError{Option: "ProxyURL", OtherOption: "Resolve"}, err)
+}
+
+func TestResolveAddress(t *testing.T) {
+	t.Parallel()
+
+	overrides := map[string]string{"example.com:443": "10.0.0.1", "example.com:80": "10.0.0.2:8080"}
+	assert.Equal(t, "10.0.0.1:443", resolveAddress(overrides, "example.com:443"))
+	assert.Equal(t, "10.0.0.2:8080", resolveAddress(overrides, "example.com:80"))
+	assert.Equal(t, "example.org:443", resolveAddress(overrides, "example.org:443"))
+}
+
+func TestRequestOptionsUnixSocket(t *testing.T) {
+	t.Parallel()
+
+	socket := filepath.Join(t.TempDir(), "http.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	HttpGetWithValidationWithOptions(t, "http://unix/version", &RequestOptions{UnixSocket: socket}, 200, "/version")
+
+	// The proxy environment variables don't apply to unix sockets, and a proxy can't be set
+	tr, err := (&RequestOptions{UnixSocket: socket}).newTransportE()
+	require.NoError(t, err)
+	assert.Nil(t, tr.Proxy)
+	_, _, err = HttpGetWithOptionsE(t, "http://unix/version", &RequestOptions{UnixSocket: socket, ProxyURL: "http://127.0.0.1:3128"})
+	assert.Equal(t, ConflictingRequestOptionsError{Option: "ProxyURL", OtherOption: "UnixSocket"}, err)
+}
+
+func TestRequestOptionsProxy(t *testing.T) {
+	t.Parallel()
+
+	proxy := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	})
+	defer proxy.Close()
+
+	HttpGetWithValidationWithOptions(t, "http://terratest.invalid/path", &RequestOptions{ProxyURL: proxy.URL}, 200, "proxied http://terratest.invalid/path")
+}
+
+func TestRequestOptionsRedirects(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+	})
+	defer ts.Close()
+
+	response := HttpGetResponseWithOptions(t, ts.URL+"/", &RequestOptions{DisableRedirects: true})
+	assert.Equal(t, 302, response.StatusCode)
+	assert.Equal(t, "/x", response.Header.Get("Location"))
+	assert.Empty(t, response.Redirects)
+
+	_, err := HttpGetResponseWithOptionsE(t, ts.URL+"/", &RequestOptions{MaxRedirects: 2})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped after 2 redirects")
+}
+
+func TestRequestOptionsHTTP2(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(bodyCopyHandler))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	tlsConfig.RootCAs.AddCert(ts.Certificate())
+
+	response := HttpGetResponseWithOptions(t, ts.URL, &RequestOptions{TLSConfig: tlsConfig})
+	assert.Equal(t, "HTTP/2.0", response.Proto)
+
+	response = HttpGetResponseWithOptions(t, ts.URL, &RequestOptions{TLSConfig: tlsConfig, DisableHTTP2: true})
+	assert.Equal(t, "HTTP/1.1", response.Proto)
+
+	// The HTTPDo functions without options keep using a bare transport, which doesn't attempt HTTP/2
+	response = HttpGetResponse(t, ts.URL, tlsConfig)
+	assert.Equal(t, "HTTP/2.0", response.Proto)
+	response = HTTPDoResponse(t, http.MethodGet, ts.URL, nil, nil, tlsConfig)
+	assert.Equal(t, "HTTP/1.1", response.Proto)
+	response = HTTPDoResponseWithOptions(t, http.MethodGet, ts.URL, nil, nil, &RequestOptions{TLSConfig: tlsConfig})
+	assert.Equal(t, "HTTP/2.0", response.Proto)
+}
+
+func TestHTTPDoUsesBareTransport(t *testing.T) {
+	t.Parallel()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+	tr, err := httpDoOptions(tlsConfig).newTransportE()
+	require.NoError(t, err)
+	assert.Nil(t, tr.Proxy)
+	assert.False(t, tr.ForceAttemptHTTP2)
+	assert.Equal(t, tlsConfig, tr.TLSClientConfig)
+
+	tr, err = (&RequestOptions{TLSConfig: tlsConfig}).newTransportE()
+	require.NoError(t, err)
+	assert.NotNil(t, tr.Proxy)
+	assert.True(t, tr.ForceAttemptHTTP2)
+}
+
+func TestRequestOptionsClientCertificates(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: true}
+
+	_, _, err := HttpGetWithOptionsE(t, ts.URL, &RequestOptions{TLSConfig: tlsConfig})
+	require.Error(t, err)
+
+	options := &RequestOptions{TLSConfig: tlsConfig, ClientCertificates: []tls.Certificate{generateClientCertificate(t, "terratest-client")}}
+	HttpGetWithValidationWithOptions(t, ts.URL, options, 200, "terratest-client")
+	assert.Empty(t, tlsConfig.Certificates)
+}
+
+// generateClientCertificate returns a self-signed certificate with the given common name.
+func generateClientCertificate(t *testing.T, commonName string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
@@ -0,0 +1,472 @@
+package http_helper
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// HttpResponse is the response to an HTTP request, with everything that tests may want to check.
+type HttpResponse struct {
+	// StatusCode is the HTTP status code, e.g. 200.
+	StatusCode int
+	// Status is the HTTP status line, e.g. "200 OK".
+	Status string
+	// Proto is the protocol of the response, e.g. "HTTP/1.1" or "HTTP/2.0".
+	Proto string
+	// Header contains the response headers. Use Header.Get for case insensitive lookups.
+	Header http.Header
+	// Cookies are the cookies set by the response.
+	Cookies []*http.Cookie
+	// Body is the raw response body. Unlike the body returned by HttpGet and HTTPDo, it is not trimmed.
+	Body []byte
+	// URL is the URL of the final request, after following redirects.
+	URL string
+	// Redirects are the redirects that were followed to get to the final URL, in order.
+	Redirects []Redirect
+	// TLS is the state of the TLS connection of the final request, including the peer certificates, or nil if the
+	// request didn't use TLS.
+	TLS *tls.ConnectionState
+	// Timing is how long the different phases of the final request took.
+	Timing HttpTiming
+}
+
+// BodyString returns the response body as a string with leading and trailing white space removed, as returned by
+// HttpGet and HTTPDo.
+func (response *HttpResponse) BodyString() string {
+	return strings.TrimSpace(string(response.Body))
+}
+
+// PeerCertificates returns the certificates presented by the server, starting with the leaf certificate, or nil if
+// the request didn't use TLS.
+func (response *HttpResponse) PeerCertificates() []*x509.Certificate {
+	if response.TLS == nil {
+		return nil
+	}
+	return response.TLS.PeerCertificates
+}
+
+// Redirect is a redirect response that was followed.
+type Redirect struct {
+	// URL is the URL of the request that was redirected.
+	URL string
+	// StatusCode is the status code of the redirect response, e.g. 301.
+	StatusCode int
+	// Location is the URL the request was redirected to.
+	Location string
+}
+
+// HttpTiming is how long the phases of an HTTP request took. Phases that didn't happen, such as DNS resolution for an
+// IP address or connecting when an existing connection was reused, are zero.
+type HttpTiming struct {
+	// DNS is how long it took to resolve the host name.
+	DNS time.Duration
+	// Connect is how long it took to establish the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is how long the TLS handshake took.
+	TLSHandshake time.Duration
+	// TimeToFirstByte is the time from the start of the request, including DNS resolution, connecting and the TLS
+	// handshake, until the first byte of the response was received.
+	TimeToFirstByte time.Duration
+	// Total is the time from the start of the first request until the body of the final response was read, including
+	// any redirects.
+	Total time.Duration
+}
+
+// HttpGetResponse performs an HTTP GET, with an optional pointer to a custom TLS configuration, on the given URL and
+// returns the response. If there's any error, fail the test.
+func HttpGetResponse(t testing.TestingT, url string, tlsConfig *tls.Config) *HttpResponse {
+	response, err := HttpGetResponseE(t, url, tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// HttpGetResponseE performs an HTTP GET, with an optional pointer to a custom TLS configuration, on the given URL and
+// returns the response, or any error.
+func HttpGetResponseE(t testing.TestingT, url string, tlsConfig *tls.Config) (*HttpResponse, error) {
+	return HttpGetResponseWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig})
+}
+
+// HttpGetResponseWithOptions performs an HTTP GET on the given URL with the given options and
+// returns the response. If there's any error, fail the test.
+func HttpGetResponseWithOptions(t testing.TestingT, url string, options *RequestOptions) *HttpResponse {
+	response, err := HttpGetResponseWithOptionsE(t, url, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// HttpGetResponseWithOptionsE performs an HTTP GET on the given URL with the given options and
+// returns the response, or any error.
+func HttpGetResponseWithOptionsE(t testing.TestingT, url string, options *RequestOptions) (*HttpResponse, error) {
+	logger.Logf(t, "Making an HTTP GET call to URL %s", url)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sendRequestE(req, options)
+}
+
+// HTTPDoResponse performs the given HTTP method on the given URL and returns the response. If there's any error, fail
+// the test.
+func HTTPDoResponse(
+	t testing.TestingT, method string, url string, body io.Reader,
+	headers map[string]string, tlsConfig *tls.Config,
+) *HttpResponse {
+	response, err := HTTPDoResponseE(t, method, url, body, headers, tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// HTTPDoResponseE performs the given HTTP method on the given URL and returns the response, or any error.
+func HTTPDoResponseE(
+	t testing.TestingT, method string, url string, body io.Reader,
+	headers map[string]string, tlsConfig *tls.Config,
+) (*HttpResponse, error) {
+	return HTTPDoResponseWithOptionsE(t, method, url, body, headers, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoResponseWithOptions performs the given HTTP method on the given URL with the given options and returns the response. If there's any error, fail
+// the test.
+func HTTPDoResponseWithOptions(
+	t testing.TestingT, method string, url string, body io.Reader,
+	headers map[string]string, options *RequestOptions,
+) *HttpResponse {
+	response, err := HTTPDoResponseWithOptionsE(t, method, url, body, headers, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// HTTPDoResponseWithOptionsE performs the given HTTP method on the given URL with the given options and returns the response, or any error.
+func HTTPDoResponseWithOptionsE(
+	t testing.TestingT, method string, url string, body io.Reader,
+	headers map[string]string, options *RequestOptions,
+) (*HttpResponse, error) {
+	logger.Logf(t, "Making an HTTP %s call to URL %s", method, url)
+
+	req, err := newRequest(method, url, body, headers)
+	if err != nil {
+		return nil, err
+	}
+	return sendRequestE(req, options)
+}
+
+// HttpGetWithResponseValidation performs an HTTP GET on the given URL and validates the response using the given
+// function. If the validation fails, fail the test.
+func HttpGetWithResponseValidation(t testing.TestingT, url string, tlsConfig *tls.Config, validateResponse func(*HttpResponse) bool) {
+	err := HttpGetWithResponseValidationE(t, url, tlsConfig, validateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithResponseValidationE performs an HTTP GET on the given URL and validates the response using the given
+// function. If the validation fails, return a ValidationFunctionFailed error.
+func HttpGetWithResponseValidationE(t testing.TestingT, url string, tlsConfig *tls.Config, validateResponse func(*HttpResponse) bool) error {
+	return HttpGetWithResponseValidationWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig}, validateResponse)
+}
+
+// HttpGetWithResponseValidationWithOptions performs an HTTP GET on the given URL with the given options and validates the response using the given
+// function. If the validation fails, fail the test.
+func HttpGetWithResponseValidationWithOptions(t testing.TestingT, url string, options *RequestOptions, validateResponse func(*HttpResponse) bool) {
+	err := HttpGetWithResponseValidationWithOptionsE(t, url, options, validateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithResponseValidationWithOptionsE performs an HTTP GET on the given URL with the given options and validates the response using the given
+// function. If the validation fails, return a ValidationFunctionFailed error.
+func HttpGetWithResponseValidationWithOptionsE(t testing.TestingT, url string, options *RequestOptions, validateResponse func(*HttpResponse) bool) error {
+	response, err := HttpGetResponseWithOptionsE(t, url, options)
+	if err != nil {
+		return err
+	}
+	return validateHttpResponse(url, response, validateResponse)
+}
+
+// HttpGetWithRetryWithResponseValidation repeatedly performs an HTTP GET on the given URL until the given validation
+// function returns true or max retries has been exceeded.
+func HttpGetWithRetryWithResponseValidation(t testing.TestingT, url string, tlsConfig *tls.Config, retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool) {
+	err := HttpGetWithRetryWithResponseValidationE(t, url, tlsConfig, retries, sleepBetweenRetries, validateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithRetryWithResponseValidationE repeatedly performs an HTTP GET on the given URL until the given validation
+// function returns true or max retries has been exceeded.
+func HttpGetWithRetryWithResponseValidationE(t testing.TestingT, url string, tlsConfig *tls.Config, retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool) error {
+	return HttpGetWithRetryWithResponseValidationWithOptionsE(t, url, &RequestOptions{TLSConfig: tlsConfig}, retries, sleepBetweenRetries, validateResponse)
+}
+
+// HttpGetWithRetryWithResponseValidationWithOptions repeatedly performs an HTTP GET on the given URL with the given options until the given validation
+// function returns true or max retries has been exceeded.
+func HttpGetWithRetryWithResponseValidationWithOptions(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool) {
+	err := HttpGetWithRetryWithResponseValidationWithOptionsE(t, url, options, retries, sleepBetweenRetries, validateResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithRetryWithResponseValidationWithOptionsE repeatedly performs an HTTP GET on the given URL with the given options until the given validation
+// function returns true or max retries has been exceeded.
+func HttpGetWithRetryWithResponseValidationWithOptionsE(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool) error {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP GET to URL %s", url), retries, sleepBetweenRetries, func() (string, error) {
+		return "", HttpGetWithResponseValidationWithOptionsE(t, url, options, validateResponse)
+	})
+
+	return err
+}
+
+// HTTPDoWithResponseValidation performs the given HTTP method on the given URL and validates the response using the
+// given function. If the validation fails, fail the test.
+func HTTPDoWithResponseValidation(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(*HttpResponse) bool, tlsConfig *tls.Config) {
+	err := HTTPDoWithResponseValidationE(t, method, url, body, headers, validateResponse, tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithResponseValidationE performs the given HTTP method on the given URL and validates the response using the
+// given function. If the validation fails, return a ValidationFunctionFailed error.
+func HTTPDoWithResponseValidationE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(*HttpResponse) bool, tlsConfig *tls.Config) error {
+	return HTTPDoWithResponseValidationWithOptionsE(t, method, url, body, headers, validateResponse, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoWithResponseValidationWithOptions performs the given HTTP method on the given URL with the given options and validates the response using the
+// given function. If the validation fails, fail the test.
+func HTTPDoWithResponseValidationWithOptions(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(*HttpResponse) bool, options *RequestOptions) {
+	err := HTTPDoWithResponseValidationWithOptionsE(t, method, url, body, headers, validateResponse, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithResponseValidationWithOptionsE performs the given HTTP method on the given URL with the given options and validates the response using the
+// given function. If the validation fails, return a ValidationFunctionFailed error.
+func HTTPDoWithResponseValidationWithOptionsE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, validateResponse func(*HttpResponse) bool, options *RequestOptions) error {
+	response, err := HTTPDoResponseWithOptionsE(t, method, url, body, headers, options)
+	if err != nil {
+		return err
+	}
+	return validateHttpResponse(url, response, validateResponse)
+}
+
+// HTTPDoWithRetryWithResponseValidation repeatedly performs the given HTTP method on the given URL until the given
+// validation function returns true or max retries has been exceeded.
+func HTTPDoWithRetryWithResponseValidation(
+	t testing.TestingT, method string, url string, body []byte, headers map[string]string,
+	retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool, tlsConfig *tls.Config,
+) {
+	err := HTTPDoWithRetryWithResponseValidationE(t, method, url, body, headers, retries, sleepBetweenRetries, validateResponse, tlsConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithRetryWithResponseValidationE repeatedly performs the given HTTP method on the given URL until the given
+// validation function returns true or max retries has been exceeded.
+func HTTPDoWithRetryWithResponseValidationE(
+	t testing.TestingT, method string, url string, body []byte, headers map[string]string,
+	retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool, tlsConfig *tls.Config,
+) error {
+	return HTTPDoWithRetryWithResponseValidationWithOptionsE(t, method, url, body, headers, retries, sleepBetweenRetries, validateResponse, httpDoOptions(tlsConfig))
+}
+
+// HTTPDoWithRetryWithResponseValidationWithOptions repeatedly performs the given HTTP method on the given URL with the given options until the given
+// validation function returns true or max retries has been exceeded.
+func HTTPDoWithRetryWithResponseValidationWithOptions(
+	t testing.TestingT, method string, url string, body []byte, headers map[string]string,
+	retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool, options *RequestOptions,
+) {
+	err := HTTPDoWithRetryWithResponseValidationWithOptionsE(t, method, url, body, headers, retries, sleepBetweenRetries, validateResponse, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithRetryWithResponseValidationWithOptionsE repeatedly performs the given HTTP method on the given URL with the given options until the given
+// validation function returns true or max retries has been exceeded.
+func HTTPDoWithRetryWithResponseValidationWithOptionsE(
+	t testing.TestingT, method string, url string, body []byte, headers map[string]string,
+	retries int, sleepBetweenRetries time.Duration, validateResponse func(*HttpResponse) bool, options *RequestOptions,
+) error {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP %s to URL %s", method, url), retries,
+		sleepBetweenRetries, func() (string, error) {
+			bodyReader := bytes.NewReader(body)
+			return "", HTTPDoWithResponseValidationWithOptionsE(t, method, url, bodyReader, headers, validateResponse, options)
+		})
+
+	return err
+}
+
+func validateHttpResponse(url string, response *HttpResponse, validateResponse func(*HttpResponse) bool) error {
+	if !validateResponse(response) {
+		return ValidationFunctionFailed{Url: url, Status: response.StatusCode, Body: response.BodyString()}
+	}
+	return nil
+}
+
+// sendRequestE sends the given request with a client configured with the given options, following redirects unless
+// they are disabled, and returns the response with its body, redirects and timing.
+func sendRequestE(req *http.Request, options *RequestOptions) (*HttpResponse, error) {
+	client, err := newRequestClientE(options)
+	if err != nil {
+		return nil, err
+	}
+	defer client.closeIdleConnections()
+	return client.sendE(req)
+}
+
+// requestClient sends requests with an HTTP client configured with RequestOptions. The client, and its connections,
+// can be reused for several requests.
+type requestClient struct {
+	options *RequestOptions
+	client  *http.Client
+}
+
+// redirectsContextKey is the key of the response whose redirects are recorded in the context of a request.
+type redirectsContextKey struct{}
+
+// newRequestClientE returns a client configured with the given options.
+func newRequestClientE(options *RequestOptions) (*requestClient, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	transport, err := options.newTransportE()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		// By default, Go does not impose a timeout, so an HTTP connection attempt can hang for a LONG time.
+		Timeout:   options.timeout(),
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if options != nil && options.DisableRedirects {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= options.maxRedirectCount() {
+				return fmt.Errorf("stopped after %d redirects", options.maxRedirectCount())
+			}
+			if response, ok := req.Context().Value(redirectsContextKey{}).(*HttpResponse); ok {
+				response.Redirects = append(response.Redirects, Redirect{
+					URL:        via[len(via)-1].URL.String(),
+					StatusCode: req.Response.StatusCode,
+					Location:   req.URL.String(),
+				})
+			}
+			return nil
+		},
+	}
+	return &requestClient{options: options, client: client}, nil
+}
+
+// closeIdleConnections closes the connections of the client that are not in use.
+func (client *requestClient) closeIdleConnections() {
+	client.client.CloseIdleConnections()
+}
+
+// sendE sends the given request, following redirects unless they are disabled, and returns the response with its
+// body, redirects and timing.
+func (client *requestClient) sendE(req *http.Request) (*HttpResponse, error) {
+	client.options.authorize(req)
+
+	response := &HttpResponse{}
+
+	timer := &requestTimer{}
+	start := time.Now()
+	ctx := context.WithValue(req.Context(), redirectsContextKey{}, response)
+	resp, err := client.client.Do(req.WithContext(httptrace.WithClientTrace(ctx, timer.clientTrace())))
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response.StatusCode = resp.StatusCode
+	response.Status = resp.Status
+	response.Proto = resp.Proto
+	response.Header = resp.Header
+	response.Cookies = resp.Cookies()
+	response.Body = body
+	response.URL = resp.Request.URL.String()
+	response.TLS = resp.TLS
+	response.Timing = timer.timing()
+	response.Timing.Total = time.Since(start)
+	return response, nil
+}
+
+// requestTimer records the timing of the phases of a request through an httptrace.ClientTrace. When redirects are
+// followed, each request resets the timer, so it ends up with the timing of the final request.
+type requestTimer struct {
+	lock                                           sync.Mutex
+	requestStart, dnsStart, connectStart, tlsStart time.Time
+	dns, connect, tlsHandshake, timeToFirstByte    time.Duration
+}
+
+func (timer *requestTimer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			timer.record(func() {
+				timer.requestStart = time.Now()
+				timer.dns, timer.connect, timer.tlsHandshake, timer.timeToFirstByte = 0, 0, 0, 0
+			})
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { timer.record(func() { timer.dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { timer.record(func() { timer.dns = time.Since(timer.dnsStart) }) },
+		ConnectStart: func(string, string) {
+			timer.record(func() {
+				// With several addresses, only the time of the last connection attempt is kept
+				timer.connectStart = time.Now()
+			})
+		},
+		ConnectDone:       func(string, string, error) { timer.record(func() { timer.connect = time.Since(timer.connectStart) }) },
+		TLSHandshakeStart: func() { timer.record(func() { timer.tlsStart = time.Now() }) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			timer.record(func() { timer.tlsHandshake = time.Since(timer.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			timer.record(func() { timer.timeToFirstByte = time.Since(timer.requestStart) })
+		},
+	}
+}
+
+// record runs the given function while holding the lock, since the trace hooks can be called from other goroutines.
+func (timer *requestTimer) record(update func()) {
+	timer.lock.Lock()
+	defer timer.lock.Unlock()
+	update()
+}
+
+func (timer *requestTimer) timing() HttpTiming {
+	timer.lock.Lock()
+	defer timer.lock.Unlock()
+	return HttpTiming{DNS: timer.dns, Connect: timer.connect, TLSHandshake: timer.tlsHandshake, TimeToFirstByte: timer.timeToFirstByte}
+}
@@ -0,0 +1,142 @@
+package http_helper
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpGetResponse(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Terratest", "yes")
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Write([]byte("  Hello, Terratest!\n"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	response := HttpGetResponse(t, ts.URL+"/old", nil)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, "200 OK", response.Status)
+	assert.Equal(t, "yes", response.Header.Get("x-terratest"))
+	require.Len(t, response.Cookies, 1)
+	assert.Equal(t, "abc", response.Cookies[0].Value)
+	assert.Equal(t, []byte("  Hello, Terratest!\n"), response.Body)
+	assert.Equal(t, "Hello, Terratest!", response.BodyString())
+	assert.Equal(t, ts.URL+"/final", response.URL)
+	assert.Equal(t, []Redirect{
+		{URL: ts.URL + "/old", StatusCode: 301, Location: ts.URL + "/new"},
+		{URL: ts.URL + "/new", StatusCode: 302, Location: ts.URL + "/final"},
+	}, response.Redirects)
+	assert.Nil(t, response.TLS)
+	assert.Nil(t, response.PeerCertificates())
+	assert.Greater(t, int64(response.Timing.TimeToFirstByte), int64(0))
+	assert.GreaterOrEqual(t, int64(response.Timing.Total), int64(response.Timing.TimeToFirstByte))
+}
+
+func TestHttpGetResponseTooManyRedirects(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	})
+	defer ts.Close()
+
+	_, err := HttpGetResponseE(t, ts.URL, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stopped after 10 redirects")
+}
+
+func TestHttpGetResponseTLS(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(bodyCopyHandler))
+	defer ts.Close()
+
+	response := HttpGetResponse(t, ts.URL, &tls.Config{InsecureSkipVerify: true})
+	require.NotNil(t, response.TLS)
+	assert.True(t, response.TLS.HandshakeComplete)
+	assert.Equal(t, ts.Certificate().Raw, response.PeerCertificates()[0].Raw)
+	assert.Greater(t, int64(response.Timing.Connect), int64(0))
+	assert.Greater(t, int64(response.Timing.TLSHandshake), int64(0))
+}
+
+func headerValueHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.Header.Get("Header-Name")))
+}
+
+func TestHTTPDoResponse(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(headerValueHandler)
+	defer ts.Close()
+
+	response := HTTPDoResponse(t, "POST", ts.URL, nil, map[string]string{"Header-Name": "Header-Value"}, nil)
+	assert.Equal(t, 200, response.StatusCode)
+	assert.Equal(t, "Header-Value", response.BodyString())
+	assert.Empty(t, response.Redirects)
+}
+
+func TestHTTPDoWithResponseValidation(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(headerValueHandler)
+	defer ts.Close()
+
+	headers := map[string]string{"Header-Name": "Header-Value"}
+	HTTPDoWithResponseValidation(t, "POST", ts.URL, nil, headers, func(response *HttpResponse) bool {
+		return response.StatusCode == 200 && response.BodyString() == "Header-Value"
+	}, nil)
+
+	err := HTTPDoWithResponseValidationE(t, "POST", ts.URL, nil, headers, func(response *HttpResponse) bool {
+		return response.Header.Get("X-Missing") != ""
+	}, nil)
+	assert.Equal(t, ValidationFunctionFailed{Url: ts.URL, Status: 200, Body: "Header-Value"}, err)
+}
+
+func TestHttpGetWithRetryWithResponseValidation(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+	})
+	defer ts.Close()
+
+	HttpGetWithRetryWithResponseValidation(t, ts.URL, nil, 10, 10*time.Millisecond, func(response *HttpResponse) bool {
+		return response.StatusCode == 200 && response.Header.Get("Content-Type") == "text/plain"
+	})
+	assert.Equal(t, 3, requests)
+}
+
+func TestHTTPDoWithRetryWithResponseValidation(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(bodyCopyHandler)
+	defer ts.Close()
+
+	body := []byte("Hello, Terratest!")
+	HTTPDoWithRetryWithResponseValidation(t, "POST", ts.URL, body, nil, 3, time.Second, func(response *HttpResponse) bool {
+		return bytes.Equal(response.Body, body)
+	}, nil)
+}
@@ -0,0 +1,515 @@
+package http_helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/jmespath/go-jmespath"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// ResponseValidator checks an HTTP response and returns an error that explains why it's not valid, or nil if it is.
+// Use the Validate functions in this package to create validators for common checks, or write your own. A validator
+// that can never pass, e.g. because it was created with an invalid expression, should return a retry.FatalError so
+// that the functions that retry until the validators pass stop immediately.
+type ResponseValidator func(response *HttpResponse) error
+
+// ValidateStatusCode returns a validator that checks that the response has the given status code.
+func ValidateStatusCode(expectedStatusCode int) ResponseValidator {
+	return func(response *HttpResponse) error {
+		if response.StatusCode != expectedStatusCode {
+			return UnexpectedStatusCodeError{Expected: expectedStatusCode, Actual: response.StatusCode}
+		}
+		return nil
+	}
+}
+
+// ValidateBodyRegex returns a validator that checks that the response body matches the given regular expression. The
+// expression is compiled once, when the validator is created. If it's invalid, the validator returns a
+// retry.FatalError.
+func ValidateBodyRegex(pattern string) ResponseValidator {
+	re, compileErr := regexp.Compile(pattern)
+	return func(response *HttpResponse) error {
+		if compileErr != nil {
+			return retry.FatalError{Underlying: compileErr}
+		}
+		if !re.Match(response.Body) {
+			return BodyRegexMismatchError{Pattern: pattern, Body: response.BodyString()}
+		}
+		return nil
+	}
+}
+
+// ValidateJSONBody returns a validator that checks that the response body is valid JSON.
+func ValidateJSONBody() ResponseValidator {
+	return func(response *HttpResponse) error {
+		_, err := parseJSONBody(response)
+		return err
+	}
+}
+
+// ValidateJSONPath returns a validator that checks that the value selected by the given JSONPath expression in the
+// JSON response body equals the expected value. The expression uses the kubectl JSONPath syntax, e.g. "$.items[0].id"
+// or "{.items[*].id}". If it can select several values, because it contains a wildcard, filter, union or slice, they
+// are compared as a list, even if only one value is selected. The expected value is compared as
+// JSON, so it can be a string, number, bool, nil, map, slice or a struct with json tags. If the values differ, the
+// error contains a diff of the expected and the actual JSON. The expression is parsed once, when the validator is
+// created. If it's invalid, the validator returns a retry.FatalError.
+func ValidateJSONPath(expression string, expected interface{}) ResponseValidator {
+	path, parseErr := parseJSONPath(expression)
+	return func(response *HttpResponse) error {
+		if parseErr != nil {
+			return retry.FatalError{Underlying: parseErr}
+		}
+		body, err := parseJSONBody(response)
+		if err != nil {
+			return err
+		}
+		actual, err := path.evaluate(body)
+		if err != nil {
+			return err
+		}
+		return compareJSONValues("JSONPath", expression, expected, actual)
+	}
+}
+
+// ValidateJMESPath returns a validator that checks that the result of the given JMESPath expression, e.g.
+// "items[?state=='running'].id | length(@)", on the JSON response body equals the expected value. The expected value
+// is compared as JSON, as with ValidateJSONPath.
+func ValidateJMESPath(expression string, expected interface{}) ResponseValidator {
+	return func(response *HttpResponse) error {
+		body, err := parseJSONBody(response)
+		if err != nil {
+			return err
+		}
+		actual, err := jmespath.Search(expression, body)
+		if err != nil {
+			return fmt.Errorf("invalid JMESPath expression %q: %s", expression, err)
+		}
+		return compareJSONValues("JMESPath", expression, expected, actual)
+	}
+}
+
+// ValidateJSONSchema returns a validator that checks that the JSON response body is valid according to the given
+// JSON Schema of draft 4, 6 or 7, which can be JSON or YAML, using gojsonschema. The formats defined by JSON Schema,
+// such as date-time and email, are checked, and $refs are resolved within the schema, or relative to the current
+// folder for other files.
+func ValidateJSONSchema(schema string) ResponseValidator {
+	return func(response *HttpResponse) error {
+		parsedSchema, err := parseYAMLOrJSON([]byte(schema))
+		if err != nil {
+			return fmt.Errorf("invalid JSON Schema: %s", err)
+		}
+		return validateJSONBodySchema(response, parsedSchema)
+	}
+}
+
+// ValidateOpenAPIResponse returns a validator that checks that the response is documented for the operation with the
+// given method and path in the given OpenAPI spec, which can be OpenAPI 3 or Swagger 2, in JSON or YAML. The path is
+// the path of the operation as it appears in the spec, e.g. "/users/{id}". The response status code must be one of
+// the documented ones, or there must be a default response, and the JSON body must be valid according to the schema
+// of that response, as with ValidateJSONSchema, with the $refs resolved against the spec.
+func ValidateOpenAPIResponse(spec []byte, method string, path string) ResponseValidator {
+	return func(response *HttpResponse) error {
+		parsedSpec, err := parseYAMLOrJSON(spec)
+		if err != nil {
+			return fmt.Errorf("invalid OpenAPI spec: %s", err)
+		}
+
+		operation, ok := findOpenAPIOperation(parsedSpec, method, path)
+		if !ok {
+			return OpenAPIOperationNotFoundError{Method: method, Path: path}
+		}
+		responses, _ := operation["responses"].(map[string]interface{})
+		documented, ok := findOpenAPIResponse(responses, response.StatusCode)
+		if !ok {
+			return UndocumentedStatusCodeError{Method: method, Path: path, StatusCode: response.StatusCode}
+		}
+		if ref, ok := documented["$ref"].(string); ok {
+			resolved, err := resolveJSONPointer(parsedSpec, ref)
+			if err != nil {
+				return err
+			}
+			documented, _ = resolved.(map[string]interface{})
+		}
+
+		schema, ok := openAPIResponseSchema(documented, response.Header.Get("Content-Type"))
+		if !ok {
+			// The response has no body, or the body isn't described by a schema
+			return nil
+		}
+		return validateJSONBodySchema(response, openAPIToJSONSchema(parsedSpec, schema))
+	}
+}
+
+// ValidateResponse checks the given response with the given validators. If any of them fail, fail the test.
+func ValidateResponse(t testing.TestingT, response *HttpResponse, validators ...ResponseValidator) {
+	err := ValidateResponseE(t, response, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ValidateResponseE checks the given response with the given validators. If any of them fail, return a
+// ResponseValidationFailed error with the errors of all the failed validators.
+func ValidateResponseE(t testing.TestingT, response *HttpResponse, validators ...ResponseValidator) error {
+	var failures []error
+	for _, validator := range validators {
+		if err := validator(response); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	failed := ResponseValidationFailed{Url: response.URL, Status: response.StatusCode, Failures: failures}
+	for _, failure := range failures {
+		if retry.IsFatalError(failure) {
+			return retry.FatalError{Underlying: failed}
+		}
+	}
+	return failed
+}
+
+// HttpGetWithValidators performs an HTTP GET on the given URL with the given options and checks the response with the
+// given validators. If any of them fail, fail the test.
+func HttpGetWithValidators(t testing.TestingT, url string, options *RequestOptions, validators ...ResponseValidator) {
+	err := HttpGetWithValidatorsE(t, url, options, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithValidatorsE performs an HTTP GET on the given URL with the given options and checks the response with the
+// given validators. If any of them fail, return a ResponseValidationFailed error.
+func HttpGetWithValidatorsE(t testing.TestingT, url string, options *RequestOptions, validators ...ResponseValidator) error {
+	response, err := HttpGetResponseWithOptionsE(t, url, options)
+	if err != nil {
+		return err
+	}
+	return ValidateResponseE(t, response, validators...)
+}
+
+// HttpGetWithRetryWithValidators repeatedly performs an HTTP GET on the given URL with the given options until all
+// the given validators pass or max retries has been exceeded.
+func HttpGetWithRetryWithValidators(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration, validators ...ResponseValidator) {
+	err := HttpGetWithRetryWithValidatorsE(t, url, options, retries, sleepBetweenRetries, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HttpGetWithRetryWithValidatorsE repeatedly performs an HTTP GET on the given URL with the given options until all
+// the given validators pass or max retries has been exceeded.
+func HttpGetWithRetryWithValidatorsE(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration, validators ...ResponseValidator) error {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP GET to URL %s", url), retries, sleepBetweenRetries, func() (string, error) {
+		return "", HttpGetWithValidatorsE(t, url, options, validators...)
+	})
+
+	return err
+}
+
+// HTTPDoWithValidators performs the given HTTP method on the given URL with the given options and checks the response
+// with the given validators. If any of them fail, fail the test.
+func HTTPDoWithValidators(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, options *RequestOptions, validators ...ResponseValidator) {
+	err := HTTPDoWithValidatorsE(t, method, url, body, headers, options, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithValidatorsE performs the given HTTP method on the given URL with the given options and checks the response
+// with the given validators. If any of them fail, return a ResponseValidationFailed error.
+func HTTPDoWithValidatorsE(t testing.TestingT, method string, url string, body io.Reader, headers map[string]string, options *RequestOptions, validators ...ResponseValidator) error {
+	response, err := HTTPDoResponseWithOptionsE(t, method, url, body, headers, options)
+	if err != nil {
+		return err
+	}
+	return ValidateResponseE(t, response, validators...)
+}
+
+// HTTPDoWithRetryWithValidators repeatedly performs the given HTTP method on the given URL with the given options
+// until all the given validators pass or max retries has been exceeded.
+func HTTPDoWithRetryWithValidators(
+	t testing.TestingT, method string, url string, body []byte, headers map[string]string, options *RequestOptions,
+	retries int, sleepBetweenRetries time.Duration, validators ...ResponseValidator,
+) {
+	err := HTTPDoWithRetryWithValidatorsE(t, method, url, body, headers, options, retries, sleepBetweenRetries, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// HTTPDoWithRetryWithValidatorsE repeatedly performs the given HTTP method on the given URL with the given options
+// until all the given validators pass or max retries has been exceeded.
+func HTTPDoWithRetryWithValidatorsE(
+	t testing.TestingT, method string, url string, body []byte, headers map[string]string, options *RequestOptions,
+	retries int, sleepBetweenRetries time.Duration, validators ...ResponseValidator,
+) error {
+	_, err := retry.DoWithRetryE(t, fmt.Sprintf("HTTP %s to URL %s", method, url), retries,
+		sleepBetweenRetries, func() (string, error) {
+			bodyReader := bytes.NewReader(body)
+			return "", HTTPDoWithValidatorsE(t, method, url, bodyReader, headers, options, validators...)
+		})
+
+	return err
+}
+
+// parseJSONBody parses the response body as JSON into maps, slices and other values as returned by json.Unmarshal
+// into an interface{}.
+func parseJSONBody(response *HttpResponse) (interface{}, error) {
+	var body interface{}
+	if err := json.Unmarshal(response.Body, &body); err != nil {
+		return nil, InvalidJSONBodyError{Body: response.BodyString(), Underlying: err}
+	}
+	return body, nil
+}
+
+// parseYAMLOrJSON parses the given YAML or JSON document into maps, slices and other values as returned by
+// json.Unmarshal.
+func parseYAMLOrJSON(document []byte) (interface{}, error) {
+	jsonDocument, err := yaml.YAMLToJSON(document)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(jsonDocument, &out)
+	return out, err
+}
+
+// jsonPathExpression is a parsed JSONPath expression.
+type jsonPathExpression struct {
+	expression string
+	list       bool
+
+	// mutex guards path, which keeps state while it's evaluated, as the validator may be shared between tests
+	mutex sync.Mutex
+	path  *jsonpath.JSONPath
+}
+
+// parseJSONPath parses the given JSONPath expression, which may omit the braces of the kubectl syntax.
+func parseJSONPath(expression string) (*jsonPathExpression, error) {
+	template := expression
+	if !strings.HasPrefix(template, "{") {
+		template = "{" + template + "}"
+	}
+
+	parsed, err := jsonpath.Parse("ValidateJSONPath", template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSONPath expression %q: %s", expression, err)
+	}
+	// JSONPath doesn't expose its parse tree, which is needed to know whether the expression selects a list, so the
+	// template is parsed by both, but only here, when the validator is created
+	path := jsonpath.New("ValidateJSONPath")
+	if err := path.Parse(template); err != nil {
+		return nil, fmt.Errorf("invalid JSONPath expression %q: %s", expression, err)
+	}
+	return &jsonPathExpression{expression: expression, path: path, list: selectsList(parsed.Root)}, nil
+}
+
+// evaluate returns the value selected by the expression in the given document, or a list of the selected values if
+// the expression can select several values.
+func (e *jsonPathExpression) evaluate(document interface{}) (interface{}, error) {
+	e.mutex.Lock()
+	results, err := e.path.FindResults(document)
+	e.mutex.Unlock()
+	if err != nil {
+		return nil, JSONPathNotFoundError{Expression: e.expression, Underlying: err}
+	}
+
+	values := []interface{}{}
+	for _, result := range results {
+		for _, value := range result {
+			values = append(values, value.Interface())
+		}
+	}
+	if len(values) == 1 && !e.list {
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// selectsList returns true if the given parsed JSONPath expression can select several values, because it has several
+// actions or contains a wildcard, filter, union, slice, recursive descent or range, in which case its result is a list
+// even when it selects a single value.
+func selectsList(root *jsonpath.ListNode) bool {
+	actions := 0
+	for _, node := range root.Nodes {
+		if _, isText := node.(*jsonpath.TextNode); !isText {
+			actions++
+		}
+	}
+	return actions > 1 || containsMultipleSelector(root)
+}
+
+func containsMultipleSelector(node jsonpath.Node) bool {
+	switch node := node.(type) {
+	case *jsonpath.ListNode:
+		for _, child := range node.Nodes {
+			if containsMultipleSelector(child) {
+				return true
+			}
+		}
+		return false
+	case *jsonpath.ArrayNode:
+		// A single index, such as [0], has a derived end
+		return !node.Params[1].Derived
+	case *jsonpath.WildcardNode, *jsonpath.FilterNode, *jsonpath.UnionNode, *jsonpath.RecursiveNode, *jsonpath.IdentifierNode:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareJSONValues returns a JSONValueMismatchError with a diff if the expected value, converted to JSON, is not the
+// same as the actual value, which was selected by the given expression in the given language.
+func compareJSONValues(language string, expression string, expected interface{}, actual interface{}) error {
+	normalizedExpected, err := normalizeJSON(expected)
+	if err != nil {
+		return err
+	}
+	normalizedActual, err := normalizeJSON(actual)
+	if err != nil {
+		return err
+	}
+	if diff := jsonDiff("$", normalizedExpected, normalizedActual); len(diff) > 0 {
+		return JSONValueMismatchError{Language: language, Expression: expression, Diff: strings.Join(diff, "\n")}
+	}
+	return nil
+}
+
+// normalizeJSON converts the given value to the values json.Unmarshal returns for its JSON, e.g. float64 for numbers
+// and maps for structs, so that values can be compared with reflect.DeepEqual.
+func normalizeJSON(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(encoded, &out)
+	return out, err
+}
+
+// jsonDiff returns the differences between the expected and the actual JSON value at the given path, in the format of
+// collections.Diff: a line for each missing value starting with "-", each extra value starting with "+" and each
+// changed value starting with "~".
+func jsonDiff(path string, expected interface{}, actual interface{}) []string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		if actual, ok := actual.(map[string]interface{}); ok {
+			var diff []string
+			for _, key := range sortedJSONKeys(expected) {
+				if actualValue, ok := actual[key]; ok {
+					diff = append(diff, jsonDiff(jsonPathChild(path, key), expected[key], actualValue)...)
+				} else {
+					diff = append(diff, fmt.Sprintf("- %s: %s", jsonPathChild(path, key), formatJSON(expected[key])))
+				}
+			}
+			for _, key := range sortedJSONKeys(actual) {
+				if _, ok := expected[key]; !ok {
+					diff = append(diff, fmt.Sprintf("+ %s: %s", jsonPathChild(path, key), formatJSON(actual[key])))
+				}
+			}
+			return diff
+		}
+	case []interface{}:
+		if actual, ok := actual.([]interface{}); ok {
+			var diff []string
+			for i := 0; i < len(expected) || i < len(actual); i++ {
+				itemPath := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(actual):
+					diff = append(diff, fmt.Sprintf("- %s: %s", itemPath, formatJSON(expected[i])))
+				case i >= len(expected):
+					diff = append(diff, fmt.Sprintf("+ %s: %s", itemPath, formatJSON(actual[i])))
+				default:
+					diff = append(diff, jsonDiff(itemPath, expected[i], actual[i])...)
+				}
+			}
+			return diff
+		}
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		return []string{fmt.Sprintf("~ %s: expected %s, actual %s", path, formatJSON(expected), formatJSON(actual))}
+	}
+	return nil
+}
+
+// validateJSONBodySchema parses the response body as JSON and validates it against the given JSON Schema.
+func validateJSONBodySchema(response *HttpResponse, schema interface{}) error {
+	body, err := parseJSONBody(response)
+	if err != nil {
+		return err
+	}
+	errs, err := validateJSONSchema(schema, body)
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return JSONSchemaValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// findOpenAPIOperation returns the operation with the given method and path in the given OpenAPI spec.
+func findOpenAPIOperation(spec interface{}, method string, path string) (map[string]interface{}, bool) {
+	root, _ := spec.(map[string]interface{})
+	paths, _ := root["paths"].(map[string]interface{})
+	pathItem, _ := paths[path].(map[string]interface{})
+	operation, ok := pathItem[strings.ToLower(method)].(map[string]interface{})
+	return operation, ok
+}
+
+// findOpenAPIResponse returns the response documented for the given status code in the given responses of an OpenAPI
+// operation, trying the exact code, the range (e.g. "2XX") and then the default response.
+func findOpenAPIResponse(responses map[string]interface{}, statusCode int) (map[string]interface{}, bool) {
+	code := strconv.Itoa(statusCode)
+	for _, key := range []string{code, code[:1] + "XX", code[:1] + "xx", "default"} {
+		if response, ok := responses[key].(map[string]interface{}); ok {
+			return response, true
+		}
+	}
+	return nil, false
+}
+
+// openAPIResponseSchema returns the schema of the body of the given OpenAPI response for the given content type. For
+// OpenAPI 3, this is the schema of the media type that matches the content type, or of the first JSON media type. For
+// Swagger 2, it's the schema of the response.
+func openAPIResponseSchema(response map[string]interface{}, contentType string) (interface{}, bool) {
+	if schema, ok := response["schema"]; ok {
+		return schema, true
+	}
+
+	content, ok := response["content"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if media, ok := content[mediaType].(map[string]interface{}); ok {
+			schema, ok := media["schema"]
+			return schema, ok
+		}
+	}
+	for _, mediaType := range sortedJSONKeys(content) {
+		if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+			if media, ok := content[mediaType].(map[string]interface{}); ok {
+				schema, ok := media["schema"]
+				return schema, ok
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,264 @@
+package http_helper
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInstancesBody = `{
+  "region": "us-east-1",
+  "instances": [
+    {"id": "i-1", "state": "running", "tags": {"Name": "web"}},
+    {"id": "i-2", "state": "stopped", "tags": {"Name": "worker"}}
+  ]
+}`
+
+func jsonResponse(body string) *HttpResponse {
+	return &HttpResponse{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       []byte(body),
+		URL:        "http://example.com/instances",
+	}
+}
+
+func TestValidateJSONPath(t *testing.T) {
+	t.Parallel()
+
+	type tags struct {
+		Name string `json:"Name"`
+	}
+
+	testCases := []struct {
+		expression string
+		expected   interface{}
+		diff       string
+	}{
+		{"$.region", "us-east-1", ""},
+		{"{.instances[0].id}", "i-1", ""},
+		{".instances[*].id", []string{"i-1", "i-2"}, ""},
+		{"$.instances[1].tags", tags{Name: "worker"}, ""},
+		{"$.instances[0].state", "stopped", `~ $: expected "stopped", actual "running"`},
+		{"$.instances[0]", map[string]interface{}{"id": "i-1", "state": "running", "zone": "a"}, "- $.zone: \"a\"\n+ $.tags: {\"Name\":\"web\"}"},
+		{"$.instances[*].id", []string{"i-1"}, `+ $[1]: "i-2"`},
+		{`$.instances[?(@.state=="running")].id`, []string{"i-1"}, ""},
+		{`$.instances[?(@.state=="running")].id`, "i-1", `~ $: expected "i-1", actual ["i-1"]`},
+		{"$.instances[0:1].id", []string{"i-1"}, ""},
+		{"$.instances[0,1].tags.Name", []string{"web", "worker"}, ""},
+		{"$.instances[*].tags.*", []string{"web", "worker"}, ""},
+		{"$..region", []string{"us-east-1"}, ""},
+		{"{.region}{.instances[1].id}", []string{"us-east-1", "i-2"}, ""},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.expression, func(t *testing.T) {
+			t.Parallel()
+			err := ValidateJSONPath(testCase.expression, testCase.expected)(jsonResponse(testInstancesBody))
+			if testCase.diff == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, JSONValueMismatchError{Language: "JSONPath", Expression: testCase.expression, Diff: testCase.diff}, err)
+			}
+		})
+	}
+}
+
+func TestValidateJSONPathErrors(t *testing.T) {
+	t.Parallel()
+
+	err := ValidateJSONPath("$.missing", "value")(jsonResponse(testInstancesBody))
+	require.Error(t, err)
+	assert.IsType(t, JSONPathNotFoundError{}, err)
+
+	err = ValidateJSONPath("$.region", "us-east-1")(jsonResponse("<html></html>"))
+	require.Error(t, err)
+	assert.IsType(t, InvalidJSONBodyError{}, err)
+
+	err = ValidateJSONPath("$.instances[", "value")(jsonResponse(testInstancesBody))
+	require.Error(t, err)
+	assert.True(t, retry.IsFatalError(err))
+}
+
+func TestValidateJMESPath(t *testing.T) {
+	t.Parallel()
+
+	response := jsonResponse(testInstancesBody)
+	assert.NoError(t, ValidateJMESPath("instances[?state=='running'].id", []string{"i-1"})(response))
+	assert.NoError(t, ValidateJMESPath("length(instances)", 2)(response))
+
+	err := ValidateJMESPath("length(instances)", 3)(response)
+	assert.Equal(t, JSONValueMismatchError{Language: "JMESPath", Expression: "length(instances)", Diff: "~ $: expected 3, actual 2"}, err)
+}
+
+func TestValidateBodyRegex(t *testing.T) {
+	t.Parallel()
+
+	response := jsonResponse(testInstancesBody)
+	assert.NoError(t, ValidateBodyRegex(`"region": "us-[a-z]+-\d"`)(response))
+	assert.IsType(t, BodyRegexMismatchError{}, ValidateBodyRegex(`eu-west-1`)(response))
+	assert.True(t, retry.IsFatalError(ValidateBodyRegex(`(`)(response)))
+}
+
+const testInstancesSchema = `
+type: object
+required: [region, instances]
+properties:
+  region:
+    type: string
+    pattern: "^[a-z]+-[a-z]+-[0-9]$"
+  instances:
+    type: array
+    items:
+      $ref: "#/definitions/instance"
+definitions:
+  instance:
+    type: object
+    required: [id, state]
+    additionalProperties: false
+    properties:
+      id: {type: string}
+      state: {enum: [running, stopped]}
+      tags:
+        type: object
+        additionalProperties: {type: string}
+`
+
+func TestValidateJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	assert.NoError(t, ValidateJSONSchema(testInstancesSchema)(jsonResponse(testInstancesBody)))
+
+	err := ValidateJSONSchema(testInstancesSchema)(jsonResponse(`{"region": "mars", "instances": [{"id": 1, "size": "xl"}, {"id": "i-3", "state": "gone"}]}`))
+	assert.Equal(t, JSONSchemaValidationError{Errors: []string{
+		`$.instances[0].id: Invalid type. Expected: string, given: integer`,
+		`$.instances[0]: Additional property size is not allowed`,
+		`$.instances[0]: state is required`,
+		`$.instances[1].state: instances.1.state must be one of the following: "running", "stopped"`,
+		`$.region: Does not match pattern '^[a-z]+-[a-z]+-[0-9]$'`,
+	}}, err)
+}
+
+const testOpenAPISpec = `
+openapi: 3.0.0
+info: {title: Instances, version: "1.0"}
+paths:
+  /instances/{id}:
+    get:
+      responses:
+        "200":
+          description: The instance
+          content:
+            application/json:
+              schema: {$ref: "#/components/schemas/Instance"}
+        "404":
+          $ref: "#/components/responses/NotFound"
+components:
+  schemas:
+    Instance:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+        zone: {type: string, nullable: true}
+        created: {type: string, format: date-time}
+  responses:
+    NotFound:
+      description: Not found
+      content:
+        application/json:
+          schema:
+            type: object
+            required: [message]
+`
+
+func TestValidateOpenAPIResponse(t *testing.T) {
+	t.Parallel()
+
+	validator := ValidateOpenAPIResponse([]byte(testOpenAPISpec), "GET", "/instances/{id}")
+
+	assert.NoError(t, validator(jsonResponse(`{"id": "i-1", "zone": null}`)))
+
+	err := validator(jsonResponse(`{"zone": "a"}`))
+	assert.Equal(t, JSONSchemaValidationError{Errors: []string{`$: id is required`}}, err)
+
+	err = validator(jsonResponse(`{"id": "i-1", "created": "yesterday"}`))
+	assert.Equal(t, JSONSchemaValidationError{Errors: []string{`$.created: Does not match format 'date-time'`}}, err)
+
+	notFound := jsonResponse(`{"message": "no such instance"}`)
+	notFound.StatusCode = 404
+	assert.NoError(t, validator(notFound))
+
+	serverError := jsonResponse(`{}`)
+	serverError.StatusCode = 500
+	assert.Equal(t, UndocumentedStatusCodeError{Method: "GET", Path: "/instances/{id}", StatusCode: 500}, validator(serverError))
+
+	err = ValidateOpenAPIResponse([]byte(testOpenAPISpec), "DELETE", "/instances/{id}")(jsonResponse(`{}`))
+	assert.Equal(t, OpenAPIOperationNotFoundError{Method: "DELETE", Path: "/instances/{id}"}, err)
+}
+
+func TestValidateResponse(t *testing.T) {
+	t.Parallel()
+
+	response := jsonResponse(testInstancesBody)
+	ValidateResponse(t, response, ValidateStatusCode(200), ValidateJSONBody(), ValidateJSONPath("$.region", "us-east-1"))
+
+	err := ValidateResponseE(t, response, ValidateStatusCode(201), ValidateJSONBody(), ValidateJSONPath("$.region", "eu-west-1"))
+	require.Error(t, err)
+	assert.Equal(t, `Validation failed for URL http://example.com/instances. Response status: 200. Failed validations:
+- Expected status code 201, but got 200
+- Value of JSONPath "$.region" differs from the expected value (-expected +actual):
+  ~ $: expected "eu-west-1", actual "us-east-1"`, err.Error())
+}
+
+func TestHttpGetWithRetryWithValidators(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.Write([]byte(`{"status": "starting"}`))
+			return
+		}
+		w.Write([]byte(`{"status": "ready"}`))
+	})
+	defer ts.Close()
+
+	HttpGetWithRetryWithValidators(t, ts.URL, nil, 10, 10*time.Millisecond, ValidateStatusCode(200), ValidateJSONPath("$.status", "ready"))
+	assert.Equal(t, 3, requests)
+}
+
+func TestHttpGetWithRetryWithValidatorsInvalidExpression(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	ts := getTestServerForFunction(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"status": "ready"}`))
+	})
+	defer ts.Close()
+
+	err := HttpGetWithRetryWithValidatorsE(t, ts.URL, nil, 10, 10*time.Millisecond, ValidateBodyRegex(`(`))
+	require.Error(t, err)
+	assert.True(t, retry.IsFatalError(err))
+	var failed ResponseValidationFailed
+	assert.True(t, errors.As(err, &failed))
+	assert.Equal(t, 1, requests)
+}
+
+func TestHTTPDoWithValidators(t *testing.T) {
+	t.Parallel()
+
+	ts := getTestServerForFunction(bodyCopyHandler)
+	defer ts.Close()
+
+	HTTPDoWithValidators(t, "POST", ts.URL, nil, nil, nil, ValidateStatusCode(200), ValidateBodyRegex(`^$`))
+	HTTPDoWithRetryWithValidators(t, "POST", ts.URL, []byte(`{"name": "terratest"}`), nil, nil, 3, time.Second, ValidateJMESPath("name", "terratest"))
+}
@@ -0,0 +1,344 @@
+package http_helper
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocketMessageType is the type of the data frames of a WebSocket message.
+type WebSocketMessageType string
+
+const (
+	// WebSocketTextMessage is a message with UTF-8 text.
+	WebSocketTextMessage WebSocketMessageType = "text"
+	// WebSocketBinaryMessage is a message with binary data.
+	WebSocketBinaryMessage WebSocketMessageType = "binary"
+)
+
+// WebSocketMessage is a message sent or received over a WebSocket.
+type WebSocketMessage struct {
+	Type WebSocketMessageType
+	Data []byte
+}
+
+// Text returns the data of the message as a string.
+func (message WebSocketMessage) Text() string {
+	return string(message.Data)
+}
+
+// WebSocket is a client connection to a WebSocket server, opened with OpenWebSocket. Close it when done.
+type WebSocket struct {
+	URL string
+
+	conn      *websocket.Conn
+	closeOnce sync.Once
+}
+
+// messageCodec sends and receives WebSocketMessages, keeping track of whether they are text or binary.
+var messageCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		message := v.(WebSocketMessage)
+		if message.Type == WebSocketBinaryMessage {
+			return message.Data, websocket.BinaryFrame, nil
+		}
+		return message.Data, websocket.TextFrame, nil
+	},
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		message := v.(*WebSocketMessage)
+		message.Data = data
+		message.Type = WebSocketTextMessage
+		if payloadType == websocket.BinaryFrame {
+			message.Type = WebSocketBinaryMessage
+		}
+		return nil
+	},
+}
+
+// OpenWebSocket opens a WebSocket connection to the given ws:// or wss:// URL with the given options. The TLS,
+// authentication, timeout, Resolve and UnixSocket options apply to the opening handshake; proxies aren't supported.
+// This will fail the test if the connection can't be opened.
+func OpenWebSocket(t testing.TestingT, url string, options *RequestOptions) *WebSocket {
+	ws, err := OpenWebSocketE(t, url, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+// OpenWebSocketE opens a WebSocket connection to the given ws:// or wss:// URL with the given options. The TLS,
+// authentication, timeout, Resolve and UnixSocket options apply to the opening handshake; proxies aren't supported.
+func OpenWebSocketE(t testing.TestingT, rawURL string, options *RequestOptions) (*WebSocket, error) {
+	logger.Logf(t, "Opening a WebSocket to URL %s", rawURL)
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options != nil && options.ProxyURL != "" {
+		return nil, UnsupportedRequestOptionError{Option: "ProxyURL", Usage: "WebSocket connections"}
+	}
+
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	var origin string
+	port := location.Port()
+	switch location.Scheme {
+	case "ws":
+		origin = "http://" + location.Host
+		if port == "" {
+			port = "80"
+		}
+	case "wss":
+		origin = "https://" + location.Host
+		if port == "" {
+			port = "443"
+		}
+	default:
+		return nil, InvalidWebSocketURLError{Url: rawURL}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), options.timeout())
+	defer cancel()
+
+	conn, err := dialWebSocketE(ctx, net.JoinHostPort(location.Hostname(), port), options)
+	if err != nil {
+		return nil, err
+	}
+	if location.Scheme == "wss" {
+		tlsConfig := options.tlsConfig()
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		if tlsConfig.ServerName == "" {
+			tlsConfig.ServerName = location.Hostname()
+		}
+		// The WebSocket handshake is an HTTP/1.1 upgrade
+		tlsConfig.NextProtos = []string{"http/1.1"}
+
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	config, err := websocket.NewConfig(rawURL, origin)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	config.Header = http.Header{}
+	options.authorize(&http.Request{Header: config.Header})
+
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	wsConn, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &WebSocket{URL: rawURL, conn: wsConn}, nil
+}
+
+// OpenWebSocketWithRetry repeatedly tries to open a WebSocket connection to the given URL with the given options until
+// it succeeds or max retries has been exceeded. This will fail the test if the connection can't be opened.
+func OpenWebSocketWithRetry(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration) *WebSocket {
+	ws, err := OpenWebSocketWithRetryE(t, url, options, retries, sleepBetweenRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ws
+}
+
+// OpenWebSocketWithRetryE repeatedly tries to open a WebSocket connection to the given URL with the given options
+// until it succeeds or max retries has been exceeded.
+func OpenWebSocketWithRetryE(t testing.TestingT, url string, options *RequestOptions, retries int, sleepBetweenRetries time.Duration) (*WebSocket, error) {
+	policy := retry.Policy{Description: fmt.Sprintf("Opening a WebSocket to URL %s", url), MaxRetries: retries, SleepBetweenRetries: sleepBetweenRetries}
+	return retry.DoE(t, policy, func() (*WebSocket, error) {
+		return OpenWebSocketE(t, url, options)
+	})
+}
+
+// WebSocketSendAndWaitWithRetry opens a WebSocket connection to the given URL, sends the given text message, unless it
+// is empty, and waits for a message that passes all the given validators, as in WaitForMessage. The whole exchange is
+// retried until it succeeds or max retries has been exceeded, and the matching message is returned. The time to wait
+// for a message is the timeout of the options. This will fail the test if no attempt succeeds.
+func WebSocketSendAndWaitWithRetry(t testing.TestingT, url string, options *RequestOptions, message string, retries int, sleepBetweenRetries time.Duration, validators ...ResponseValidator) WebSocketMessage {
+	received, err := WebSocketSendAndWaitWithRetryE(t, url, options, message, retries, sleepBetweenRetries, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return received
+}
+
+// WebSocketSendAndWaitWithRetryE opens a WebSocket connection to the given URL, sends the given text message, unless
+// it is empty, and waits for a message that passes all the given validators, as in WaitForMessageE. The whole exchange
+// is retried until it succeeds or max retries has been exceeded, and the matching message is returned. The time to
+// wait for a message is the timeout of the options.
+func WebSocketSendAndWaitWithRetryE(t testing.TestingT, url string, options *RequestOptions, message string, retries int, sleepBetweenRetries time.Duration, validators ...ResponseValidator) (WebSocketMessage, error) {
+	policy := retry.Policy{Description: fmt.Sprintf("WebSocket exchange with URL %s", url), MaxRetries: retries, SleepBetweenRetries: sleepBetweenRetries}
+	return retry.DoE(t, policy, func() (WebSocketMessage, error) {
+		ws, err := OpenWebSocketE(t, url, options)
+		if err != nil {
+			return WebSocketMessage{}, err
+		}
+		defer ws.Close()
+
+		if message != "" {
+			if err := ws.SendTextE(t, message); err != nil {
+				return WebSocketMessage{}, err
+			}
+		}
+		return ws.WaitForMessageE(t, options.timeout(), validators...)
+	})
+}
+
+// SendText sends a text message. This will fail the test if the message can't be sent.
+func (ws *WebSocket) SendText(t testing.TestingT, text string) {
+	if err := ws.SendTextE(t, text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SendTextE sends a text message.
+func (ws *WebSocket) SendTextE(t testing.TestingT, text string) error {
+	return messageCodec.Send(ws.conn, WebSocketMessage{Type: WebSocketTextMessage, Data: []byte(text)})
+}
+
+// SendBinary sends a binary message. This will fail the test if the message can't be sent.
+func (ws *WebSocket) SendBinary(t testing.TestingT, data []byte) {
+	if err := ws.SendBinaryE(t, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SendBinaryE sends a binary message.
+func (ws *WebSocket) SendBinaryE(t testing.TestingT, data []byte) error {
+	return messageCodec.Send(ws.conn, WebSocketMessage{Type: WebSocketBinaryMessage, Data: data})
+}
+
+// SendJSON sends the given value encoded as JSON in a text message. This will fail the test if the value can't be
+// encoded or the message can't be sent.
+func (ws *WebSocket) SendJSON(t testing.TestingT, value interface{}) {
+	if err := ws.SendJSONE(t, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// SendJSONE sends the given value encoded as JSON in a text message.
+func (ws *WebSocket) SendJSONE(t testing.TestingT, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return messageCodec.Send(ws.conn, WebSocketMessage{Type: WebSocketTextMessage, Data: data})
+}
+
+// Receive waits up to the given timeout for the next message and returns it. This will fail the test if no message is
+// received in time or the connection is closed.
+func (ws *WebSocket) Receive(t testing.TestingT, timeout time.Duration) WebSocketMessage {
+	message, err := ws.ReceiveE(t, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// ReceiveE waits up to the given timeout for the next message and returns it. Returns an error if no message is
+// received in time or the connection is closed.
+func (ws *WebSocket) ReceiveE(t testing.TestingT, timeout time.Duration) (WebSocketMessage, error) {
+	return ws.receiveBefore(time.Now().Add(timeout))
+}
+
+// WaitForMessage reads messages until one passes all the given validators, and returns it. Each message is validated
+// as the body of an HttpResponse, so validators of the body, like ValidateJSONPath or ValidateBodyRegex, can be used.
+// Messages that don't pass are skipped. This will fail the test if no message passes within the given timeout.
+func (ws *WebSocket) WaitForMessage(t testing.TestingT, timeout time.Duration, validators ...ResponseValidator) WebSocketMessage {
+	message, err := ws.WaitForMessageE(t, timeout, validators...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+// WaitForMessageE reads messages until one passes all the given validators, and returns it. Each message is validated
+// as the body of an HttpResponse, so validators of the body, like ValidateJSONPath or ValidateBodyRegex, can be used.
+// Messages that don't pass are skipped. Returns a WebSocketMessageNotReceivedError if no message passes within the
+// given timeout.
+func (ws *WebSocket) WaitForMessageE(t testing.TestingT, timeout time.Duration, validators ...ResponseValidator) (WebSocketMessage, error) {
+	deadline := time.Now().Add(timeout)
+	notReceived := WebSocketMessageNotReceivedError{Url: ws.URL, Timeout: timeout}
+
+	for {
+		message, err := ws.receiveBefore(deadline)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return WebSocketMessage{}, notReceived
+			}
+			return WebSocketMessage{}, err
+		}
+
+		response := &HttpResponse{StatusCode: http.StatusSwitchingProtocols, URL: ws.URL, Body: message.Data}
+		var failures []error
+		for _, validator := range validators {
+			if err := validator(response); err != nil {
+				failures = append(failures, err)
+			}
+		}
+		if len(failures) == 0 {
+			return message, nil
+		}
+
+		notReceived.Received = append(notReceived.Received, message)
+		notReceived.LastFailures = failures
+	}
+}
+
+// Close closes the connection. It's safe to call Close more than once.
+func (ws *WebSocket) Close() {
+	ws.closeOnce.Do(func() {
+		ws.conn.Close()
+	})
+}
+
+// receiveBefore reads the next message, failing if it doesn't arrive before the given deadline.
+func (ws *WebSocket) receiveBefore(deadline time.Time) (WebSocketMessage, error) {
+	if err := ws.conn.SetReadDeadline(deadline); err != nil {
+		return WebSocketMessage{}, err
+	}
+	var message WebSocketMessage
+	err := messageCodec.Receive(ws.conn, &message)
+	return message, err
+}
+
+// dialWebSocketE opens the connection for a WebSocket to the given "host:port" address, honoring the Resolve and
+// UnixSocket options.
+func dialWebSocketE(ctx context.Context, address string, options *RequestOptions) (net.Conn, error) {
+	dialer := &net.Dialer{}
+	switch {
+	case options == nil:
+		return dialer.DialContext(ctx, "tcp", address)
+	case options.UnixSocket != "":
+		return dialer.DialContext(ctx, "unix", options.UnixSocket)
+	default:
+		return dialer.DialContext(ctx, "tcp", resolveAddress(options.Resolve, address))
+	}
+}
@@ -0,0 +1,130 @@
+package http_helper
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	t.Parallel()
+
+	server := startWebSocketServer(t, nil)
+	ws := OpenWebSocket(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	defer ws.Close()
+
+	assert.Equal(t, WebSocketMessage{Type: WebSocketTextMessage, Data: []byte(`{"event": "welcome", "authorization": ""}`)}, ws.Receive(t, time.Second))
+
+	ws.SendText(t, "hello")
+	assert.Equal(t, "echo: hello", ws.Receive(t, time.Second).Text())
+
+	ws.SendBinary(t, []byte{0, 1, 2})
+	assert.Equal(t, WebSocketMessage{Type: WebSocketBinaryMessage, Data: []byte{0, 1, 2}}, ws.Receive(t, time.Second))
+
+	ws.SendJSON(t, map[string]int{"count": 3})
+	assert.Equal(t, `echo: {"count":3}`, ws.Receive(t, time.Second).Text())
+
+	ws.SendText(t, "ticks")
+	message := ws.WaitForMessage(t, time.Second, ValidateJSONPath("$.tick", 3))
+	assert.Equal(t, `{"tick": 3}`, message.Text())
+
+	ws.SendText(t, "ticks")
+	_, err := ws.WaitForMessageE(t, 200*time.Millisecond, ValidateJSONPath("$.tick", 4))
+	require.Error(t, err)
+	notReceived, ok := err.(WebSocketMessageNotReceivedError)
+	require.True(t, ok)
+	assert.Len(t, notReceived.Received, 3)
+	assert.Len(t, notReceived.LastFailures, 1)
+	assert.Contains(t, err.Error(), "Received 3 messages")
+
+	_, err = ws.ReceiveE(t, 50*time.Millisecond)
+	assert.Error(t, err)
+
+	ws.Close()
+	ws.Close()
+	assert.Error(t, ws.SendTextE(t, "closed"))
+}
+
+func TestWebSocketTLSAndAuth(t *testing.T) {
+	t.Parallel()
+
+	server := startWebSocketServer(t, &MockServerOptions{TLS: true})
+	url := "wss" + strings.TrimPrefix(server.URL, "https") + "/ws"
+
+	ws := OpenWebSocket(t, url, &RequestOptions{TLSConfig: server.TLSConfig(), BearerToken: "secret"})
+	defer ws.Close()
+	ws.WaitForMessage(t, time.Second, ValidateJSONPath("$.authorization", "Bearer secret"))
+
+	// Without the CA, the certificate isn't trusted
+	_, err := OpenWebSocketE(t, url, nil)
+	assert.Error(t, err)
+}
+
+func TestWebSocketSendAndWaitWithRetry(t *testing.T) {
+	t.Parallel()
+
+	server := startWebSocketServer(t, nil)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	message := WebSocketSendAndWaitWithRetry(t, url, nil, "ping", 2, 10*time.Millisecond, ValidateBodyRegex("^echo: ping$"))
+	assert.Equal(t, "echo: ping", message.Text())
+
+	_, err := WebSocketSendAndWaitWithRetryE(t, url, &RequestOptions{Timeout: 100 * time.Millisecond}, "ping", 1, 10*time.Millisecond, ValidateBodyRegex("pong"))
+	assert.Error(t, err)
+}
+
+func TestOpenWebSocketErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := OpenWebSocketE(t, "http://127.0.0.1/ws", nil)
+	assert.Equal(t, InvalidWebSocketURLError{Url: "http://127.0.0.1/ws"}, err)
+
+	_, err = OpenWebSocketE(t, "ws://127.0.0.1/ws", &RequestOptions{ProxyURL: "http://127.0.0.1:3128"})
+	assert.IsType(t, UnsupportedRequestOptionError{}, err)
+
+	// The server isn't a WebSocket server
+	server := NewMockServer(t, nil)
+	_, err = OpenWebSocketE(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	assert.Error(t, err)
+
+	server.Close()
+	_, err = OpenWebSocketWithRetryE(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil, 1, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+// startWebSocketServer starts a mock server with a WebSocket endpoint at /ws. It sends a welcome message with the
+// Authorization header of the handshake, echoes text messages prefixed with "echo: " and binary messages as they are,
+// and answers "ticks" with three JSON messages.
+func startWebSocketServer(t *testing.T, options *MockServerOptions) *MockServer {
+	server := NewMockServer(t, options)
+	server.HandleFunc("/ws", websocket.Handler(func(conn *websocket.Conn) {
+		welcome := fmt.Sprintf(`{"event": "welcome", "authorization": "%s"}`, conn.Request().Header.Get("Authorization"))
+		if websocket.Message.Send(conn, welcome) != nil {
+			return
+		}
+
+		for {
+			var message WebSocketMessage
+			if messageCodec.Receive(conn, &message) != nil {
+				return
+			}
+
+			switch {
+			case message.Type == WebSocketBinaryMessage:
+				messageCodec.Send(conn, message)
+			case message.Text() == "ticks":
+				for tick := 1; tick <= 3; tick++ {
+					websocket.Message.Send(conn, fmt.Sprintf(`{"tick": %d}`, tick))
+				}
+			default:
+				websocket.Message.Send(conn, "echo: "+message.Text())
+			}
+		}
+	}).ServeHTTP)
+	return server
+}
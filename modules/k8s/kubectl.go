@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
 )
@@ -40,6 +41,7 @@ func RunKubectlAndGetOutputE(t testing.TestingT, options *KubectlOptions, args .
 		Command: "kubectl",
 		Args:    cmdArgs,
 		Env:     options.Env,
+		Logger:  logger.Default.WithFields(logger.Fields{logger.FieldModule: "k8s"}),
 	}
 	return shell.RunCommandAndGetOutputE(t, command)
 }
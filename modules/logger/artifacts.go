@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ArtifactsDirEnvVar is the environment variable used to set the root directory for test artifacts. When it is set,
+// the Default and Terratest loggers also write the log entries of each test to <root>/<test name>.log.
+const ArtifactsDirEnvVar = "TERRATEST_ARTIFACTS_DIR"
+
+// ArtifactsRoot returns the root directory for test artifacts: the value of TERRATEST_ARTIFACTS_DIR if it is set, or
+// a terratest-artifacts folder in the system temp directory otherwise.
+func ArtifactsRoot() string {
+	if dir := os.Getenv(ArtifactsDirEnvVar); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "terratest-artifacts")
+}
+
+// ArtifactsDir returns the directory where the given test can save artifacts, such as command output, plan JSON or
+// kubectl dumps, creating it if necessary. The directory is <root>/<test name>, so subtests get a nested directory
+// inside the directory of their parent test. Fails the test if the directory can't be created.
+func ArtifactsDir(t testing.TestingT) string {
+	dir, err := ArtifactsDirE(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// ArtifactsDirE returns the directory where the given test can save artifacts, such as command output, plan JSON or
+// kubectl dumps, creating it if necessary. The directory is <root>/<test name>, so subtests get a nested directory
+// inside the directory of their parent test.
+func ArtifactsDirE(t testing.TestingT) (string, error) {
+	dir := artifactsPath(ArtifactsRoot(), t.Name())
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// WriteArtifact writes the given data to a file with the given name in the artifacts directory of the test, and
+// returns the path of that file. Fails the test on any error.
+func WriteArtifact(t testing.TestingT, name string, data []byte) string {
+	path, err := WriteArtifactE(t, name, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// WriteArtifactE writes the given data to a file with the given name in the artifacts directory of the test, and
+// returns the path of that file.
+func WriteArtifactE(t testing.TestingT, name string, data []byte) (string, error) {
+	dir, err := ArtifactsDirE(t)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+// unsafePathChars matches characters in test names that are not allowed in file names on some platforms.
+var unsafePathChars = regexp.MustCompile(`[<>:"\\|?*]`)
+
+// artifactsPath returns the path for the given test name under root. Each level of subtest becomes a directory.
+func artifactsPath(root string, testName string) string {
+	parts := strings.Split(testName, "/")
+	for i, part := range parts {
+		part = unsafePathChars.ReplaceAllString(part, "_")
+		if part == "" || part == "." || part == ".." {
+			part = "_"
+		}
+		parts[i] = part
+	}
+	return filepath.Join(append([]string{root}, parts...)...)
+}
+
+type artifactsLogger struct {
+	root  string
+	tee   TestLogger
+	lock  *sync.Mutex
+	files map[string]*os.File
+}
+
+// NewArtifactsLogger returns a StructuredLogger that writes the entries of each test, as they are logged, to
+// <root>/<test name>.log, in the text format and regardless of their level. Each entry is also passed on to tee, if it
+// is not nil, so the output can still be streamed to stdout. The log file of a test is closed when the test completes.
+func NewArtifactsLogger(root string, tee TestLogger) StructuredLogger {
+	return artifactsLogger{
+		root:  root,
+		tee:   tee,
+		lock:  &sync.Mutex{},
+		files: map[string]*os.File{},
+	}
+}
+
+func (logger artifactsLogger) Logf(t testing.TestingT, format string, args ...interface{}) {
+	logger.LogEntry(t, newEntry(t, 1, LevelInfo, fmt.Sprintf(format, args...), nil))
+}
+
+func (logger artifactsLogger) LogEntry(t testing.TestingT, entry Entry) {
+	if err := logger.writeEntry(t, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing log file for test %s: %s\n", entry.TestName, err)
+	}
+
+	if structured, ok := logger.tee.(StructuredLogger); ok {
+		structured.LogEntry(t, entry)
+	} else if logger.tee != nil {
+		logger.tee.Logf(t, "%s", entry.Message)
+	}
+}
+
+// writeEntry appends the entry to the log file of its test, opening the file on first use.
+func (logger artifactsLogger) writeEntry(t testing.TestingT, entry Entry) error {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	file, ok := logger.files[entry.TestName]
+	if !ok {
+		path := artifactsPath(logger.root, entry.TestName) + ".log"
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+
+		var err error
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		logger.files[entry.TestName] = file
+
+		// Entries logged after the test completes, e.g. from background goroutines, reopen the file in append mode.
+		if tt, ok := t.(cleanup); ok {
+			testName := entry.TestName
+			tt.Cleanup(func() { logger.closeFile(testName) })
+		}
+	}
+
+	_, err := file.Write(formatText(entry))
+	return err
+}
+
+// closeFile closes the log file of the given test, if it is open.
+func (logger artifactsLogger) closeFile(testName string) {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+
+	if file, ok := logger.files[testName]; ok {
+		file.Close()
+		delete(logger.files, testName)
+	}
+}
+
+// cleanup is used to close log files when a test completes. testing.T implements this interface, for example.
+type cleanup interface {
+	Cleanup(func())
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactsLoggerWritesFilePerTest(t *testing.T) {
+	t.Parallel()
+
+	root, err := ioutil.TempDir("", "terratest-artifacts")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	var stdout bytes.Buffer
+	l := New(NewArtifactsLogger(root, NewStreamLogger(&stdout, LevelInfo, FormatText)))
+
+	l.Logf(t, "parent output")
+	t.Run("sub test", func(t *testing.T) {
+		l.Debugf(t, "subtest debug output")
+		l.Logf(t, "subtest output")
+	})
+	l.Logf(t, "parent output after subtest")
+
+	parentLog, err := ioutil.ReadFile(filepath.Join(root, t.Name()+".log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(parentLog), "parent output\n")
+	assert.Contains(t, string(parentLog), "parent output after subtest\n")
+	assert.NotContains(t, string(parentLog), "subtest output")
+
+	subtestLog, err := ioutil.ReadFile(filepath.Join(root, t.Name(), "sub_test.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(subtestLog), "subtest debug output\n")
+	assert.Contains(t, string(subtestLog), "subtest output\n")
+
+	// Only the stdout logger filters by level
+	assert.Contains(t, stdout.String(), "subtest output\n")
+	assert.NotContains(t, stdout.String(), "subtest debug output")
+}
+
+func TestArtifactsDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "terratest-artifacts")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	os.Setenv(ArtifactsDirEnvVar, root)
+	defer os.Unsetenv(ArtifactsDirEnvVar)
+
+	t.Run("nested", func(t *testing.T) {
+		dir := ArtifactsDir(t)
+		assert.Equal(t, filepath.Join(root, "TestArtifactsDir", "nested"), dir)
+		assert.DirExists(t, dir)
+
+		path := WriteArtifact(t, "plan/plan.json", []byte("{}"))
+		assert.Equal(t, filepath.Join(dir, "plan", "plan.json"), path)
+		contents, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "{}", string(contents))
+	})
+}
+
+func TestArtifactsPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, filepath.Join("root", "TestFoo", "case_1_"), artifactsPath("root", `TestFoo/case:1?`))
+	assert.Equal(t, filepath.Join("root", "TestFoo", "_"), artifactsPath("root", "TestFoo/.."))
+}
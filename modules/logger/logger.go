@@ -16,7 +16,7 @@ import (
 var (
 	// Default is the default logger that is used for the Logf function, if no one is provided. It uses the
 	// TerratestLogger to log messages. This can be overwritten to change the logging globally.
-	Default = New(terratestLogger{})
+	Default = New(newLoggerFromEnv())
 	// Discard discards all logging.
 	Discard = New(discardLogger{})
 	// Terratest logs the given format and arguments, formatted using fmt.Sprintf, to stdout, along with a timestamp and
@@ -35,7 +35,9 @@ var (
 	//    because there is no log output with t.Logf (e.g., CircleCI kills tests after 10 minutes of no log output). With
 	//    this log method, you get log output continuously.
 	//
-	Terratest = New(terratestLogger{})
+	// The minimum level and output format can be set with the TERRATEST_LOG_LEVEL and TERRATEST_LOG_FORMAT environment
+	// variables.
+	Terratest = New(newLoggerFromEnv())
 	// TestingT can be used to use Go's testing.T to log. If this is used, but no testing.T is provided, it will fallback
 	// to Default.
 	TestingT = New(testingT{})
@@ -46,28 +48,158 @@ type TestLogger interface {
 }
 
 type Logger struct {
-	l TestLogger
+	l      TestLogger
+	fields Fields
 }
 
 func New(l TestLogger) *Logger {
 	return &Logger{
-		l,
+		l: l,
 	}
 }
 
+// WithFields returns a new Logger that logs to the same destination as l and attaches the given fields, in addition
+// to the fields of l, to every entry.
+func (l *Logger) WithFields(fields Fields) *Logger {
+	merged := Fields{}
+	var underlying TestLogger
+	if l != nil {
+		underlying = l.l
+		for key, value := range l.fields {
+			merged[key] = value
+		}
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return &Logger{
+		l:      underlying,
+		fields: merged,
+	}
+}
+
+// Logf logs the given format and arguments at info level.
 func (l *Logger) Logf(t testing.TestingT, format string, args ...interface{}) {
 	if tt, ok := t.(helper); ok {
 		tt.Helper()
 	}
 
-	// methods can be called on (typed) nil pointers. In this case, use the Default function to log. This enables the
-	// caller to do `var l *Logger` and then use the logger already.
-	if l == nil || l.l == nil {
-		Default.Logf(t, format, args...)
+	l.log(t, 1, LevelInfo, format, args...)
+}
+
+// Debugf logs the given format and arguments at debug level.
+func (l *Logger) Debugf(t testing.TestingT, format string, args ...interface{}) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	l.log(t, 1, LevelDebug, format, args...)
+}
+
+// Infof logs the given format and arguments at info level.
+func (l *Logger) Infof(t testing.TestingT, format string, args ...interface{}) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	l.log(t, 1, LevelInfo, format, args...)
+}
+
+// Warnf logs the given format and arguments at warn level.
+func (l *Logger) Warnf(t testing.TestingT, format string, args ...interface{}) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	l.log(t, 1, LevelWarn, format, args...)
+}
+
+// Errorf logs the given format and arguments at error level.
+func (l *Logger) Errorf(t testing.TestingT, format string, args ...interface{}) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	l.log(t, 1, LevelError, format, args...)
+}
+
+// log sends an entry to the underlying logger. The argument callDepth is the number of stack frames between log and
+// the code that is doing the logging. If the underlying logger is not a StructuredLogger, the level and fields are
+// dropped and the format and arguments are passed to its Logf method as is.
+func (l *Logger) log(t testing.TestingT, callDepth int, level Level, format string, args ...interface{}) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	underlying, fields := l.resolve()
+
+	structured, ok := underlying.(StructuredLogger)
+	if !ok {
+		underlying.Logf(t, format, args...)
 		return
 	}
 
-	l.l.Logf(t, format, args...)
+	structured.LogEntry(t, newEntry(t, callDepth+1, level, fmt.Sprintf(format, args...), fields))
+}
+
+// LogEntry passes the entry on to the underlying logger, adding the fields of l to it. This makes it possible to use a
+// Logger wherever a StructuredLogger is expected, e.g. as the tee of NewArtifactsLogger.
+func (l *Logger) LogEntry(t testing.TestingT, entry Entry) {
+	if tt, ok := t.(helper); ok {
+		tt.Helper()
+	}
+
+	underlying, fields := l.resolve()
+
+	structured, ok := underlying.(StructuredLogger)
+	if !ok {
+		underlying.Logf(t, "%s", entry.Message)
+		return
+	}
+
+	if len(fields) > 0 {
+		merged := Fields{}
+		for key, value := range fields {
+			merged[key] = value
+		}
+		for key, value := range entry.Fields {
+			merged[key] = value
+		}
+		entry.Fields = merged
+	}
+	structured.LogEntry(t, entry)
+}
+
+// resolve returns the TestLogger to log to and the fields to attach to each entry. Methods can be called on (typed)
+// nil pointers. In this case, use the Default logger. This enables the caller to do `var l *Logger` and then use the
+// logger already.
+func (l *Logger) resolve() (TestLogger, Fields) {
+	if l != nil && l.l != nil {
+		return l.l, l.fields
+	}
+
+	var fields Fields
+	if l != nil {
+		fields = l.fields
+	}
+
+	if Default == nil || Default == l || Default.l == nil {
+		return terratestLogger{}, fields
+	}
+
+	if len(Default.fields) == 0 {
+		return Default.l, fields
+	}
+
+	merged := Fields{}
+	for key, value := range Default.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return Default.l, merged
 }
 
 // helper is used to mark this library as a "helper", and thus not appearing in the line numbers. testing.T implements
@@ -96,12 +228,28 @@ func (_ testingT) Logf(t testing.TestingT, format string, args ...interface{}) {
 	return
 }
 
+func (_ testingT) LogEntry(t testing.TestingT, entry Entry) {
+	tt, ok := t.(*gotesting.T)
+	if !ok {
+		// fallback
+		os.Stdout.Write(formatText(entry))
+		return
+	}
+
+	tt.Helper()
+	tt.Log(entry.Message)
+}
+
 type terratestLogger struct{}
 
 func (_ terratestLogger) Logf(t testing.TestingT, format string, args ...interface{}) {
 	DoLog(t, 3, os.Stdout, fmt.Sprintf(format, args...))
 }
 
+func (_ terratestLogger) LogEntry(_ testing.TestingT, entry Entry) {
+	os.Stdout.Write(formatText(entry))
+}
+
 // Deprecated: use Logger instead, as it provides more flexibility on logging.
 // Logf logs the given format and arguments, formatted using fmt.Sprintf, to stdout, along with a timestamp and information
 // about what test and file is doing the logging. Before Go 1.14, this is an alternative to t.Logf as it logs to stdout
@@ -119,12 +267,13 @@ func (_ terratestLogger) Logf(t testing.TestingT, format string, args ...interfa
 //    because there is no log output with t.Logf (e.g., CircleCI kills tests after 10 minutes of no log output). With
 //    this log method, you get log output continuously.
 // Although t.Logf now supports streaming output since Go 1.14, this is kept for compatibility purposes.
+// Logf is logged at info level through the Default logger.
 func Logf(t testing.TestingT, format string, args ...interface{}) {
 	if tt, ok := t.(helper); ok {
 		tt.Helper()
 	}
 
-	DoLog(t, 2, os.Stdout, fmt.Sprintf(format, args...))
+	Default.log(t, 1, LevelInfo, format, args...)
 }
 
 // Log logs the given arguments to stdout, along with a timestamp and information about what test and file is doing the
@@ -135,7 +284,7 @@ func Log(t testing.TestingT, args ...interface{}) {
 		tt.Helper()
 	}
 
-	DoLog(t, 2, os.Stdout, args...)
+	Default.log(t, 1, LevelInfo, "%s", strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
 }
 
 // DoLog logs the given arguments to the given writer, along with a timestamp and information about what test and file is
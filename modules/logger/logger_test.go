@@ -2,12 +2,14 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 
 	tftesting "github.com/gruntwork-io/terratest/modules/testing"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDoLog(t *testing.T) {
@@ -52,3 +54,104 @@ func TestCustomLogger(t *testing.T) {
 	assert.Equal(t, "log output 2", c.logs[1])
 	assert.Equal(t, "subtest log", c.logs[2])
 }
+
+func TestStreamLoggerFiltersByLevel(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	l := New(NewStreamLogger(&buffer, LevelInfo, FormatText))
+
+	l.Debugf(t, "debug output")
+	l.Logf(t, "info output")
+	l.Warnf(t, "warn output")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, fmt.Sprintf("^%s .+? logger_test.go:[0-9]+: info output$", t.Name()), lines[0])
+	assert.Regexp(t, fmt.Sprintf("^%s .+? logger_test.go:[0-9]+: \\[WARN\\] warn output$", t.Name()), lines[1])
+}
+
+func TestStreamLoggerJSONFormat(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	l := New(NewStreamLogger(&buffer, LevelDebug, FormatJSON)).WithFields(Fields{FieldModule: "terraform"})
+	l.WithFields(Fields{FieldAttempt: 2}).Errorf(t, "apply failed: %s", "boom")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, t.Name(), entry["test"])
+	assert.Equal(t, "apply failed: boom", entry["msg"])
+	assert.Regexp(t, "^logger_test.go:[0-9]+$", entry["caller"])
+	assert.Equal(t, map[string]interface{}{"module": "terraform", "attempt": float64(2)}, entry["fields"])
+}
+
+func TestParseJSONEntry(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	l := New(NewStreamLogger(&buffer, LevelDebug, FormatJSON)).WithFields(Fields{FieldCommand: "terraform"})
+	l.Warnf(t, "retrying")
+
+	entry, err := ParseJSONEntry(bytes.TrimSpace(buffer.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarn, entry.Level)
+	assert.Equal(t, t.Name(), entry.TestName)
+	assert.Equal(t, "retrying", entry.Message)
+	assert.Regexp(t, "^logger_test.go:[0-9]+$", entry.Caller)
+	assert.Equal(t, Fields{FieldCommand: "terraform"}, entry.Fields)
+	assert.False(t, entry.Time.IsZero())
+
+	for _, line := range []string{
+		"=== RUN   TestFoo",
+		`{"Time":"2020-01-01T00:00:00Z","Action":"run","Test":"TestFoo"}`,
+		`{"time":"2020-01-01T00:00:00Z","test":"TestFoo"}`,
+		`{"time":"2020-01-01T00:00:00Z","level":"verbose","test":"TestFoo","msg":""}`,
+	} {
+		_, err := ParseJSONEntry([]byte(line))
+		assert.Error(t, err, line)
+	}
+}
+
+func TestWithFieldsOnNilLogger(t *testing.T) {
+	t.Parallel()
+
+	var l *Logger
+	withFields := l.WithFields(Fields{FieldCommand: "terraform"})
+	withFields.Logf(t, "this should be logged with the default logger")
+	assert.Equal(t, Fields{FieldCommand: "terraform"}, withFields.fields)
+}
+
+func TestCustomLoggerReceivesAllLevels(t *testing.T) {
+	t.Parallel()
+
+	c := &customLogger{}
+	l := New(c).WithFields(Fields{FieldModule: "k8s"})
+	l.Debugf(t, "debug %d", 1)
+	l.Errorf(t, "error %d", 2)
+
+	assert.Equal(t, []string{"debug 1", "error 2"}, c.logs)
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name          string
+		expectedLevel Level
+		expectError   bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{"warning", LevelWarn, false},
+		{" error ", LevelError, false},
+		{"verbose", LevelDebug, true},
+	}
+
+	for _, testCase := range testCases {
+		level, err := ParseLevel(testCase.name)
+		assert.Equal(t, testCase.expectedLevel, level)
+		assert.Equal(t, testCase.expectError, err != nil)
+	}
+}
@@ -66,3 +66,13 @@ func TestPanicExample(t *testing.T) {
 	t.Parallel()
 	testExample(t, "panic")
 }
+
+func TestJSONExample(t *testing.T) {
+	t.Parallel()
+	testExample(t, "json")
+}
+
+func TestStructuredExample(t *testing.T) {
+	t.Parallel()
+	testExample(t, "structured")
+}
@@ -0,0 +1,195 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	junitparser "github.com/jstemmer/go-junit-report/parser"
+	"github.com/sirupsen/logrus"
+)
+
+// TestEvent is a single event emitted by `go test -json` (see `go doc test2json`).
+type TestEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// isJSONEventStream peeks at the first non whitespace character of the reader to detect if it is a `go test -json`
+// event stream, rather than plain `go test -v` output. Output that starts with an entry of the terratest logger in the
+// json format is not an event stream.
+func isJSONEventStream(reader *bufio.Reader) bool {
+	for size := 1; ; size++ {
+		peeked, err := reader.Peek(size)
+		if len(peeked) < size {
+			return false
+		}
+
+		char := peeked[size-1]
+		switch char {
+		case ' ', '\t', '\r', '\n':
+			if err != nil {
+				return false
+			}
+			continue
+		}
+		if char != '{' {
+			return false
+		}
+
+		// Peek returns as much as it can along with an error when the input or the buffer is shorter than asked for
+		buffered, _ := reader.Peek(reader.Size())
+		firstLine := string(buffered[size-1:])
+		if end := strings.IndexByte(firstLine, '\n'); end != -1 {
+			firstLine = firstLine[:end]
+		}
+		_, isLogEntry := parseLogEntryLine(firstLine)
+		return !isLogEntry
+	}
+}
+
+// jsonPackage accumulates the results of a single package in a `go test -json` event stream.
+type jsonPackage struct {
+	pkg   *junitparser.Package
+	tests map[string]*junitparser.Test
+	// Output is not guaranteed to be split on newlines, so partial lines are buffered per test until they are complete.
+	partialLines map[string]string
+}
+
+// parseAndStoreJSONTestOutput reads a `go test -json` event stream, stores the output of each test under the outputDir
+// in a file named by the test name, collects package level output and test results in `summary.log`, and returns the
+// results as a junit report. Tests that never report a result, e.g. because the test binary panicked or timed out, are
+// reported as failed.
+func parseAndStoreJSONTestOutput(
+	logger *logrus.Logger,
+	read io.Reader,
+	outputDir string,
+) *junitparser.Report {
+	logWriter := LogWriter{
+		lookup:    make(map[string]*os.File),
+		outputDir: outputDir,
+	}
+	defer logWriter.closeFiles(logger)
+
+	report := &junitparser.Report{}
+	packages := map[string]*jsonPackage{}
+	var packageOrder []string
+
+	getPackage := func(name string) *jsonPackage {
+		pkg, hasKey := packages[name]
+		if !hasKey {
+			pkg = &jsonPackage{
+				pkg:          &junitparser.Package{Name: name},
+				tests:        map[string]*junitparser.Test{},
+				partialLines: map[string]string{},
+			}
+			packages[name] = pkg
+			packageOrder = append(packageOrder, name)
+		}
+		return pkg
+	}
+
+	getTest := func(pkg *jsonPackage, name string) *junitparser.Test {
+		test, hasKey := pkg.tests[name]
+		if !hasKey {
+			test = &junitparser.Test{Name: name, Result: junitparser.FAIL, Output: []string{}}
+			pkg.tests[name] = test
+			pkg.pkg.Tests = append(pkg.pkg.Tests, test)
+		}
+		return test
+	}
+
+	writeLine := func(pkg *jsonPackage, testName string, line string) {
+		// Output that go test can't attribute to a test, e.g. from a goroutine that outlives it, may still be a log
+		// entry that names its test
+		if entry, isLogEntry := parseLogEntryLine(line); testName == "" && isLogEntry {
+			testName = entry.TestName
+		}
+
+		if testName == "" {
+			logWriter.writeLog(logger, "summary", line)
+			return
+		}
+
+		logWriter.writeLog(logger, testName, line)
+		if isResultLine(line) {
+			logWriter.writeLog(logger, "summary", line)
+		} else if !isStatusLine(line) {
+			test := getTest(pkg, testName)
+			test.Output = append(test.Output, line)
+		}
+	}
+
+	decoder := json.NewDecoder(read)
+	for {
+		var event TestEvent
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logger.Errorf("Error decoding go test -json event: %s", err)
+			break
+		}
+
+		pkg := getPackage(event.Package)
+		elapsed := time.Duration(event.Elapsed * float64(time.Second))
+
+		switch event.Action {
+		case "run":
+			if event.Test != "" {
+				getTest(pkg, event.Test)
+			}
+
+		case "output":
+			lines := strings.Split(pkg.partialLines[event.Test]+event.Output, "\n")
+			for _, line := range lines[:len(lines)-1] {
+				writeLine(pkg, event.Test, line)
+			}
+			pkg.partialLines[event.Test] = lines[len(lines)-1]
+
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				pkg.pkg.Duration = elapsed
+				continue
+			}
+
+			test := getTest(pkg, event.Test)
+			test.Duration = elapsed
+			switch event.Action {
+			case "pass":
+				test.Result = junitparser.PASS
+			case "fail":
+				test.Result = junitparser.FAIL
+			case "skip":
+				test.Result = junitparser.SKIP
+			}
+		}
+	}
+
+	for _, name := range packageOrder {
+		pkg := packages[name]
+
+		var testNames []string
+		for testName := range pkg.partialLines {
+			testNames = append(testNames, testName)
+		}
+		sort.Strings(testNames)
+		for _, testName := range testNames {
+			if line := pkg.partialLines[testName]; line != "" {
+				writeLine(pkg, testName, line)
+			}
+		}
+		report.Packages = append(report.Packages, *pkg.pkg)
+	}
+
+	return report
+}
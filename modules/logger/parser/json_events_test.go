@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	junitparser "github.com/jstemmer/go-junit-report/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsJSONEventStream(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		in       string
+		expected bool
+	}{
+		{"JSONEvent", `{"Action":"run","Test":"TestSnafu"}`, true},
+		{"JSONEventWithLeadingWhitespace", "\n  \t{\"Action\":\"run\"}", true},
+		{"PlainOutput", "=== RUN   TestSnafu", false},
+		{"StructuredLogEntry", `{"time":"2020-01-01T00:00:00Z","level":"info","test":"TestSnafu","msg":"hello"}` + "\n=== RUN   TestSnafu", false},
+		{"Empty", "", false},
+		{"OnlyWhitespace", "\n\n  ", false},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			reader := bufio.NewReader(strings.NewReader(testCase.in))
+			assert.Equal(t, testCase.expected, isJSONEventStream(reader))
+
+			// Detection must not consume the reader
+			rest, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.in, string(rest))
+		})
+	}
+}
+
+func TestParseJSONTestOutputJoinsPartialLines(t *testing.T) {
+	t.Parallel()
+
+	dir := getTempDir(t)
+	defer os.RemoveAll(dir)
+
+	events := strings.Join([]string{
+		`{"Action":"run","Package":"pkg","Test":"TestSnafu"}`,
+		`{"Action":"output","Package":"pkg","Test":"TestSnafu","Output":"TestSnafu part one, "}`,
+		`{"Action":"output","Package":"pkg","Test":"TestSnafu","Output":"part two\nTestSnafu unterminated"}`,
+		`{"Action":"run","Package":"pkg","Test":"TestNeverFinished"}`,
+		`{"Action":"fail","Package":"pkg","Elapsed":1.5}`,
+	}, "\n")
+
+	report := parseAndStoreJSONTestOutput(NewTestLogger(t), strings.NewReader(events), dir)
+
+	require.Len(t, report.Packages, 1)
+	pkg := report.Packages[0]
+	assert.Equal(t, "pkg", pkg.Name)
+	assert.Equal(t, 1.5, pkg.Duration.Seconds())
+	require.Len(t, pkg.Tests, 2)
+	assert.Equal(t, []string{"TestSnafu part one, part two", "TestSnafu unterminated"}, pkg.Tests[0].Output)
+	assert.Equal(t, junitparser.FAIL, pkg.Tests[1].Result)
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "TestSnafu.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "TestSnafu part one, part two\nTestSnafu unterminated\n", string(contents))
+}
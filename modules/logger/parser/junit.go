@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	junitparser "github.com/jstemmer/go-junit-report/parser"
+)
+
+// The JUnit types below mirror the ones in github.com/jstemmer/go-junit-report/formatter, with the addition of
+// system-out on each test case, which that formatter does not support.
+
+// JUnitTestSuites is a collection of JUnit test suites.
+type JUnitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite is a single JUnit test suite which may contain many test cases.
+type JUnitTestSuite struct {
+	XMLName    xml.Name         `xml:"testsuite"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Time       string           `xml:"time,attr"`
+	Name       string           `xml:"name,attr"`
+	Properties *JUnitProperties `xml:"properties,omitempty"`
+	TestCases  []JUnitTestCase  `xml:"testcase"`
+}
+
+// JUnitTestCase is a single test case with its result. SystemOut contains the broken out log of the test.
+type JUnitTestCase struct {
+	XMLName     xml.Name          `xml:"testcase"`
+	Classname   string            `xml:"classname,attr"`
+	Name        string            `xml:"name,attr"`
+	Time        string            `xml:"time,attr"`
+	SkipMessage *JUnitSkipMessage `xml:"skipped,omitempty"`
+	Failure     *JUnitFailure     `xml:"failure,omitempty"`
+	SystemOut   *JUnitOutput      `xml:"system-out,omitempty"`
+}
+
+// JUnitOutput contains the output of a test case.
+type JUnitOutput struct {
+	Contents string `xml:",cdata"`
+}
+
+// JUnitSkipMessage contains the reason why a test case was skipped.
+type JUnitSkipMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// JUnitProperties contains the properties of a test suite.
+type JUnitProperties struct {
+	Properties []JUnitProperty `xml:"property"`
+}
+
+// JUnitProperty represents a key/value pair used to define properties.
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// JUnitFailure contains data related to a failed test.
+type JUnitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// writeJunitReportXML writes a JUnit XML representation of the given report to w, attaching the log file of each test
+// found in logDir (as written by the parsers) as the system-out of the corresponding test case.
+func writeJunitReportXML(report *junitparser.Report, logDir string, w io.Writer) error {
+	suites := JUnitTestSuites{}
+
+	for _, pkg := range report.Packages {
+		suite := JUnitTestSuite{
+			Tests:     len(pkg.Tests) + len(pkg.Benchmarks),
+			Time:      formatSeconds(pkg.Duration, 3),
+			Name:      pkg.Name,
+			TestCases: []JUnitTestCase{},
+		}
+		if pkg.CoveragePct != "" {
+			suite.Properties = &JUnitProperties{[]JUnitProperty{{"coverage.statements.pct", pkg.CoveragePct}}}
+		}
+
+		classname := pkg.Name
+		if idx := strings.LastIndex(classname, "/"); idx > -1 && idx < len(pkg.Name) {
+			classname = pkg.Name[idx+1:]
+		}
+
+		for _, test := range pkg.Tests {
+			testCase := JUnitTestCase{
+				Classname: classname,
+				Name:      test.Name,
+				Time:      formatSeconds(test.Duration, 3),
+			}
+			if log := readTestLog(logDir, test.Name); log != "" {
+				testCase.SystemOut = &JUnitOutput{Contents: log}
+			}
+
+			switch test.Result {
+			case junitparser.FAIL:
+				suite.Failures++
+				testCase.Failure = &JUnitFailure{
+					Message:  "Failed",
+					Contents: strings.Join(test.Output, "\n"),
+				}
+			case junitparser.SKIP:
+				testCase.SkipMessage = &JUnitSkipMessage{strings.Join(test.Output, "\n")}
+			}
+
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+
+		for _, benchmark := range pkg.Benchmarks {
+			suite.TestCases = append(suite.TestCases, JUnitTestCase{
+				Classname: classname,
+				Name:      benchmark.Name,
+				Time:      formatSeconds(benchmark.Duration, 9),
+			})
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	bytes, err := xml.MarshalIndent(suites, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+	writer.WriteString(xml.Header)
+	writer.Write(bytes)
+	writer.WriteByte('\n')
+	return writer.Flush()
+}
+
+// readTestLog returns the contents of the broken out log file for the given test, or an empty string if there is none.
+// Characters that are not allowed in XML, such as the escape codes used for colored output, are dropped.
+func readTestLog(logDir string, testName string) string {
+	contents, err := ioutil.ReadFile(testLogPath(logDir, testName))
+	if err != nil {
+		return ""
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' || (r >= 0x20 && r != 0xFFFE && r != 0xFFFF) {
+			return r
+		}
+		return -1
+	}, string(contents))
+}
+
+// testLogPath returns the path of the broken out log file for the given test.
+func testLogPath(logDir string, testName string) string {
+	return filepath.Join(logDir, testName+".log")
+}
+
+func formatSeconds(d time.Duration, precision int) string {
+	return fmt.Sprintf("%.*f", precision, d.Seconds())
+}
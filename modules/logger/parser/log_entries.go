@@ -0,0 +1,43 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	terratestlogger "github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// ReadLogEntries reads the entries written by the terratest logger with TERRATEST_LOG_FORMAT=json from the reader, in
+// the order they were written. Lines that are not log entries, such as the status and result lines of go test, are
+// skipped.
+func ReadLogEntries(reader io.Reader) ([]terratestlogger.Entry, error) {
+	var entries []terratestlogger.Entry
+
+	bufferedReader := bufio.NewReader(reader)
+	for {
+		data, err := bufferedReader.ReadString('\n')
+		if entry, isLogEntry := parseLogEntryLine(data); isLogEntry {
+			entries = append(entries, entry)
+		}
+
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+	}
+}
+
+// parseLogEntryLine decodes a line written by the terratest logger with TERRATEST_LOG_FORMAT=json. Returns false if
+// the line is not a log entry.
+func parseLogEntryLine(text string) (terratestlogger.Entry, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") {
+		return terratestlogger.Entry{}, false
+	}
+
+	entry, err := terratestlogger.ParseJSONEntry([]byte(text))
+	return entry, err == nil
+}
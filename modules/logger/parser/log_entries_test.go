@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	terratestlogger "github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLogEntries(t *testing.T) {
+	t.Parallel()
+
+	file := openFile(t, "./fixtures/structured_example.log")
+	defer file.Close()
+
+	entries, err := ReadLogEntries(file)
+	require.NoError(t, err)
+	require.Len(t, entries, 8)
+
+	first := entries[0]
+	assert.Equal(t, "TestApply", first.TestName)
+	assert.Equal(t, terratestlogger.LevelInfo, first.Level)
+	assert.Equal(t, "retry.go:91", first.Caller)
+	assert.Equal(t, "terraform [init -upgrade=false]", first.Message)
+	assert.Equal(t, terratestlogger.Fields{terratestlogger.FieldAttempt: float64(1)}, first.Fields)
+	assert.Equal(t, "2020-06-01T10:00:00.000000001Z", first.Time.Format("2006-01-02T15:04:05.999999999Z07:00"))
+
+	var levels []terratestlogger.Level
+	for _, entry := range entries {
+		levels = append(levels, entry.Level)
+	}
+	assert.Equal(t, []terratestlogger.Level{
+		terratestlogger.LevelInfo, terratestlogger.LevelInfo, terratestlogger.LevelDebug, terratestlogger.LevelDebug,
+		terratestlogger.LevelWarn, terratestlogger.LevelInfo, terratestlogger.LevelInfo, terratestlogger.LevelError,
+	}, levels)
+}
+
+func TestReadLogEntriesWithoutTrailingNewline(t *testing.T) {
+	t.Parallel()
+
+	entries, err := ReadLogEntries(strings.NewReader("=== RUN   TestSnafu\n" + `{"time":"2020-01-01T00:00:00Z","level":"info","test":"TestSnafu","msg":"last"}`))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "last", entries[0].Message)
+}
+
+func TestParseJSONTestOutputRoutesUnattributedLogEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := getTempDir(t)
+	defer os.RemoveAll(dir)
+
+	entry := `{"time":"2020-01-01T00:00:00Z","level":"info","test":"TestSnafu","caller":"cleanup.go:10","msg":"destroyed"}`
+	output, err := json.Marshal(entry + "\n")
+	require.NoError(t, err)
+	events := strings.Join([]string{
+		`{"Action":"run","Package":"pkg","Test":"TestSnafu"}`,
+		`{"Action":"pass","Package":"pkg","Test":"TestSnafu","Elapsed":0.5}`,
+		`{"Action":"output","Package":"pkg","Output":` + string(output) + `}`,
+		`{"Action":"pass","Package":"pkg","Elapsed":1}`,
+	}, "\n")
+
+	parseAndStoreJSONTestOutput(NewTestLogger(t), strings.NewReader(events), dir)
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "TestSnafu.log"))
+	require.NoError(t, err)
+	assert.Equal(t, entry+"\n", string(log))
+	_, err = os.Stat(filepath.Join(dir, "summary.log"))
+	assert.True(t, os.IsNotExist(err))
+}
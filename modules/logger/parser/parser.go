@@ -13,8 +13,27 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// SpawnParsers will spawn the log parser and junit report parsers off of a single reader.
+// SpawnParsers will spawn the log parser and junit report parsers off of a single reader. The reader can contain
+// either plain `go test -v` output or a `go test -json` event stream. Once the logs are broken out by test, the junit
+// report, with each test's log attached, and the summaries are stored in the output directory.
 func SpawnParsers(logger *logrus.Logger, reader io.Reader, outputDir string) {
+	bufferedReader := bufio.NewReader(reader)
+
+	var report *junitparser.Report
+	if isJSONEventStream(bufferedReader) {
+		report = parseAndStoreJSONTestOutput(logger, bufferedReader, outputDir)
+	} else {
+		report = spawnTextParsers(logger, bufferedReader, outputDir)
+	}
+
+	if report != nil {
+		storeReports(logger, outputDir, report)
+	}
+}
+
+// spawnTextParsers will spawn the log parser and junit report parsers for plain `go test -v` output off of a single
+// reader, returning the junit report, or nil if the output could not be parsed into one.
+func spawnTextParsers(logger *logrus.Logger, reader io.Reader, outputDir string) *junitparser.Report {
 	forkedReader, forkedWriter := io.Pipe()
 	teedReader := io.TeeReader(reader, forkedWriter)
 	var waitForParsers sync.WaitGroup
@@ -25,22 +44,25 @@ func SpawnParsers(logger *logrus.Logger, reader io.Reader, outputDir string) {
 		defer waitForParsers.Done()
 		parseAndStoreTestOutput(logger, teedReader, outputDir)
 	}()
+
+	var report *junitparser.Report
 	go func() {
 		defer waitForParsers.Done()
-		report, err := junitparser.Parse(forkedReader, "")
-		if err == nil {
-			storeJunitReport(logger, outputDir, report)
-		} else {
+		var err error
+		report, err = junitparser.Parse(forkedReader, "")
+		if err != nil {
 			logger.Errorf("Error parsing test output into junit report: %s", err)
+			report = nil
 		}
 	}()
 	waitForParsers.Wait()
+	return report
 }
 
 // RegEx for parsing test status lines. Pulled from jstemmer/go-junit-report
 var (
 	regexResult  = regexp.MustCompile(`--- (PASS|FAIL|SKIP): (.+) \((\d+\.\d+)(?: ?seconds|s)\)`)
-	regexStatus  = regexp.MustCompile(`=== (RUN|PAUSE|CONT)\s+(.+)`)
+	regexStatus  = regexp.MustCompile(`=== (RUN|PAUSE|CONT|NAME)\s+(.+)`)
 	regexSummary = regexp.MustCompile(`^(ok|FAIL)\s+([^ ]+)\s+(?:(\d+\.\d+)s|\(cached\)|(\[\w+ failed]))(?:\s+coverage:\s+(\d+\.\d+)%\sof\sstatements(?:\sin\s.+)?)?$`)
 	regexPanic   = regexp.MustCompile(`^panic:`)
 )
@@ -133,6 +155,8 @@ func parseAndStoreTestOutput(
 			// detected when we reach a dedented line.
 			testResultMarkers = testResultMarkers.removeDedentedTestResultMarkers(indentLevel)
 
+			entry, isLogEntry := parseLogEntryLine(data)
+
 			// Handle each possible category of test lines
 			switch {
 			case isSummaryLine(data):
@@ -142,6 +166,11 @@ func parseAndStoreTestOutput(
 				testName := getTestNameFromStatusLine(data)
 				logWriter.writeLog(logger, testName, data)
 
+			case isLogEntry:
+				// Entries logged with TERRATEST_LOG_FORMAT=json carry the test name, so no heuristic is needed
+				logWriter.writeLog(logger, entry.TestName, data)
+				previousTestName = entry.TestName
+
 			case strings.HasPrefix(data, "Test"):
 				// Heuristic: `go test` will only execute test functions named `Test.*`, so we assume any line prefixed
 				// with `Test` is a test output for a named test. Also assume that test output will be space delimeted and
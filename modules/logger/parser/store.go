@@ -7,7 +7,6 @@ import (
 
 	"github.com/gruntwork-io/go-commons/errors"
 	"github.com/gruntwork-io/go-commons/files"
-	junitformatter "github.com/jstemmer/go-junit-report/formatter"
 	junitparser "github.com/jstemmer/go-junit-report/parser"
 	"github.com/sirupsen/logrus"
 )
@@ -92,20 +91,35 @@ func ensureDirectoryExists(logger *logrus.Logger, dirName string) error {
 	return nil
 }
 
-// storeJunitReport takes a parsed Junit report and stores it as report.xml in the output directory
-func storeJunitReport(logger *logrus.Logger, outputDir string, report *junitparser.Report) {
+// storeReports takes a parsed test report and stores it in the output directory as:
+// - report.xml, in junit format, with the broken out log of each test attached as its system-out.
+// - summary.json and summary.html, with totals, durations, failures and the slowest tests.
+// The broken out logs must already be stored in the output directory.
+func storeReports(logger *logrus.Logger, outputDir string, report *junitparser.Report) {
 	ensureDirectoryExists(logger, outputDir)
-	filename := filepath.Join(outputDir, "report.xml")
+	summary := newSummary(report, outputDir)
+
+	storeReport(logger, filepath.Join(outputDir, "report.xml"), func(f *os.File) error {
+		return writeJunitReportXML(report, outputDir, f)
+	})
+	storeReport(logger, filepath.Join(outputDir, "summary.json"), func(f *os.File) error {
+		return writeSummaryJSON(summary, f)
+	})
+	storeReport(logger, filepath.Join(outputDir, "summary.html"), func(f *os.File) error {
+		return writeSummaryHTML(summary, f)
+	})
+}
+
+// storeReport creates the file at the provided filename and writes a report to it with the provided write function.
+func storeReport(logger *logrus.Logger, filename string, write func(f *os.File) error) {
 	f, err := os.Create(filename)
 	if err != nil {
-		logger.Errorf("Error making file %s for junit report", filename)
+		logger.Errorf("Error making file %s for report", filename)
 		return
 	}
 	defer f.Close()
 
-	err = junitformatter.JUnitReportXML(report, false, "", f)
-	if err != nil {
-		logger.Errorf("Error formatting junit xml report: %s", err)
-		return
+	if err := write(f); err != nil {
+		logger.Errorf("Error writing report %s: %s", filename, err)
 	}
 }
@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	junitparser "github.com/jstemmer/go-junit-report/parser"
+)
+
+// maxSlowestTests is the number of tests listed in the slowest tests section of the summaries.
+const maxSlowestTests = 10
+
+// Summary is an overview of a test run, stored as summary.json and rendered as summary.html.
+type Summary struct {
+	Tests           int              `json:"tests"`
+	Passed          int              `json:"passed"`
+	Failed          int              `json:"failed"`
+	Skipped         int              `json:"skipped"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	Packages        []PackageSummary `json:"packages"`
+	Failures        []TestSummary    `json:"failures"`
+	SlowestTests    []TestSummary    `json:"slowest_tests"`
+}
+
+// PackageSummary is an overview of the test results of a single package.
+type PackageSummary struct {
+	Name            string  `json:"name"`
+	Tests           int     `json:"tests"`
+	Passed          int     `json:"passed"`
+	Failed          int     `json:"failed"`
+	Skipped         int     `json:"skipped"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// TestSummary is the result of a single test. LogFile is the path of the broken out log of the test, relative to the
+// output directory, if there is one.
+type TestSummary struct {
+	Package         string  `json:"package"`
+	Name            string  `json:"name"`
+	Result          string  `json:"result"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	LogFile         string  `json:"log_file,omitempty"`
+}
+
+// newSummary computes the summary of the given report. The log file of each test is looked up in logDir.
+func newSummary(report *junitparser.Report, logDir string) Summary {
+	summary := Summary{
+		Packages:     []PackageSummary{},
+		Failures:     []TestSummary{},
+		SlowestTests: []TestSummary{},
+	}
+
+	var allTests []TestSummary
+	for _, pkg := range report.Packages {
+		pkgSummary := PackageSummary{
+			Name:            pkg.Name,
+			Tests:           len(pkg.Tests),
+			DurationSeconds: pkg.Duration.Seconds(),
+		}
+
+		for _, test := range pkg.Tests {
+			testSummary := TestSummary{
+				Package:         pkg.Name,
+				Name:            test.Name,
+				DurationSeconds: test.Duration.Seconds(),
+			}
+			if _, err := os.Stat(testLogPath(logDir, test.Name)); err == nil {
+				testSummary.LogFile = filepath.ToSlash(test.Name + ".log")
+			}
+
+			switch test.Result {
+			case junitparser.PASS:
+				testSummary.Result = "PASS"
+				pkgSummary.Passed++
+			case junitparser.FAIL:
+				testSummary.Result = "FAIL"
+				pkgSummary.Failed++
+				summary.Failures = append(summary.Failures, testSummary)
+			case junitparser.SKIP:
+				testSummary.Result = "SKIP"
+				pkgSummary.Skipped++
+			}
+			allTests = append(allTests, testSummary)
+		}
+
+		summary.Tests += pkgSummary.Tests
+		summary.Passed += pkgSummary.Passed
+		summary.Failed += pkgSummary.Failed
+		summary.Skipped += pkgSummary.Skipped
+		summary.DurationSeconds += pkgSummary.DurationSeconds
+		summary.Packages = append(summary.Packages, pkgSummary)
+	}
+
+	sort.SliceStable(allTests, func(i, j int) bool {
+		return allTests[i].DurationSeconds > allTests[j].DurationSeconds
+	})
+	if len(allTests) > maxSlowestTests {
+		allTests = allTests[:maxSlowestTests]
+	}
+	summary.SlowestTests = append(summary.SlowestTests, allTests...)
+
+	return summary
+}
+
+// writeSummaryJSON writes the summary as indented JSON to w.
+func writeSummaryJSON(summary Summary, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+// writeSummaryHTML renders the summary as a standalone HTML page to w. Test names link to their log files.
+func writeSummaryHTML(summary Summary, w io.Writer) error {
+	return summaryHTMLTemplate.Execute(w, summary)
+}
+
+var summaryHTMLTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"seconds": func(seconds float64) string { return fmt.Sprintf("%.3fs", seconds) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Test summary</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.PASS { color: #2e7d32; }
+.FAIL { color: #c62828; font-weight: bold; }
+.SKIP { color: #757575; }
+</style>
+</head>
+<body>
+<h1>Test summary</h1>
+<p>{{.Tests}} tests: <span class="PASS">{{.Passed}} passed</span>, <span class="FAIL">{{.Failed}} failed</span>, <span class="SKIP">{{.Skipped}} skipped</span> in {{seconds .DurationSeconds}}</p>
+{{define "tests"}}<table>
+<tr><th>Package</th><th>Test</th><th>Result</th><th>Duration</th></tr>
+{{range .}}<tr><td>{{.Package}}</td><td>{{if .LogFile}}<a href="{{.LogFile}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</td><td class="{{.Result}}">{{.Result}}</td><td>{{seconds .DurationSeconds}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Failures</h2>
+{{if .Failures}}{{template "tests" .Failures}}{{else}}<p>None</p>
+{{end}}<h2>Slowest tests</h2>
+{{template "tests" .SlowestTests}}<h2>Packages</h2>
+<table>
+<tr><th>Package</th><th>Tests</th><th>Passed</th><th>Failed</th><th>Skipped</th><th>Duration</th></tr>
+{{range .Packages}}<tr><td>{{.Name}}</td><td>{{.Tests}}</td><td>{{.Passed}}</td><td>{{.Failed}}</td><td>{{.Skipped}}</td><td>{{seconds .DurationSeconds}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,287 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// LogLevelEnvVar is the environment variable used to set the minimum level of the entries logged by the Default
+	// and Terratest loggers. Must be one of debug, info, warn or error. Defaults to debug, which logs everything.
+	LogLevelEnvVar = "TERRATEST_LOG_LEVEL"
+	// LogFormatEnvVar is the environment variable used to set the output format of the Default and Terratest loggers.
+	// Must be one of text or json. Defaults to text.
+	LogFormatEnvVar = "TERRATEST_LOG_FORMAT"
+)
+
+// Well known field names that Terratest attaches to log entries.
+const (
+	// FieldModule is the Terratest module doing the logging, such as terraform or k8s.
+	FieldModule = "module"
+	// FieldCommand is the external command whose output is being logged, such as terraform or kubectl.
+	FieldCommand = "command"
+	// FieldAttempt is the 1-based attempt number of a retried action.
+	FieldAttempt = "attempt"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (level Level) String() string {
+	if name, ok := levelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int(level))
+}
+
+// MarshalJSON encodes the level as its name.
+func (level Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(level.String())
+}
+
+// UnmarshalJSON decodes a level from its name.
+func (level *Level) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	*level = parsed
+	return nil
+}
+
+// ParseLevel converts a level name, such as "info" or "WARN", to a Level.
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelDebug, fmt.Errorf("invalid log level %q: must be one of debug, info, warn or error", name)
+}
+
+// Format is the output format of a stream logger.
+type Format string
+
+const (
+	// FormatText is the historical Terratest format: the test name, a timestamp and the caller, followed by the
+	// message. Fields are not included, so the output can still be parsed by terratest_log_parser.
+	FormatText Format = "text"
+	// FormatJSON writes each entry as a JSON object on its own line.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat converts a format name, such as "text" or "json", to a Format.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	}
+	return FormatText, fmt.Errorf("invalid log format %q: must be one of text or json", name)
+}
+
+// Fields are key value pairs attached to a log entry.
+type Fields map[string]interface{}
+
+// Entry is a single structured log entry.
+type Entry struct {
+	Time     time.Time
+	Level    Level
+	TestName string
+	// Caller is the file and line number that logged the entry, e.g. "cmd.go:75".
+	Caller  string
+	Message string
+	Fields  Fields
+}
+
+// StructuredLogger is a TestLogger that can also handle leveled entries with fields. Loggers that only implement
+// TestLogger receive every entry through Logf, regardless of its level, and without its fields.
+type StructuredLogger interface {
+	TestLogger
+	LogEntry(t testing.TestingT, entry Entry)
+}
+
+// newEntry creates an entry for the given test. The argument callDepth is the number of stack frames to ascend from the
+// caller of newEntry to the code that is doing the logging, with 0 identifying the caller of newEntry.
+func newEntry(t testing.TestingT, callDepth int, level Level, message string, fields Fields) Entry {
+	return Entry{
+		Time:     time.Now(),
+		Level:    level,
+		TestName: t.Name(),
+		Caller:   CallerPrefix(callDepth + 2),
+		Message:  message,
+		Fields:   fields,
+	}
+}
+
+type streamLogger struct {
+	writer io.Writer
+	level  Level
+	format Format
+	lock   *sync.Mutex
+}
+
+// NewStreamLogger returns a StructuredLogger that writes entries of at least the given level to the given writer, in
+// the given format.
+func NewStreamLogger(writer io.Writer, level Level, format Format) StructuredLogger {
+	return streamLogger{
+		writer: writer,
+		level:  level,
+		format: format,
+		lock:   &sync.Mutex{},
+	}
+}
+
+// newLoggerFromEnv returns a StructuredLogger that writes to stdout, configured with the TERRATEST_LOG_LEVEL and
+// TERRATEST_LOG_FORMAT environment variables. If TERRATEST_ARTIFACTS_DIR is set, the entries of each test are also
+// written to their own log file under that directory.
+func newLoggerFromEnv() StructuredLogger {
+	level := LevelDebug
+	if value := os.Getenv(LogLevelEnvVar); value != "" {
+		parsed, err := ParseLevel(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring %s: %s\n", LogLevelEnvVar, err)
+		} else {
+			level = parsed
+		}
+	}
+
+	format := FormatText
+	if value := os.Getenv(LogFormatEnvVar); value != "" {
+		parsed, err := ParseFormat(value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring %s: %s\n", LogFormatEnvVar, err)
+		} else {
+			format = parsed
+		}
+	}
+
+	stdout := NewStreamLogger(os.Stdout, level, format)
+	if dir := os.Getenv(ArtifactsDirEnvVar); dir != "" {
+		return NewArtifactsLogger(dir, stdout)
+	}
+	return stdout
+}
+
+func (logger streamLogger) Logf(t testing.TestingT, format string, args ...interface{}) {
+	logger.LogEntry(t, newEntry(t, 1, LevelInfo, fmt.Sprintf(format, args...), nil))
+}
+
+func (logger streamLogger) LogEntry(_ testing.TestingT, entry Entry) {
+	if entry.Level < logger.level {
+		return
+	}
+
+	var line []byte
+	if logger.format == FormatJSON {
+		line = formatJSON(entry)
+	} else {
+		line = formatText(entry)
+	}
+
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	logger.writer.Write(line)
+}
+
+// formatText formats the entry the same way as DoLog. Warnings and errors are tagged with their level.
+func formatText(entry Entry) []byte {
+	message := entry.Message
+	if entry.Level >= LevelWarn {
+		message = fmt.Sprintf("[%s] %s", strings.ToUpper(entry.Level.String()), message)
+	}
+	return []byte(fmt.Sprintf("%s %s %s: %s\n", entry.TestName, entry.Time.Format(time.RFC3339), entry.Caller, message))
+}
+
+type jsonEntry struct {
+	Time     string `json:"time"`
+	Level    Level  `json:"level"`
+	TestName string `json:"test"`
+	Caller   string `json:"caller"`
+	Message  string `json:"msg"`
+	Fields   Fields `json:"fields,omitempty"`
+}
+
+// formatJSON formats the entry as a single line JSON object. Field values that can't be encoded as JSON are formatted
+// with fmt instead.
+func formatJSON(entry Entry) []byte {
+	encoded := jsonEntry{
+		Time:     entry.Time.Format(time.RFC3339Nano),
+		Level:    entry.Level,
+		TestName: entry.TestName,
+		Caller:   entry.Caller,
+		Message:  entry.Message,
+		Fields:   entry.Fields,
+	}
+
+	line, err := json.Marshal(encoded)
+	if err != nil {
+		encoded.Fields = Fields{}
+		for key, value := range entry.Fields {
+			encoded.Fields[key] = fmt.Sprint(value)
+		}
+		line, _ = json.Marshal(encoded)
+	}
+	return append(line, '\n')
+}
+
+// ParseJSONEntry decodes a single line written by a logger in the json format back into an entry. Returns an error if
+// the line is not a JSON object with at least the time, test and msg keys, so that it can be used to tell entries apart
+// from other output, such as the status lines of go test.
+func ParseJSONEntry(line []byte) (Entry, error) {
+	var decoded struct {
+		jsonEntry
+		Time *string `json:"time"`
+		// Message is a pointer, so that an entry with an empty message can be told apart from a missing msg key
+		Message *string `json:"msg"`
+	}
+	if err := json.Unmarshal(line, &decoded); err != nil {
+		return Entry{}, err
+	}
+	if decoded.Time == nil || decoded.TestName == "" || decoded.Message == nil {
+		return Entry{}, fmt.Errorf("not a log entry: the time, test and msg keys are required")
+	}
+
+	entryTime, err := time.Parse(time.RFC3339Nano, *decoded.Time)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{
+		Time:     entryTime,
+		Level:    decoded.Level,
+		TestName: decoded.TestName,
+		Caller:   decoded.Caller,
+		Message:  *decoded.Message,
+		Fields:   decoded.Fields,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package net_helper
+
+import (
+	"fmt"
+	"time"
+)
+
+// PortNotOpenError is an error that occurs if a port was expected to accept connections, but doesn't.
+type PortNotOpenError struct {
+	Protocol string
+	Address  string
+	State    PortState
+	Cause    error
+}
+
+func (err PortNotOpenError) Error() string {
+	return fmt.Sprintf("Expected %s port %s to be open, but it is %s: %v", err.Protocol, err.Address, err.State, err.Cause)
+}
+
+func (err PortNotOpenError) Unwrap() error {
+	return err.Cause
+}
+
+// PortOpenError is an error that occurs if a port was expected to reject or drop connections, but accepts them.
+type PortOpenError struct {
+	Protocol string
+	Address  string
+}
+
+func (err PortOpenError) Error() string {
+	return fmt.Sprintf("Expected %s port %s to be closed, but it accepts connections", err.Protocol, err.Address)
+}
+
+// NoUDPResponseError is an error that occurs if a UDP probe didn't get a response back.
+type NoUDPResponseError struct {
+	Address string
+	State   PortState
+	Timeout time.Duration
+}
+
+func (err NoUDPResponseError) Error() string {
+	return fmt.Sprintf("No response from UDP port %s within %s (port is %s)", err.Address, err.Timeout, err.State)
+}
+
+// TLSHandshakeError is an error that occurs if the TLS handshake with an endpoint fails.
+type TLSHandshakeError struct {
+	Address string
+	Cause   error
+}
+
+func (err TLSHandshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake with %s failed: %v", err.Address, err.Cause)
+}
+
+func (err TLSHandshakeError) Unwrap() error {
+	return err.Cause
+}
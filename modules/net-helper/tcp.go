@@ -0,0 +1,170 @@
+// Package net_helper contains helpers to check network endpoints over TCP, UDP and TLS.
+package net_helper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DefaultDialTimeout is how long the helpers without an explicit timeout wait for a connection to be established.
+const DefaultDialTimeout = 5 * time.Second
+
+// PortState is the state of a port as seen from the machine running the test.
+type PortState string
+
+const (
+	// PortOpen means the port accepted a connection (TCP) or sent a response (UDP).
+	PortOpen PortState = "open"
+	// PortClosed means the host actively rejected the connection, e.g. with a TCP RST or an ICMP port unreachable.
+	PortClosed PortState = "closed"
+	// PortFiltered means there was no answer at all, which is usually a firewall, security group or NSG dropping
+	// the packets.
+	PortFiltered PortState = "filtered"
+	// PortOpenOrFiltered means a UDP port didn't answer: UDP services are free to ignore unexpected payloads, so it's
+	// impossible to tell an open port from a filtered one.
+	PortOpenOrFiltered PortState = "open|filtered"
+)
+
+// ProbeTCPPort tries to open a TCP connection to the given address (host:port) and returns the state of the port.
+// This will fail the test if the state can't be determined, e.g. because the host name doesn't resolve.
+func ProbeTCPPort(t testing.TestingT, address string, timeout time.Duration) PortState {
+	state, err := ProbeTCPPortE(t, address, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// ProbeTCPPortE tries to open a TCP connection to the given address (host:port) and returns the state of the port.
+// Returns an error if the state can't be determined, e.g. because the host name doesn't resolve.
+func ProbeTCPPortE(t testing.TestingT, address string, timeout time.Duration) (PortState, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err == nil {
+		conn.Close()
+		return PortOpen, nil
+	}
+	return classifyDialError(err)
+}
+
+// IsTCPPortOpen returns true if the given address (host:port) accepts TCP connections within the default dial timeout.
+func IsTCPPortOpen(t testing.TestingT, address string) bool {
+	state, err := ProbeTCPPortE(t, address, DefaultDialTimeout)
+	return err == nil && state == PortOpen
+}
+
+// AssertPortOpen checks that the given address (host:port) accepts TCP connections. This will fail the test if it
+// doesn't.
+func AssertPortOpen(t testing.TestingT, address string) {
+	if err := AssertPortOpenE(t, address); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertPortOpenE checks that the given address (host:port) accepts TCP connections and returns an error if it doesn't.
+func AssertPortOpenE(t testing.TestingT, address string) error {
+	conn, err := net.DialTimeout("tcp", address, DefaultDialTimeout)
+	if err == nil {
+		conn.Close()
+		return nil
+	}
+
+	state, classifyErr := classifyDialError(err)
+	if classifyErr != nil {
+		return classifyErr
+	}
+	return PortNotOpenError{Protocol: "TCP", Address: address, State: state, Cause: err}
+}
+
+// AssertPortClosed checks that the given address (host:port) doesn't accept TCP connections, whether because nothing
+// listens on it or because a firewall, security group or NSG drops the traffic. This will fail the test if the port is
+// open.
+func AssertPortClosed(t testing.TestingT, address string) {
+	if err := AssertPortClosedE(t, address); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// AssertPortClosedE checks that the given address (host:port) doesn't accept TCP connections, whether because nothing
+// listens on it or because a firewall, security group or NSG drops the traffic. Returns an error if the port is open.
+func AssertPortClosedE(t testing.TestingT, address string) error {
+	state, err := ProbeTCPPortE(t, address, DefaultDialTimeout)
+	if err != nil {
+		return err
+	}
+	if state == PortOpen {
+		return PortOpenError{Protocol: "TCP", Address: address}
+	}
+
+	logger.Logf(t, "TCP port %s is %s", address, state)
+	return nil
+}
+
+// WaitForTCPPortOpen repeatedly tries to open a TCP connection to the given address (host:port) until it succeeds or
+// maxRetries has been exceeded. This will fail the test if the port never opens.
+func WaitForTCPPortOpen(t testing.TestingT, address string, maxRetries int, sleepBetweenRetries time.Duration) {
+	if err := WaitForTCPPortOpenE(t, address, maxRetries, sleepBetweenRetries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WaitForTCPPortOpenE repeatedly tries to open a TCP connection to the given address (host:port) until it succeeds or
+// maxRetries has been exceeded. Returns an error if the port never opens.
+func WaitForTCPPortOpenE(t testing.TestingT, address string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("Waiting for TCP port %s to open", address),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+	_, err := retry.DoE(t, policy, func() (interface{}, error) {
+		return nil, AssertPortOpenE(t, address)
+	})
+	return err
+}
+
+// WaitForTCPPortClosed repeatedly tries to open a TCP connection to the given address (host:port) until it fails or
+// maxRetries has been exceeded. This is useful to check that a security group change has been applied. This will fail
+// the test if the port stays open.
+func WaitForTCPPortClosed(t testing.TestingT, address string, maxRetries int, sleepBetweenRetries time.Duration) {
+	if err := WaitForTCPPortClosedE(t, address, maxRetries, sleepBetweenRetries); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WaitForTCPPortClosedE repeatedly tries to open a TCP connection to the given address (host:port) until it fails or
+// maxRetries has been exceeded. This is useful to check that a security group change has been applied. Returns an
+// error if the port stays open.
+func WaitForTCPPortClosedE(t testing.TestingT, address string, maxRetries int, sleepBetweenRetries time.Duration) error {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("Waiting for TCP port %s to close", address),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+	_, err := retry.DoE(t, policy, func() (interface{}, error) {
+		return nil, AssertPortClosedE(t, address)
+	})
+	return err
+}
+
+// classifyDialError maps the error returned when dialing a port to the state of that port. Errors that don't say
+// anything about the port, such as DNS resolution failures, are returned as is.
+func classifyDialError(err error) (PortState, error) {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return PortClosed, nil
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return PortFiltered, nil
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		// Usually an ICMP host or network unreachable, sent back by a firewall rejecting the traffic
+		return PortFiltered, nil
+	default:
+		return "", err
+	}
+}
@@ -0,0 +1,127 @@
+package net_helper
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPPortChecks(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go acceptAndClose(listener)
+
+	open := listener.Addr().String()
+	closed := closedTCPAddress(t)
+
+	assert.Equal(t, PortOpen, ProbeTCPPort(t, open, time.Second))
+	assert.Equal(t, PortClosed, ProbeTCPPort(t, closed, time.Second))
+	assert.True(t, IsTCPPortOpen(t, open))
+	assert.False(t, IsTCPPortOpen(t, closed))
+
+	AssertPortOpen(t, open)
+	AssertPortClosed(t, closed)
+
+	err = AssertPortOpenE(t, closed)
+	require.Error(t, err)
+	assert.Equal(t, PortClosed, err.(PortNotOpenError).State)
+	assert.Equal(t, PortOpenError{Protocol: "TCP", Address: open}, AssertPortClosedE(t, open))
+
+	_, err = ProbeTCPPortE(t, "not-a-valid-address", time.Second)
+	assert.Error(t, err)
+}
+
+func TestWaitForTCPPort(t *testing.T) {
+	t.Parallel()
+
+	address := closedTCPAddress(t)
+	assert.Error(t, WaitForTCPPortOpenE(t, address, 1, 10*time.Millisecond))
+
+	// Start listening only after a couple of attempts have failed
+	listening := make(chan net.Listener)
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			close(listening)
+			return
+		}
+		go acceptAndClose(listener)
+		listening <- listener
+	}()
+
+	WaitForTCPPortOpen(t, address, 20, 100*time.Millisecond)
+	listener, ok := <-listening
+	require.True(t, ok)
+
+	assert.Error(t, WaitForTCPPortClosedE(t, address, 1, 10*time.Millisecond))
+	listener.Close()
+	WaitForTCPPortClosed(t, address, 20, 100*time.Millisecond)
+}
+
+func TestClassifyDialError(t *testing.T) {
+	t.Parallel()
+
+	dialError := func(err error) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}
+	}
+
+	testCases := []struct {
+		name     string
+		err      error
+		expected PortState
+	}{
+		{"refused", dialError(syscall.ECONNREFUSED), PortClosed},
+		{"timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, PortFiltered},
+		{"host unreachable", dialError(syscall.EHOSTUNREACH), PortFiltered},
+		{"network unreachable", dialError(syscall.ENETUNREACH), PortFiltered},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			state, err := classifyDialError(testCase.err)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, state)
+		})
+	}
+
+	dnsError := &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "does-not-exist.invalid", IsNotFound: true}}
+	_, err := classifyDialError(dnsError)
+	assert.Equal(t, dnsError, err)
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// closedTCPAddress returns the address of a local TCP port that nothing listens on.
+func closedTCPAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return address
+}
+
+func acceptAndClose(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}
+}
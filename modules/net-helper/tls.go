@@ -0,0 +1,210 @@
+package net_helper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"golang.org/x/crypto/ocsp"
+)
+
+// TLSInfo describes the outcome of a TLS handshake with an endpoint.
+type TLSInfo struct {
+	// ServerName is the name sent in the SNI extension and used to verify the certificate.
+	ServerName string
+	// Version is the negotiated TLS version, e.g. tls.VersionTLS13, and VersionName its name, e.g. "TLS 1.3".
+	Version     uint16
+	VersionName string
+	// CipherSuite is the negotiated cipher suite and CipherSuiteName its name, e.g. "TLS_AES_128_GCM_SHA256".
+	CipherSuite     uint16
+	CipherSuiteName string
+	// NegotiatedProtocol is the protocol agreed on with ALPN, if any, e.g. "h2".
+	NegotiatedProtocol string
+	// PeerCertificates is the certificate chain as sent by the server, starting with the leaf certificate.
+	PeerCertificates []*x509.Certificate
+	// DNSNames and IPAddresses are the Subject Alternative Names of the leaf certificate.
+	DNSNames    []string
+	IPAddresses []net.IP
+	// NotBefore and NotAfter are the validity bounds of the leaf certificate, and ExpiresIn how long it is still valid
+	// for at the time of the handshake (negative if it has already expired).
+	NotBefore time.Time
+	NotAfter  time.Time
+	ExpiresIn time.Duration
+	// Verified is true if the chain is trusted by the configured root CAs (or the system roots) and valid for
+	// ServerName. Otherwise, VerificationError explains why not.
+	Verified          bool
+	VerificationError error
+	// OCSPStaple is the OCSP response stapled by the server, or nil if it didn't staple one.
+	OCSPStaple *OCSPStaple
+}
+
+// OCSPStaple is an OCSP response stapled by a server during the TLS handshake.
+type OCSPStaple struct {
+	Raw []byte
+	// Status is "good", "revoked" or "unknown".
+	Status     string
+	ProducedAt time.Time
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	RevokedAt  time.Time
+	// Error explains why the response couldn't be parsed or doesn't match the certificate, in which case only Raw is
+	// set.
+	Error error
+}
+
+// InspectTLS performs a TLS handshake with the given address (host:port) and returns the negotiated parameters and the
+// certificate chain of the server. The handshake succeeds even if the certificate isn't trusted, so that it can be
+// inspected: check the Verified field for that. If config is nil, the system roots are used and the host of the address
+// is used as the server name. This will fail the test if the handshake fails.
+func InspectTLS(t testing.TestingT, address string, config *tls.Config) *TLSInfo {
+	info, err := InspectTLSE(t, address, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// InspectTLSE performs a TLS handshake with the given address (host:port) and returns the negotiated parameters and
+// the certificate chain of the server. The handshake succeeds even if the certificate isn't trusted, so that it can be
+// inspected: check the Verified field for that. If config is nil, the system roots are used and the host of the
+// address is used as the server name. Returns an error if the handshake fails.
+func InspectTLSE(t testing.TestingT, address string, config *tls.Config) (*TLSInfo, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	var handshakeConfig *tls.Config
+	if config == nil {
+		handshakeConfig = &tls.Config{}
+	} else {
+		handshakeConfig = config.Clone()
+	}
+	if handshakeConfig.ServerName == "" {
+		handshakeConfig.ServerName = host
+	}
+	// Verification is done below, so that untrusted certificates can still be inspected
+	handshakeConfig.InsecureSkipVerify = true
+
+	dialer := &net.Dialer{Timeout: DefaultDialTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, handshakeConfig)
+	if err != nil {
+		return nil, TLSHandshakeError{Address: address, Cause: err}
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	info := &TLSInfo{
+		ServerName:         handshakeConfig.ServerName,
+		Version:            state.Version,
+		VersionName:        tlsVersionName(state.Version),
+		CipherSuite:        state.CipherSuite,
+		CipherSuiteName:    tls.CipherSuiteName(state.CipherSuite),
+		NegotiatedProtocol: state.NegotiatedProtocol,
+		PeerCertificates:   state.PeerCertificates,
+	}
+	if len(state.PeerCertificates) == 0 {
+		info.VerificationError = fmt.Errorf("server sent no certificate")
+		return info, nil
+	}
+
+	leaf := state.PeerCertificates[0]
+	info.DNSNames = leaf.DNSNames
+	info.IPAddresses = leaf.IPAddresses
+	info.NotBefore = leaf.NotBefore
+	info.NotAfter = leaf.NotAfter
+	info.ExpiresIn = time.Until(leaf.NotAfter)
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, info.VerificationError = leaf.Verify(x509.VerifyOptions{
+		DNSName:       handshakeConfig.ServerName,
+		Roots:         handshakeConfig.RootCAs,
+		Intermediates: intermediates,
+	})
+	info.Verified = info.VerificationError == nil
+
+	if len(state.OCSPResponse) > 0 {
+		info.OCSPStaple = parseOCSPStaple(state.OCSPResponse, state.PeerCertificates)
+	}
+
+	return info, nil
+}
+
+// InspectTLSWithRetry repeatedly performs a TLS handshake with the given address (host:port) until it succeeds or
+// maxRetries has been exceeded, and returns the outcome like InspectTLS. This will fail the test if the handshake never
+// succeeds.
+func InspectTLSWithRetry(t testing.TestingT, address string, config *tls.Config, maxRetries int, sleepBetweenRetries time.Duration) *TLSInfo {
+	info, err := InspectTLSWithRetryE(t, address, config, maxRetries, sleepBetweenRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// InspectTLSWithRetryE repeatedly performs a TLS handshake with the given address (host:port) until it succeeds or
+// maxRetries has been exceeded, and returns the outcome like InspectTLSE. Returns an error if the handshake never
+// succeeds.
+func InspectTLSWithRetryE(t testing.TestingT, address string, config *tls.Config, maxRetries int, sleepBetweenRetries time.Duration) (*TLSInfo, error) {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("TLS handshake with %s", address),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+	return retry.DoE(t, policy, func() (*TLSInfo, error) {
+		return InspectTLSE(t, address, config)
+	})
+}
+
+// parseOCSPStaple parses a stapled OCSP response for the leaf of the given chain. The signature is checked against the
+// issuer when the server sent one.
+func parseOCSPStaple(raw []byte, chain []*x509.Certificate) *OCSPStaple {
+	var issuer *x509.Certificate
+	if len(chain) > 1 {
+		issuer = chain[1]
+	}
+
+	response, err := ocsp.ParseResponseForCert(raw, chain[0], issuer)
+	if err != nil {
+		return &OCSPStaple{Raw: raw, Error: fmt.Errorf("invalid OCSP staple: %v", err)}
+	}
+
+	staple := &OCSPStaple{
+		Raw:        raw,
+		ProducedAt: response.ProducedAt,
+		ThisUpdate: response.ThisUpdate,
+		NextUpdate: response.NextUpdate,
+		RevokedAt:  response.RevokedAt,
+	}
+	switch response.Status {
+	case ocsp.Good:
+		staple.Status = "good"
+	case ocsp.Revoked:
+		staple.Status = "revoked"
+	default:
+		staple.Status = "unknown"
+	}
+	return staple
+}
+
+// tlsVersionName returns the name of the given TLS version, e.g. "TLS 1.3".
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
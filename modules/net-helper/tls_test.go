@@ -0,0 +1,185 @@
+package net_helper
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestInspectTLS(t *testing.T) {
+	t.Parallel()
+
+	ca, leaf := generateTestCertificates(t)
+	address := startTLSServer(t, leaf)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	info := InspectTLS(t, address, &tls.Config{RootCAs: roots, NextProtos: []string{"h2", "http/1.1"}})
+	assert.True(t, info.Verified)
+	assert.NoError(t, info.VerificationError)
+	assert.Equal(t, "127.0.0.1", info.ServerName)
+	assert.Equal(t, "TLS 1.3", info.VersionName)
+	assert.NotEmpty(t, info.CipherSuiteName)
+	assert.Equal(t, "h2", info.NegotiatedProtocol)
+	require.Len(t, info.PeerCertificates, 2)
+	assert.Equal(t, "terratest", info.PeerCertificates[0].Subject.CommonName)
+	assert.Equal(t, []string{"localhost"}, info.DNSNames)
+	require.Len(t, info.IPAddresses, 1)
+	assert.True(t, info.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+	assert.Equal(t, leaf.Leaf.NotAfter, info.NotAfter)
+	assert.InDelta(t, float64(24*time.Hour), float64(info.ExpiresIn), float64(time.Minute))
+
+	require.NotNil(t, info.OCSPStaple)
+	assert.Equal(t, "good", info.OCSPStaple.Status)
+	assert.Equal(t, leaf.OCSPStaple, info.OCSPStaple.Raw)
+	assert.False(t, info.OCSPStaple.NextUpdate.IsZero())
+	assert.NoError(t, info.OCSPStaple.Error)
+}
+
+func TestInspectTLSMalformedOCSPStaple(t *testing.T) {
+	t.Parallel()
+
+	ca, leaf := generateTestCertificates(t)
+	leaf.OCSPStaple = []byte("not an OCSP response")
+	address := startTLSServer(t, leaf)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	// The staple is reported without discarding the rest of the handshake
+	info, err := InspectTLSE(t, address, &tls.Config{RootCAs: roots})
+	require.NoError(t, err)
+	assert.True(t, info.Verified)
+	require.NotNil(t, info.OCSPStaple)
+	assert.Error(t, info.OCSPStaple.Error)
+	assert.Equal(t, leaf.OCSPStaple, info.OCSPStaple.Raw)
+	assert.Empty(t, info.OCSPStaple.Status)
+}
+
+func TestInspectTLSVerification(t *testing.T) {
+	t.Parallel()
+
+	ca, leaf := generateTestCertificates(t)
+	address := startTLSServer(t, leaf)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	// Untrusted certificates can still be inspected
+	info := InspectTLS(t, address, nil)
+	assert.False(t, info.Verified)
+	assert.Error(t, info.VerificationError)
+	assert.Equal(t, "terratest", info.PeerCertificates[0].Subject.CommonName)
+
+	info = InspectTLS(t, address, &tls.Config{RootCAs: roots, ServerName: "other.invalid"})
+	assert.False(t, info.Verified)
+	assert.IsType(t, x509.HostnameError{}, info.VerificationError)
+
+	info = InspectTLS(t, address, &tls.Config{RootCAs: roots, ServerName: "localhost", MaxVersion: tls.VersionTLS12})
+	assert.True(t, info.Verified)
+	assert.Equal(t, "TLS 1.2", info.VersionName)
+	assert.Equal(t, tls.CipherSuiteName(info.CipherSuite), info.CipherSuiteName)
+}
+
+func TestInspectTLSErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := InspectTLSE(t, closedTCPAddress(t), nil)
+	require.Error(t, err)
+	assert.IsType(t, TLSHandshakeError{}, err)
+
+	_, err = InspectTLSWithRetryE(t, closedTCPAddress(t), nil, 1, 10*time.Millisecond)
+	assert.Error(t, err)
+
+	// A plain TCP server doesn't speak TLS
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go acceptAndClose(listener)
+	_, err = InspectTLSE(t, listener.Addr().String(), nil)
+	assert.IsType(t, TLSHandshakeError{}, err)
+}
+
+// startTLSServer starts a TLS server on a local port that serves the given certificate and completes the handshake
+// before closing each connection, and returns its address.
+func startTLSServer(t *testing.T, cert tls.Certificate) string {
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"h2"}}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// generateTestCertificates generates a CA and a leaf certificate signed by it for localhost and 127.0.0.1, valid for
+// a day. The leaf certificate chain includes the CA and a stapled OCSP response signed by it.
+func generateTestCertificates(t *testing.T) (tls.Certificate, tls.Certificate) {
+	now := time.Now()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "terratest CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "terratest"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leafCert, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	staple, err := ocsp.CreateResponse(caCert, caCert, ocsp.Response{
+		Status:       ocsp.Good,
+		SerialNumber: leafCert.SerialNumber,
+		ThisUpdate:   now.Add(-time.Minute),
+		NextUpdate:   now.Add(time.Hour),
+	}, caKey)
+	require.NoError(t, err)
+
+	ca := tls.Certificate{Certificate: [][]byte{caDER}, PrivateKey: caKey, Leaf: caCert}
+	leaf := tls.Certificate{Certificate: [][]byte{leafDER, caDER}, PrivateKey: leafKey, Leaf: leafCert, OCSPStaple: staple}
+	return ca, leaf
+}
@@ -0,0 +1,98 @@
+package net_helper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// UDPProbeResult is the outcome of sending a single UDP datagram to a port.
+type UDPProbeResult struct {
+	State    PortState
+	Response []byte
+}
+
+// ProbeUDPPort sends the given payload to the given address (host:port) and waits up to timeout for a response. Since
+// UDP is connectionless, the port is only reported as open if something answers, and as closed if the host sends back
+// an ICMP port unreachable. Otherwise it is open|filtered. This will fail the test on any other error.
+func ProbeUDPPort(t testing.TestingT, address string, payload []byte, timeout time.Duration) UDPProbeResult {
+	result, err := ProbeUDPPortE(t, address, payload, timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// ProbeUDPPortE sends the given payload to the given address (host:port) and waits up to timeout for a response. Since
+// UDP is connectionless, the port is only reported as open if something answers, and as closed if the host sends back
+// an ICMP port unreachable. Otherwise it is open|filtered. Returns an error on any other failure.
+func ProbeUDPPortE(t testing.TestingT, address string, payload []byte, timeout time.Duration) (UDPProbeResult, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return UDPProbeResult{}, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return UDPProbeResult{}, err
+	}
+	if _, err := conn.Write(payload); err != nil {
+		return udpProbeResultFromError(err)
+	}
+
+	buffer := make([]byte, 65535)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return udpProbeResultFromError(err)
+	}
+	return UDPProbeResult{State: PortOpen, Response: buffer[:n]}, nil
+}
+
+// WaitForUDPResponse repeatedly sends the given payload to the given address (host:port) until a response comes back
+// or maxRetries has been exceeded, and returns that response. This will fail the test if nothing ever answers.
+func WaitForUDPResponse(t testing.TestingT, address string, payload []byte, maxRetries int, sleepBetweenRetries time.Duration) []byte {
+	response, err := WaitForUDPResponseE(t, address, payload, maxRetries, sleepBetweenRetries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// WaitForUDPResponseE repeatedly sends the given payload to the given address (host:port) until a response comes back
+// or maxRetries has been exceeded, and returns that response. Returns an error if nothing ever answers.
+func WaitForUDPResponseE(t testing.TestingT, address string, payload []byte, maxRetries int, sleepBetweenRetries time.Duration) ([]byte, error) {
+	policy := retry.Policy{
+		Description:         fmt.Sprintf("Waiting for a response from UDP port %s", address),
+		MaxRetries:          maxRetries,
+		SleepBetweenRetries: sleepBetweenRetries,
+	}
+	return retry.DoE(t, policy, func() ([]byte, error) {
+		result, err := ProbeUDPPortE(t, address, payload, DefaultDialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if result.State != PortOpen {
+			return nil, NoUDPResponseError{Address: address, State: result.State, Timeout: DefaultDialTimeout}
+		}
+		return result.Response, nil
+	})
+}
+
+// udpProbeResultFromError maps the error returned while writing or reading a UDP datagram to the state of the port.
+func udpProbeResultFromError(err error) (UDPProbeResult, error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		// Unlike with TCP, silence is also what an open port ignoring the payload looks like
+		return UDPProbeResult{State: PortOpenOrFiltered}, nil
+	}
+
+	state, err := classifyDialError(err)
+	if err != nil {
+		return UDPProbeResult{}, err
+	}
+	return UDPProbeResult{State: state}, nil
+}
@@ -0,0 +1,60 @@
+package net_helper
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeUDPPort(t *testing.T) {
+	t.Parallel()
+
+	echo := startUDPServer(t, func(payload []byte) []byte { return append([]byte("echo "), payload...) })
+	silent := startUDPServer(t, func(payload []byte) []byte { return nil })
+	closed := closedUDPAddress(t)
+
+	result := ProbeUDPPort(t, echo, []byte("ping"), time.Second)
+	assert.Equal(t, UDPProbeResult{State: PortOpen, Response: []byte("echo ping")}, result)
+
+	assert.Equal(t, UDPProbeResult{State: PortOpenOrFiltered}, ProbeUDPPort(t, silent, []byte("ping"), 100*time.Millisecond))
+	assert.Equal(t, UDPProbeResult{State: PortClosed}, ProbeUDPPort(t, closed, []byte("ping"), time.Second))
+
+	assert.Equal(t, []byte("echo hello"), WaitForUDPResponse(t, echo, []byte("hello"), 1, 10*time.Millisecond))
+	_, err := WaitForUDPResponseE(t, closed, []byte("hello"), 1, 10*time.Millisecond)
+	assert.Error(t, err)
+}
+
+// startUDPServer starts a UDP server on a local port that answers each datagram with the output of handler, unless it
+// returns nil, and returns its address.
+func startUDPServer(t *testing.T, handler func(payload []byte) []byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buffer := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			if response := handler(buffer[:n]); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// closedUDPAddress returns the address of a local UDP port that nothing listens on.
+func closedUDPAddress(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := conn.LocalAddr().String()
+	require.NoError(t, conn.Close())
+	return address
+}
@@ -97,6 +97,7 @@ func BuildArtifactE(t testing.TestingT, options *Options) (string, error) {
 		Args:       formatPackerArgs(options),
 		Env:        options.Env,
 		WorkingDir: options.WorkingDir,
+		Logger:     options.Logger.WithFields(logger.Fields{logger.FieldModule: "packer"}),
 	}
 
 	description := fmt.Sprintf("%s %v", cmd.Command, cmd.Args)
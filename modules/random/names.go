@@ -0,0 +1,277 @@
+package random
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// SeedEnvVar is the environment variable used to set the seed of the names generated by UniqueName. Every run logs
+// the seed it used, so setting this to the seed of a failed run reproduces the names of that run.
+const SeedEnvVar = "TERRATEST_RANDOM_SEED"
+
+const (
+	nameChars      = "0123456789abcdefghijklmnopqrstuvwxyz"
+	nameLetters    = "abcdefghijklmnopqrstuvwxyz"
+	runIDLength    = 4
+	nameIDLength   = 6 // Should be good for 36^6 = 2+ billion combinations per test and prefix
+	minTestNameLen = 3
+)
+
+// NameProfile describes the naming rules of a type of resource. Names generated by UniqueName always start and end
+// with a letter or digit.
+type NameProfile struct {
+	// Name of the profile, used in error messages.
+	Name string
+	// MinLength is the minimum length of names.
+	MinLength int
+	// MaxLength is the maximum length of names.
+	MaxLength int
+	// Lowercase is true if names can't contain uppercase letters.
+	Lowercase bool
+	// AllowedChars are the characters, besides letters and digits, that names can contain.
+	AllowedChars string
+	// Separator is put between the parts of names and replaces characters that are not allowed. If empty, the parts
+	// are concatenated and characters that are not allowed are removed.
+	Separator string
+	// StartWithLetter is true if names must start with a letter.
+	StartWithLetter bool
+}
+
+var (
+	// S3BucketNameProfile is for S3 bucket names: 3-63 lowercase letters, digits and hyphens.
+	S3BucketNameProfile = NameProfile{Name: "S3 bucket", MinLength: 3, MaxLength: 63, Lowercase: true, AllowedChars: "-", Separator: "-"}
+	// AzureStorageAccountNameProfile is for Azure storage account names: 3-24 lowercase letters and digits.
+	AzureStorageAccountNameProfile = NameProfile{Name: "Azure storage account", MinLength: 3, MaxLength: 24, Lowercase: true}
+	// KubernetesNameProfile is for the names of most Kubernetes resources, which must be DNS-1123 labels: up to 63
+	// lowercase letters, digits and hyphens.
+	KubernetesNameProfile = NameProfile{Name: "Kubernetes", MinLength: 1, MaxLength: 63, Lowercase: true, AllowedChars: "-", Separator: "-"}
+	// IAMNameProfile is for AWS IAM role and user names: up to 64 letters, digits and +=,.@_- characters.
+	IAMNameProfile = NameProfile{Name: "IAM", MinLength: 1, MaxLength: 64, AllowedChars: "+=,.@_-", Separator: "-"}
+	// GCPNameProfile is for the names of most GCP resources, such as Compute Instances: up to 63 lowercase letters,
+	// digits and hyphens, starting with a letter.
+	GCPNameProfile = NameProfile{Name: "GCP", MinLength: 1, MaxLength: 63, Lowercase: true, AllowedChars: "-", Separator: "-", StartWithLetter: true}
+)
+
+// UniqueName returns a name for a resource that follows the rules of the given profile and is made of the given prefix,
+// the name of the current test, the ID of the current run (see RunId) and a unique ID, e.g.
+// "myapp-testfoo-k3x9-a8b2cd". The test name is shortened, or left out, if the name would be too long. The names are
+// derived from a seed that is logged on first use, and that can be set with the TERRATEST_RANDOM_SEED environment
+// variable to reproduce the names of a previous run. This will fail the test if the prefix is too long for the profile.
+func UniqueName(t testing.TestingT, prefix string, profile NameProfile) string {
+	name, err := UniqueNameE(t, prefix, profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// UniqueNameE returns a name for a resource that follows the rules of the given profile and is made of the given
+// prefix, the name of the current test, the ID of the current run (see RunId) and a unique ID. See UniqueName.
+func UniqueNameE(t testing.TestingT, prefix string, profile NameProfile) (string, error) {
+	seed, err := nameSeedE(t)
+	if err != nil {
+		return "", err
+	}
+
+	prefix = profile.sanitize(prefix)
+	testName := profile.sanitize(t.Name())
+
+	index := nextNameIndex(seed, profile.Name, prefix, t.Name())
+	uniqueID := nameID(fmt.Sprintf("%d/%s/%s/%s/%d", seed, profile.Name, prefix, t.Name(), index))
+
+	// Fit the prefix and the test name into what's left of the maximum length after the IDs and separators
+	budget := profile.MaxLength - runIDLength - len(profile.Separator) - nameIDLength
+	if prefix != "" {
+		budget -= len(prefix) + len(profile.Separator)
+		if budget < 0 {
+			return "", NameTooLongError{Prefix: prefix, Profile: profile.Name, MaxLength: profile.MaxLength}
+		}
+	}
+	if testName != "" {
+		budget -= len(profile.Separator)
+		if budget < minTestNameLen {
+			testName = ""
+		} else if len(testName) > budget {
+			testName = profile.trim(testName[:budget])
+		}
+	}
+
+	var parts []string
+	for _, part := range []string{prefix, testName, runID(seed), uniqueID} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	name := strings.Join(parts, profile.Separator)
+
+	if err := profile.Validate(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Validate returns an error if the given name doesn't follow the rules of the profile.
+func (profile NameProfile) Validate(name string) error {
+	if len(name) < profile.MinLength || len(name) > profile.MaxLength {
+		return InvalidNameError{Name: name, Profile: profile.Name, Reason: fmt.Sprintf("length must be between %d and %d", profile.MinLength, profile.MaxLength)}
+	}
+	for _, char := range name {
+		if !profile.isAllowed(char) {
+			return InvalidNameError{Name: name, Profile: profile.Name, Reason: fmt.Sprintf("character %q is not allowed", char)}
+		}
+	}
+	if name != "" {
+		if profile.StartWithLetter && !isLetter(rune(name[0])) {
+			return InvalidNameError{Name: name, Profile: profile.Name, Reason: "must start with a letter"}
+		}
+		if !isAlphanumeric(rune(name[0])) || !isAlphanumeric(rune(name[len(name)-1])) {
+			return InvalidNameError{Name: name, Profile: profile.Name, Reason: "must start and end with a letter or digit"}
+		}
+	}
+	return nil
+}
+
+// RunId returns the ID of the current run, which is part of all the names returned by UniqueName, so that all the
+// resources created by a run can be found. The ID is derived from the seed, see UniqueName.
+func RunId(t testing.TestingT) string {
+	seed, err := nameSeedE(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runID(seed)
+}
+
+// sanitize converts the given string to the characters allowed by the profile, replacing other characters with the
+// separator.
+func (profile NameProfile) sanitize(value string) string {
+	if profile.Lowercase {
+		value = strings.ToLower(value)
+	}
+
+	var out strings.Builder
+	for _, char := range value {
+		switch {
+		case profile.isAllowed(char) && !strings.ContainsRune(profile.Separator, char):
+			out.WriteRune(char)
+		case profile.Separator != "" && !strings.HasSuffix(out.String(), profile.Separator):
+			out.WriteString(profile.Separator)
+		}
+	}
+	return profile.trim(out.String())
+}
+
+// trim removes the characters at the start and end of the given string that are not letters or digits, or that are
+// not letters at the start if the profile requires names to start with a letter.
+func (profile NameProfile) trim(value string) string {
+	value = strings.TrimRightFunc(value, func(char rune) bool { return !isAlphanumeric(char) })
+	return strings.TrimLeftFunc(value, func(char rune) bool {
+		return !isAlphanumeric(char) || (profile.StartWithLetter && !isLetter(char))
+	})
+}
+
+func (profile NameProfile) isAllowed(char rune) bool {
+	if char > unicode.MaxASCII {
+		return false
+	}
+	if profile.Lowercase && unicode.IsUpper(char) {
+		return false
+	}
+	return isAlphanumeric(char) || strings.ContainsRune(profile.AllowedChars, char)
+}
+
+func isLetter(char rune) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')
+}
+
+func isAlphanumeric(char rune) bool {
+	return isLetter(char) || (char >= '0' && char <= '9')
+}
+
+// runID returns the run ID for the given seed, which starts with a letter so that names without a prefix and test
+// name are valid for profiles that require a leading letter.
+func runID(seed int64) string {
+	id := nameID(fmt.Sprintf("%d/run", seed))
+	return string(nameLetters[strings.IndexByte(nameChars, id[0])%len(nameLetters)]) + id[1:runIDLength]
+}
+
+// nameID returns an ID of nameIDLength lowercase letters and digits derived from the given key.
+func nameID(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	value := binary.BigEndian.Uint64(hash[:8])
+
+	var out strings.Builder
+	for i := 0; i < nameIDLength; i++ {
+		out.WriteByte(nameChars[value%uint64(len(nameChars))])
+		value /= uint64(len(nameChars))
+	}
+	return out.String()
+}
+
+var (
+	defaultNameSeed     int64
+	defaultNameSeedOnce sync.Once
+
+	nameIndexesLock sync.Mutex
+	nameIndexes     = map[string]int{}
+)
+
+// nameSeedE returns the seed set with TERRATEST_RANDOM_SEED or, if it's not set, a random seed chosen and logged once
+// per process.
+func nameSeedE(t testing.TestingT) (int64, error) {
+	if value := os.Getenv(SeedEnvVar); value != "" {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value for %s: %q is not an integer", SeedEnvVar, value)
+		}
+		return seed, nil
+	}
+
+	defaultNameSeedOnce.Do(func() {
+		defaultNameSeed = newRand().Int63()
+		logger.Logf(t, "Generating resource names with seed %d. Set %s=%d to reproduce them.", defaultNameSeed, SeedEnvVar, defaultNameSeed)
+	})
+	return defaultNameSeed, nil
+}
+
+// nextNameIndex returns how many names have been generated before for the given seed, profile, prefix and test, so
+// that repeated calls from the same test return different names, in a reproducible order.
+func nextNameIndex(seed int64, profile string, prefix string, testName string) int {
+	nameIndexesLock.Lock()
+	defer nameIndexesLock.Unlock()
+
+	key := fmt.Sprintf("%d/%s/%s/%s", seed, profile, prefix, testName)
+	index := nameIndexes[key]
+	nameIndexes[key]++
+	return index
+}
+
+// NameTooLongError is returned when a name prefix doesn't fit in the maximum length of a profile.
+type NameTooLongError struct {
+	Prefix    string
+	Profile   string
+	MaxLength int
+}
+
+func (err NameTooLongError) Error() string {
+	return fmt.Sprintf("Prefix %q is too long for %s names, which can be at most %d characters long including a unique ID", err.Prefix, err.Profile, err.MaxLength)
+}
+
+// InvalidNameError is returned when a name doesn't follow the rules of a profile.
+type InvalidNameError struct {
+	Name    string
+	Profile string
+	Reason  string
+}
+
+func (err InvalidNameError) Error() string {
+	return fmt.Sprintf("%q is not a valid %s name: %s", err.Name, err.Profile, err.Reason)
+}
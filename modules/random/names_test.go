@@ -0,0 +1,111 @@
+package random
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUniqueNameFollowsProfiles(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		profile  NameProfile
+		prefix   string
+		expected string
+	}{
+		{S3BucketNameProfile, "My_App", "my-app-testuniquenamefollowsprofiles-"},
+		{AzureStorageAccountNameProfile, "my-app", "myapptestuniq"},
+		{KubernetesNameProfile, "--my.app--", "my-app-testuniquenamefollowsprofiles-"},
+		{IAMNameProfile, "My_App", "My_App-TestUniqueNameFollowsProfiles-"},
+		{GCPNameProfile, "1app", "app-testuniquenamefollowsprofiles-"},
+		{GCPNameProfile, "", "testuniquenamefollowsprofiles-"},
+	}
+
+	for _, testCase := range testCases {
+		for i := 0; i < 100; i++ {
+			name := UniqueName(t, testCase.prefix, testCase.profile)
+			assert.NoError(t, testCase.profile.Validate(name))
+			assert.True(t, strings.HasPrefix(name, testCase.expected), "%s does not start with %s", name, testCase.expected)
+			assert.Contains(t, name, RunId(t))
+		}
+	}
+}
+
+func TestUniqueNameIsUnique(t *testing.T) {
+	t.Parallel()
+
+	previouslySeen := map[string]bool{}
+
+	for i := 0; i < 1000; i++ {
+		name := UniqueName(t, "app", AzureStorageAccountNameProfile)
+		assert.Len(t, name, 24)
+		assert.NotContains(t, previouslySeen, name)
+
+		previouslySeen[name] = true
+	}
+}
+
+func TestUniqueNameShortensTestName(t *testing.T) {
+	t.Parallel()
+
+	name := UniqueName(t, "app", NameProfile{Name: "short", MaxLength: 20, Lowercase: true, AllowedChars: "-", Separator: "-"})
+	assert.Regexp(t, `^app-test-[a-z][a-z0-9]{3}-[a-z0-9]{6}$`, name)
+
+	name = UniqueName(t, "app", NameProfile{Name: "shorter", MaxLength: 16, Lowercase: true, AllowedChars: "-", Separator: "-"})
+	assert.Regexp(t, `^app-[a-z][a-z0-9]{3}-[a-z0-9]{6}$`, name)
+
+	_, err := UniqueNameE(t, "application", NameProfile{Name: "shortest", MaxLength: 16, Lowercase: true, AllowedChars: "-", Separator: "-"})
+	assert.Equal(t, NameTooLongError{Prefix: "application", Profile: "shortest", MaxLength: 16}, err)
+}
+
+func TestUniqueNameIsReproducibleWithSeed(t *testing.T) {
+	t.Setenv(SeedEnvVar, "12345")
+
+	first := []string{UniqueName(t, "app", S3BucketNameProfile), UniqueName(t, "app", S3BucketNameProfile)}
+	assert.NotEqual(t, first[0], first[1])
+
+	// Forget the names generated so far, as if this were a new run
+	nameIndexesLock.Lock()
+	nameIndexes = map[string]int{}
+	nameIndexesLock.Unlock()
+
+	second := []string{UniqueName(t, "app", S3BucketNameProfile), UniqueName(t, "app", S3BucketNameProfile)}
+	assert.Equal(t, first, second)
+
+	t.Setenv(SeedEnvVar, "54321")
+	assert.NotEqual(t, first[0], UniqueName(t, "app", S3BucketNameProfile))
+
+	t.Setenv(SeedEnvVar, "abc")
+	_, err := UniqueNameE(t, "app", S3BucketNameProfile)
+	require.Error(t, err)
+}
+
+func TestNameProfileValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		profile NameProfile
+		name    string
+		valid   bool
+	}{
+		{S3BucketNameProfile, "my-bucket-1", true},
+		{S3BucketNameProfile, "My-Bucket", false},
+		{S3BucketNameProfile, "my_bucket", false},
+		{S3BucketNameProfile, "ab", false},
+		{S3BucketNameProfile, "my-bucket-", false},
+		{AzureStorageAccountNameProfile, "mystorageaccount123", true},
+		{AzureStorageAccountNameProfile, "my-storage", false},
+		{AzureStorageAccountNameProfile, "mystorageaccount12345678", true},
+		{AzureStorageAccountNameProfile, "mystorageaccount123456789", false},
+		{GCPNameProfile, "1instance", false},
+		{IAMNameProfile, "My.Role@Name", true},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.profile.Validate(testCase.name)
+		assert.Equal(t, testCase.valid, err == nil, "%s: %v", testCase.name, err)
+	}
+}
@@ -2,8 +2,10 @@
 package retry
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/stretchr/testify/require"
@@ -50,61 +52,132 @@ func DoWithTimeoutE(t testing.TestingT, actionDescription string, timeout time.D
 	}
 }
 
-// DoWithRetry runs the specified action. If it returns a string, return that string. If it returns a FatalError, return that error
-// immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
-// maxRetries retries. If maxRetries is exceeded, fail the test.
-func DoWithRetry(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) string {
-	out, err := DoWithRetryE(t, actionDescription, maxRetries, sleepBetweenRetries, action)
+// Policy describes how an action should be retried: the description used in log output and errors, the maximum
+// number of retries, and how long to sleep between attempts.
+type Policy struct {
+	Description         string
+	MaxRetries          int
+	SleepBetweenRetries time.Duration
+}
+
+// Do runs the specified action. If it returns a value, return that value. If it returns a FatalError, fail the test
+// immediately. If it returns any other type of error, sleep for policy.SleepBetweenRetries and try again, up to a
+// maximum of policy.MaxRetries retries. If MaxRetries is exceeded, fail the test.
+func Do[T any](t testing.TestingT, policy Policy, action func() (T, error)) T {
+	out, err := DoE(t, policy, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// DoE runs the specified action. If it returns a value, return that value. If it returns a FatalError, return that
+// error immediately. If it returns any other type of error, sleep for policy.SleepBetweenRetries and try again, up to a
+// maximum of policy.MaxRetries retries. If MaxRetries is exceeded, return a MaxRetriesExceeded error.
+func DoE[T any](t testing.TestingT, policy Policy, action func() (T, error)) (T, error) {
+	var output T
+	var err error
+	var attempts []Attempt
+
+	for i := 0; i <= policy.MaxRetries; i++ {
+		log := logger.Default.WithFields(logger.Fields{logger.FieldAttempt: i + 1})
+		log.Logf(t, "%s", policy.Description)
+
+		startTime := time.Now()
+		output, err = action()
+		if err == nil {
+			return output, nil
+		}
+
+		if IsFatalError(err) {
+			log.Logf(t, "Returning due to fatal error: %v", err)
+			return output, err
+		}
+
+		attempts = append(attempts, newAttempt(i+1, startTime, err))
+
+		log.Warnf(t, "%s returned an error: %s. Sleeping for %s and will try again.", policy.Description, err.Error(), policy.SleepBetweenRetries)
+		time.Sleep(policy.SleepBetweenRetries)
+	}
+
+	return output, MaxRetriesExceeded{Description: policy.Description, MaxRetries: policy.MaxRetries, Attempts: attempts}
+}
+
+// newAttempt records a failed attempt that started at startTime and just returned the given error. If the error was
+// classified as retryable by DoWithRetryableErrorsE, the matching entry is recorded alongside the original error.
+func newAttempt(number int, startTime time.Time, err error) Attempt {
+	attempt := Attempt{
+		Number:    number,
+		Error:     err,
+		StartTime: startTime,
+		Duration:  time.Since(startTime),
+	}
+
+	var retryable retryableError
+	if errors.As(err, &retryable) {
+		attempt.Error = retryable.Underlying
+		attempt.RetryableErrorPattern = retryable.Pattern
+		attempt.RetryableErrorMessage = retryable.Message
+	}
+
+	return attempt
+}
+
+// Eventually runs the specified action until it returns a value without an error that satisfies the given predicate,
+// and returns that value. Errors are handled the same way as in Do. Fails the test if the predicate is still not
+// satisfied after policy.MaxRetries retries.
+func Eventually[T any](t testing.TestingT, policy Policy, action func() (T, error), predicate func(T) bool) T {
+	out, err := EventuallyE(t, policy, action, predicate)
 	if err != nil {
 		t.Fatal(err)
 	}
 	return out
 }
 
+// EventuallyE runs the specified action until it returns a value without an error that satisfies the given predicate,
+// and returns that value. Errors are handled the same way as in DoE. Returns a MaxRetriesExceeded error if the
+// predicate is still not satisfied after policy.MaxRetries retries.
+func EventuallyE[T any](t testing.TestingT, policy Policy, action func() (T, error), predicate func(T) bool) (T, error) {
+	return DoE(t, policy, func() (T, error) {
+		output, err := action()
+		if err != nil {
+			return output, err
+		}
+
+		if !predicate(output) {
+			return output, PredicateNotSatisfied{Description: policy.Description}
+		}
+
+		return output, nil
+	})
+}
+
+// DoWithRetry runs the specified action. If it returns a string, return that string. If it returns a FatalError, return that error
+// immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
+// maxRetries retries. If maxRetries is exceeded, fail the test.
+func DoWithRetry(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) string {
+	return Do(t, Policy{Description: actionDescription, MaxRetries: maxRetries, SleepBetweenRetries: sleepBetweenRetries}, action)
+}
+
 // DoWithRetryE runs the specified action. If it returns a string, return that string. If it returns a FatalError, return that error
 // immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
 // maxRetries retries. If maxRetries is exceeded, return a MaxRetriesExceeded error.
 func DoWithRetryE(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) (string, error) {
-	out, err := DoWithRetryInterfaceE(t, actionDescription, maxRetries, sleepBetweenRetries, func() (interface{}, error) { return action() })
-	return out.(string), err
+	return DoE(t, Policy{Description: actionDescription, MaxRetries: maxRetries, SleepBetweenRetries: sleepBetweenRetries}, action)
 }
 
 // DoWithRetryInterface runs the specified action. If it returns a value, return that value. If it returns a FatalError, return that error
 // immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
 // maxRetries retries. If maxRetries is exceeded, fail the test.
 func DoWithRetryInterface(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (interface{}, error)) interface{} {
-	out, err := DoWithRetryInterfaceE(t, actionDescription, maxRetries, sleepBetweenRetries, action)
-	if err != nil {
-		t.Fatal(err)
-	}
-	return out
+	return Do(t, Policy{Description: actionDescription, MaxRetries: maxRetries, SleepBetweenRetries: sleepBetweenRetries}, action)
 }
 
 // DoWithRetryInterfaceE runs the specified action. If it returns a value, return that value. If it returns a FatalError, return that error
 // immediately. If it returns any other type of error, sleep for sleepBetweenRetries and try again, up to a maximum of
 // maxRetries retries. If maxRetries is exceeded, return a MaxRetriesExceeded error.
 func DoWithRetryInterfaceE(t testing.TestingT, actionDescription string, maxRetries int, sleepBetweenRetries time.Duration, action func() (interface{}, error)) (interface{}, error) {
-	var output interface{}
-	var err error
-
-	for i := 0; i <= maxRetries; i++ {
-		logger.Log(t, actionDescription)
-
-		output, err = action()
-		if err == nil {
-			return output, nil
-		}
-
-		if _, isFatalErr := err.(FatalError); isFatalErr {
-			logger.Logf(t, "Returning due to fatal error: %v", err)
-			return output, err
-		}
-
-		logger.Logf(t, "%s returned an error: %s. Sleeping for %s and will try again.", actionDescription, err.Error(), sleepBetweenRetries)
-		time.Sleep(sleepBetweenRetries)
-	}
-
-	return output, MaxRetriesExceeded{Description: actionDescription, MaxRetries: maxRetries}
+	return DoE(t, Policy{Description: actionDescription, MaxRetries: maxRetries, SleepBetweenRetries: sleepBetweenRetries}, action)
 }
 
 // DoWithRetryableErrors runs the specified action. If it returns a value, return that value. If it returns an error,
@@ -112,7 +185,7 @@ func DoWithRetryInterfaceE(t testing.TestingT, actionDescription string, maxRetr
 // matches any of the regular expressions in the specified retryableErrors map. If there is a match, sleep for
 // sleepBetweenRetries, and retry the specified action, up to a maximum of maxRetries retries. If there is no match,
 // return that error immediately, wrapped in a FatalError. If maxRetries is exceeded, return a MaxRetriesExceeded error.
-func DoWithRetryableErrors(t testing.TestingT, actionDescription string, retryableErrors map[string]string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) string {
+func DoWithRetryableErrors[T any](t testing.TestingT, actionDescription string, retryableErrors map[string]string, maxRetries int, sleepBetweenRetries time.Duration, action func() (T, error)) T {
 	out, err := DoWithRetryableErrorsE(t, actionDescription, retryableErrors, maxRetries, sleepBetweenRetries, action)
 	require.NoError(t, err)
 	return out
@@ -123,26 +196,31 @@ func DoWithRetryableErrors(t testing.TestingT, actionDescription string, retryab
 // matches any of the regular expressions in the specified retryableErrors map. If there is a match, sleep for
 // sleepBetweenRetries, and retry the specified action, up to a maximum of maxRetries retries. If there is no match,
 // return that error immediately, wrapped in a FatalError. If maxRetries is exceeded, return a MaxRetriesExceeded error.
-func DoWithRetryableErrorsE(t testing.TestingT, actionDescription string, retryableErrors map[string]string, maxRetries int, sleepBetweenRetries time.Duration, action func() (string, error)) (string, error) {
+// The output is only matched against the regular expressions when it is a string or a byte slice.
+func DoWithRetryableErrorsE[T any](t testing.TestingT, actionDescription string, retryableErrors map[string]string, maxRetries int, sleepBetweenRetries time.Duration, action func() (T, error)) (T, error) {
 	retryableErrorsRegexp := map[*regexp.Regexp]string{}
 	for errorStr, errorMessage := range retryableErrors {
 		errorRegex, err := regexp.Compile(errorStr)
 		if err != nil {
-			return "", FatalError{Underlying: err}
+			var zero T
+			return zero, FatalError{Underlying: err}
 		}
 		retryableErrorsRegexp[errorRegex] = errorMessage
 	}
 
-	return DoWithRetryE(t, actionDescription, maxRetries, sleepBetweenRetries, func() (string, error) {
+	policy := Policy{Description: actionDescription, MaxRetries: maxRetries, SleepBetweenRetries: sleepBetweenRetries}
+
+	return DoE(t, policy, func() (T, error) {
 		output, err := action()
 		if err == nil {
 			return output, nil
 		}
 
+		outputStr := outputString(output)
 		for errorRegexp, errorMessage := range retryableErrorsRegexp {
-			if errorRegexp.MatchString(output) || errorRegexp.MatchString(err.Error()) {
+			if errorRegexp.MatchString(outputStr) || errorRegexp.MatchString(err.Error()) {
 				logger.Logf(t, "'%s' failed with the error '%s' but this error was expected and warrants a retry. Further details: %s\n", actionDescription, err.Error(), errorMessage)
-				return output, err
+				return output, retryableError{Underlying: err, Pattern: errorRegexp.String(), Message: errorMessage}
 			}
 		}
 
@@ -150,6 +228,19 @@ func DoWithRetryableErrorsE(t testing.TestingT, actionDescription string, retrya
 	})
 }
 
+// outputString returns the output of an action as a string, so it can be matched against retryable errors. Outputs
+// that are neither strings nor byte slices are returned as an empty string.
+func outputString(output interface{}) string {
+	switch value := output.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	default:
+		return ""
+	}
+}
+
 // Done can be stopped.
 type Done struct {
 	stop chan bool
@@ -198,14 +289,72 @@ func (err TimeoutExceeded) Error() string {
 	return fmt.Sprintf("'%s' did not complete before timeout of %s", err.Description, err.Timeout)
 }
 
-// MaxRetriesExceeded is an error that occurs when the maximum amount of retries is exceeded.
+// MaxRetriesExceeded is an error that occurs when the maximum amount of retries is exceeded. It records every failed
+// attempt, and unwraps to the error returned by the last one, so errors.Is and errors.As can be used to inspect it.
 type MaxRetriesExceeded struct {
 	Description string
 	MaxRetries  int
+	Attempts    []Attempt
 }
 
+// attemptsShownInError is how many of the first and of the last attempts are listed in the message of a
+// MaxRetriesExceeded error. The attempts in between are only counted, so that the message stays readable with many
+// retries.
+const attemptsShownInError = 3
+
 func (err MaxRetriesExceeded) Error() string {
-	return fmt.Sprintf("'%s' unsuccessful after %d retries", err.Description, err.MaxRetries)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "'%s' unsuccessful after %d retries", err.Description, err.MaxRetries)
+	for i, attempt := range err.Attempts {
+		if i >= attemptsShownInError && i < len(err.Attempts)-attemptsShownInError {
+			if i == attemptsShownInError {
+				fmt.Fprintf(&sb, "\n... %d more attempts ...", len(err.Attempts)-2*attemptsShownInError)
+			}
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s", attempt)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the error returned by the last attempt.
+func (err MaxRetriesExceeded) Unwrap() error {
+	if len(err.Attempts) == 0 {
+		return nil
+	}
+	return err.Attempts[len(err.Attempts)-1].Error
+}
+
+// Attempt records the outcome of a single failed attempt to run a retried action.
+type Attempt struct {
+	// Number is the 1-based index of the attempt.
+	Number int
+	// Error is the error returned by the action.
+	Error     error
+	StartTime time.Time
+	Duration  time.Duration
+	// RetryableErrorPattern is the key of the retryableErrors map passed to DoWithRetryableErrorsE that matched the
+	// error, and RetryableErrorMessage is its value. Both are empty when the error was not classified.
+	RetryableErrorPattern string
+	RetryableErrorMessage string
+}
+
+func (attempt Attempt) String() string {
+	description := fmt.Sprintf("attempt %d at %s (took %s): %v", attempt.Number, attempt.StartTime.Format(time.RFC3339), attempt.Duration, attempt.Error)
+	if attempt.RetryableErrorPattern != "" {
+		description += fmt.Sprintf(" [matched retryable error '%s': %s]", attempt.RetryableErrorPattern, attempt.RetryableErrorMessage)
+	}
+	return description
+}
+
+// PredicateNotSatisfied is an error that occurs when the output of an action does not satisfy the predicate passed to
+// Eventually.
+type PredicateNotSatisfied struct {
+	Description string
+}
+
+func (err PredicateNotSatisfied) Error() string {
+	return fmt.Sprintf("output of '%s' did not satisfy the predicate", err.Description)
 }
 
 // FatalError is a marker interface for errors that should not be retried.
@@ -216,3 +365,30 @@ type FatalError struct {
 func (err FatalError) Error() string {
 	return fmt.Sprintf("FatalError{Underlying: %v}", err.Underlying)
 }
+
+// Unwrap returns the underlying error.
+func (err FatalError) Unwrap() error {
+	return err.Underlying
+}
+
+// IsFatalError returns true if the given error is, or wraps, a FatalError.
+func IsFatalError(err error) bool {
+	var fatalErr FatalError
+	return errors.As(err, &fatalErr)
+}
+
+// retryableError wraps an error that matched one of the retryable errors passed to DoWithRetryableErrorsE, so the
+// match can be recorded in the attempt history.
+type retryableError struct {
+	Underlying error
+	Pattern    string
+	Message    string
+}
+
+func (err retryableError) Error() string {
+	return err.Underlying.Error()
+}
+
+func (err retryableError) Unwrap() error {
+	return err.Underlying
+}
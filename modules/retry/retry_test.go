@@ -1,11 +1,14 @@
 package retry
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDoWithRetry(t *testing.T) {
@@ -49,7 +52,7 @@ func TestDoWithRetry(t *testing.T) {
 			actualOutput, err := DoWithRetryE(t, testCase.description, testCase.maxRetries, 1*time.Millisecond, testCase.action)
 			assert.Equal(t, expectedOutput, actualOutput)
 			if testCase.expectedError != nil {
-				assert.Equal(t, testCase.expectedError, err)
+				assertRetryErrorEqual(t, testCase.expectedError, err)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, expectedOutput, actualOutput)
@@ -247,7 +250,7 @@ func TestDoWithRetryableErrors(t *testing.T) {
 			actualOutput, err := DoWithRetryableErrorsE(t, testCase.description, testCase.retryableErrors, testCase.maxRetries, 1*time.Millisecond, testCase.action)
 			assert.Equal(t, expectedOutput, actualOutput)
 			if testCase.expectedError != nil {
-				assert.Equal(t, testCase.expectedError, err)
+				assertRetryErrorEqual(t, testCase.expectedError, err)
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, expectedOutput, actualOutput)
@@ -256,8 +259,181 @@ func TestDoWithRetryableErrors(t *testing.T) {
 	}
 }
 
+// assertRetryErrorEqual asserts that actual equals expected. For MaxRetriesExceeded errors, it also checks that one
+// attempt was recorded per try, rather than comparing the attempt history itself, as that contains timings.
+func assertRetryErrorEqual(t *testing.T, expected error, actual error) {
+	expectedMaxRetriesErr, isMaxRetriesErr := expected.(MaxRetriesExceeded)
+	if !isMaxRetriesErr {
+		assert.Equal(t, expected, actual)
+		return
+	}
+
+	actualMaxRetriesErr, ok := actual.(MaxRetriesExceeded)
+	require.True(t, ok, "expected a MaxRetriesExceeded error, got %v", actual)
+	assert.Equal(t, expectedMaxRetriesErr.Description, actualMaxRetriesErr.Description)
+	assert.Equal(t, expectedMaxRetriesErr.MaxRetries, actualMaxRetriesErr.MaxRetries)
+	assert.Len(t, actualMaxRetriesErr.Attempts, expectedMaxRetriesErr.MaxRetries+1)
+}
+
 type ErrorCounter int
 
 func (count ErrorCounter) Error() string {
 	return fmt.Sprintf("%d", int(count))
 }
+
+type testOutput struct {
+	Name  string
+	Count int
+}
+
+func TestDoWithStructOutput(t *testing.T) {
+	t.Parallel()
+
+	expectedError := fmt.Errorf("expected error")
+	count := 0
+	action := func() (*testOutput, error) {
+		count++
+		if count < 3 {
+			return nil, expectedError
+		}
+		return &testOutput{Name: "expected", Count: count}, nil
+	}
+
+	policy := Policy{Description: t.Name(), MaxRetries: 5, SleepBetweenRetries: 1 * time.Millisecond}
+	actualOutput, err := DoE(t, policy, action)
+	assert.NoError(t, err)
+	assert.Equal(t, &testOutput{Name: "expected", Count: 3}, actualOutput)
+}
+
+func TestDoWithRetryableErrorsStructOutput(t *testing.T) {
+	t.Parallel()
+
+	expectedError := fmt.Errorf("expected error")
+	action := func() (testOutput, error) { return testOutput{Name: "expected"}, expectedError }
+
+	_, err := DoWithRetryableErrorsE(t, t.Name(), map[string]string{"^expected.*$": "match expected error"}, 2, 1*time.Millisecond, action)
+	assertRetryErrorEqual(t, MaxRetriesExceeded{Description: t.Name(), MaxRetries: 2}, err)
+
+	_, err = DoWithRetryableErrorsE(t, t.Name(), map[string]string{"expected": "match the output"}, 2, 1*time.Millisecond, func() (testOutput, error) {
+		return testOutput{Name: "expected"}, fmt.Errorf("some other error")
+	})
+	assert.Equal(t, FatalError{Underlying: fmt.Errorf("some other error")}, err)
+}
+
+func TestEventually(t *testing.T) {
+	t.Parallel()
+
+	createCounter := func() func() (int, error) {
+		count := 0
+		return func() (int, error) {
+			count++
+			return count, nil
+		}
+	}
+
+	testCases := []struct {
+		description    string
+		maxRetries     int
+		expectedOutput int
+		expectedError  error
+	}{
+		{"Predicate satisfied after 5 attempts", 10, 5, nil},
+		{"Predicate not satisfied before retries run out", 3, 4, MaxRetriesExceeded{Description: "Predicate not satisfied before retries run out", MaxRetries: 3}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase // capture range variable for each test case
+
+		t.Run(testCase.description, func(t *testing.T) {
+			t.Parallel()
+
+			policy := Policy{Description: testCase.description, MaxRetries: testCase.maxRetries, SleepBetweenRetries: 1 * time.Millisecond}
+			actualOutput, err := EventuallyE(t, policy, createCounter(), func(count int) bool { return count >= 5 })
+			assert.Equal(t, testCase.expectedOutput, actualOutput)
+			if testCase.expectedError != nil {
+				assertRetryErrorEqual(t, testCase.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMaxRetriesExceededRecordsAttempts(t *testing.T) {
+	t.Parallel()
+
+	count := 0
+	action := func() (string, error) {
+		count++
+		if count == 1 {
+			return "", fmt.Errorf("connection refused")
+		}
+		return "", ErrorCounter(count)
+	}
+	retryableErrors := map[string]string{
+		"connection refused": "the server is not up yet",
+		"^[0-9]+$":           "the error counter is still going",
+	}
+
+	_, err := DoWithRetryableErrorsE(t, t.Name(), retryableErrors, 2, 1*time.Millisecond, action)
+
+	var maxRetriesErr MaxRetriesExceeded
+	require.True(t, errors.As(err, &maxRetriesErr))
+	require.Len(t, maxRetriesErr.Attempts, 3)
+
+	firstAttempt := maxRetriesErr.Attempts[0]
+	assert.Equal(t, 1, firstAttempt.Number)
+	assert.EqualError(t, firstAttempt.Error, "connection refused")
+	assert.Equal(t, "connection refused", firstAttempt.RetryableErrorPattern)
+	assert.Equal(t, "the server is not up yet", firstAttempt.RetryableErrorMessage)
+	assert.False(t, firstAttempt.StartTime.IsZero())
+
+	lastAttempt := maxRetriesErr.Attempts[2]
+	assert.Equal(t, 3, lastAttempt.Number)
+	assert.Equal(t, ErrorCounter(3), lastAttempt.Error)
+	assert.Equal(t, "^[0-9]+$", lastAttempt.RetryableErrorPattern)
+	assert.True(t, lastAttempt.StartTime.After(firstAttempt.StartTime))
+
+	assert.True(t, errors.Is(err, ErrorCounter(3)))
+	var counter ErrorCounter
+	require.True(t, errors.As(err, &counter))
+	assert.Equal(t, ErrorCounter(3), counter)
+
+	assert.Contains(t, err.Error(), "attempt 1 at")
+	assert.Contains(t, err.Error(), "connection refused")
+	assert.Contains(t, err.Error(), "the error counter is still going")
+}
+
+func TestMaxRetriesExceededMessageIsBounded(t *testing.T) {
+	t.Parallel()
+
+	_, err := DoWithRetryE(t, t.Name(), 59, 1*time.Millisecond, func() (string, error) {
+		return "", fmt.Errorf("still failing")
+	})
+
+	var maxRetriesErr MaxRetriesExceeded
+	require.True(t, errors.As(err, &maxRetriesErr))
+	assert.Len(t, maxRetriesErr.Attempts, 60)
+
+	message := err.Error()
+	assert.Equal(t, 2*attemptsShownInError, strings.Count(message, "still failing"))
+	assert.Contains(t, message, "attempt 3 at")
+	assert.NotContains(t, message, "attempt 4 at")
+	assert.Contains(t, message, "... 54 more attempts ...")
+	assert.NotContains(t, message, "attempt 57 at")
+	assert.Contains(t, message, "attempt 58 at")
+	assert.Contains(t, message, "attempt 60 at")
+}
+
+func TestFatalErrorIsDistinguishable(t *testing.T) {
+	t.Parallel()
+
+	underlying := fmt.Errorf("permission denied")
+	_, err := DoWithRetryE(t, t.Name(), 10, 1*time.Millisecond, func() (string, error) {
+		return "", fmt.Errorf("wrapped: %w", FatalError{Underlying: underlying})
+	})
+
+	assert.True(t, IsFatalError(err))
+	assert.True(t, errors.Is(err, underlying))
+	assert.False(t, IsFatalError(MaxRetriesExceeded{Description: t.Name()}))
+}
@@ -95,9 +95,10 @@ func (e *ErrWithCmdOutput) Error() string {
 
 // runCommand runs a shell command and stores each line from stdout and stderr in Output. Depending on the logger, the
 // stdout and stderr of that command will also be printed to the stdout and stderr of this Go program to make debugging
-// easier.
+// easier. The output is logged at debug level, so it can be filtered out while keeping the command being run.
 func runCommand(t testing.TestingT, command Command) (*output, error) {
-	command.Logger.Logf(t, "Running command %s with args %s", command.Command, command.Args)
+	log := command.Logger.WithFields(logger.Fields{logger.FieldCommand: command.Command})
+	log.Infof(t, "Running command %s with args %s", command.Command, command.Args)
 
 	cmd := exec.Command(command.Command, command.Args...)
 	cmd.Dir = command.WorkingDir
@@ -119,7 +120,7 @@ func runCommand(t testing.TestingT, command Command) (*output, error) {
 		return nil, err
 	}
 
-	output, err := readStdoutAndStderr(t, command.Logger, stdout, stderr)
+	output, err := readStdoutAndStderr(t, log, stdout, stderr)
 	if err != nil {
 		return output, err
 	}
@@ -176,7 +177,7 @@ func readData(t testing.TestingT, log *logger.Logger, reader *bufio.Reader, writ
 			break
 		}
 
-		log.Logf(t, line)
+		log.Debugf(t, "%s", line)
 		if _, err := writer.WriteString(line); err != nil {
 			return err
 		}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/gruntwork-io/terratest/modules/collections"
+	"github.com/gruntwork-io/terratest/modules/logger"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/testing"
@@ -15,7 +16,7 @@ func generateCommand(options *Options, args ...string) shell.Command {
 		Args:       args,
 		WorkingDir: options.TerraformDir,
 		Env:        options.EnvVars,
-		Logger:     options.Logger,
+		Logger:     options.Logger.WithFields(logger.Fields{logger.FieldModule: "terraform"}),
 	}
 	return cmd
 }
@@ -0,0 +1,176 @@
+package test_selection
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// goPackage is the part of the output of go list -json used to find the packages that depend on a package.
+type goPackage struct {
+	ImportPath     string
+	Dir            string
+	Standard       bool
+	Deps           []string
+	IgnoredGoFiles []string
+}
+
+// findDependentPackages returns the folders of the packages under the root folder that depend on the packages in the
+// given folders, including through their tests, and the packages in the given folders themselves. All the folders are
+// relative to the root folder. Returns false if the dependencies can't be found, e.g. because a folder is not a package
+// of a Go module under the root folder or go list fails, in which case every package should be considered affected.
+func findDependentPackages(rootFolder string, changedPackages map[string]bool) (map[string]bool, bool) {
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return nil, false
+	}
+	// go list reports the folders of the packages with symlinks resolved
+	absRootFolder, err = filepath.EvalSymlinks(absRootFolder)
+	if err != nil {
+		return nil, false
+	}
+
+	moduleFolders, err := findGoModules(absRootFolder)
+	if err != nil || len(moduleFolders) == 0 {
+		return nil, false
+	}
+
+	// The folders of the packages each package folder depends on
+	dependencies := map[string]map[string]bool{}
+	for _, moduleFolder := range moduleFolders {
+		packages, err := listGoPackages(moduleFolder)
+		if err != nil {
+			return nil, false
+		}
+
+		byImportPath := map[string]goPackage{}
+		for _, pkg := range packages {
+			byImportPath[pkg.ImportPath] = pkg
+		}
+
+		for _, pkg := range packages {
+			folder, err := filepath.Rel(absRootFolder, pkg.Dir)
+			if pkg.Standard || err != nil || folder == ".." || strings.HasPrefix(folder, ".."+string(filepath.Separator)) {
+				continue
+			}
+
+			// The imports of the files excluded by build constraints, e.g. tests that only run with a build tag, are
+			// not part of the dependencies reported by go list
+			deps := append([]string{}, pkg.Deps...)
+			for _, file := range pkg.IgnoredGoFiles {
+				imports, err := parseImports(filepath.Join(pkg.Dir, file))
+				if err != nil {
+					return nil, false
+				}
+				for _, importPath := range imports {
+					deps = append(deps, importPath)
+					deps = append(deps, byImportPath[importPath].Deps...)
+				}
+			}
+
+			if dependencies[folder] == nil {
+				dependencies[folder] = map[string]bool{}
+			}
+			for _, dep := range deps {
+				if depPackage, ok := byImportPath[dep]; ok && !depPackage.Standard {
+					if depFolder, err := filepath.Rel(absRootFolder, depPackage.Dir); err == nil {
+						dependencies[folder][depFolder] = true
+					}
+				}
+			}
+		}
+	}
+
+	for folder := range changedPackages {
+		if _, ok := dependencies[folder]; !ok {
+			return nil, false
+		}
+	}
+
+	dependents := map[string]bool{}
+	for folder, depFolders := range dependencies {
+		if changedPackages[folder] {
+			dependents[folder] = true
+		}
+		for depFolder := range depFolders {
+			if changedPackages[depFolder] {
+				dependents[folder] = true
+			}
+		}
+	}
+	return dependents, true
+}
+
+// findGoModules returns the folders with a go.mod file under the root folder. Hidden, vendor, testdata and
+// node_modules folders are skipped.
+func findGoModules(rootFolder string) ([]string, error) {
+	var folders []string
+	err := filepath.Walk(rootFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != rootFolder && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() == "go.mod" {
+			folders = append(folders, filepath.Dir(path))
+		}
+		return nil
+	})
+	return folders, err
+}
+
+// listGoPackages returns the packages of the Go module in the given folder, including their tests, and all their
+// dependencies, using go list.
+func listGoPackages(moduleFolder string) ([]goPackage, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", "list", "-e", "-deps", "-test", "-json", "./...")
+	cmd.Dir = moduleFolder
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed in %s: %v: %s", moduleFolder, err, stderr.String())
+	}
+
+	var packages []goPackage
+	decoder := json.NewDecoder(&stdout)
+	for {
+		var pkg goPackage
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			return packages, nil
+		} else if err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg)
+	}
+}
+
+// parseImports returns the import paths of the given Go file.
+func parseImports(path string) ([]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []string
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, importPath)
+	}
+	return imports, nil
+}
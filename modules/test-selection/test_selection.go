@@ -0,0 +1,326 @@
+// Package test_selection selects the Go tests to run for the changes to a repo of Terraform modules, so that only the
+// tests of the modules affected by a change run on each pull request.
+//
+// Tests are linked to the modules they test in two ways:
+//
+//   - By convention: string literals in the body of a test function that are paths to Terraform modules, relative to
+//     the folder of the test, e.g. TerraformDir: "../examples/terraform-aws-example". Paths relative to another folder
+//     referenced in the same test are found too, as in CopyTerraformFolderToTemp(t, "../", "examples/aws").
+//   - By annotation: comments with "terratest:module" followed by one or more paths to modules, relative to the folder
+//     of the test, on the test function or at the top of the test file to apply to all the tests in the file, e.g.
+//     "// terratest:module ../modules/vpc ../modules/subnets". Use annotations when the module paths are built
+//     dynamically or passed through helper functions.
+package test_selection
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+)
+
+// ModuleAnnotation is the comment prefix used to link tests to the Terraform modules they test.
+const ModuleAnnotation = "terratest:module"
+
+// Test is a Go test function and the Terraform modules it tests.
+type Test struct {
+	// Package is the folder of the package of the test, relative to the root folder.
+	Package string
+	// Name is the name of the test function.
+	Name string
+	// Modules are the folders of the Terraform modules the test tests, relative to the root folder, in sorted order.
+	Modules []string
+}
+
+// PackageTests are the tests to run in a package.
+type PackageTests struct {
+	// Package is the folder of the package, relative to the root folder.
+	Package string
+	// Tests are the names of the test functions to run, in sorted order.
+	Tests []string
+}
+
+// RunPattern returns the pattern to pass to the -run flag of go test to run only the tests of the package.
+func (packageTests PackageTests) RunPattern() string {
+	return fmt.Sprintf("^(%s)$", strings.Join(packageTests.Tests, "|"))
+}
+
+// SelectTests returns the tests under the root folder that should run for the given changed files, relative to the
+// root folder: the tests of the Terraform modules affected by the changes (see files.AffectedTerraformModules) and
+// the tests in packages with changed Go files or that depend on them, as reported by go list for the Go modules under
+// the root folder. All the tests are selected if a go.mod or go.sum file changed, or if the packages that depend on the
+// changed Go files can't be found, e.g. because go list fails.
+func SelectTests(rootFolder string, changedFiles []string) ([]Test, error) {
+	affected, err := files.AffectedTerraformModules(rootFolder, changedFiles)
+	if err != nil {
+		return nil, err
+	}
+	isAffected := map[string]bool{}
+	for _, module := range affected {
+		isAffected[module] = true
+	}
+
+	changedPackages := map[string]bool{}
+	selectAll := false
+	for _, file := range changedFiles {
+		file = filepath.Clean(filepath.FromSlash(file))
+		switch {
+		case strings.HasSuffix(file, ".go"):
+			changedPackages[filepath.Dir(file)] = true
+		case filepath.Base(file) == "go.mod" || filepath.Base(file) == "go.sum":
+			selectAll = true
+		}
+	}
+
+	dependentPackages := map[string]bool{}
+	if len(changedPackages) > 0 && !selectAll {
+		var found bool
+		dependentPackages, found = findDependentPackages(rootFolder, changedPackages)
+		selectAll = !found
+	}
+
+	tests, err := FindTests(rootFolder)
+	if err != nil {
+		return nil, err
+	}
+	if selectAll {
+		return tests, nil
+	}
+
+	selected := []Test{}
+	for _, test := range tests {
+		if dependentPackages[test.Package] || anyAffected(test.Modules, isAffected) {
+			selected = append(selected, test)
+		}
+	}
+	return selected, nil
+}
+
+func anyAffected(modules []string, isAffected map[string]bool) bool {
+	for _, module := range modules {
+		if isAffected[module] {
+			return true
+		}
+	}
+	return false
+}
+
+// GroupByPackage groups the given tests by package, in sorted order.
+func GroupByPackage(tests []Test) []PackageTests {
+	byPackage := map[string][]string{}
+	for _, test := range tests {
+		byPackage[test.Package] = append(byPackage[test.Package], test.Name)
+	}
+
+	groups := []PackageTests{}
+	for pkg, names := range byPackage {
+		sort.Strings(names)
+		groups = append(groups, PackageTests{Package: pkg, Tests: names})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Package < groups[j].Package })
+	return groups
+}
+
+// FindTests returns the Go test functions in the _test.go files under the root folder, and the Terraform modules
+// under the root folder they test, sorted by package and name. Hidden, vendor, testdata and node_modules folders are
+// skipped.
+func FindTests(rootFolder string) ([]Test, error) {
+	absRootFolder, err := filepath.Abs(rootFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := files.FindTerraformModules(absRootFolder)
+	if err != nil {
+		return nil, err
+	}
+	finder := testFinder{rootFolder: absRootFolder, isModule: map[string]bool{}}
+	for _, module := range modules {
+		finder.isModule[module] = true
+	}
+
+	tests := []Test{}
+	err = filepath.Walk(absRootFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != absRootFolder && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		fileTests, err := finder.findTestsInFile(path)
+		if err != nil {
+			return err
+		}
+		tests = append(tests, fileTests...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tests, func(i, j int) bool {
+		if tests[i].Package != tests[j].Package {
+			return tests[i].Package < tests[j].Package
+		}
+		return tests[i].Name < tests[j].Name
+	})
+	return tests, nil
+}
+
+type testFinder struct {
+	rootFolder string
+	// isModule contains the folders of the Terraform modules, relative to the root folder.
+	isModule map[string]bool
+}
+
+// findTestsInFile returns the test functions in the given Go file and the modules they test.
+func (finder testFinder) findTestsInFile(path string) ([]Test, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := filepath.Dir(path)
+	pkg, err := filepath.Rel(finder.rootFolder, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Annotations in comments above the package clause apply to all the tests in the file
+	var fileModules []string
+	for _, comment := range file.Comments {
+		if comment.End() < file.Package {
+			fileModules = append(fileModules, finder.annotatedModules(folder, comment)...)
+		}
+	}
+
+	tests := []Test{}
+	for _, decl := range file.Decls {
+		function, ok := decl.(*ast.FuncDecl)
+		if !ok || !isTestFunction(function) {
+			continue
+		}
+
+		modules := append([]string{}, fileModules...)
+		modules = append(modules, finder.annotatedModules(folder, function.Doc)...)
+		modules = append(modules, finder.referencedModules(folder, function.Body)...)
+		tests = append(tests, Test{Package: pkg, Name: function.Name.Name, Modules: uniqueSorted(modules)})
+	}
+	return tests, nil
+}
+
+// isTestFunction returns true if the given function is a test function, i.e. a function named TestXxx, other than
+// TestMain, that takes a single parameter.
+func isTestFunction(function *ast.FuncDecl) bool {
+	name := function.Name.Name
+	if function.Recv != nil || !strings.HasPrefix(name, "Test") || name == "TestMain" || function.Body == nil {
+		return false
+	}
+	if rest := []rune(name[len("Test"):]); len(rest) > 0 && unicode.IsLower(rest[0]) {
+		return false
+	}
+	return function.Type.Params != nil && len(function.Type.Params.List) == 1 && len(function.Type.Params.List[0].Names) <= 1
+}
+
+// annotatedModules returns the modules listed in the terratest:module annotations of the given comments, relative to
+// the given folder. Annotated paths that are not modules are ignored.
+func (finder testFinder) annotatedModules(folder string, comments *ast.CommentGroup) []string {
+	if comments == nil {
+		return nil
+	}
+
+	var modules []string
+	for _, line := range strings.Split(comments.Text(), "\n") {
+		index := strings.Index(line, ModuleAnnotation)
+		if index < 0 {
+			continue
+		}
+		paths := strings.FieldsFunc(line[index+len(ModuleAnnotation):], func(char rune) bool {
+			return char == ',' || char == ' ' || char == '\t'
+		})
+		for _, path := range paths {
+			if module, ok := finder.module(folder, path); ok {
+				modules = append(modules, module)
+			}
+		}
+	}
+	return modules
+}
+
+// referencedModules returns the modules referenced by string literals in the given function body, relative to the
+// given folder or to other folders referenced in the body.
+func (finder testFinder) referencedModules(folder string, body *ast.BlockStmt) []string {
+	var literals []string
+	ast.Inspect(body, func(node ast.Node) bool {
+		if literal, ok := node.(*ast.BasicLit); ok && literal.Kind == token.STRING {
+			if value, err := strconv.Unquote(literal.Value); err == nil && value != "" && !strings.ContainsAny(value, "\n*?") {
+				literals = append(literals, value)
+			}
+		}
+		return true
+	})
+
+	var modules []string
+	bases := []string{folder}
+	for _, literal := range literals {
+		if module, ok := finder.module(folder, literal); ok {
+			modules = append(modules, module)
+		} else if path := filepath.Join(folder, filepath.FromSlash(literal)); files.IsExistingDir(path) && finder.isInRoot(path) {
+			bases = append(bases, path)
+		}
+	}
+	for _, base := range bases[1:] {
+		for _, literal := range literals {
+			if module, ok := finder.module(base, literal); ok {
+				modules = append(modules, module)
+			}
+		}
+	}
+	return modules
+}
+
+// module returns the module at the given path, relative to the given folder, if there is one.
+func (finder testFinder) module(folder string, path string) (string, bool) {
+	if filepath.IsAbs(path) {
+		return "", false
+	}
+	relative, err := filepath.Rel(finder.rootFolder, filepath.Join(folder, filepath.FromSlash(path)))
+	if err != nil || !finder.isModule[relative] {
+		return "", false
+	}
+	return relative, true
+}
+
+func (finder testFinder) isInRoot(path string) bool {
+	relative, err := filepath.Rel(finder.rootFolder, path)
+	return err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+func uniqueSorted(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
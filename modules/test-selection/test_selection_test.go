@@ -0,0 +1,203 @@
+package test_selection
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleTests = `// terratest:module ../modules/iam
+package test
+
+import "testing"
+
+func TestVpcExample(t *testing.T) {
+	terraformOptions := &terraform.Options{TerraformDir: "../examples/vpc"}
+	_ = terraformOptions
+}
+
+func TestCopiedExample(t *testing.T) {
+	folder := test_structure.CopyTerraformFolderToTemp(t, "../", "examples/complete")
+	_ = folder
+}
+
+// TestAnnotated tests the subnets module, which is found through a helper.
+// terratest:module ../modules/subnets, ../modules/missing
+func TestAnnotated(t *testing.T) {
+	runExample(t, "subnets")
+}
+
+func TestUnrelated(t *testing.T) {
+	_ = "us-east-1"
+}
+
+func TestMain(m *testing.M) {}
+
+func testHelper(t *testing.T) {
+	_ = "../examples/vpc"
+}
+`
+
+const otherTests = `package other
+
+import "testing"
+
+func TestOther(t *testing.T) {
+	_ = "../../examples/vpc"
+}
+`
+
+const taggedTests = `//go:build integration
+
+package other
+
+import (
+	"testing"
+
+	"example.com/test/helpers"
+)
+
+func TestTagged(t *testing.T) {
+	helpers.Run(t)
+}
+`
+
+const helperPackage = `package helpers
+
+import (
+	"testing"
+
+	"example.com/test/helpers/retry"
+)
+
+func Run(t *testing.T) {
+	retry.Do(t)
+}
+`
+
+// writeTestRepo creates a repo with Terraform modules and tests, and returns its path.
+func writeTestRepo(t *testing.T) string {
+	root, err := ioutil.TempDir("", "test-selection")
+	require.NoError(t, err)
+
+	repoFiles := map[string]string{
+		"modules/vpc/main.tf":                `module "subnets" { source = "../subnets" }`,
+		"modules/vpc/templates/user-data.sh": "",
+		"modules/subnets/main.tf":            "",
+		"modules/iam/main.tf":                "",
+		"examples/vpc/main.tf":               `module "vpc" { source = "../../modules/vpc" }`,
+		"examples/complete/main.tf":          `module "iam" { source = "../../modules/iam" }`,
+		"test/example_test.go":               exampleTests,
+		"test/helpers.go":                    "package test",
+		"test/other/other_test.go":           otherTests,
+		"test/other/tagged_test.go":          taggedTests,
+		"test/helpers/helpers.go":            helperPackage,
+		"test/helpers/retry/retry.go":        "package retry",
+		"test/go.mod":                        "module example.com/test\n\ngo 1.18\n",
+		"scripts/tool.go":                    "package main",
+		"test/.cache/ignored_test.go":        "not go",
+		"README.md":                          "",
+	}
+	for path, contents := range repoFiles {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	return root
+}
+
+func TestFindTests(t *testing.T) {
+	t.Parallel()
+
+	root := writeTestRepo(t)
+	defer os.RemoveAll(root)
+
+	tests, err := FindTests(root)
+	require.NoError(t, err)
+
+	iam := filepath.Join("modules", "iam")
+	expected := []Test{
+		{Package: "test", Name: "TestAnnotated", Modules: []string{iam, filepath.Join("modules", "subnets")}},
+		{Package: "test", Name: "TestCopiedExample", Modules: []string{filepath.Join("examples", "complete"), iam}},
+		{Package: "test", Name: "TestUnrelated", Modules: []string{iam}},
+		{Package: "test", Name: "TestVpcExample", Modules: []string{filepath.Join("examples", "vpc"), iam}},
+		{Package: filepath.Join("test", "other"), Name: "TestOther", Modules: []string{filepath.Join("examples", "vpc")}},
+		{Package: filepath.Join("test", "other"), Name: "TestTagged", Modules: []string{}},
+	}
+	assert.Equal(t, expected, tests)
+}
+
+func TestSelectTests(t *testing.T) {
+	t.Parallel()
+
+	root := writeTestRepo(t)
+	defer os.RemoveAll(root)
+
+	allTests := []PackageTests{
+		{Package: "test", Tests: []string{"TestAnnotated", "TestCopiedExample", "TestUnrelated", "TestVpcExample"}},
+		{Package: filepath.Join("test", "other"), Tests: []string{"TestOther", "TestTagged"}},
+	}
+
+	testCases := []struct {
+		name         string
+		changedFiles []string
+		expected     []PackageTests
+	}{
+		{"nothing", []string{}, []PackageTests{}},
+		{"docs", []string{"README.md"}, []PackageTests{}},
+		{
+			"dependency",
+			[]string{"modules/subnets/main.tf"},
+			[]PackageTests{
+				{Package: "test", Tests: []string{"TestAnnotated", "TestVpcExample"}},
+				{Package: filepath.Join("test", "other"), Tests: []string{"TestOther"}},
+			},
+		},
+		{
+			"template",
+			[]string{"modules/vpc/templates/user-data.sh"},
+			[]PackageTests{
+				{Package: "test", Tests: []string{"TestVpcExample"}},
+				{Package: filepath.Join("test", "other"), Tests: []string{"TestOther"}},
+			},
+		},
+		{
+			"file annotation",
+			[]string{"modules/iam/main.tf"},
+			[]PackageTests{{Package: "test", Tests: []string{"TestAnnotated", "TestCopiedExample", "TestUnrelated", "TestVpcExample"}}},
+		},
+		{
+			"test code",
+			[]string{"test/other/other_test.go"},
+			[]PackageTests{{Package: filepath.Join("test", "other"), Tests: []string{"TestOther", "TestTagged"}}},
+		},
+		{
+			"imported package",
+			[]string{"test/helpers/retry/retry.go"},
+			[]PackageTests{{Package: filepath.Join("test", "other"), Tests: []string{"TestOther", "TestTagged"}}},
+		},
+		{
+			"package with tests",
+			[]string{"test/helpers.go"},
+			[]PackageTests{{Package: "test", Tests: []string{"TestAnnotated", "TestCopiedExample", "TestUnrelated", "TestVpcExample"}}},
+		},
+		{"go.mod", []string{"test/go.mod"}, allTests},
+		{"outside of a go module", []string{"scripts/tool.go"}, allTests},
+	}
+
+	for _, testCase := range testCases {
+		tests, err := SelectTests(root, testCase.changedFiles)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, GroupByPackage(tests), testCase.name)
+	}
+}
+
+func TestRunPattern(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "^(TestA|TestB)$", PackageTests{Package: "test", Tests: []string{"TestA", "TestB"}}.RunPattern())
+}
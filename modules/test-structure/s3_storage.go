@@ -0,0 +1,116 @@
+package test_structure
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// S3Store is a KeyValueStore that stores values as objects in an S3 bucket, or in a bucket of an S3 compatible service
+// such as MinIO. Use it with NewKeyValueStorage to store test data in S3.
+type S3Store struct {
+	Client s3iface.S3API
+	Bucket string
+	// Prefix is prepended to all keys, e.g. to share a bucket with other data.
+	Prefix string
+}
+
+// NewS3Store returns an S3Store for the given bucket. If endpoint is not empty, the store connects to the S3 compatible
+// service at that endpoint instead of AWS S3, using path style requests. Fails the test on errors.
+func NewS3Store(t testing.TestingT, region string, endpoint string, bucket string, prefix string) *S3Store {
+	store, err := NewS3StoreE(t, region, endpoint, bucket, prefix)
+	require.NoError(t, err)
+	return store
+}
+
+// NewS3StoreE returns an S3Store for the given bucket. If endpoint is not empty, the store connects to the S3
+// compatible service at that endpoint instead of AWS S3, using path style requests.
+func NewS3StoreE(t testing.TestingT, region string, endpoint string, bucket string, prefix string) (*S3Store, error) {
+	if endpoint == "" {
+		client, err := aws.NewS3ClientE(t, region)
+		if err != nil {
+			return nil, err
+		}
+		return &S3Store{Client: client, Bucket: bucket, Prefix: prefix}, nil
+	}
+
+	sess, err := session.NewSession(awsSDK.NewConfig().
+		WithRegion(region).
+		WithEndpoint(endpoint).
+		WithS3ForcePathStyle(true))
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{Client: s3.New(sess), Bucket: bucket, Prefix: prefix}, nil
+}
+
+// Put uploads the value as the object with the given key.
+func (store *S3Store) Put(key string, value []byte) error {
+	_, err := store.Client.PutObject(&s3.PutObjectInput{
+		Bucket: awsSDK.String(store.Bucket),
+		Key:    awsSDK.String(store.objectKey(key)),
+		Body:   bytes.NewReader(value),
+	})
+	return err
+}
+
+// Get downloads the object with the given key.
+func (store *S3Store) Get(key string) ([]byte, error) {
+	output, err := store.Client.GetObject(&s3.GetObjectInput{
+		Bucket: awsSDK.String(store.Bucket),
+		Key:    awsSDK.String(store.objectKey(key)),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrTestDataNotFound
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+	return ioutil.ReadAll(output.Body)
+}
+
+// Delete deletes the object with the given key.
+func (store *S3Store) Delete(key string) error {
+	_, err := store.Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: awsSDK.String(store.Bucket),
+		Key:    awsSDK.String(store.objectKey(key)),
+	})
+	return err
+}
+
+// List returns the keys of all the objects that start with the given prefix, without the store prefix.
+func (store *S3Store) List(prefix string) ([]string, error) {
+	var keys []string
+	err := store.Client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: awsSDK.String(store.Bucket),
+		Prefix: awsSDK.String(store.objectKey(prefix)),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(awsSDK.StringValue(object.Key), store.keyPrefix()))
+		}
+		return true
+	})
+	return keys, err
+}
+
+func (store *S3Store) objectKey(key string) string {
+	return store.keyPrefix() + key
+}
+
+func (store *S3Store) keyPrefix() string {
+	if store.Prefix == "" {
+		return ""
+	}
+	return path.Clean(store.Prefix) + "/"
+}
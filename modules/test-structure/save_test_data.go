@@ -2,10 +2,10 @@ package test_structure
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"path/filepath"
+	"reflect"
 
 	"github.com/gruntwork-io/terratest/modules/aws"
 	"github.com/gruntwork-io/terratest/modules/files"
@@ -20,107 +20,73 @@ import (
 // SaveTerraformOptions serializes and saves TerraformOptions into the given folder. This allows you to create TerraformOptions during setup
 // and to reuse that TerraformOptions later during validation and teardown.
 func SaveTerraformOptions(t testing.TestingT, testFolder string, terraformOptions *terraform.Options) {
-	SaveTestData(t, formatTerraformOptionsPath(testFolder), terraformOptions)
+	Save(t, testFolder, terraformOptions)
 }
 
 // LoadTerraformOptions loads and unserializes TerraformOptions from the given folder. This allows you to reuse a TerraformOptions that was
 // created during an earlier setup step in later validation and teardown steps.
 func LoadTerraformOptions(t testing.TestingT, testFolder string) *terraform.Options {
-	var terraformOptions terraform.Options
-	LoadTestData(t, formatTerraformOptionsPath(testFolder), &terraformOptions)
-	return &terraformOptions
-}
-
-// formatTerraformOptionsPath formats a path to save TerraformOptions in the given folder.
-func formatTerraformOptionsPath(testFolder string) string {
-	return FormatTestDataPath(testFolder, "TerraformOptions.json")
+	return Load[*terraform.Options](t, testFolder)
 }
 
 // SavePackerOptions serializes and saves PackerOptions into the given folder. This allows you to create PackerOptions during setup
 // and to reuse that PackerOptions later during validation and teardown.
 func SavePackerOptions(t testing.TestingT, testFolder string, packerOptions *packer.Options) {
-	SaveTestData(t, formatPackerOptionsPath(testFolder), packerOptions)
+	Save(t, testFolder, packerOptions)
 }
 
 // LoadPackerOptions loads and unserializes PackerOptions from the given folder. This allows you to reuse a PackerOptions that was
 // created during an earlier setup step in later validation and teardown steps.
 func LoadPackerOptions(t testing.TestingT, testFolder string) *packer.Options {
-	var packerOptions packer.Options
-	LoadTestData(t, formatPackerOptionsPath(testFolder), &packerOptions)
-	return &packerOptions
-}
-
-// formatPackerOptionsPath formats a path to save PackerOptions in the given folder.
-func formatPackerOptionsPath(testFolder string) string {
-	return FormatTestDataPath(testFolder, "PackerOptions.json")
+	return Load[*packer.Options](t, testFolder)
 }
 
 // SaveEc2KeyPair serializes and saves an Ec2KeyPair into the given folder. This allows you to create an Ec2KeyPair during setup
 // and to reuse that Ec2KeyPair later during validation and teardown.
 func SaveEc2KeyPair(t testing.TestingT, testFolder string, keyPair *aws.Ec2Keypair) {
-	SaveTestData(t, formatEc2KeyPairPath(testFolder), keyPair)
+	Save(t, testFolder, keyPair)
 }
 
 // LoadEc2KeyPair loads and unserializes an Ec2KeyPair from the given folder. This allows you to reuse an Ec2KeyPair that was
 // created during an earlier setup step in later validation and teardown steps.
 func LoadEc2KeyPair(t testing.TestingT, testFolder string) *aws.Ec2Keypair {
-	var keyPair aws.Ec2Keypair
-	LoadTestData(t, formatEc2KeyPairPath(testFolder), &keyPair)
-	return &keyPair
-}
-
-// formatEc2KeyPairPath formats a path to save an Ec2KeyPair in the given folder.
-func formatEc2KeyPairPath(testFolder string) string {
-	return FormatTestDataPath(testFolder, "Ec2KeyPair.json")
+	return Load[*aws.Ec2Keypair](t, testFolder)
 }
 
 // SaveKubectlOptions serializes and saves KubectlOptions into the given folder. This allows you to create a KubectlOptions during setup
 // and reuse that KubectlOptions later during validation and teardown.
 func SaveKubectlOptions(t testing.TestingT, testFolder string, kubectlOptions *k8s.KubectlOptions) {
-	SaveTestData(t, formatKubectlOptionsPath(testFolder), kubectlOptions)
+	Save(t, testFolder, kubectlOptions)
 }
 
 // LoadKubectlOptions loads and unserializes a KubectlOptions from the given folder. This allows you to reuse a KubectlOptions that was
 // created during an earlier setup step in later validation and teardown steps.
 func LoadKubectlOptions(t testing.TestingT, testFolder string) *k8s.KubectlOptions {
-	var kubectlOptions k8s.KubectlOptions
-	LoadTestData(t, formatKubectlOptionsPath(testFolder), &kubectlOptions)
-	return &kubectlOptions
-}
-
-// formatKubectlOptionsPath formats a path to save a KubectlOptions in the given folder.
-func formatKubectlOptionsPath(testFolder string) string {
-	return FormatTestDataPath(testFolder, "KubectlOptions.json")
+	return Load[*k8s.KubectlOptions](t, testFolder)
 }
 
 // SaveString serializes and saves a uniquely named string value into the given folder. This allows you to create one or more string
 // values during one stage -- each with a unique name -- and to reuse those values during later stages.
 func SaveString(t testing.TestingT, testFolder string, name string, val string) {
-	path := formatNamedTestDataPath(testFolder, name)
-	SaveTestData(t, path, val)
+	SaveNamed(t, testFolder, name, val)
 }
 
 // LoadString loads and unserializes a uniquely named string value from the given folder. This allows you to reuse one or more string
 // values that were created during an earlier setup step in later steps.
 func LoadString(t testing.TestingT, testFolder string, name string) string {
-	var val string
-	LoadTestData(t, formatNamedTestDataPath(testFolder, name), &val)
-	return val
+	return LoadNamed[string](t, testFolder, name)
 }
 
 // SaveInt saves a uniquely named int value into the given folder. This allows you to create one or more int
 // values during one stage -- each with a unique name -- and to reuse those values during later stages.
 func SaveInt(t testing.TestingT, testFolder string, name string, val int) {
-	path := formatNamedTestDataPath(testFolder, name)
-	SaveTestData(t, path, val)
+	SaveNamed(t, testFolder, name, val)
 }
 
 // LoadInt loads a uniquely named int value from the given folder. This allows you to reuse one or more int
 // values that were created during an earlier setup step in later steps.
 func LoadInt(t testing.TestingT, testFolder string, name string) int {
-	var val int
-	LoadTestData(t, formatNamedTestDataPath(testFolder, name), &val)
-	return val
+	return LoadNamed[int](t, testFolder, name)
 }
 
 // SaveArtifactID serializes and saves an Artifact ID into the given folder. This allows you to build an Artifact during setup and to reuse that
@@ -163,27 +129,30 @@ func FormatTestDataPath(testFolder string, filename string) string {
 }
 
 // SaveTestData serializes and saves a value used at test time to the given path. This allows you to create some sort of test data
-// (e.g., TerraformOptions) during setup and to reuse this data later during validation and teardown.
+// (e.g., TerraformOptions) during setup and to reuse this data later during validation and teardown. The value is stored
+// in TestDataStorage, which is the local disk by default.
 func SaveTestData(t testing.TestingT, path string, value interface{}) {
 	logger.Logf(t, "Storing test data in %s so it can be reused later", path)
 
-	if IsTestDataPresent(t, path) {
-		logger.Logf(t, "[WARNING] The named test data at path %s is non-empty. Save operation will overwrite existing value with \"%v\".\n.", path, value)
-	}
-
 	bytes, err := json.Marshal(value)
 	if err != nil {
 		t.Fatalf("Failed to convert value %s to JSON: %v", path, err)
 	}
 
-	logger.Logf(t, "Marshalled JSON: %s", string(bytes))
+	// Secrets, such as the values of the Vars of terraform.Options, are redacted from the logs.
+	valueType := reflect.TypeOf(value)
+	redacted := redactedValue
+	if valueType != nil {
+		redacted = redactJSON(bytes, valueType, lookupTestDataType(valueType).SecretFields)
+	}
 
-	parentDir := filepath.Dir(path)
-	if err := os.MkdirAll(parentDir, 0777); err != nil {
-		t.Fatalf("Failed to create folder %s: %v", parentDir, err)
+	if IsTestDataPresent(t, path) {
+		logger.Logf(t, "[WARNING] The named test data at path %s is non-empty. Save operation will overwrite existing value with \"%s\".\n.", path, redacted)
 	}
 
-	if err := ioutil.WriteFile(path, bytes, 0644); err != nil {
+	logger.Logf(t, "Marshalled JSON: %s", redacted)
+
+	if err := testDataStorage(t).Put(path, bytes); err != nil {
 		t.Fatalf("Failed to save value %s: %v", path, err)
 	}
 }
@@ -194,11 +163,17 @@ func SaveTestData(t testing.TestingT, path string, value interface{}) {
 func LoadTestData(t testing.TestingT, path string, value interface{}) {
 	logger.Logf(t, "Loading test data from %s", path)
 
-	bytes, err := ioutil.ReadFile(path)
+	bytes, err := testDataStorage(t).Get(path)
 	if err != nil {
 		t.Fatalf("Failed to load value from %s: %v", path, err)
 	}
 
+	// Values saved by Save are wrapped with their type and schema version.
+	_, bytes, err = unwrapTestData(bytes)
+	if err != nil {
+		t.Fatalf("Failed to parse JSON for value %s: %v", path, err)
+	}
+
 	if err := json.Unmarshal(bytes, value); err != nil {
 		t.Fatalf("Failed to parse JSON for value %s: %v", path, err)
 	}
@@ -206,18 +181,18 @@ func LoadTestData(t testing.TestingT, path string, value interface{}) {
 
 // IsTestDataPresent returns true if a file exists at $path and the test data there is non-empty.
 func IsTestDataPresent(t testing.TestingT, path string) bool {
-	exists, err := files.FileExistsE(path)
+	bytes, err := testDataStorage(t).Get(path)
+	if errors.Is(err, ErrTestDataNotFound) {
+		return false
+	}
 	if err != nil {
 		t.Fatalf("Failed to load test data from %s due to unexpected error: %v", path, err)
 	}
-	if !exists {
-		return false
-	}
-
-	bytes, err := ioutil.ReadFile(path)
 
+	// Values saved by Save are wrapped with their type and schema version.
+	_, bytes, err = unwrapTestData(bytes)
 	if err != nil {
-		t.Fatalf("Failed to load test data from %s due to unexpected error: %v", path, err)
+		t.Fatalf("Failed to parse JSON while testing whether it is empty: %v", err)
 	}
 
 	if isEmptyJSON(t, bytes) {
@@ -274,13 +249,15 @@ func isEmptyJSON(t testing.TestingT, bytes []byte) bool {
 
 // CleanupTestData cleans up the test data at the given path.
 func CleanupTestData(t testing.TestingT, path string) {
-	if files.FileExists(path) {
-		logger.Logf(t, "Cleaning up test data from %s", path)
-		if err := os.Remove(path); err != nil {
-			t.Fatalf("Failed to clean up file at %s: %v", path, err)
-		}
-	} else {
+	storage := testDataStorage(t)
+	if _, err := storage.Get(path); errors.Is(err, ErrTestDataNotFound) {
 		logger.Logf(t, "%s does not exist. Nothing to cleanup.", path)
+		return
+	}
+
+	logger.Logf(t, "Cleaning up test data from %s", path)
+	if err := storage.Delete(path); err != nil {
+		t.Fatalf("Failed to clean up file at %s: %v", path, err)
 	}
 }
 
@@ -294,17 +271,16 @@ func CleanupTestDataFolder(t testing.TestingT, path string) {
 // CleanupTestDataFolderE cleans up the .test-data folder inside the given folder.
 func CleanupTestDataFolderE(t testing.TestingT, path string) error {
 	path = filepath.Join(path, ".test-data")
-	exists, err := files.FileExistsE(path)
+	storage, err := testDataStorageE(t)
 	if err != nil {
-		logger.Logf(t, "Failed to clean up test data folder at %s: %v", path, err)
 		return err
 	}
 
-	if !exists {
+	if _, isLocal := storage.(LocalStorage); isLocal && !files.FileExists(path) {
 		logger.Logf(t, "%s does not exist. Nothing to cleanup.", path)
 		return nil
 	}
-	if err := os.RemoveAll(path); err != nil {
+	if err := storage.DeletePrefix(path); err != nil {
 		logger.Logf(t, "Failed to clean up test data folder at %s: %v", path, err)
 		return err
 	}
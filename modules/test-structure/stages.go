@@ -0,0 +1,442 @@
+package test_structure
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const (
+	// ONLY_STAGE_ENV_VAR_PREFIX is the prefix of the environment variables used to run only some stages of a Stages
+	// runner. If `ONLY_<stageName>` is set for any of the stages, all the other stages are skipped.
+	ONLY_STAGE_ENV_VAR_PREFIX = "ONLY_"
+	// RESUME_FROM_ENV_VAR is the environment variable used to resume a Stages runner from the given stage. The stages
+	// that come before it are skipped, as long as they completed in a previous run.
+	RESUME_FROM_ENV_VAR = "RESUME_FROM"
+
+	// stagesStateFile is the name of the file in the .test-data folder in which the completed stages are recorded.
+	stagesStateFile = "stages.json"
+)
+
+// Stage is a single named step of a test, such as deploy, validate or teardown.
+type Stage struct {
+	// Name of the stage. Used in the SKIP_<name> and ONLY_<name> environment variables and as the value of RESUME_FROM.
+	Name string
+	// DependsOn are the names of the stages that must run before this one.
+	DependsOn []string
+	// Teardown stages clean up after the stages they depend on. They run after all the other stages, in reverse order,
+	// even if a stage fails.
+	Teardown bool
+	// Run executes the stage. It should fail the test on errors, for example by calling t.Fatal or using require.
+	Run func()
+}
+
+// Stages runs the stages of a test in dependency order. It records the stages that completed in the .test-data folder
+// of TestFolder, so a later run can pick up where this one left off, and logs a table with the result and duration
+// of each stage at the end.
+//
+// Which stages run can be controlled with the following environment variables:
+//
+//   - SKIP_<stage>: skip the given stage, as with RunTestStage.
+//   - ONLY_<stage>: only run the stages for which this is set.
+//   - RESUME_FROM=<stage>: skip the stages before the given one that completed in a previous run.
+//
+// Since RESUME_FROM relies on TestFolder being the same between runs, use StageEnvVarSet rather than copying the test
+// folder to a new temp folder in each run when any of these is set.
+//
+// Teardown stages always run last, even if another stage fails, provided each of their dependencies either ran in
+// this run or completed in a previous one. This way a teardown stage does not have to be deferred by hand, and it also
+// cleans up after a stage that failed halfway.
+//
+//	test_structure.Stages{
+//		TestFolder: workingDir,
+//		Stages: []test_structure.Stage{
+//			{Name: "deploy", Run: func() { terraform.InitAndApply(t, terraformOptions) }},
+//			{Name: "validate", DependsOn: []string{"deploy"}, Run: func() { validate(t, terraformOptions) }},
+//			{Name: "teardown", DependsOn: []string{"deploy"}, Teardown: true, Run: func() { terraform.Destroy(t, terraformOptions) }},
+//		},
+//	}.Run(t)
+type Stages struct {
+	// TestFolder is the folder in whose .test-data folder the completed stages are recorded. If empty, nothing is
+	// recorded and RESUME_FROM can't skip any stage.
+	TestFolder string
+	Stages     []Stage
+}
+
+// StageStatus is the outcome of a stage in a Stages run.
+type StageStatus string
+
+const (
+	StagePassed  StageStatus = "passed"
+	StageFailed  StageStatus = "failed"
+	StageSkipped StageStatus = "skipped"
+	// StageBlocked means the stage did not run because one of its dependencies failed or was blocked.
+	StageBlocked StageStatus = "blocked"
+	// StageNotRun means the stage did not run because the test stopped before reaching it.
+	StageNotRun StageStatus = "not run"
+)
+
+// StageResult is the outcome and duration of a stage in a Stages run.
+type StageResult struct {
+	Name     string
+	Status   StageStatus
+	Duration time.Duration
+}
+
+// stagesState is the record of completed stages stored in the .test-data folder.
+type stagesState struct {
+	Completed []string
+}
+
+// stagesRun holds the state of a single execution of Stages.
+type stagesRun struct {
+	t       testing.TestingT
+	stages  Stages
+	results map[string]*StageResult
+	// order are the names of the stages in the order they were considered for running.
+	order     []string
+	completed map[string]bool
+	only      map[string]bool
+	resumeAt  int
+}
+
+// Run executes the stages in dependency order, and then the teardown stages in reverse order, and returns the result of
+// each stage. Fails the test if the stages are misconfigured, e.g. if a dependency doesn't exist or there is a
+// dependency cycle.
+func (stages Stages) Run(t testing.TestingT) (results []StageResult) {
+	regular, teardown, err := stages.order()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := &stagesRun{
+		t:         t,
+		stages:    stages,
+		results:   map[string]*StageResult{},
+		completed: map[string]bool{},
+		only:      map[string]bool{},
+		resumeAt:  -1,
+	}
+	for _, stage := range stages.Stages {
+		run.results[stage.Name] = &StageResult{Name: stage.Name, Status: StageNotRun}
+		if os.Getenv(ONLY_STAGE_ENV_VAR_PREFIX+stage.Name) != "" {
+			run.only[stage.Name] = true
+		}
+	}
+	for _, name := range run.loadState() {
+		run.completed[name] = true
+	}
+	if resumeFrom := os.Getenv(RESUME_FROM_ENV_VAR); resumeFrom != "" {
+		for i, stage := range regular {
+			if stage.Name == resumeFrom {
+				run.resumeAt = i
+			}
+		}
+		if run.resumeAt < 0 {
+			t.Fatalf("The %s environment variable is set to '%s', which is not a stage of this test.", RESUME_FROM_ENV_VAR, resumeFrom)
+		}
+	}
+
+	// Teardown and the report are deferred, so they also happen if a stage stops the test with t.FailNow or panics.
+	// Each teardown stage is deferred separately, so that one teardown stage stopping the test doesn't prevent the
+	// others from running.
+	defer func() {
+		results = run.orderedResults()
+		logStageResults(t, results)
+	}()
+	for i := len(teardown) - 1; i >= 0; i-- {
+		defer run.runStage(teardown[i], -1)
+	}
+
+	for i, stage := range regular {
+		run.runStage(stage, i)
+	}
+	return
+}
+
+// StageEnvVarSet returns true if a SKIP_<stage> or ONLY_<stage> environment variable is set for one of the stages, or
+// if RESUME_FROM is set. Like SkipStageEnvVarSet, this can be used to tell if the test is running in a local dev
+// environment, e.g. to reuse the same test folder between runs so that RESUME_FROM can pick up where the previous run
+// left off. Environment variables that start with ONLY_ but don't name one of the stages are ignored.
+func (stages Stages) StageEnvVarSet() bool {
+	if os.Getenv(RESUME_FROM_ENV_VAR) != "" {
+		return true
+	}
+	for _, stage := range stages.Stages {
+		if os.Getenv(SKIP_STAGE_ENV_VAR_PREFIX+stage.Name) != "" || os.Getenv(ONLY_STAGE_ENV_VAR_PREFIX+stage.Name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// order validates the stages and sorts them topologically. Regular stages are returned in declaration order where
+// possible, teardown stages in reverse declaration order where possible.
+func (stages Stages) order() ([]Stage, []Stage, error) {
+	index := map[string]int{}
+	for i, stage := range stages.Stages {
+		if stage.Name == "" {
+			return nil, nil, fmt.Errorf("stage %d has no name", i)
+		}
+		if stage.Run == nil {
+			return nil, nil, fmt.Errorf("stage '%s' has no Run function", stage.Name)
+		}
+		if _, exists := index[stage.Name]; exists {
+			return nil, nil, fmt.Errorf("stage '%s' is defined more than once", stage.Name)
+		}
+		index[stage.Name] = i
+	}
+
+	var regular, teardown []Stage
+	for _, stage := range stages.Stages {
+		for _, dependency := range stage.DependsOn {
+			i, exists := index[dependency]
+			if !exists {
+				return nil, nil, fmt.Errorf("stage '%s' depends on stage '%s', which does not exist", stage.Name, dependency)
+			}
+			if stages.Stages[i].Teardown && !stage.Teardown {
+				return nil, nil, fmt.Errorf("stage '%s' depends on teardown stage '%s', but only teardown stages can do that", stage.Name, dependency)
+			}
+		}
+		if stage.Teardown {
+			teardown = append([]Stage{stage}, teardown...)
+		} else {
+			regular = append(regular, stage)
+		}
+	}
+
+	regular, err := sortStages(regular)
+	if err != nil {
+		return nil, nil, err
+	}
+	teardown, err = sortStages(teardown)
+	if err != nil {
+		return nil, nil, err
+	}
+	return regular, teardown, nil
+}
+
+// sortStages sorts the given stages so that each stage comes after its dependencies within the list, keeping the
+// given order wherever the dependencies allow it. Dependencies on stages that are not in the list are ignored.
+func sortStages(stages []Stage) ([]Stage, error) {
+	inList := map[string]bool{}
+	for _, stage := range stages {
+		inList[stage.Name] = true
+	}
+
+	done := map[string]bool{}
+	var sorted []Stage
+	for len(sorted) < len(stages) {
+		progress := false
+		for _, stage := range stages {
+			if done[stage.Name] || !dependenciesDone(stage, inList, done) {
+				continue
+			}
+			sorted = append(sorted, stage)
+			done[stage.Name] = true
+			progress = true
+			break
+		}
+
+		if !progress {
+			var remaining []string
+			for _, stage := range stages {
+				if !done[stage.Name] {
+					remaining = append(remaining, stage.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between stages %s", strings.Join(remaining, ", "))
+		}
+	}
+	return sorted, nil
+}
+
+func dependenciesDone(stage Stage, inList map[string]bool, done map[string]bool) bool {
+	for _, dependency := range stage.DependsOn {
+		if inList[dependency] && !done[dependency] {
+			return false
+		}
+	}
+	return true
+}
+
+// runStage runs the given stage, unless it is skipped or blocked, and records its result. The argument position is the
+// index of the stage in the regular stages, or -1 for teardown stages.
+func (run *stagesRun) runStage(stage Stage, position int) {
+	result := run.results[stage.Name]
+	run.order = append(run.order, stage.Name)
+
+	if reason := run.skipReason(stage, position); reason != "" {
+		logger.Logf(run.t, "%s, so skipping stage '%s'.", reason, stage.Name)
+		result.Status = StageSkipped
+		return
+	}
+	if reason := run.blockReason(stage); reason != "" {
+		logger.Logf(run.t, "%s, so not running stage '%s'.", reason, stage.Name)
+		result.Status = StageBlocked
+		return
+	}
+	for _, dependency := range stage.DependsOn {
+		if run.results[dependency].Status == StageSkipped && !run.completed[dependency] {
+			logger.Default.Warnf(run.t, "Stage '%s' depends on stage '%s', which was skipped and has not completed in a previous run.", stage.Name, dependency)
+		}
+	}
+
+	logger.Logf(run.t, "Executing stage '%s'.", stage.Name)
+	start := time.Now()
+	failedBefore := run.failed()
+	returned := false
+	// A stage that stops the test with t.FailNow or panics never returns, so it is recorded as failed here.
+	defer func() {
+		result.Duration = time.Since(start)
+		if !returned {
+			result.Status = StageFailed
+		}
+	}()
+
+	stage.Run()
+	returned = true
+	if !run.passed(stage, failedBefore) {
+		result.Status = StageFailed
+		return
+	}
+	result.Status = StagePassed
+
+	if stage.Teardown {
+		// The resources created by the dependencies are gone, so they have to run again before the stages that use them.
+		for _, dependency := range stage.DependsOn {
+			delete(run.completed, dependency)
+		}
+	} else {
+		run.completed[stage.Name] = true
+	}
+	run.saveState()
+}
+
+// passed returns whether the given stage, which returned normally, passed. The stage failed if it marked the test as
+// failed, e.g. with t.Error. If the test had already failed before the stage ran, that can't be attributed to the stage,
+// so it gets the outcome that is safe for the stages after it: a regular stage counts as failed, so that the stages
+// that depend on it are blocked and it runs again when resuming, while a teardown stage counts as passed, so that the
+// stages it tore down run again when resuming.
+func (run *stagesRun) passed(stage Stage, failedBefore bool) bool {
+	if !run.failed() {
+		return true
+	}
+	if !failedBefore {
+		return false
+	}
+
+	if stage.Teardown {
+		logger.Default.Warnf(run.t, "The test had already failed before teardown stage '%s', so an error it reported without stopping the test can't be detected. Counting it as passed.", stage.Name)
+		return true
+	}
+	logger.Default.Warnf(run.t, "The test had already failed before stage '%s', so it can't be told whether the stage passed. Counting it as failed; use t.Fatal or require in stages so their failures can be detected.", stage.Name)
+	return false
+}
+
+// failed returns true if the test has been marked as failed, e.g. by t.Error, for implementations of TestingT that
+// expose it, such as testing.T.
+func (run *stagesRun) failed() bool {
+	if failer, ok := run.t.(interface{ Failed() bool }); ok {
+		return failer.Failed()
+	}
+	return false
+}
+
+// skipReason returns why the given stage should be skipped based on the environment variables, or an empty string if
+// it should run.
+func (run *stagesRun) skipReason(stage Stage, position int) string {
+	skipEnvVar := SKIP_STAGE_ENV_VAR_PREFIX + stage.Name
+	if os.Getenv(skipEnvVar) != "" {
+		return fmt.Sprintf("The '%s' environment variable is set", skipEnvVar)
+	}
+	if len(run.only) > 0 && !run.only[stage.Name] {
+		return fmt.Sprintf("An %s<stage> environment variable is set for other stages", ONLY_STAGE_ENV_VAR_PREFIX)
+	}
+	if position >= 0 && position < run.resumeAt && run.completed[stage.Name] {
+		return fmt.Sprintf("The %s environment variable is set to a later stage and this stage completed in a previous run", RESUME_FROM_ENV_VAR)
+	}
+	return ""
+}
+
+// blockReason returns why the given stage can't run because of the outcome of its dependencies, or an empty string if
+// it can. Regular stages are blocked by dependencies that failed or were blocked themselves. Teardown stages are
+// blocked by dependencies that did not run in this run and have not completed in a previous one, as there is nothing
+// to clean up.
+func (run *stagesRun) blockReason(stage Stage) string {
+	for _, dependency := range stage.DependsOn {
+		status := run.results[dependency].Status
+		if stage.Teardown {
+			if status != StagePassed && status != StageFailed && !run.completed[dependency] {
+				return fmt.Sprintf("Stage '%s' did not run and has not completed in a previous run", dependency)
+			}
+		} else if status == StageFailed || status == StageBlocked {
+			return fmt.Sprintf("Stage '%s' %s", dependency, status)
+		}
+	}
+	return ""
+}
+
+// loadState returns the names of the stages recorded as completed in the .test-data folder.
+func (run *stagesRun) loadState() []string {
+	if run.stages.TestFolder == "" {
+		return nil
+	}
+	path := FormatTestDataPath(run.stages.TestFolder, stagesStateFile)
+	if !IsTestDataPresent(run.t, path) {
+		return nil
+	}
+
+	var state stagesState
+	LoadTestData(run.t, path, &state)
+	return state.Completed
+}
+
+// saveState records the completed stages in the .test-data folder.
+func (run *stagesRun) saveState() {
+	if run.stages.TestFolder == "" {
+		return
+	}
+
+	state := stagesState{Completed: []string{}}
+	for name := range run.completed {
+		state.Completed = append(state.Completed, name)
+	}
+	sort.Strings(state.Completed)
+	SaveTestData(run.t, FormatTestDataPath(run.stages.TestFolder, stagesStateFile), state)
+}
+
+// orderedResults returns the results in the order the stages were considered for running, followed by the stages that
+// were not reached in the order they were declared.
+func (run *stagesRun) orderedResults() []StageResult {
+	var results []StageResult
+	for _, name := range run.order {
+		results = append(results, *run.results[name])
+	}
+	for _, stage := range run.stages.Stages {
+		if run.results[stage.Name].Status == StageNotRun {
+			results = append(results, *run.results[stage.Name])
+		}
+	}
+	return results
+}
+
+// logStageResults logs a table with the status and duration of each stage.
+func logStageResults(t testing.TestingT, results []StageResult) {
+	var table bytes.Buffer
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "STAGE\tSTATUS\tDURATION")
+	for _, result := range results {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", result.Name, result.Status, result.Duration.Round(time.Millisecond))
+	}
+	writer.Flush()
+
+	logger.Logf(t, "Stage results:\n%s", strings.TrimSuffix(table.String(), "\n"))
+}
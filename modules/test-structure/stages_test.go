@@ -0,0 +1,236 @@
+package test_structure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stagesT is a TestingT that records failures instead of failing the real test, so failing stages can be tested.
+type stagesT struct {
+	*testing.T
+	failed bool
+}
+
+func (t *stagesT) Fail()                                     { t.failed = true }
+func (t *stagesT) FailNow()                                  { t.failed = true; runtime.Goexit() }
+func (t *stagesT) Failed() bool                              { return t.failed }
+func (t *stagesT) Error(args ...interface{})                 { t.Log(args...); t.Fail() }
+func (t *stagesT) Errorf(format string, args ...interface{}) { t.Logf(format, args...); t.Fail() }
+func (t *stagesT) Fatal(args ...interface{})                 { t.Log(args...); t.FailNow() }
+func (t *stagesT) Fatalf(format string, args ...interface{}) { t.Logf(format, args...); t.FailNow() }
+
+// runStages runs the given stages in their own goroutine, so stages can stop it with FailNow, and returns the names of
+// the stages that ran in order, and whether the test failed.
+func runStages(t *testing.T, testFolder string, failing string, stages ...Stage) ([]string, bool) {
+	fakeT := &stagesT{T: t}
+	var ran []string
+	for i := range stages {
+		name := stages[i].Name
+		stages[i].Run = func() {
+			ran = append(ran, name)
+			if name == failing {
+				fakeT.Fatalf("stage %s failed", name)
+			}
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Stages{TestFolder: testFolder, Stages: stages}.Run(fakeT)
+	}()
+	<-done
+
+	return ran, fakeT.failed
+}
+
+func testStages() []Stage {
+	return []Stage{
+		{Name: "teardown", DependsOn: []string{"deploy"}, Teardown: true},
+		{Name: "validate", DependsOn: []string{"deploy"}},
+		{Name: "deploy"},
+		{Name: "cleanupKeys", DependsOn: []string{"keys"}, Teardown: true},
+		{Name: "keys"},
+	}
+}
+
+func TestStagesRunInDependencyOrder(t *testing.T) {
+	t.Parallel()
+
+	ran, failed := runStages(t, "", "", testStages()...)
+	assert.False(t, failed)
+	assert.Equal(t, []string{"deploy", "validate", "keys", "cleanupKeys", "teardown"}, ran)
+}
+
+func TestStagesReturnResults(t *testing.T) {
+	t.Parallel()
+
+	results := Stages{Stages: []Stage{
+		{Name: "teardown", DependsOn: []string{"deploy"}, Teardown: true, Run: func() {}},
+		{Name: "deploy", Run: func() {}},
+	}}.Run(t)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "deploy", results[0].Name)
+	assert.Equal(t, StagePassed, results[0].Status)
+	assert.Equal(t, "teardown", results[1].Name)
+	assert.Equal(t, StagePassed, results[1].Status)
+}
+
+func TestStagesRunTeardownAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		failing  string
+		expected []string
+	}{
+		{"validate", []string{"deploy", "validate", "teardown"}},
+		{"deploy", []string{"deploy", "teardown"}},
+		{"keys", []string{"deploy", "validate", "keys", "cleanupKeys", "teardown"}},
+		{"cleanupKeys", []string{"deploy", "validate", "keys", "cleanupKeys", "teardown"}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.failing, func(t *testing.T) {
+			t.Parallel()
+
+			ran, failed := runStages(t, "", testCase.failing, testStages()...)
+			assert.True(t, failed)
+			assert.Equal(t, testCase.expected, ran)
+		})
+	}
+}
+
+func TestStagesInvalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		stages []Stage
+	}{
+		{"missing dependency", []Stage{{Name: "validate", DependsOn: []string{"deploy"}}}},
+		{"duplicate", []Stage{{Name: "deploy"}, {Name: "deploy"}}},
+		{"cycle", []Stage{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}}},
+		{"depends on teardown", []Stage{{Name: "teardown", Teardown: true}, {Name: "validate", DependsOn: []string{"teardown"}}}},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			ran, failed := runStages(t, "", "", testCase.stages...)
+			assert.True(t, failed)
+			assert.Empty(t, ran)
+		})
+	}
+}
+
+func TestStagesResumeFrom(t *testing.T) {
+	testFolder, err := ioutil.TempDir("", "stages-resume")
+	require.NoError(t, err)
+	defer CleanupTestDataFolder(t, testFolder)
+
+	// The first run deploys, but fails validation and skips the teardown to allow debugging.
+	t.Setenv("SKIP_teardown", "true")
+	ran, failed := runStages(t, testFolder, "validate", testStages()...)
+	assert.True(t, failed)
+	assert.Equal(t, []string{"deploy", "validate"}, ran)
+
+	// The second run resumes from validation, without deploying again.
+	t.Setenv("SKIP_teardown", "")
+	t.Setenv(RESUME_FROM_ENV_VAR, "validate")
+	ran, failed = runStages(t, testFolder, "", testStages()...)
+	assert.False(t, failed)
+	assert.Equal(t, []string{"validate", "keys", "cleanupKeys", "teardown"}, ran)
+
+	// The deployment was torn down, so resuming again has to deploy again.
+	ran, failed = runStages(t, testFolder, "", testStages()...)
+	assert.False(t, failed)
+	assert.Equal(t, []string{"deploy", "validate", "keys", "cleanupKeys", "teardown"}, ran)
+}
+
+func TestStagesOnly(t *testing.T) {
+	testFolder, err := ioutil.TempDir("", "stages-only")
+	require.NoError(t, err)
+	defer CleanupTestDataFolder(t, testFolder)
+
+	t.Setenv(fmt.Sprintf("%sdeploy", ONLY_STAGE_ENV_VAR_PREFIX), "true")
+	ran, failed := runStages(t, testFolder, "", testStages()...)
+	assert.False(t, failed)
+	assert.Equal(t, []string{"deploy"}, ran)
+
+	// Only tearing down works, because the deployment was recorded in the test folder.
+	t.Setenv(fmt.Sprintf("%sdeploy", ONLY_STAGE_ENV_VAR_PREFIX), "")
+	t.Setenv(fmt.Sprintf("%steardown", ONLY_STAGE_ENV_VAR_PREFIX), "true")
+	ran, failed = runStages(t, testFolder, "", testStages()...)
+	assert.False(t, failed)
+	assert.Equal(t, []string{"teardown"}, ran)
+}
+
+func TestStagesDetectNonFatalFailuresAfterEarlierFailure(t *testing.T) {
+	t.Parallel()
+
+	fakeT := &stagesT{T: t}
+	var ran []string
+	stage := func(name string, fail bool) func() {
+		return func() {
+			ran = append(ran, name)
+			if fail {
+				fakeT.Errorf("stage %s failed", name)
+			}
+		}
+	}
+
+	var results []StageResult
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		results = Stages{Stages: []Stage{
+			{Name: "deploy", Run: stage("deploy", false)},
+			{Name: "validate", DependsOn: []string{"deploy"}, Run: stage("validate", true)},
+			{Name: "keys", Run: stage("keys", true)},
+			{Name: "useKeys", DependsOn: []string{"keys"}, Run: stage("useKeys", false)},
+			{Name: "teardown", DependsOn: []string{"deploy"}, Teardown: true, Run: stage("teardown", false)},
+		}}.Run(fakeT)
+	}()
+	<-done
+
+	assert.True(t, fakeT.failed)
+	assert.Equal(t, []string{"deploy", "validate", "keys", "teardown"}, ran)
+
+	statuses := map[string]StageStatus{}
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+	}
+	assert.Equal(t, map[string]StageStatus{
+		"deploy":   StagePassed,
+		"validate": StageFailed,
+		"keys":     StageFailed,
+		"useKeys":  StageBlocked,
+		"teardown": StagePassed,
+	}, statuses)
+}
+
+func TestStageEnvVarSet(t *testing.T) {
+	stages := Stages{Stages: testStages()}
+
+	t.Setenv(fmt.Sprintf("%sDISPLAY", ONLY_STAGE_ENV_VAR_PREFIX), "1")
+	assert.False(t, stages.StageEnvVarSet())
+	assert.False(t, SkipStageEnvVarSet())
+
+	t.Setenv(fmt.Sprintf("%svalidate", ONLY_STAGE_ENV_VAR_PREFIX), "true")
+	assert.True(t, stages.StageEnvVarSet())
+	assert.False(t, SkipStageEnvVarSet())
+
+	t.Setenv(fmt.Sprintf("%svalidate", ONLY_STAGE_ENV_VAR_PREFIX), "")
+	t.Setenv(RESUME_FROM_ENV_VAR, "validate")
+	assert.True(t, stages.StageEnvVarSet())
+	assert.False(t, SkipStageEnvVarSet())
+}
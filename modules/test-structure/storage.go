@@ -0,0 +1,494 @@
+package test_structure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// TestDataStorageEnvVar is the environment variable used to select where test data is stored when TestDataStorage
+	// is not set. Must be one of local (the default) or s3.
+	TestDataStorageEnvVar = "TERRATEST_TEST_DATA_STORAGE"
+	// TestDataNamespaceEnvVar is the environment variable used to store test data in a namespace, such as the ID of a CI
+	// pipeline, so concurrent runs that share a storage backend don't overwrite each other's data.
+	TestDataNamespaceEnvVar = "TERRATEST_TEST_DATA_NAMESPACE"
+	// TestDataEncryptionKeyEnvVar is the environment variable used to set a passphrase with which test data is
+	// encrypted at rest. Useful for sensitive values, such as the private key stored by SaveEc2KeyPair.
+	TestDataEncryptionKeyEnvVar = "TERRATEST_TEST_DATA_ENCRYPTION_KEY"
+	// TestDataS3BucketEnvVar is the environment variable used to set the bucket in which test data is stored when
+	// TERRATEST_TEST_DATA_STORAGE is s3.
+	TestDataS3BucketEnvVar = "TERRATEST_TEST_DATA_S3_BUCKET"
+	// TestDataS3PrefixEnvVar is the environment variable used to set a prefix for the keys of test data stored in S3.
+	TestDataS3PrefixEnvVar = "TERRATEST_TEST_DATA_S3_PREFIX"
+	// TestDataS3EndpointEnvVar is the environment variable used to set the endpoint of an S3 compatible service, such
+	// as MinIO, in which test data is stored. If unset, AWS S3 is used.
+	TestDataS3EndpointEnvVar = "TERRATEST_TEST_DATA_S3_ENDPOINT"
+	// TestDataS3RegionEnvVar is the environment variable used to set the region of the bucket in which test data is
+	// stored. Defaults to us-east-1.
+	TestDataS3RegionEnvVar = "TERRATEST_TEST_DATA_S3_REGION"
+)
+
+// ErrTestDataNotFound is returned by storage backends when there is no test data for a key.
+var ErrTestDataNotFound = errors.New("test data not found")
+
+// Storage is a backend in which test data is stored. Keys are the paths returned by FormatTestDataPath, e.g.
+// /path/to/test/folder/.test-data/TerraformOptions.json. Storing test data somewhere other than the local disk allows
+// the setup, validation and teardown stages of a test to run on different machines, e.g. in separate CI jobs.
+//
+// The stages only share test data if they use the same test folder. The temp folders created by
+// CopyTerraformFolderToTemp and CopyTerraformModuleToTemp have a random path, so the test data saved in them can't be
+// loaded by a stage that runs on another machine. Set the SKIP_<stage> environment variables when running the stages
+// separately, so that those functions return the original test folder instead of copying it.
+type Storage interface {
+	// Put stores the data under the given key, overwriting any existing data.
+	Put(key string, data []byte) error
+	// Get returns the data stored under the given key, or ErrTestDataNotFound if there is none.
+	Get(key string) ([]byte, error)
+	// Delete deletes the data stored under the given key. Deleting a key that doesn't exist is not an error.
+	Delete(key string) error
+	// DeletePrefix deletes all the data stored under the given folder, such as a .test-data folder.
+	DeletePrefix(prefix string) error
+}
+
+// TestDataStorage is the storage used by SaveTestData, LoadTestData, IsTestDataPresent and the cleanup functions, and
+// by all the functions built on them. If it is nil, which is the default, the storage is configured with the
+// TERRATEST_TEST_DATA_* environment variables (see NewStorageFromEnv).
+var TestDataStorage Storage
+
+// testDataStorage returns the storage to use for test data, failing the test if it can't be configured.
+func testDataStorage(t testing.TestingT) Storage {
+	storage, err := testDataStorageE(t)
+	require.NoError(t, err)
+	return storage
+}
+
+// testDataStorageE returns the storage to use for test data: TestDataStorage if it is set, or the storage configured
+// with the environment variables otherwise.
+func testDataStorageE(t testing.TestingT) (Storage, error) {
+	if TestDataStorage != nil {
+		return TestDataStorage, nil
+	}
+	return NewStorageFromEnvE(t)
+}
+
+// NewStorageFromEnv returns the storage configured with the TERRATEST_TEST_DATA_* environment variables. Fails the
+// test if the configuration is invalid. See NewStorageFromEnvE.
+func NewStorageFromEnv(t testing.TestingT) Storage {
+	storage, err := NewStorageFromEnvE(t)
+	require.NoError(t, err)
+	return storage
+}
+
+// NewStorageFromEnvE returns the storage configured with the following environment variables:
+//
+//   - TERRATEST_TEST_DATA_STORAGE: local (the default) to store test data in the .test-data folder on the local disk,
+//     or s3 to store it in TERRATEST_TEST_DATA_S3_BUCKET, optionally under TERRATEST_TEST_DATA_S3_PREFIX, in
+//     TERRATEST_TEST_DATA_S3_REGION or at TERRATEST_TEST_DATA_S3_ENDPOINT.
+//   - TERRATEST_TEST_DATA_NAMESPACE: store test data in the given namespace.
+//   - TERRATEST_TEST_DATA_ENCRYPTION_KEY: encrypt test data with a key derived from the given passphrase.
+//
+// The storage is built once per configuration and reused by later calls, so that a new S3 session is not created for
+// every piece of test data that is saved or loaded.
+//
+// With s3, the keys are made relative to the working directory, so test data can be shared between machines that check
+// out the code at different paths, but not for test folders outside the working directory, such as the temp folders
+// created by CopyTerraformFolderToTemp and CopyTerraformModuleToTemp (see Storage).
+func NewStorageFromEnvE(t testing.TestingT) (Storage, error) {
+	config := storageConfig{
+		backend:    strings.ToLower(os.Getenv(TestDataStorageEnvVar)),
+		bucket:     os.Getenv(TestDataS3BucketEnvVar),
+		prefix:     os.Getenv(TestDataS3PrefixEnvVar),
+		endpoint:   os.Getenv(TestDataS3EndpointEnvVar),
+		region:     os.Getenv(TestDataS3RegionEnvVar),
+		namespace:  os.Getenv(TestDataNamespaceEnvVar),
+		passphrase: os.Getenv(TestDataEncryptionKeyEnvVar),
+	}
+
+	storageCacheLock.Lock()
+	defer storageCacheLock.Unlock()
+
+	if storage, ok := storageCache[config]; ok {
+		return storage, nil
+	}
+	storage, err := newStorageE(t, config)
+	if err != nil {
+		return nil, err
+	}
+	storageCache[config] = storage
+	return storage, nil
+}
+
+// storageConfig is the configuration of a storage, as read from the TERRATEST_TEST_DATA_* environment variables.
+type storageConfig struct {
+	backend    string
+	bucket     string
+	prefix     string
+	endpoint   string
+	region     string
+	namespace  string
+	passphrase string
+}
+
+var (
+	// storageCache holds the storages built by NewStorageFromEnvE for each configuration.
+	storageCache     = map[storageConfig]Storage{}
+	storageCacheLock sync.Mutex
+)
+
+// newStorageE builds the storage with the given configuration.
+func newStorageE(t testing.TestingT, config storageConfig) (Storage, error) {
+	var storage Storage
+
+	switch config.backend {
+	case "", "local":
+		storage = LocalStorage{}
+	case "s3":
+		if config.bucket == "" {
+			return nil, fmt.Errorf("%s is set to s3, but %s is not set", TestDataStorageEnvVar, TestDataS3BucketEnvVar)
+		}
+		region := config.region
+		if region == "" {
+			region = "us-east-1"
+		}
+		store, err := NewS3StoreE(t, region, config.endpoint, config.bucket, config.prefix)
+		if err != nil {
+			return nil, err
+		}
+		storage = NewKeyValueStorage(store)
+	default:
+		return nil, fmt.Errorf("invalid value %q for %s: must be one of local or s3", config.backend, TestDataStorageEnvVar)
+	}
+
+	if config.namespace != "" {
+		storage = NewNamespacedStorage(storage, config.namespace)
+	}
+	if config.passphrase != "" {
+		storage = NewEncryptedStorage(storage, config.passphrase)
+	}
+	return storage, nil
+}
+
+// LocalStorage stores test data in files on the local disk, using the keys as file paths.
+type LocalStorage struct{}
+
+// Put writes the data to the file at the given path, creating its folder if necessary.
+func (LocalStorage) Put(key string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(key), 0777); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(key, data, 0644)
+}
+
+// Get reads the file at the given path.
+func (LocalStorage) Get(key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(key)
+	if os.IsNotExist(err) {
+		return nil, ErrTestDataNotFound
+	}
+	return data, err
+}
+
+// Delete removes the file at the given path.
+func (LocalStorage) Delete(key string) error {
+	if err := os.Remove(key); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix removes the folder at the given path and everything in it.
+func (LocalStorage) DeletePrefix(prefix string) error {
+	return os.RemoveAll(prefix)
+}
+
+// KeyValueStore is a minimal client for a key value store, such as S3, Consul or Redis, that can be used as a test data
+// storage backend with NewKeyValueStorage.
+type KeyValueStore interface {
+	// Put stores the value under the given key.
+	Put(key string, value []byte) error
+	// Get returns the value stored under the given key, or ErrTestDataNotFound if there is none.
+	Get(key string) ([]byte, error)
+	// Delete deletes the value stored under the given key, if any.
+	Delete(key string) error
+	// List returns all the keys that start with the given prefix.
+	List(prefix string) ([]string, error)
+}
+
+type keyValueStorage struct {
+	store KeyValueStore
+}
+
+// NewKeyValueStorage returns a Storage that stores test data in the given key value store. Keys are made relative to
+// the working directory, so runners that check out the same code at different paths share the test data of each test.
+// The keys of test folders outside the working directory, such as temp folders with a random path, are not relative,
+// so their test data is not shared (see Storage).
+func NewKeyValueStorage(store KeyValueStore) Storage {
+	return keyValueStorage{store: store}
+}
+
+func (storage keyValueStorage) Put(key string, data []byte) error {
+	return storage.store.Put(storeKey(key), data)
+}
+
+func (storage keyValueStorage) Get(key string) ([]byte, error) {
+	return storage.store.Get(storeKey(key))
+}
+
+func (storage keyValueStorage) Delete(key string) error {
+	return storage.store.Delete(storeKey(key))
+}
+
+func (storage keyValueStorage) DeletePrefix(prefix string) error {
+	keys, err := storage.store.List(storeKey(prefix) + "/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := storage.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeKey converts a test data path into a key for a key value store: a slash separated path, relative to the working
+// directory if the path is inside it, in which parent folder references are replaced with "_parent_".
+func storeKey(path string) string {
+	if filepath.IsAbs(path) {
+		if workingDir, err := os.Getwd(); err == nil {
+			if relative, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(relative, "..") {
+				path = relative
+			}
+		}
+	}
+
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	var key []string
+	for _, part := range parts {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			part = "_parent_"
+		}
+		key = append(key, part)
+	}
+	return strings.Join(key, "/")
+}
+
+// MemoryStore is a KeyValueStore that keeps values in memory. It is mostly useful to share test data between the tests
+// of a single process without touching the disk.
+type MemoryStore struct {
+	lock   sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: map[string][]byte{}}
+}
+
+// Put stores a copy of the value under the given key.
+func (store *MemoryStore) Put(key string, value []byte) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	store.values[key] = append([]byte{}, value...)
+	return nil
+}
+
+// Get returns a copy of the value stored under the given key.
+func (store *MemoryStore) Get(key string) ([]byte, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	value, ok := store.values[key]
+	if !ok {
+		return nil, ErrTestDataNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+// Delete deletes the value stored under the given key.
+func (store *MemoryStore) Delete(key string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	delete(store.values, key)
+	return nil
+}
+
+// List returns the keys that start with the given prefix, in sorted order.
+func (store *MemoryStore) List(prefix string) ([]string, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+	var keys []string
+	for key := range store.values {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+type namespacedStorage struct {
+	storage   Storage
+	namespace string
+}
+
+// NewNamespacedStorage returns a Storage that stores test data in a namespace of the given storage, such as the ID of a
+// CI pipeline. The namespace becomes a folder inside the .test-data folder, e.g.
+// /path/to/test/folder/.test-data/<namespace>/TerraformOptions.json, or, for keys without a .test-data folder, a folder
+// next to the file.
+func NewNamespacedStorage(storage Storage, namespace string) Storage {
+	return namespacedStorage{storage: storage, namespace: namespace}
+}
+
+func (storage namespacedStorage) Put(key string, data []byte) error {
+	return storage.storage.Put(storage.namespaced(key), data)
+}
+
+func (storage namespacedStorage) Get(key string) ([]byte, error) {
+	return storage.storage.Get(storage.namespaced(key))
+}
+
+func (storage namespacedStorage) Delete(key string) error {
+	return storage.storage.Delete(storage.namespaced(key))
+}
+
+func (storage namespacedStorage) DeletePrefix(prefix string) error {
+	if filepath.Base(prefix) == ".test-data" {
+		return storage.storage.DeletePrefix(filepath.Join(prefix, storage.namespace))
+	}
+	return storage.storage.DeletePrefix(storage.namespaced(prefix))
+}
+
+// namespaced inserts the namespace after the .test-data folder of the key, or before the file name if there is none.
+func (storage namespacedStorage) namespaced(key string) string {
+	key = filepath.Clean(key)
+	parts := strings.Split(key, string(filepath.Separator))
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] == ".test-data" {
+			namespaced := append(append(append([]string{}, parts[:i+1]...), storage.namespace), parts[i+1:]...)
+			return strings.Join(namespaced, string(filepath.Separator))
+		}
+	}
+	return filepath.Join(filepath.Dir(key), storage.namespace, filepath.Base(key))
+}
+
+// encryptedDataHeader marks data encrypted by an encrypted storage, and the version of the encryption scheme.
+var encryptedDataHeader = []byte("terratest-encrypted-v1:")
+
+const (
+	// encryptionSaltSize is the size of the random salt with which the key of each piece of test data is derived.
+	encryptionSaltSize = 16
+	// The scrypt parameters recommended for interactive logins, which take less than 100ms per key.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+type encryptedStorage struct {
+	Storage
+	passphrase string
+	// keys caches the keys derived for each salt, so each piece of test data only pays for scrypt once per process.
+	keys *sync.Map
+}
+
+// NewEncryptedStorage returns a Storage that encrypts test data with AES-256-GCM before storing it in the given
+// storage. The key of each piece of test data is derived from the given passphrase with scrypt and a random salt,
+// stored along with the data. All the stages of a test must use the same passphrase.
+func NewEncryptedStorage(storage Storage, passphrase string) Storage {
+	return encryptedStorage{Storage: storage, passphrase: passphrase, keys: &sync.Map{}}
+}
+
+func (storage encryptedStorage) Put(key string, data []byte) error {
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	aead, err := storage.aead(salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	encrypted := append(append(append([]byte{}, encryptedDataHeader...), salt...), nonce...)
+	// The key is used as additional data, so encrypted values can't be swapped between keys.
+	encrypted = aead.Seal(encrypted, nonce, data, []byte(filepath.Base(key)))
+	return storage.Storage.Put(key, encrypted)
+}
+
+func (storage encryptedStorage) Get(key string) ([]byte, error) {
+	encrypted, err := storage.Storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(string(encrypted), string(encryptedDataHeader)) {
+		return nil, EncryptedTestDataError{Key: key, Reason: "it is not encrypted"}
+	}
+	encrypted = encrypted[len(encryptedDataHeader):]
+	if len(encrypted) < encryptionSaltSize {
+		return nil, EncryptedTestDataError{Key: key, Reason: "it is truncated"}
+	}
+
+	salt, encrypted := encrypted[:encryptionSaltSize], encrypted[encryptionSaltSize:]
+	aead, err := storage.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) < aead.NonceSize() {
+		return nil, EncryptedTestDataError{Key: key, Reason: "it is truncated"}
+	}
+
+	nonce, ciphertext := encrypted[:aead.NonceSize()], encrypted[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, []byte(filepath.Base(key)))
+	if err != nil {
+		return nil, EncryptedTestDataError{Key: key, Reason: "it was encrypted with a different key or has been modified"}
+	}
+	return data, nil
+}
+
+// aead returns the AES-GCM cipher with the key derived from the passphrase and the given salt.
+func (storage encryptedStorage) aead(salt []byte) (cipher.AEAD, error) {
+	key, ok := storage.keys.Load(string(salt))
+	if !ok {
+		derived, err := scrypt.Key([]byte(storage.passphrase), salt, scryptN, scryptR, scryptP, 32)
+		if err != nil {
+			return nil, err
+		}
+		key, _ = storage.keys.LoadOrStore(string(salt), derived)
+	}
+
+	block, err := aes.NewCipher(key.([]byte))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedTestDataError is returned when test data can't be decrypted.
+type EncryptedTestDataError struct {
+	Key    string
+	Reason string
+}
+
+func (err EncryptedTestDataError) Error() string {
+	return fmt.Sprintf("Failed to decrypt test data %s: %s", err.Key, err.Reason)
+}
@@ -0,0 +1,248 @@
+package test_structure
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStorage(t *testing.T, storage Storage, folder string) {
+	key := FormatTestDataPath(folder, "value.json")
+	otherKey := FormatTestDataPath(folder, "other.json")
+
+	_, err := storage.Get(key)
+	assert.Equal(t, ErrTestDataNotFound, err)
+
+	require.NoError(t, storage.Put(key, []byte(`"foo"`)))
+	require.NoError(t, storage.Put(otherKey, []byte(`"bar"`)))
+	data, err := storage.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, `"foo"`, string(data))
+
+	require.NoError(t, storage.Delete(key))
+	_, err = storage.Get(key)
+	assert.Equal(t, ErrTestDataNotFound, err)
+	require.NoError(t, storage.Delete(key))
+
+	require.NoError(t, storage.DeletePrefix(filepath.Join(folder, ".test-data")))
+	_, err = storage.Get(otherKey)
+	assert.Equal(t, ErrTestDataNotFound, err)
+}
+
+func TestLocalStorage(t *testing.T) {
+	t.Parallel()
+
+	folder, err := ioutil.TempDir("", "local-storage")
+	require.NoError(t, err)
+	testStorage(t, LocalStorage{}, folder)
+}
+
+func TestKeyValueStorage(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	testStorage(t, NewKeyValueStorage(store), "../../examples/terraform-basic-example")
+
+	keys, err := store.List("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestStoreKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{".test-data/foo.json", ".test-data/foo.json"},
+		{"./examples/.test-data/foo.json", "examples/.test-data/foo.json"},
+		{"../../examples/.test-data/foo.json", "_parent_/_parent_/examples/.test-data/foo.json"},
+		{"/tmp/foo/.test-data/foo.json", "tmp/foo/.test-data/foo.json"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, storeKey(testCase.path), testCase.path)
+	}
+}
+
+func TestNamespacedStorage(t *testing.T) {
+	t.Parallel()
+
+	folder, err := ioutil.TempDir("", "namespaced-storage")
+	require.NoError(t, err)
+	storage := NewNamespacedStorage(LocalStorage{}, "run-1")
+	testStorage(t, storage, folder)
+
+	require.NoError(t, storage.Put(FormatTestDataPath(folder, "value.json"), []byte(`"foo"`)))
+	assert.FileExists(t, filepath.Join(folder, ".test-data", "run-1", "value.json"))
+
+	_, err = NewNamespacedStorage(LocalStorage{}, "run-2").Get(FormatTestDataPath(folder, "value.json"))
+	assert.Equal(t, ErrTestDataNotFound, err)
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryStore()
+	storage := NewEncryptedStorage(NewKeyValueStorage(store), "secret passphrase")
+	testStorage(t, storage, "test")
+
+	key := FormatTestDataPath("test", "Ec2KeyPair.json")
+	require.NoError(t, storage.Put(key, []byte("private key")))
+	encrypted, err := store.Get(storeKey(key))
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "private key")
+
+	data, err := storage.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, "private key", string(data))
+
+	_, err = NewEncryptedStorage(NewKeyValueStorage(store), "other passphrase").Get(key)
+	assert.IsType(t, EncryptedTestDataError{}, err)
+
+	require.NoError(t, store.Put(storeKey(key), []byte("plain text")))
+	_, err = storage.Get(key)
+	assert.IsType(t, EncryptedTestDataError{}, err)
+}
+
+// Each piece of test data is encrypted with its own salt, so the same data encrypted with the same passphrase by two
+// stores gives different ciphertexts, which any store with the passphrase can decrypt.
+func TestEncryptedStorageSalt(t *testing.T) {
+	t.Parallel()
+
+	key := FormatTestDataPath("test", "Ec2KeyPair.json")
+	store1 := NewMemoryStore()
+	store2 := NewMemoryStore()
+	require.NoError(t, NewEncryptedStorage(NewKeyValueStorage(store1), "secret passphrase").Put(key, []byte("private key")))
+	require.NoError(t, NewEncryptedStorage(NewKeyValueStorage(store2), "secret passphrase").Put(key, []byte("private key")))
+
+	encrypted1, err := store1.Get(storeKey(key))
+	require.NoError(t, err)
+	encrypted2, err := store2.Get(storeKey(key))
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted1, encrypted2)
+	salt := func(encrypted []byte) []byte {
+		return encrypted[len(encryptedDataHeader) : len(encryptedDataHeader)+encryptionSaltSize]
+	}
+	assert.NotEqual(t, salt(encrypted1), salt(encrypted2))
+
+	for _, store := range []*MemoryStore{store1, store2} {
+		data, err := NewEncryptedStorage(NewKeyValueStorage(store), "secret passphrase").Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, "private key", string(data))
+	}
+}
+
+// fakeS3 is a minimal stand-in for S3 that supports the requests made by S3Store with path style addressing.
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+type fakeS3ListResult struct {
+	XMLName  xml.Name `xml:"ListBucketResult"`
+	Contents []struct {
+		Key string
+	}
+}
+
+func (s3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s3.lock.Lock()
+	defer s3.lock.Unlock()
+
+	key := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		var result fakeS3ListResult
+		for objectKey := range s3.objects {
+			if strings.HasPrefix(objectKey, r.URL.Query().Get("prefix")) {
+				result.Contents = append(result.Contents, struct{ Key string }{objectKey})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		xml.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodPut:
+		s3.objects[key[1]], _ = ioutil.ReadAll(r.Body)
+	case r.Method == http.MethodGet:
+		object, ok := s3.objects[key[1]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>"))
+			return
+		}
+		w.Write(object)
+	case r.Method == http.MethodDelete:
+		delete(s3.objects, key[1])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestTestDataInS3FromEnv(t *testing.T) {
+	s3 := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(s3)
+	defer server.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "fake")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+	t.Setenv(TestDataStorageEnvVar, "s3")
+	t.Setenv(TestDataS3EndpointEnvVar, server.URL)
+	t.Setenv(TestDataS3BucketEnvVar, "test-data")
+	t.Setenv(TestDataS3PrefixEnvVar, "terratest")
+	t.Setenv(TestDataNamespaceEnvVar, "pipeline-123")
+	t.Setenv(TestDataEncryptionKeyEnvVar, "secret passphrase")
+
+	path := FormatTestDataPath("examples", "data.json")
+	assert.False(t, IsTestDataPresent(t, path))
+
+	expected := testData{Foo: "foo", Bar: true, Baz: map[string]interface{}{"abc": "def"}}
+	SaveTestData(t, path, expected)
+	assert.True(t, IsTestDataPresent(t, path))
+
+	actual := testData{}
+	LoadTestData(t, path, &actual)
+	assert.Equal(t, expected, actual)
+
+	object, ok := s3.objects["terratest/examples/.test-data/pipeline-123/data.json"]
+	require.True(t, ok)
+	assert.NotContains(t, string(object), "foo")
+
+	CleanupTestDataFolder(t, "examples")
+	assert.False(t, IsTestDataPresent(t, path))
+	assert.Empty(t, s3.objects)
+}
+
+func TestNewStorageFromEnvIsCachedPerConfiguration(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "fake")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "fake")
+	t.Setenv(TestDataStorageEnvVar, "s3")
+	t.Setenv(TestDataS3EndpointEnvVar, "http://127.0.0.1:9000")
+	t.Setenv(TestDataS3BucketEnvVar, "test-data")
+
+	s3Store := func() *S3Store {
+		return NewStorageFromEnv(t).(keyValueStorage).store.(*S3Store)
+	}
+
+	first := s3Store()
+	assert.Same(t, first, s3Store())
+
+	t.Setenv(TestDataS3PrefixEnvVar, "other")
+	other := s3Store()
+	assert.True(t, first != other)
+	assert.Same(t, other, s3Store())
+
+	t.Setenv(TestDataS3BucketEnvVar, "")
+	_, err := NewStorageFromEnvE(t)
+	assert.Error(t, err)
+}
@@ -16,6 +16,10 @@ import (
 // SKIP_STAGE_ENV_VAR_PREFIX is the prefix used for skipping stage environment variables.
 const SKIP_STAGE_ENV_VAR_PREFIX = "SKIP_"
 
+// KeepTempEnvVar is the environment variable used to keep the temp folders created by CopyTerraformModuleToTemp after
+// the test completes, e.g. to inspect the Terraform state of a failed test.
+const KeepTempEnvVar = "TERRATEST_KEEP_TEMP"
+
 // RunTestStage executes the given test stage (e.g., setup, teardown, validation) if an environment variable of the name
 // `SKIP_<stageName>` (e.g., SKIP_teardown) is not set.
 func RunTestStage(t testing.TestingT, stageName string, stage func()) {
@@ -94,6 +98,45 @@ func CopyTerraformFolderToTemp(t testing.TestingT, rootFolder string, terraformM
 	return tmpTestFolder
 }
 
+// CopyTerraformModuleToTemp copies the given terraform module folder, and only the local modules it depends on through
+// `source = "../.."` style references, from the given root folder to a randomly-named temp folder, and returns the path
+// to the given terraform module folder within the new temp root folder. This is much faster than
+// CopyTerraformFolderToTemp for large repos with many modules. See files.CopyTerraformModule for the options, such as
+// ignore patterns and hard links.
+//
+// The temp folder is removed when the test completes, unless the TERRATEST_KEEP_TEMP environment variable is set, e.g.
+// to inspect the Terraform state after a failure. As with CopyTerraformFolderToTemp, if any of the SKIP_<stage>
+// environment variables is set, nothing is copied and the path to the original terraform module folder is returned.
+func CopyTerraformModuleToTemp(t testing.TestingT, rootFolder string, terraformModuleFolder string, options files.CopyOptions) string {
+	if SkipStageEnvVarSet() {
+		logger.Logf(t, "A SKIP_XXX environment variable is set. Using original examples folder rather than a temp folder so we can cache data between stages for faster local testing.")
+		return filepath.Join(rootFolder, terraformModuleFolder)
+	}
+
+	tmpRootFolder, err := files.CopyTerraformModuleToTemp(rootFolder, terraformModuleFolder, cleanName(t.Name()), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpTestFolder := filepath.Join(tmpRootFolder, terraformModuleFolder)
+	logger.Logf(t, "Copied terraform module %s and its dependencies to %s", filepath.Join(rootFolder, terraformModuleFolder), tmpTestFolder)
+
+	if tt, ok := t.(interface{ Cleanup(func()) }); ok {
+		tmpDir := filepath.Dir(tmpRootFolder)
+		tt.Cleanup(func() {
+			if os.Getenv(KeepTempEnvVar) != "" {
+				logger.Logf(t, "The %s environment variable is set, so keeping temp folder %s.", KeepTempEnvVar, tmpDir)
+				return
+			}
+			if err := os.RemoveAll(tmpDir); err != nil {
+				logger.Logf(t, "Failed to remove temp folder %s: %v", tmpDir, err)
+			}
+		})
+	}
+
+	return tmpTestFolder
+}
+
 func cleanName(originalName string) string {
 	parts := strings.Split(originalName, "/")
 	return parts[len(parts)-1]
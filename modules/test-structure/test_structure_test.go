@@ -1,6 +1,13 @@
 package test_structure
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/stretchr/testify/assert"
+)
 
 func TestCopyToTempFolder(t *testing.T) {
 	tempFolder := CopyTerraformFolderToTemp(t, "../../", "examples")
@@ -13,3 +20,24 @@ func TestCopySubtestToTempFolder(t *testing.T) {
 		t.Log(tempFolder)
 	})
 }
+
+func TestCopyTerraformModuleToTempIsRemoved(t *testing.T) {
+	var tempFolder string
+	t.Run("Subtest", func(t *testing.T) {
+		tempFolder = CopyTerraformModuleToTemp(t, "../../", "examples/terraform-hello-world-example", files.CopyOptions{})
+		assert.FileExists(t, filepath.Join(tempFolder, "main.tf"))
+	})
+	_, err := os.Stat(tempFolder)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCopyTerraformModuleToTempIsKept(t *testing.T) {
+	t.Setenv(KeepTempEnvVar, "true")
+
+	var tempFolder string
+	t.Run("Subtest", func(t *testing.T) {
+		tempFolder = CopyTerraformModuleToTemp(t, "../../", "examples/terraform-hello-world-example", files.CopyOptions{})
+	})
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(filepath.Dir(tempFolder))))
+	assert.DirExists(t, tempFolder)
+}
@@ -0,0 +1,402 @@
+package test_structure
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gruntwork-io/terratest/modules/aws"
+	"github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/packer"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDataType describes how the values of a type are saved as test data.
+type TestDataType struct {
+	// Name of the type, which is also the name of the file in which Save stores values of the type, without the .json
+	// extension. Defaults to the Go type name, e.g. "mypackage.Config".
+	Name string
+	// Version of the schema of the type. Bump it when the type changes in a way that makes previously saved values
+	// unusable, so Load fails with a StaleTestDataError instead of returning a half-populated value. Defaults to 1.
+	Version int
+	// SecretFields are the JSON paths of fields whose values are redacted when the value is logged, e.g. "Vars" or
+	// "Credentials.Password". Use "*" to match any map key or slice element. Fields can also be marked as secret with
+	// a `terratest:"secret"` struct tag.
+	SecretFields []string
+}
+
+var (
+	testDataTypesLock sync.RWMutex
+	testDataTypes     = map[reflect.Type]TestDataType{}
+)
+
+func init() {
+	RegisterTestDataType[terraform.Options](TestDataType{
+		Name:         "TerraformOptions",
+		SecretFields: []string{"Vars", "EnvVars", "BackendConfig"},
+	})
+	RegisterTestDataType[packer.Options](TestDataType{
+		Name:         "PackerOptions",
+		SecretFields: []string{"Vars", "Env"},
+	})
+	RegisterTestDataType[aws.Ec2Keypair](TestDataType{
+		Name:         "Ec2KeyPair",
+		SecretFields: []string{"PrivateKey"},
+	})
+	RegisterTestDataType[k8s.KubectlOptions](TestDataType{
+		Name:         "KubectlOptions",
+		SecretFields: []string{"Env"},
+	})
+}
+
+// RegisterTestDataType registers how values of the type T are saved by Save and loaded by Load. Pointers are
+// dereferenced, so registering T also applies to *T.
+func RegisterTestDataType[T any](dataType TestDataType) {
+	testDataTypesLock.Lock()
+	defer testDataTypesLock.Unlock()
+	testDataTypes[baseType(reflect.TypeOf((*T)(nil)).Elem())] = dataType
+}
+
+// lookupTestDataType returns the registered TestDataType of the given type, with the defaults filled in.
+func lookupTestDataType(valueType reflect.Type) TestDataType {
+	valueType = baseType(valueType)
+
+	testDataTypesLock.RLock()
+	dataType := testDataTypes[valueType]
+	testDataTypesLock.RUnlock()
+
+	if dataType.Name == "" {
+		dataType.Name = valueType.String()
+	}
+	if dataType.Version == 0 {
+		dataType.Version = 1
+	}
+	return dataType
+}
+
+func baseType(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+	return valueType
+}
+
+// testDataHeader identifies the type and schema version of a value saved by Save.
+type testDataHeader struct {
+	Type    string
+	Version int
+}
+
+// testDataEnvelope is the format in which Save stores values. Test data without a header, such as data saved by
+// older versions of Terratest, is treated as version 1 of the expected type.
+type testDataEnvelope struct {
+	TerratestTestData *testDataHeader
+	Value             json.RawMessage
+}
+
+// StaleTestDataError is returned when test data was saved with a different schema version of its type than the one
+// the code loading it expects, e.g. by a previous run of the test before the type changed.
+type StaleTestDataError struct {
+	Path            string
+	Type            string
+	SavedVersion    int
+	ExpectedVersion int
+}
+
+func (err StaleTestDataError) Error() string {
+	return fmt.Sprintf("The test data in %s was saved with version %d of %s, but version %d is expected. Clean up the test data and re-run the stage that saves it.", err.Path, err.SavedVersion, err.Type, err.ExpectedVersion)
+}
+
+// TestDataTypeMismatchError is returned when the test data loaded from a path was saved as a different type.
+type TestDataTypeMismatchError struct {
+	Path         string
+	SavedType    string
+	ExpectedType string
+}
+
+func (err TestDataTypeMismatchError) Error() string {
+	return fmt.Sprintf("The test data in %s was saved as %s, but %s is expected.", err.Path, err.SavedType, err.ExpectedType)
+}
+
+// Save saves the value into the given folder, under the name of its type (see RegisterTestDataType), along with the
+// schema version of the type. This allows you to create some sort of test data during setup and to reuse it later
+// during validation and teardown with Load. Fails the test on errors.
+func Save[T any](t testing.TestingT, testFolder string, value T) {
+	require.NoError(t, SaveE(t, testFolder, value))
+}
+
+// SaveE saves the value into the given folder, under the name of its type (see RegisterTestDataType), along with the
+// schema version of the type. This allows you to create some sort of test data during setup and to reuse it later
+// during validation and teardown with LoadE.
+func SaveE[T any](t testing.TestingT, testFolder string, value T) error {
+	dataType := lookupTestDataType(reflect.TypeOf((*T)(nil)).Elem())
+	return saveTyped(t, FormatTestDataPath(testFolder, dataType.Name+".json"), dataType, value)
+}
+
+// SaveNamed saves the value into the given folder under the given name, along with the schema version of its type.
+// This allows you to save several values of the same type, e.g. the IDs of several AMIs. Fails the test on errors.
+func SaveNamed[T any](t testing.TestingT, testFolder string, name string, value T) {
+	require.NoError(t, SaveNamedE(t, testFolder, name, value))
+}
+
+// SaveNamedE saves the value into the given folder under the given name, along with the schema version of its type.
+// This allows you to save several values of the same type, e.g. the IDs of several AMIs.
+func SaveNamedE[T any](t testing.TestingT, testFolder string, name string, value T) error {
+	dataType := lookupTestDataType(reflect.TypeOf((*T)(nil)).Elem())
+	return saveTyped(t, formatNamedTestDataPath(testFolder, name), dataType, value)
+}
+
+// Load loads a value saved by Save from the given folder. Fails the test if there is no such value, if it was saved as
+// another type, or if it was saved with another schema version of its type.
+func Load[T any](t testing.TestingT, testFolder string) T {
+	value, err := LoadE[T](t, testFolder)
+	require.NoError(t, err)
+	return value
+}
+
+// LoadE loads a value saved by SaveE from the given folder. Returns a StaleTestDataError if it was saved with another
+// schema version of its type, and a TestDataTypeMismatchError if it was saved as another type.
+func LoadE[T any](t testing.TestingT, testFolder string) (T, error) {
+	dataType := lookupTestDataType(reflect.TypeOf((*T)(nil)).Elem())
+	return loadTyped[T](t, FormatTestDataPath(testFolder, dataType.Name+".json"), dataType)
+}
+
+// LoadNamed loads a value saved by SaveNamed under the given name from the given folder. Fails the test if there is no
+// such value, if it was saved as another type, or if it was saved with another schema version of its type.
+func LoadNamed[T any](t testing.TestingT, testFolder string, name string) T {
+	value, err := LoadNamedE[T](t, testFolder, name)
+	require.NoError(t, err)
+	return value
+}
+
+// LoadNamedE loads a value saved by SaveNamedE under the given name from the given folder. Returns a
+// StaleTestDataError if it was saved with another schema version of its type, and a TestDataTypeMismatchError if it was
+// saved as another type.
+func LoadNamedE[T any](t testing.TestingT, testFolder string, name string) (T, error) {
+	dataType := lookupTestDataType(reflect.TypeOf((*T)(nil)).Elem())
+	return loadTyped[T](t, formatNamedTestDataPath(testFolder, name), dataType)
+}
+
+func saveTyped(t testing.TestingT, path string, dataType TestDataType, value interface{}) error {
+	logger.Logf(t, "Storing %s test data in %s so it can be reused later", dataType.Name, path)
+
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("Failed to convert value %s to JSON: %v", path, err)
+	}
+	logger.Logf(t, "Marshalled JSON: %s", redactJSON(valueBytes, reflect.TypeOf(value), dataType.SecretFields))
+
+	envelopeBytes, err := json.Marshal(testDataEnvelope{
+		TerratestTestData: &testDataHeader{Type: dataType.Name, Version: dataType.Version},
+		Value:             valueBytes,
+	})
+	if err != nil {
+		return err
+	}
+
+	storage, err := testDataStorageE(t)
+	if err != nil {
+		return err
+	}
+	if err := storage.Put(path, envelopeBytes); err != nil {
+		return fmt.Errorf("Failed to save value %s: %v", path, err)
+	}
+	return nil
+}
+
+func loadTyped[T any](t testing.TestingT, path string, dataType TestDataType) (T, error) {
+	logger.Logf(t, "Loading %s test data from %s", dataType.Name, path)
+
+	var value T
+	storage, err := testDataStorageE(t)
+	if err != nil {
+		return value, err
+	}
+	data, err := storage.Get(path)
+	if err != nil {
+		return value, fmt.Errorf("Failed to load value from %s: %v", path, err)
+	}
+
+	header, valueBytes, err := unwrapTestData(data)
+	if err != nil {
+		return value, fmt.Errorf("Failed to parse JSON for value %s: %v", path, err)
+	}
+	if header.Type != "" && header.Type != dataType.Name {
+		return value, TestDataTypeMismatchError{Path: path, SavedType: header.Type, ExpectedType: dataType.Name}
+	}
+	if header.Version != dataType.Version {
+		return value, StaleTestDataError{Path: path, Type: dataType.Name, SavedVersion: header.Version, ExpectedVersion: dataType.Version}
+	}
+
+	if err := json.Unmarshal(valueBytes, &value); err != nil {
+		return value, fmt.Errorf("Failed to parse JSON for value %s: %v", path, err)
+	}
+	return value, nil
+}
+
+// unwrapTestData returns the header and the value of test data saved by Save. Test data without a header is returned
+// as is, with a header for version 1 of an unknown type.
+func unwrapTestData(data []byte) (testDataHeader, []byte, error) {
+	legacy := testDataHeader{Version: 1}
+
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		return legacy, data, nil
+	}
+
+	var envelope testDataEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return legacy, nil, err
+	}
+	if envelope.TerratestTestData == nil {
+		return legacy, data, nil
+	}
+	return *envelope.TerratestTestData, envelope.Value, nil
+}
+
+// redactedValue replaces the values of secret fields in logs.
+const redactedValue = "<redacted>"
+
+// SecretKeyPattern matches the names of fields and map keys whose values are always redacted when test data is
+// logged, whatever their type.
+var SecretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|private_?key|access_?key|credential)`)
+
+// redactJSON returns the given JSON with the values of secret fields replaced: the fields marked with a
+// `terratest:"secret"` tag in valueType, the given secret field paths, and the fields and map keys that match
+// SecretKeyPattern. If the JSON can't be parsed, it is entirely redacted.
+func redactJSON(data []byte, valueType reflect.Type, secretFields []string) string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return redactedValue
+	}
+
+	paths := append([]string{}, secretFields...)
+	if valueType != nil {
+		paths = append(paths, secretTaggedFields(valueType, "", map[reflect.Type]bool{})...)
+	}
+	for _, path := range paths {
+		value = redactPath(value, strings.Split(path, "."))
+	}
+	value = redactSecretKeys(value)
+
+	var redacted strings.Builder
+	encoder := json.NewEncoder(&redacted)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return redactedValue
+	}
+	return strings.TrimSuffix(redacted.String(), "\n")
+}
+
+// secretTaggedFields returns the JSON paths of the fields tagged with `terratest:"secret"` in the given type.
+func secretTaggedFields(valueType reflect.Type, prefix string, visited map[reflect.Type]bool) []string {
+	valueType = baseType(valueType)
+	if visited[valueType] {
+		return nil
+	}
+	visited[valueType] = true
+	defer delete(visited, valueType)
+
+	join := func(name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+
+	switch valueType.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return secretTaggedFields(valueType.Elem(), join("*"), visited)
+	case reflect.Struct:
+	default:
+		return nil
+	}
+
+	var paths []string
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := field.Name
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if jsonName != "" {
+			name = jsonName
+		} else if field.Anonymous && baseType(field.Type).Kind() == reflect.Struct {
+			// The fields of embedded structs are promoted to the parent object.
+			paths = append(paths, secretTaggedFields(field.Type, prefix, visited)...)
+			continue
+		}
+
+		if field.Tag.Get("terratest") == "secret" {
+			paths = append(paths, join(name))
+		} else {
+			paths = append(paths, secretTaggedFields(field.Type, join(name), visited)...)
+		}
+	}
+	return paths
+}
+
+// redactPath replaces the value at the given path in the decoded JSON value. If that value is an object, such as the
+// Vars of terraform.Options, its keys are kept and all its values are replaced.
+func redactPath(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		switch typed := value.(type) {
+		case nil:
+			return nil
+		case map[string]interface{}:
+			for key, child := range typed {
+				if child != nil {
+					typed[key] = redactedValue
+				}
+			}
+			return typed
+		}
+		return redactedValue
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if path[0] == "*" || path[0] == key {
+				typed[key] = redactPath(child, path[1:])
+			}
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, child := range typed {
+				typed[i] = redactPath(child, path[1:])
+			}
+		}
+	}
+	return value
+}
+
+// redactSecretKeys replaces the values of the keys matching SecretKeyPattern anywhere in the decoded JSON value.
+func redactSecretKeys(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if SecretKeyPattern.MatchString(key) && child != nil {
+				typed[key] = redactedValue
+			} else {
+				typed[key] = redactSecretKeys(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range typed {
+			typed[i] = redactSecretKeys(child)
+		}
+	}
+	return value
+}
@@ -0,0 +1,184 @@
+package testing
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// RecordingT is an implementation of TestingT that records failures instead of reporting them to go test. Use it to
+// check that a helper fails a test, or to call Terratest functions outside of go test, e.g. in CLI tools, together
+// with Try. As with testing.T, FailNow, Fatal and Fatalf stop the calling goroutine with runtime.Goexit, so call
+// functions that may fail through Try when the calling goroutine must keep running. RecordingT is safe for concurrent
+// use.
+type RecordingT struct {
+	name string
+
+	lock     sync.Mutex
+	failed   bool
+	messages []string
+	cleanups []func()
+}
+
+var _ TestingT = (*RecordingT)(nil)
+
+// NewRecordingT returns a RecordingT with the given test name.
+func NewRecordingT(name string) *RecordingT {
+	return &RecordingT{name: name}
+}
+
+// Fail marks the test as having failed.
+func (t *RecordingT) Fail() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.failed = true
+}
+
+// FailNow marks the test as having failed and stops the calling goroutine with runtime.Goexit.
+func (t *RecordingT) FailNow() {
+	t.Fail()
+	runtime.Goexit()
+}
+
+// Fatal records the message, formatted as with fmt.Sprint, and calls FailNow.
+func (t *RecordingT) Fatal(args ...interface{}) {
+	t.record(fmt.Sprint(args...))
+	t.FailNow()
+}
+
+// Fatalf records the message, formatted as with fmt.Sprintf, and calls FailNow.
+func (t *RecordingT) Fatalf(format string, args ...interface{}) {
+	t.record(fmt.Sprintf(format, args...))
+	t.FailNow()
+}
+
+// Error records the message, formatted as with fmt.Sprint, and marks the test as having failed.
+func (t *RecordingT) Error(args ...interface{}) {
+	t.record(fmt.Sprint(args...))
+}
+
+// Errorf records the message, formatted as with fmt.Sprintf, and marks the test as having failed.
+func (t *RecordingT) Errorf(format string, args ...interface{}) {
+	t.record(fmt.Sprintf(format, args...))
+}
+
+// Name returns the name of the test.
+func (t *RecordingT) Name() string {
+	return t.name
+}
+
+// Helper does nothing. It exists so helpers that mark themselves with Helper, such as the logger, can be used.
+func (t *RecordingT) Helper() {}
+
+// Cleanup registers a function to call when RunCleanups is called.
+func (t *RecordingT) Cleanup(cleanup func()) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.cleanups = append(t.cleanups, cleanup)
+}
+
+// RunCleanups calls the functions registered with Cleanup, in reverse order, and forgets them.
+func (t *RecordingT) RunCleanups() {
+	for {
+		t.lock.Lock()
+		if len(t.cleanups) == 0 {
+			t.lock.Unlock()
+			return
+		}
+		cleanup := t.cleanups[len(t.cleanups)-1]
+		t.cleanups = t.cleanups[:len(t.cleanups)-1]
+		t.lock.Unlock()
+
+		cleanup()
+	}
+}
+
+// Failed returns true if the test has failed.
+func (t *RecordingT) Failed() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.failed
+}
+
+// Messages returns the messages passed to Fatal, Fatalf, Error and Errorf, in order.
+func (t *RecordingT) Messages() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]string{}, t.messages...)
+}
+
+// Err returns a TestFailedError with the recorded messages if the test has failed, or nil otherwise.
+func (t *RecordingT) Err() error {
+	if !t.Failed() {
+		return nil
+	}
+	return TestFailedError{Name: t.name, Messages: t.Messages()}
+}
+
+func (t *RecordingT) record(message string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.failed = true
+	t.messages = append(t.messages, message)
+}
+
+// Try calls the given function with a new RecordingT with the given name, and returns the failures of the function as
+// a TestFailedError, or nil if it didn't fail. The function runs in its own goroutine, so that Fatal and FailNow only
+// stop the function, not the caller, and panics are recovered and returned as failures. The functions registered with
+// Cleanup are called before Try returns. This makes it possible to call Terratest functions that fail the test, i.e.
+// those without an E suffix, from code that handles errors, such as CLI tools and long-running programs:
+//
+//	err := testing.Try("deploy", func(t testing.TestingT) {
+//		terraform.InitAndApply(t, terraformOptions)
+//	})
+func Try(name string, fn func(t TestingT)) error {
+	_, err := TryValue(name, func(t TestingT) struct{} {
+		fn(t)
+		return struct{}{}
+	})
+	return err
+}
+
+// TryValue calls the given function like Try and also returns the value it returned, or the zero value if it failed:
+//
+//	ip, err := testing.TryValue("get-ip", func(t testing.TestingT) string {
+//		return terraform.Output(t, terraformOptions, "public_ip")
+//	})
+func TryValue[T any](name string, fn func(t TestingT) T) (T, error) {
+	t := NewRecordingT(name)
+
+	var result T
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				t.record(fmt.Sprintf("panic: %v", recovered))
+			}
+		}()
+		result = fn(t)
+	}()
+	<-done
+
+	t.RunCleanups()
+
+	if err := t.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// TestFailedError is returned by Try and RecordingT.Err when a test failed.
+type TestFailedError struct {
+	Name     string
+	Messages []string
+}
+
+func (err TestFailedError) Error() string {
+	if len(err.Messages) == 0 {
+		return fmt.Sprintf("%s failed", err.Name)
+	}
+	return fmt.Sprintf("%s failed: %s", err.Name, strings.Join(err.Messages, "; "))
+}
@@ -0,0 +1,59 @@
+package testing
+
+import (
+	"errors"
+	gotesting "testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingTRecordsFailures(t *gotesting.T) {
+	t.Parallel()
+
+	recording := NewRecordingT("recorded")
+	assert.Equal(t, "recorded", recording.Name())
+	assert.False(t, recording.Failed())
+	assert.NoError(t, recording.Err())
+
+	recording.Errorf("first %d", 1)
+	recording.Error("second")
+	assert.True(t, recording.Failed())
+	assert.Equal(t, []string{"first 1", "second"}, recording.Messages())
+	assert.Equal(t, TestFailedError{Name: "recorded", Messages: []string{"first 1", "second"}}, recording.Err())
+}
+
+func TestTry(t *gotesting.T) {
+	t.Parallel()
+
+	var cleanedUp []string
+	err := Try("fatal", func(t TestingT) {
+		t.(*RecordingT).Cleanup(func() { cleanedUp = append(cleanedUp, "first") })
+		t.(*RecordingT).Cleanup(func() { cleanedUp = append(cleanedUp, "second") })
+		require.NoError(t, errors.New("boom"))
+		cleanedUp = append(cleanedUp, "not reached")
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fatal failed: ")
+	assert.Contains(t, err.Error(), "boom")
+	assert.Equal(t, []string{"second", "first"}, cleanedUp)
+
+	assert.NoError(t, Try("passed", func(t TestingT) {}))
+	assert.Equal(t, TestFailedError{Name: "failed", Messages: []string{}}, Try("failed", func(t TestingT) { t.FailNow() }))
+	assert.Equal(t, TestFailedError{Name: "panicked", Messages: []string{"panic: oops"}}, Try("panicked", func(t TestingT) { panic("oops") }))
+}
+
+func TestTryValue(t *gotesting.T) {
+	t.Parallel()
+
+	value, err := TryValue("passed", func(t TestingT) int { return 42 })
+	assert.NoError(t, err)
+	assert.Equal(t, 42, value)
+
+	value, err = TryValue("failed", func(t TestingT) int {
+		t.Error("wrong answer")
+		return 41
+	})
+	assert.Equal(t, TestFailedError{Name: "failed", Messages: []string{"wrong answer"}}, err)
+	assert.Equal(t, 0, value)
+}